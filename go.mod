@@ -12,6 +12,8 @@ require (
 	github.com/uptrace/bun/driver/pgdriver v1.1.9
 	go.uber.org/zap v1.24.0
 	golang.org/x/image v0.3.0
+	golang.org/x/net v0.8.0
+	golang.org/x/text v0.8.0
 )
 
 require (
@@ -57,8 +59,6 @@ require (
 	github.com/vmihailenco/msgpack/v5 v5.3.5 // indirect
 	github.com/vmihailenco/tagparser/v2 v2.0.0 // indirect
 	golang.org/x/crypto v0.6.0 // indirect
-	golang.org/x/net v0.8.0 // indirect
 	golang.org/x/sys v0.8.0 // indirect
-	golang.org/x/text v0.8.0 // indirect
 	mellium.im/sasl v0.3.0 // indirect
 )
@@ -0,0 +1,68 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/lesnoi-kot/karten-backend/src/userservice"
+)
+
+type AgendaTaskDTO struct {
+	ID           string     `json:"id"`
+	Key          string     `json:"key"`
+	Name         string     `json:"name"`
+	Priority     int16      `json:"priority"`
+	DueDate      *time.Time `json:"due_date"`
+	Estimate     *float64   `json:"estimate"`
+	DateCreated  time.Time  `json:"date_created"`
+	TaskListID   string     `json:"task_list_id"`
+	TaskListName string     `json:"task_list_name"`
+	BoardID      string     `json:"board_id"`
+	BoardName    string     `json:"board_name"`
+	ProjectID    string     `json:"project_id"`
+	ProjectName  string     `json:"project_name"`
+}
+
+type AgendaDTO struct {
+	Date         string           `json:"date"`
+	TimeZone     string           `json:"time_zone"`
+	Overdue      []*AgendaTaskDTO `json:"overdue"`
+	Today        []*AgendaTaskDTO `json:"today"`
+	Upcoming     []*AgendaTaskDTO `json:"upcoming"`
+	HighPriority []*AgendaTaskDTO `json:"high_priority"`
+}
+
+func (api *APIService) getAgenda(c echo.Context) error {
+	query := struct {
+		TimeZone string `query:"tz"`
+		Days     int    `query:"days" validate:"min=0,max=90"`
+		Limit    int    `query:"limit" validate:"min=1,max=200"`
+	}{
+		Days:  7,
+		Limit: 50,
+	}
+	if err := c.Bind(&query); err != nil {
+		return err
+	}
+	if err := c.Validate(&query); err != nil {
+		return err
+	}
+
+	loc, err := time.LoadLocation(query.TimeZone)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Unknown time zone")
+	}
+
+	agenda, err := api.mustGetUserService(c).GetAgenda(&userservice.GetAgendaOptions{
+		Now:          time.Now(),
+		Location:     loc,
+		UpcomingDays: query.Days,
+		Limit:        query.Limit,
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, OK(agendaToDTO(agenda)))
+}
@@ -0,0 +1,149 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/lesnoi-kot/karten-backend/src/userservice"
+)
+
+// Seconds by percentile name, e.g. "p85"
+type DistributionDTO struct {
+	Count       int              `json:"count"`
+	Percentiles map[string]int64 `json:"percentiles"`
+}
+
+type ListTimeDTO struct {
+	TaskListID   string `json:"task_list_id"`
+	TaskListName string `json:"task_list_name"`
+	DistributionDTO
+}
+
+type FlowMetricsDTO struct {
+	From        time.Time        `json:"from"`
+	To          time.Time        `json:"to"`
+	StartListID string           `json:"start_list_id"`
+	EndListID   string           `json:"end_list_id"`
+	LeadTime    *DistributionDTO `json:"lead_time"`
+	CycleTime   *DistributionDTO `json:"cycle_time"`
+	Lists       []*ListTimeDTO   `json:"lists"`
+}
+
+func (api *APIService) getBoardFlowMetrics(c echo.Context) error {
+	var query struct {
+		From        string `query:"from" validate:"required"`
+		To          string `query:"to" validate:"required"`
+		TimeZone    string `query:"tz"`
+		StartListID string `query:"start_list_id"`
+		EndListID   string `query:"end_list_id"`
+	}
+	if err := c.Bind(&query); err != nil {
+		return err
+	}
+	if err := c.Validate(&query); err != nil {
+		return err
+	}
+
+	from, to, _, err := parseReportRange(query.From, query.To, query.TimeZone)
+	if err != nil {
+		return err
+	}
+
+	boardID := c.Param("id")
+	metrics, err := api.mustGetUserService(c).GetBoardFlowMetrics(&userservice.GetBoardFlowMetricsOptions{
+		BoardID:     boardID,
+		From:        from.UTC(),
+		To:          to.UTC(),
+		StartListID: query.StartListID,
+		EndListID:   query.EndListID,
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, OK(flowMetricsToDTO(metrics, from, to)))
+}
+
+type CumulativeFlowListDTO struct {
+	TaskListID   string `json:"task_list_id"`
+	TaskListName string `json:"task_list_name"`
+	Archived     bool   `json:"archived"`
+	Counts       []int  `json:"counts"`
+}
+
+type CumulativeFlowDTO struct {
+	Dates []string                 `json:"dates"`
+	Lists []*CumulativeFlowListDTO `json:"lists"`
+}
+
+type BurndownPointDTO struct {
+	Date              string   `json:"date"`
+	Remaining         int      `json:"remaining"`
+	Done              int      `json:"done"`
+	RemainingEstimate *float64 `json:"remaining_estimate"`
+	DoneEstimate      *float64 `json:"done_estimate"`
+}
+
+type BurndownDTO struct {
+	DoneListID string              `json:"done_list_id"`
+	Points     []*BurndownPointDTO `json:"points"`
+}
+
+// Snapshots are taken per UTC day.
+const maxSnapshotDays = 366
+
+func parseSnapshotsQuery(c echo.Context) (*userservice.GetBoardSnapshotsOptions, error) {
+	var query struct {
+		From string `query:"from" validate:"required"`
+		To   string `query:"to" validate:"required"`
+	}
+	if err := c.Bind(&query); err != nil {
+		return nil, err
+	}
+	if err := c.Validate(&query); err != nil {
+		return nil, err
+	}
+
+	from, to, _, err := parseReportRange(query.From, query.To, "UTC")
+	if err != nil {
+		return nil, err
+	}
+	if to.Sub(from) > maxSnapshotDays*24*time.Hour {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Date range is too long")
+	}
+
+	return &userservice.GetBoardSnapshotsOptions{
+		BoardID: c.Param("id"),
+		From:    from,
+		To:      to.AddDate(0, 0, -1),
+	}, nil
+}
+
+func (api *APIService) getBoardCumulativeFlow(c echo.Context) error {
+	opts, err := parseSnapshotsQuery(c)
+	if err != nil {
+		return err
+	}
+
+	flow, err := api.mustGetUserService(c).GetBoardCumulativeFlow(opts)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, OK(cumulativeFlowToDTO(flow)))
+}
+
+func (api *APIService) getBoardBurndown(c echo.Context) error {
+	opts, err := parseSnapshotsQuery(c)
+	if err != nil {
+		return err
+	}
+
+	burndown, err := api.mustGetUserService(c).GetBoardBurndown(opts)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, OK(burndownToDTO(burndown)))
+}
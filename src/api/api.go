@@ -72,6 +72,7 @@ func NewAPI(cfg APIConfig) *APIService {
 	corsConfig := middleware.CORSConfig{
 		AllowOrigins:     cfg.AllowOrigins,
 		AllowCredentials: true, // Allow cookies in cross origin requests.
+		ExposeHeaders:    []string{wipLimitWarningHeader},
 	}
 
 	csrfConfig := middleware.CSRFConfig{
@@ -79,6 +80,7 @@ func NewAPI(cfg APIConfig) *APIService {
 		CookieDomain:   cfg.CookieDomain,
 		CookieSecure:   !cfg.Debug && cfg.CookieDomain != "" && strings.HasPrefix(cfg.FrontendURL, "https://"),
 		CookiePath:     "/",
+		Skipper:        api.isInboundRequest,
 	}
 
 	sessionStore := sessions.NewFilesystemStore(
@@ -144,10 +146,20 @@ func initRoutes(api *APIService) {
 		root.POST("/login", api.guestLogIn)
 	}
 
+	root.GET("/agenda", api.getAgenda, requireAuth)
+	root.GET("/search", api.search, requireAuth)
+	root.GET("/calendar/:token", api.getFeedCalendar)
+	root.POST("/inbound/email", api.receiveEmail)
+	root.POST("/inbound/webhooks/:token", api.receiveIncomingWebhook, incomingWebhookLimits()...)
+	root.POST("/inbound/forge/:id", api.receiveForgeEvent, forgeEventLimits()...)
+
 	users := root.Group("/users", requireAuth)
 	users.GET("/self", api.getCurrentUser, injectUser)
 	users.DELETE("/self", api.deleteUser)
 	users.POST("/self/logout", api.logOut)
+	users.GET("/self/feeds", api.getFeedTokens)
+	users.POST("/self/feeds", api.addFeedToken)
+	users.DELETE("/self/feeds/:id", api.deleteFeedToken)
 
 	projects := root.Group("/projects", requireAuth)
 	projects.GET("", api.getProjects)
@@ -157,16 +169,47 @@ func initRoutes(api *APIService) {
 	projects.PATCH("/:id", api.editProject)
 	projects.DELETE("/:id", api.deleteProject)
 	projects.POST("/:id/boards", api.addBoard)
+	projects.POST("/:id/import", api.importBoard)
+	projects.POST("/:id/import/trello", api.importTrelloBoard)
+	projects.GET("/:id/csv", api.exportProjectCSV)
 	projects.DELETE("/:id/boards", api.clearProject)
+	projects.GET("/:id/archive", api.getProjectArchive)
+	projects.GET("/:id/timeline", api.getProjectTimeline)
+	projects.POST("/:id/timeline/shift", api.shiftProjectTasks)
+	projects.GET("/:id/views", api.getProjectViews)
+	projects.POST("/:id/views", api.addProjectView)
+	projects.GET("/:id/webhooks", api.getWebhooks)
+	projects.POST("/:id/webhooks", api.addWebhook)
+	projects.GET("/:id/forge-hooks", api.getForgeHooks)
+	projects.POST("/:id/forge-hooks", api.addForgeHook)
 
 	boards := root.Group("/boards", requireAuth)
 	boards.GET("/:id", api.getBoard)
 	boards.PATCH("/:id", api.editBoard)
 	boards.DELETE("/:id", api.deleteBoard)
+	boards.GET("/:id/export", api.exportBoard)
+	boards.GET("/:id/csv", api.exportBoardCSV)
+	boards.GET("/:id/inbound-email", api.getBoardInboundEmail)
+	boards.PUT("/:id/inbound-email", api.resetBoardInboundEmail)
+	boards.DELETE("/:id/inbound-email", api.disableBoardInboundEmail)
+	boards.POST("/:id/csv", api.importBoardCSV)
 	boards.PUT("/:id/favorite", api.favoriteBoard)
 	boards.DELETE("/:id/favorite", api.unfavoriteBoard)
+	boards.PUT("/:id/archive", api.archiveBoard)
+	boards.DELETE("/:id/archive", api.unarchiveBoard)
 	boards.POST("/:id/task-lists", api.addTaskList)
 	boards.POST("/:id/labels", api.addLabel)
+	boards.GET("/:id/analytics/flow", api.getBoardFlowMetrics)
+	boards.GET("/:id/analytics/cfd", api.getBoardCumulativeFlow)
+	boards.GET("/:id/analytics/burndown", api.getBoardBurndown)
+	boards.GET("/:id/sprints", api.getSprints)
+	boards.POST("/:id/sprints", api.addSprint)
+	boards.GET("/:id/lanes", api.getLanes)
+	boards.POST("/:id/lanes", api.addLane)
+	boards.GET("/:id/views", api.getBoardViews)
+	boards.POST("/:id/views", api.addBoardView)
+	boards.GET("/:id/automations", api.getAutomationRules)
+	boards.POST("/:id/automations", api.addAutomationRule)
 
 	taskLists := root.Group("/task-lists", requireAuth)
 	taskLists.GET("/:id", api.getTaskList)
@@ -174,8 +217,16 @@ func initRoutes(api *APIService) {
 	taskLists.DELETE("/:id", api.deleteTaskList)
 	taskLists.POST("/:id/tasks", api.addTask)
 	taskLists.DELETE("/:id/tasks", api.clearTaskList)
+	taskLists.PUT("/:id/archive", api.archiveTaskList)
+	taskLists.DELETE("/:id/archive", api.unarchiveTaskList)
+	taskLists.GET("/:id/incoming-webhooks", api.getIncomingWebhooks)
+	taskLists.POST("/:id/incoming-webhooks", api.addIncomingWebhook)
+
+	incomingWebhooks := root.Group("/incoming-webhooks", requireAuth)
+	incomingWebhooks.DELETE("/:id", api.deleteIncomingWebhook)
 
 	tasks := root.Group("/tasks", requireAuth)
+	tasks.GET("/by-key/:key", api.getTaskByKey)
 	tasks.GET("/:id", api.getTask)
 	tasks.PATCH("/:id", api.editTask)
 	tasks.DELETE("/:id", api.deleteTask)
@@ -186,6 +237,44 @@ func initRoutes(api *APIService) {
 	tasks.DELETE("/:id/tracking", api.stopTaskTracking)
 	tasks.POST("/:id/labels", api.addLabelToTask)
 	tasks.DELETE("/:id/labels", api.deleteLabelFromTask)
+	tasks.PUT("/:id/archive", api.archiveTask)
+	tasks.DELETE("/:id/archive", api.unarchiveTask)
+	tasks.GET("/:id/revisions", api.getTaskRevisions)
+	tasks.POST("/:id/revisions/:rev/restore", api.restoreTaskRevision)
+	tasks.POST("/:id/dependencies", api.addTaskDependency)
+	tasks.DELETE("/:id/dependencies/:depends_on_id", api.deleteTaskDependency)
+	tasks.GET("/:id/links", api.getTaskLinks)
+
+	sprints := root.Group("/sprints", requireAuth)
+	sprints.GET("/:id", api.getSprint)
+	sprints.PATCH("/:id", api.editSprint)
+	sprints.DELETE("/:id", api.deleteSprint)
+	sprints.POST("/:id/close", api.closeSprint)
+
+	lanes := root.Group("/lanes", requireAuth)
+	lanes.PATCH("/:id", api.editLane)
+	lanes.DELETE("/:id", api.deleteLane)
+
+	views := root.Group("/views", requireAuth)
+	views.GET("/:id", api.getView)
+	views.PATCH("/:id", api.editView)
+	views.DELETE("/:id", api.deleteView)
+
+	automations := root.Group("/automations", requireAuth)
+	automations.GET("/:id", api.getAutomationRule)
+	automations.PATCH("/:id", api.editAutomationRule)
+	automations.DELETE("/:id", api.deleteAutomationRule)
+	automations.GET("/:id/runs", api.getAutomationRuns)
+
+	webhooks := root.Group("/webhooks", requireAuth)
+	webhooks.GET("/:id", api.getWebhook)
+	webhooks.PATCH("/:id", api.editWebhook)
+	webhooks.DELETE("/:id", api.deleteWebhook)
+	webhooks.GET("/:id/deliveries", api.getWebhookDeliveries)
+	webhooks.POST("/:id/deliveries/:delivery_id/redeliver", api.redeliverWebhook)
+
+	forgeHooks := root.Group("/forge-hooks", requireAuth)
+	forgeHooks.DELETE("/:id", api.deleteForgeHook)
 
 	comments := root.Group("/comments", requireAuth)
 	comments.GET("/:id", api.getComment)
@@ -202,6 +291,14 @@ func initRoutes(api *APIService) {
 	labels := root.Group("/labels", requireAuth)
 	labels.PATCH("/:id", api.editLabel)
 	labels.DELETE("/:id", api.deleteLabel)
+
+	reports := root.Group("/reports", requireAuth)
+	reports.GET("/timesheet", api.getTimesheet)
+	reports.GET("/estimates", api.getEstimateReport)
+
+	trash := root.Group("/trash", requireAuth)
+	trash.GET("", api.getTrash)
+	trash.POST("/restore", api.restoreFromTrash)
 }
 
 func (api *APIService) ping(c echo.Context) error {
@@ -244,6 +341,7 @@ func (api *APIService) getUserService(c echo.Context) (*userservice.UserService,
 		Context: context.Background(),
 		UserID:  userID,
 		Store:   api.store,
+		Logger:  api.logger,
 	}, nil
 }
 
@@ -257,5 +355,6 @@ func (api *APIService) mustGetUserService(c echo.Context) *userservice.UserServi
 		Context: context.Background(),
 		UserID:  userID,
 		Store:   api.store,
+		Logger:  api.logger,
 	}
 }
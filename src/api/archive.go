@@ -0,0 +1,140 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/lesnoi-kot/karten-backend/src/userservice"
+	"github.com/samber/lo"
+)
+
+type ArchivedItemDTO struct {
+	Type         string    `json:"type"`
+	ID           string    `json:"id"`
+	Name         string    `json:"name"`
+	BoardID      string    `json:"board_id"`
+	BoardName    string    `json:"board_name"`
+	TaskListID   string    `json:"task_list_id,omitempty"`
+	TaskListName string    `json:"task_list_name,omitempty"`
+	DateArchived time.Time `json:"date_archived"`
+}
+
+type ArchiveDTO struct {
+	Items []*ArchivedItemDTO `json:"items"`
+	Total int                `json:"total"`
+}
+
+func (api *APIService) getProjectArchive(c echo.Context) error {
+	var query struct {
+		Query  string `query:"q"`
+		Type   string `query:"type" validate:"omitempty,oneof=board task_list task"`
+		Limit  int    `query:"limit" validate:"min=0,max=100"`
+		Offset int    `query:"offset" validate:"min=0"`
+	}
+	if err := c.Bind(&query); err != nil {
+		return err
+	}
+	if err := c.Validate(&query); err != nil {
+		return err
+	}
+	if query.Limit == 0 {
+		query.Limit = 20
+	}
+
+	projectID := c.Param("id")
+	userService := api.mustGetUserService(c)
+	items, total, err := userService.GetProjectArchive(&userservice.GetProjectArchiveOptions{
+		ProjectID: projectID,
+		Query:     query.Query,
+		Type:      query.Type,
+		Limit:     query.Limit,
+		Offset:    query.Offset,
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, OK(&ArchiveDTO{
+		Items: lo.Map(items, func(item *userservice.ArchivedItem, _ int) *ArchivedItemDTO {
+			return archivedItemToDTO(item)
+		}),
+		Total: total,
+	}))
+}
+
+func (api *APIService) archiveBoard(c echo.Context) error {
+	boardID := c.Param("id")
+	if err := api.mustGetUserService(c).ArchiveBoard(boardID); err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusOK)
+}
+
+func (api *APIService) unarchiveBoard(c echo.Context) error {
+	boardID := c.Param("id")
+	if err := api.mustGetUserService(c).UnarchiveBoard(boardID); err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusOK)
+}
+
+func (api *APIService) archiveTaskList(c echo.Context) error {
+	taskListID := c.Param("id")
+	if err := api.mustGetUserService(c).ArchiveTaskList(taskListID); err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusOK)
+}
+
+func (api *APIService) unarchiveTaskList(c echo.Context) error {
+	var body struct {
+		BoardID *string `json:"board_id"`
+	}
+	if err := c.Bind(&body); err != nil {
+		return err
+	}
+
+	taskListID := c.Param("id")
+	err := api.mustGetUserService(c).UnarchiveTaskList(&userservice.UnarchiveTaskListOptions{
+		TaskListID: taskListID,
+		BoardID:    body.BoardID,
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusOK)
+}
+
+func (api *APIService) archiveTask(c echo.Context) error {
+	taskID := c.Param("id")
+	if err := api.mustGetUserService(c).ArchiveTask(taskID); err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusOK)
+}
+
+func (api *APIService) unarchiveTask(c echo.Context) error {
+	var body struct {
+		TaskListID *string `json:"task_list_id"`
+	}
+	if err := c.Bind(&body); err != nil {
+		return err
+	}
+
+	taskID := c.Param("id")
+	err := api.mustGetUserService(c).UnarchiveTask(&userservice.UnarchiveTaskOptions{
+		TaskID:     taskID,
+		TaskListID: body.TaskListID,
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusOK)
+}
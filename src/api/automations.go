@@ -0,0 +1,224 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/lesnoi-kot/karten-backend/src/store"
+	"github.com/lesnoi-kot/karten-backend/src/userservice"
+	"github.com/samber/lo"
+)
+
+type AutomationTriggerDTO struct {
+	Type           string `json:"type" validate:"required,oneof=task_moved label_added due_date_passed schedule"`
+	TaskListID     string `json:"task_list_id,omitempty"`
+	FromTaskListID string `json:"from_task_list_id,omitempty"`
+	LabelID        int    `json:"label_id,omitempty"`
+	IntervalHours  int    `json:"interval_hours,omitempty" validate:"min=0"`
+}
+
+type AutomationConditionDTO struct {
+	Type       string `json:"type" validate:"required,oneof=in_list not_in_list has_label lacks_label min_priority in_list_for_days overdue"`
+	TaskListID string `json:"task_list_id,omitempty"`
+	LabelID    int    `json:"label_id,omitempty"`
+	Priority   int16  `json:"priority,omitempty" validate:"min=0,max=4"`
+	Days       int    `json:"days,omitempty" validate:"min=0"`
+}
+
+type AutomationActionDTO struct {
+	Type       string `json:"type" validate:"required,oneof=move add_label remove_label set_due_date comment archive"`
+	TaskListID string `json:"task_list_id,omitempty"`
+	LabelID    int    `json:"label_id,omitempty"`
+	Days       *int   `json:"days,omitempty"`
+	Text       string `json:"text,omitempty" validate:"max=4096"`
+}
+
+type AutomationRuleDTO struct {
+	ID          string                    `json:"id"`
+	BoardID     string                    `json:"board_id"`
+	Name        string                    `json:"name"`
+	Enabled     bool                      `json:"enabled"`
+	Trigger     *AutomationTriggerDTO     `json:"trigger"`
+	Conditions  []*AutomationConditionDTO `json:"conditions"`
+	Actions     []*AutomationActionDTO    `json:"actions"`
+	LastRunAt   *time.Time                `json:"last_run_at"`
+	DateCreated time.Time                 `json:"date_created"`
+}
+
+type AutomationRunDTO struct {
+	ID          int64     `json:"id"`
+	RuleID      string    `json:"rule_id"`
+	TaskID      *string   `json:"task_id"`
+	Trigger     string    `json:"trigger"`
+	Status      string    `json:"status"`
+	Error       string    `json:"error,omitempty"`
+	Depth       int       `json:"depth"`
+	DateCreated time.Time `json:"date_created"`
+}
+
+func (api *APIService) getAutomationRules(c echo.Context) error {
+	rules, err := api.mustGetUserService(c).GetAutomationRules(c.Param("id"))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, OK(lo.Map(rules, func(rule *store.AutomationRule, _ int) *AutomationRuleDTO {
+		return automationRuleToDTO(rule)
+	})))
+}
+
+func (api *APIService) addAutomationRule(c echo.Context) error {
+	var body struct {
+		Name       string                    `json:"name" validate:"required,min=1,max=64"`
+		Enabled    *bool                     `json:"enabled"`
+		Trigger    AutomationTriggerDTO      `json:"trigger"`
+		Conditions []*AutomationConditionDTO `json:"conditions" validate:"dive,required"`
+		Actions    []*AutomationActionDTO    `json:"actions" validate:"required,min=1,dive,required"`
+	}
+	if err := c.Bind(&body); err != nil {
+		return err
+	}
+
+	body.Name = strings.TrimSpace(body.Name)
+	if err := c.Validate(&body); err != nil {
+		return err
+	}
+
+	rule, err := api.mustGetUserService(c).AddAutomationRule(&userservice.AddAutomationRuleOptions{
+		BoardID:    c.Param("id"),
+		Name:       body.Name,
+		Enabled:    body.Enabled == nil || *body.Enabled,
+		Trigger:    automationTriggerFromDTO(&body.Trigger),
+		Conditions: automationConditionsFromDTO(body.Conditions),
+		Actions:    automationActionsFromDTO(body.Actions),
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, OK(automationRuleToDTO(rule)))
+}
+
+func (api *APIService) getAutomationRule(c echo.Context) error {
+	rule, err := api.mustGetUserService(c).GetAutomationRule(c.Param("id"))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, OK(automationRuleToDTO(rule)))
+}
+
+func (api *APIService) editAutomationRule(c echo.Context) error {
+	var body struct {
+		Name       *string                    `json:"name" validate:"omitempty,min=1,max=64"`
+		Enabled    *bool                      `json:"enabled"`
+		Trigger    *AutomationTriggerDTO      `json:"trigger"`
+		Conditions *[]*AutomationConditionDTO `json:"conditions" validate:"omitempty,dive,required"`
+		Actions    *[]*AutomationActionDTO    `json:"actions" validate:"omitempty,min=1,dive,required"`
+	}
+	if err := c.Bind(&body); err != nil {
+		return err
+	}
+
+	if body.Name != nil {
+		*body.Name = strings.TrimSpace(*body.Name)
+	}
+	if err := c.Validate(&body); err != nil {
+		return err
+	}
+
+	opts := &userservice.EditAutomationRuleOptions{
+		RuleID:  c.Param("id"),
+		Name:    body.Name,
+		Enabled: body.Enabled,
+	}
+	if body.Trigger != nil {
+		opts.Trigger = lo.ToPtr(automationTriggerFromDTO(body.Trigger))
+	}
+	if body.Conditions != nil {
+		opts.Conditions = lo.ToPtr(automationConditionsFromDTO(*body.Conditions))
+	}
+	if body.Actions != nil {
+		opts.Actions = lo.ToPtr(automationActionsFromDTO(*body.Actions))
+	}
+
+	userService := api.mustGetUserService(c)
+	if err := userService.EditAutomationRule(opts); err != nil {
+		return err
+	}
+
+	rule, err := userService.GetAutomationRule(opts.RuleID)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, OK(automationRuleToDTO(rule)))
+}
+
+func (api *APIService) deleteAutomationRule(c echo.Context) error {
+	if err := api.mustGetUserService(c).DeleteAutomationRule(c.Param("id")); err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (api *APIService) getAutomationRuns(c echo.Context) error {
+	limit := 50
+	if value := c.QueryParam("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > 500 {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid limit")
+		}
+		limit = parsed
+	}
+
+	runs, err := api.mustGetUserService(c).GetAutomationRuns(&userservice.GetAutomationRunsOptions{
+		RuleID: c.Param("id"),
+		Limit:  limit,
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, OK(lo.Map(runs, func(run *store.AutomationRun, _ int) *AutomationRunDTO {
+		return automationRunToDTO(run)
+	})))
+}
+
+func automationTriggerFromDTO(dto *AutomationTriggerDTO) store.AutomationTrigger {
+	return store.AutomationTrigger{
+		Type:           dto.Type,
+		TaskListID:     dto.TaskListID,
+		FromTaskListID: dto.FromTaskListID,
+		LabelID:        dto.LabelID,
+		IntervalHours:  dto.IntervalHours,
+	}
+}
+
+func automationConditionsFromDTO(dtos []*AutomationConditionDTO) []*store.AutomationCondition {
+	return lo.Map(dtos, func(dto *AutomationConditionDTO, _ int) *store.AutomationCondition {
+		return &store.AutomationCondition{
+			Type:       dto.Type,
+			TaskListID: dto.TaskListID,
+			LabelID:    dto.LabelID,
+			Priority:   dto.Priority,
+			Days:       dto.Days,
+		}
+	})
+}
+
+func automationActionsFromDTO(dtos []*AutomationActionDTO) []*store.AutomationAction {
+	return lo.Map(dtos, func(dto *AutomationActionDTO, _ int) *store.AutomationAction {
+		return &store.AutomationAction{
+			Type:       dto.Type,
+			TaskListID: dto.TaskListID,
+			LabelID:    dto.LabelID,
+			Days:       dto.Days,
+			Text:       strings.TrimSpace(dto.Text),
+		}
+	})
+}
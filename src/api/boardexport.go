@@ -0,0 +1,146 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/samber/lo"
+
+	"github.com/lesnoi-kot/karten-backend/src/modules/boarddoc"
+	"github.com/lesnoi-kot/karten-backend/src/userservice"
+)
+
+func (api *APIService) exportBoard(c echo.Context) error {
+	var query struct {
+		Format          string `query:"format" validate:"omitempty,oneof=zip markdown html"`
+		IncludeArchived bool   `query:"archived"`
+	}
+	if err := c.Bind(&query); err != nil {
+		return err
+	}
+	if err := c.Validate(&query); err != nil {
+		return err
+	}
+
+	boardID := c.Param("id")
+	if query.Format == "markdown" || query.Format == "html" {
+		return api.exportBoardDocument(c, boardID, query.Format, query.IncludeArchived)
+	}
+
+	// Buffered, so a failure halfway is still reported with a proper status.
+	var archive bytes.Buffer
+	err := api.mustGetUserService(c).ExportBoard(&userservice.ExportBoardOptions{
+		BoardID:     boardID,
+		FileStorage: api.fileStorage,
+		Writer:      &archive,
+	})
+	if err != nil {
+		return err
+	}
+
+	c.Response().Header().Set(
+		echo.HeaderContentDisposition,
+		fmt.Sprintf(`attachment; filename="board-%s.zip"`, boardID),
+	)
+	return c.Blob(http.StatusOK, "application/zip", archive.Bytes())
+}
+
+// Renders the board into a single Markdown file or a printable HTML page with
+// the image thumbnails inlined.
+func (api *APIService) exportBoardDocument(c echo.Context, boardID, format string, includeArchived bool) error {
+	args := &userservice.GetBoardDocumentOptions{
+		BoardID:         boardID,
+		IncludeArchived: includeArchived,
+	}
+	if format == "html" {
+		args.FileStorage = api.fileStorage
+	}
+
+	board, err := api.mustGetUserService(c).GetBoardDocument(args)
+	if err != nil {
+		return err
+	}
+
+	var document bytes.Buffer
+	contentType, extension := "text/markdown; charset=utf-8", "md"
+	if format == "html" {
+		contentType, extension = echo.MIMETextHTMLCharsetUTF8, "html"
+		err = boarddoc.WriteHTML(&document, board)
+	} else {
+		err = boarddoc.WriteMarkdown(&document, board)
+	}
+	if err != nil {
+		return err
+	}
+
+	c.Response().Header().Set(
+		echo.HeaderContentDisposition,
+		fmt.Sprintf(`attachment; filename="board-%s.%s"`, boardID, extension),
+	)
+	return c.Blob(http.StatusOK, contentType, document.Bytes())
+}
+
+func (api *APIService) importBoard(c echo.Context) error {
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "No archive file")
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	board, err := api.mustGetUserService(c).ImportBoard(&userservice.ImportBoardOptions{
+		ProjectID:   c.Param("id"),
+		FileStorage: api.fileStorage,
+		Archive:     file,
+		Size:        fileHeader.Size,
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, OK(boardToDTO(board)))
+}
+
+type TrelloImportDTO struct {
+	Board    *BoardDTO `json:"board"`
+	Unmapped []string  `json:"unmapped"`
+}
+
+func (api *APIService) importTrelloBoard(c echo.Context) error {
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "No export file")
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return err
+	}
+
+	board, report, err := api.mustGetUserService(c).ImportTrelloBoard(&userservice.ImportTrelloBoardOptions{
+		ProjectID:   c.Param("id"),
+		FileStorage: api.fileStorage,
+		Data:        data,
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, OK(TrelloImportDTO{
+		Board:    boardToDTO(board),
+		Unmapped: lo.Ternary(report.Unmapped != nil, report.Unmapped, []string{}),
+	}))
+}
@@ -23,22 +23,39 @@ type BoardDTO struct {
 	DateLastViewed time.Time   `json:"date_last_viewed"`
 	Color          store.Color `json:"color"`
 	CoverURL       string      `json:"cover_url,omitempty"`
-
-	ProjectName string         `json:"project_name"`
-	TaskLists   []*TaskListDTO `json:"task_lists,omitempty"`
-	Labels      []*LabelDTO    `json:"labels,omitempty"`
+	EstimateUnit   string      `json:"estimate_unit"`
+	Estimate       float64     `json:"estimate"`
+	SpentTime      int64       `json:"spent_time"`
+	LaneMode       string      `json:"lane_mode"`
+
+	ProjectName string          `json:"project_name"`
+	TaskLists   []*TaskListDTO  `json:"task_lists,omitempty"`
+	Labels      []*LabelDTO     `json:"labels,omitempty"`
+	Sprint      *SprintDTO      `json:"sprint,omitempty"`
+	Lanes       []*LaneDTO      `json:"lanes,omitempty"`
+	View        *ViewDTO        `json:"view,omitempty"`
+	Groups      []*TaskGroupDTO `json:"groups,omitempty"`
 }
 
 func (api *APIService) getBoard(c echo.Context) error {
 	boardID := c.Param("id")
 	mode := c.QueryParam("mode")
+	sprint := c.QueryParam("sprint") // Sprint ID or "active"
 	userService := api.mustGetUserService(c)
-	board, err := userService.GetBoard(&userservice.GetBoardOptions{
+	opts := &userservice.GetBoardOptions{
 		BoardID:          boardID,
 		IncludeTaskLists: mode != "shallow",
 		IncludeTasks:     mode != "shallow",
 		IncludeProject:   true,
-	})
+		ViewID:           c.QueryParam("view"),
+	}
+	if sprint == "active" {
+		opts.ActiveSprint = true
+	} else {
+		opts.SprintID = sprint
+	}
+
+	board, err := userService.GetBoard(opts)
 	if err != nil {
 		return err
 	}
@@ -82,6 +99,9 @@ func (api *APIService) editBoard(c echo.Context) error {
 		Archived *bool         `json:"archived"`
 		Color    *store.Color  `json:"color"`
 		CoverID  *store.FileID `json:"cover_id"`
+
+		EstimateUnit *string `json:"estimate_unit" validate:"omitempty,oneof=time points"`
+		LaneMode     *string `json:"lane_mode" validate:"omitempty,oneof=none explicit label"`
 	}
 	if err := c.Bind(&body); err != nil {
 		return err
@@ -102,6 +122,9 @@ func (api *APIService) editBoard(c echo.Context) error {
 		Archived: body.Archived,
 		Color:    body.Color,
 		CoverID:  body.CoverID,
+
+		EstimateUnit: body.EstimateUnit,
+		LaneMode:     body.LaneMode,
 	})
 	if err != nil {
 		return err
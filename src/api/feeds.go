@@ -0,0 +1,104 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/lesnoi-kot/karten-backend/src/modules/ical"
+	"github.com/lesnoi-kot/karten-backend/src/settings"
+	"github.com/lesnoi-kot/karten-backend/src/userservice"
+)
+
+type FeedTokenDTO struct {
+	ID               string     `json:"id"`
+	BoardID          *string    `json:"board_id"`
+	Name             string     `json:"name"`
+	Component        string     `json:"component"`
+	IncludeCompleted bool       `json:"include_completed"`
+	DateCreated      time.Time  `json:"date_created"`
+	DateLastUsed     *time.Time `json:"date_last_used"`
+
+	// Only on creation, the secret is not kept.
+	URL string `json:"url,omitempty"`
+}
+
+func (api *APIService) getFeedTokens(c echo.Context) error {
+	tokens, err := api.mustGetUserService(c).GetFeedTokens()
+	if err != nil {
+		return err
+	}
+
+	dto := make([]*FeedTokenDTO, len(tokens))
+	for i, token := range tokens {
+		dto[i] = feedTokenToDTO(token)
+	}
+
+	return c.JSON(http.StatusOK, OK(dto))
+}
+
+func (api *APIService) addFeedToken(c echo.Context) error {
+	var body struct {
+		BoardID          string `json:"board_id"`
+		Name             string `json:"name" validate:"max=64"`
+		Component        string `json:"component" validate:"omitempty,oneof=event todo"`
+		IncludeCompleted bool   `json:"include_completed"`
+	}
+	if err := c.Bind(&body); err != nil {
+		return err
+	}
+
+	body.Name = strings.TrimSpace(body.Name)
+	if err := c.Validate(&body); err != nil {
+		return err
+	}
+
+	token, secret, err := api.mustGetUserService(c).AddFeedToken(&userservice.AddFeedTokenOptions{
+		BoardID:          strings.TrimSpace(body.BoardID),
+		Name:             body.Name,
+		Component:        ical.Component(body.Component),
+		IncludeCompleted: body.IncludeCompleted,
+	})
+	if err != nil {
+		return err
+	}
+
+	dto := feedTokenToDTO(token)
+	dto.URL, err = url.JoinPath(settings.AppConfig.BackendURL, api.apiPrefix, "calendar", secret+".ics")
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, OK(dto))
+}
+
+func (api *APIService) deleteFeedToken(c echo.Context) error {
+	if err := api.mustGetUserService(c).DeleteFeedToken(c.Param("id")); err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// Public, the secret token in the path authenticates the calendar app.
+func (api *APIService) getFeedCalendar(c echo.Context) error {
+	secret := strings.TrimSuffix(c.Param("token"), ".ics")
+
+	token, calendar, err := userservice.GetFeedCalendar(context.Background(), api.store, secret)
+	if err != nil {
+		return err
+	}
+
+	var feed bytes.Buffer
+	if err := ical.Write(&feed, calendar, ical.Component(token.Component), time.Now()); err != nil {
+		return err
+	}
+
+	c.Response().Header().Set(echo.HeaderCacheControl, "private, max-age=300")
+	return c.Blob(http.StatusOK, "text/calendar; charset=utf-8", feed.Bytes())
+}
@@ -0,0 +1,141 @@
+package api
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/samber/lo"
+
+	"github.com/lesnoi-kot/karten-backend/src/settings"
+	"github.com/lesnoi-kot/karten-backend/src/store"
+	"github.com/lesnoi-kot/karten-backend/src/userservice"
+)
+
+// Push payloads list up to 20 commits, GitHub caps them at 25 MB altogether.
+const forgeEventBodyLimit = "5M"
+
+type ForgeHookDTO struct {
+	ID           string     `json:"id"`
+	ProjectID    string     `json:"project_id"`
+	Name         string     `json:"name"`
+	DateCreated  time.Time  `json:"date_created"`
+	DateLastUsed *time.Time `json:"date_last_used"`
+
+	// Only on creation, the secret is not shown later.
+	URL    string `json:"url,omitempty"`
+	Secret string `json:"secret,omitempty"`
+}
+
+type TaskLinkDTO struct {
+	ID          string    `json:"id"`
+	TaskID      string    `json:"task_id"`
+	Kind        string    `json:"kind"`
+	URL         string    `json:"url"`
+	ExternalID  string    `json:"external_id"`
+	Repository  string    `json:"repository"`
+	Title       string    `json:"title"`
+	Author      string    `json:"author"`
+	State       string    `json:"state"`
+	Completed   bool      `json:"completed"`
+	DateCreated time.Time `json:"date_created"`
+	DateUpdated time.Time `json:"date_updated"`
+}
+
+type ForgeEventResultDTO struct {
+	Linked    int `json:"linked"`
+	Completed int `json:"completed"`
+}
+
+func (api *APIService) getForgeHooks(c echo.Context) error {
+	hooks, err := api.mustGetUserService(c).GetForgeHooks(c.Param("id"))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, OK(lo.Map(hooks, func(hook *store.ForgeHook, _ int) *ForgeHookDTO {
+		return forgeHookToDTO(hook)
+	})))
+}
+
+func (api *APIService) addForgeHook(c echo.Context) error {
+	var body struct {
+		Name string `json:"name" validate:"max=64"`
+	}
+	if err := c.Bind(&body); err != nil {
+		return err
+	}
+
+	body.Name = strings.TrimSpace(body.Name)
+	if err := c.Validate(&body); err != nil {
+		return err
+	}
+
+	hook, err := api.mustGetUserService(c).AddForgeHook(&userservice.AddForgeHookOptions{
+		ProjectID: c.Param("id"),
+		Name:      body.Name,
+	})
+	if err != nil {
+		return err
+	}
+
+	dto := forgeHookToDTO(hook)
+	dto.Secret = hook.Secret
+	dto.URL, err = url.JoinPath(settings.AppConfig.BackendURL, api.apiPrefix, "inbound", "forge", hook.ID)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, OK(dto))
+}
+
+func (api *APIService) deleteForgeHook(c echo.Context) error {
+	if err := api.mustGetUserService(c).DeleteForgeHook(c.Param("id")); err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (api *APIService) getTaskLinks(c echo.Context) error {
+	links, err := api.mustGetUserService(c).GetTaskLinks(c.Param("id"))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, OK(lo.Map(links, func(link *store.TaskLink, _ int) *TaskLinkDTO {
+		return taskLinkToDTO(link)
+	})))
+}
+
+// Public, the signature of the body made with the hook secret authenticates
+// the forge.
+func (api *APIService) receiveForgeEvent(c echo.Context) error {
+	body, err := io.ReadAll(c.Request().Body)
+	if err != nil {
+		return err
+	}
+
+	result, err := userservice.ReceiveForgeEvent(context.Background(), api.store, &userservice.ReceiveForgeEventOptions{
+		HookID: c.Param("id"),
+		Header: c.Request().Header,
+		Body:   body,
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, OK(&ForgeEventResultDTO{
+		Linked:    result.Linked,
+		Completed: result.Completed,
+	}))
+}
+
+func forgeEventLimits() []echo.MiddlewareFunc {
+	return []echo.MiddlewareFunc{middleware.BodyLimit(forgeEventBodyLimit)}
+}
@@ -0,0 +1,119 @@
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/lesnoi-kot/karten-backend/src/modules/email"
+	"github.com/lesnoi-kot/karten-backend/src/settings"
+	"github.com/lesnoi-kot/karten-backend/src/userservice"
+)
+
+const inboundSecretHeader = "X-Inbound-Secret"
+
+type InboundEmailDTO struct {
+	Enabled bool   `json:"enabled"`
+	Address string `json:"address,omitempty"`
+}
+
+type ReceivedEmailDTO struct {
+	BoardID   string  `json:"board_id"`
+	TaskID    string  `json:"task_id"`
+	CommentID *string `json:"comment_id"`
+	Duplicate bool    `json:"duplicate"`
+}
+
+func inboundEmailToDTO(token string) *InboundEmailDTO {
+	if token == "" {
+		return &InboundEmailDTO{}
+	}
+
+	address := token
+	if settings.AppConfig.InboundEmailDomain != "" {
+		address += "@" + settings.AppConfig.InboundEmailDomain
+	}
+
+	return &InboundEmailDTO{Enabled: true, Address: address}
+}
+
+func (api *APIService) getBoardInboundEmail(c echo.Context) error {
+	token, err := api.mustGetUserService(c).GetBoardInboundToken(c.Param("id"))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, OK(inboundEmailToDTO(token)))
+}
+
+// Enables inbound email or changes the address if it is on.
+func (api *APIService) resetBoardInboundEmail(c echo.Context) error {
+	token, err := api.mustGetUserService(c).ResetBoardInboundToken(c.Param("id"))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, OK(inboundEmailToDTO(token)))
+}
+
+func (api *APIService) disableBoardInboundEmail(c echo.Context) error {
+	if err := api.mustGetUserService(c).DisableBoardInbound(c.Param("id")); err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// Public, called by the mail server (or a pipe script after the MTA) with the
+// raw RFC 5322 message as the body. The shared secret in the header
+// authenticates the mail server, the envelope recipient can be passed in the
+// query when the message headers lack it.
+func (api *APIService) receiveEmail(c echo.Context) error {
+	secret := settings.AppConfig.InboundEmailSecret
+	if secret == "" {
+		return echo.ErrNotFound
+	}
+
+	given := c.Request().Header.Get(inboundSecretHeader)
+	if subtle.ConstantTimeCompare([]byte(given), []byte(secret)) != 1 {
+		return echo.ErrUnauthorized
+	}
+
+	msg, err := email.Parse(c.Request().Body)
+	if err != nil {
+		return err
+	}
+
+	var recipients []string
+	for _, recipient := range c.QueryParams()["recipient"] {
+		if recipient = strings.TrimSpace(recipient); recipient != "" {
+			recipients = append(recipients, recipient)
+		}
+	}
+
+	received, err := userservice.ReceiveEmail(context.Background(), api.store, &userservice.ReceiveEmailOptions{
+		Message:     msg,
+		Recipients:  recipients,
+		Domain:      settings.AppConfig.InboundEmailDomain,
+		FileStorage: api.fileStorage,
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, OK(&ReceivedEmailDTO{
+		BoardID:   received.BoardID,
+		TaskID:    received.TaskID,
+		CommentID: (*string)(received.CommentID),
+		Duplicate: received.Duplicate,
+	}))
+}
+
+// Inbound requests come from mail servers and other tools, not from a
+// browser.
+func (api *APIService) isInboundRequest(c echo.Context) bool {
+	return strings.HasPrefix(c.Request().URL.Path, api.apiPrefix+"/inbound/")
+}
@@ -0,0 +1,148 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/samber/lo"
+
+	"github.com/lesnoi-kot/karten-backend/src/settings"
+	"github.com/lesnoi-kot/karten-backend/src/store"
+	"github.com/lesnoi-kot/karten-backend/src/userservice"
+)
+
+const (
+	// Tasks a client can create by the incoming webhooks, per second with
+	// bursts.
+	incomingWebhookRate  = 0.5
+	incomingWebhookBurst = 10
+
+	incomingWebhookBodyLimit = "64K"
+)
+
+type IncomingWebhookDTO struct {
+	ID           string     `json:"id"`
+	TaskListID   string     `json:"task_list_id"`
+	Name         string     `json:"name"`
+	DateCreated  time.Time  `json:"date_created"`
+	DateLastUsed *time.Time `json:"date_last_used"`
+
+	// Only on creation, the token is not kept.
+	URL string `json:"url,omitempty"`
+}
+
+type IncomingTaskDTO struct {
+	Task          *TaskDTO `json:"task"`
+	UnknownLabels []string `json:"unknown_labels"`
+}
+
+func (api *APIService) getIncomingWebhooks(c echo.Context) error {
+	hooks, err := api.mustGetUserService(c).GetIncomingWebhooks(c.Param("id"))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, OK(lo.Map(hooks, func(hook *store.IncomingWebhook, _ int) *IncomingWebhookDTO {
+		return incomingWebhookToDTO(hook)
+	})))
+}
+
+func (api *APIService) addIncomingWebhook(c echo.Context) error {
+	var body struct {
+		Name string `json:"name" validate:"max=64"`
+	}
+	if err := c.Bind(&body); err != nil {
+		return err
+	}
+
+	body.Name = strings.TrimSpace(body.Name)
+	if err := c.Validate(&body); err != nil {
+		return err
+	}
+
+	hook, secret, err := api.mustGetUserService(c).AddIncomingWebhook(&userservice.AddIncomingWebhookOptions{
+		TaskListID: c.Param("id"),
+		Name:       body.Name,
+	})
+	if err != nil {
+		return err
+	}
+
+	dto := incomingWebhookToDTO(hook)
+	dto.URL, err = url.JoinPath(settings.AppConfig.BackendURL, api.apiPrefix, "inbound", "webhooks", secret)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, OK(dto))
+}
+
+func (api *APIService) deleteIncomingWebhook(c echo.Context) error {
+	if err := api.mustGetUserService(c).DeleteIncomingWebhook(c.Param("id")); err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// Public, the secret token in the path authenticates the sender.
+func (api *APIService) receiveIncomingWebhook(c echo.Context) error {
+	var body struct {
+		Name    string     `json:"name" validate:"required,min=1,max=512"`
+		Text    string     `json:"text" validate:"max=65536"`
+		Labels  []string   `json:"labels" validate:"max=20"`
+		DueDate *time.Time `json:"due_date"`
+	}
+	if err := c.Bind(&body); err != nil {
+		return err
+	}
+
+	body.Name = strings.TrimSpace(body.Name)
+	body.Text = strings.TrimSpace(body.Text)
+	if err := c.Validate(&body); err != nil {
+		// Other tools call this, they need to know what is wrong.
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	task, unknownLabels, err := userservice.ReceiveIncomingWebhook(context.Background(), api.store, c.Param("token"), &userservice.IncomingTaskOptions{
+		Name:    body.Name,
+		Text:    body.Text,
+		Labels:  body.Labels,
+		DueDate: body.DueDate,
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, OK(&IncomingTaskDTO{
+		Task:          taskToDTO(task),
+		UnknownLabels: lo.Ternary(unknownLabels != nil, unknownLabels, []string{}),
+	}))
+}
+
+// Limits the size of the requests to the incoming webhooks and their rate
+// from each client. The token is not checked yet, so the client address is
+// the key: keying on the token would give a fresh allowance to every made up
+// one.
+func incomingWebhookLimits() []echo.MiddlewareFunc {
+	rateLimiterStore := middleware.NewRateLimiterMemoryStoreWithConfig(middleware.RateLimiterMemoryStoreConfig{
+		Rate:      incomingWebhookRate,
+		Burst:     incomingWebhookBurst,
+		ExpiresIn: 5 * time.Minute,
+	})
+
+	return []echo.MiddlewareFunc{
+		middleware.BodyLimit(incomingWebhookBodyLimit),
+		middleware.RateLimiterWithConfig(middleware.RateLimiterConfig{
+			Store: rateLimiterStore,
+			IdentifierExtractor: func(c echo.Context) (string, error) {
+				return c.RealIP(), nil
+			},
+		}),
+	}
+}
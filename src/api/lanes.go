@@ -0,0 +1,106 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/lesnoi-kot/karten-backend/src/store"
+	"github.com/lesnoi-kot/karten-backend/src/userservice"
+	"github.com/samber/lo"
+)
+
+type LaneCellDTO struct {
+	TaskListID string   `json:"task_list_id"`
+	TaskIDs    []string `json:"task_ids"`
+}
+
+type LaneDTO struct {
+	ID       string `json:"id"` // Empty for the tasks without a lane
+	BoardID  string `json:"board_id"`
+	Name     string `json:"name"`
+	Position int64  `json:"position"`
+	LabelID  *int   `json:"label_id,omitempty"`
+
+	Cells []*LaneCellDTO `json:"cells,omitempty"`
+}
+
+func (api *APIService) getLanes(c echo.Context) error {
+	lanes, err := api.mustGetUserService(c).GetLanes(c.Param("id"))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, OK(lo.Map(lanes, func(lane *store.Lane, _ int) *LaneDTO {
+		return laneToDTO(lane)
+	})))
+}
+
+func (api *APIService) addLane(c echo.Context) error {
+	var body struct {
+		Name     string `json:"name" validate:"required,min=1,max=64"`
+		Position int64  `json:"position"`
+	}
+	if err := c.Bind(&body); err != nil {
+		return err
+	}
+
+	body.Name = strings.TrimSpace(body.Name)
+	if err := c.Validate(&body); err != nil {
+		return err
+	}
+
+	lane, err := api.mustGetUserService(c).AddLane(&userservice.AddLaneOptions{
+		BoardID:  c.Param("id"),
+		Name:     body.Name,
+		Position: body.Position,
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, OK(laneToDTO(lane)))
+}
+
+func (api *APIService) editLane(c echo.Context) error {
+	var body struct {
+		Name     *string `json:"name" validate:"omitempty,min=1,max=64"`
+		Position *int64  `json:"position"`
+	}
+	if err := c.Bind(&body); err != nil {
+		return err
+	}
+
+	if body.Name != nil {
+		*body.Name = strings.TrimSpace(*body.Name)
+	}
+	if err := c.Validate(&body); err != nil {
+		return err
+	}
+
+	laneID := c.Param("id")
+	userService := api.mustGetUserService(c)
+	err := userService.EditLane(&userservice.EditLaneOptions{
+		LaneID:   laneID,
+		Name:     body.Name,
+		Position: body.Position,
+	})
+	if err != nil {
+		return err
+	}
+
+	lane, err := userService.GetLane(laneID)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, OK(laneToDTO(lane)))
+}
+
+func (api *APIService) deleteLane(c echo.Context) error {
+	if err := api.mustGetUserService(c).DeleteLane(c.Param("id")); err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
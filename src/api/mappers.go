@@ -1,18 +1,27 @@
 package api
 
 import (
+	"fmt"
+	"time"
+
 	"github.com/samber/lo"
 
+	"github.com/lesnoi-kot/karten-backend/src/modules/flowmetrics"
+	"github.com/lesnoi-kot/karten-backend/src/modules/timesheet"
 	"github.com/lesnoi-kot/karten-backend/src/store"
 	"github.com/lesnoi-kot/karten-backend/src/urlprovider"
+	"github.com/lesnoi-kot/karten-backend/src/userservice"
 )
 
 func projectToDTO(project *store.Project) *ProjectDTO {
 	dto := &ProjectDTO{
-		ID:      project.ID,
-		UserID:  project.UserID,
-		ShortID: project.ShortID,
-		Name:    project.Name,
+		ID:        project.ID,
+		UserID:    project.UserID,
+		ShortID:   project.ShortID,
+		Name:      project.Name,
+		KeyPrefix: project.KeyPrefix,
+
+		SkipWeekends: project.SkipWeekends,
 	}
 
 	if project.Avatar != nil {
@@ -87,6 +96,10 @@ func boardToDTO(board *store.Board) *BoardDTO {
 		DateCreated:    board.DateCreated,
 		DateLastViewed: board.DateLastViewed,
 		Color:          board.Color,
+		EstimateUnit:   board.EstimateUnit,
+		Estimate:       board.Estimate,
+		SpentTime:      board.SpentTime,
+		LaneMode:       board.LaneMode,
 	}
 
 	if board.Project != nil {
@@ -97,6 +110,30 @@ func boardToDTO(board *store.Board) *BoardDTO {
 		dto.CoverURL = urlprovider.GetFileURL(board.Cover)
 	}
 
+	if board.Sprint != nil {
+		dto.Sprint = sprintToDTO(board.Sprint)
+	}
+
+	if board.View != nil {
+		dto.View = viewToDTO(board.View)
+	}
+
+	if board.Groups != nil {
+		dto.Groups = lo.Map(board.Groups, func(group *store.TaskGroup, _ int) *TaskGroupDTO {
+			return &TaskGroupDTO{
+				Key:     group.Key,
+				Name:    group.Name,
+				TaskIDs: group.TaskIDs,
+			}
+		})
+	}
+
+	if len(board.Lanes) > 0 {
+		dto.Lanes = lo.Map(board.Lanes, func(lane *store.Lane, index int) *LaneDTO {
+			return laneToDTO(lane)
+		})
+	}
+
 	if len(board.TaskLists) > 0 {
 		dto.TaskLists = lo.Map(board.TaskLists, func(taskList *store.TaskList, index int) *TaskListDTO {
 			return taskListToDTO(taskList)
@@ -122,6 +159,11 @@ func taskListToDTO(taskList *store.TaskList) *TaskListDTO {
 		Archived:    taskList.Archived,
 		DateCreated: taskList.DateCreated,
 		Color:       taskList.Color,
+		Estimate:    taskList.Estimate,
+		SpentTime:   taskList.SpentTime,
+		TaskCount:   taskList.TaskCount,
+		WIPLimit:    taskList.WIPLimit,
+		WIPMode:     taskList.WIPMode,
 	}
 
 	if len(taskList.Tasks) > 0 {
@@ -148,6 +190,8 @@ func taskToDTO(task *store.Task) *TaskDTO {
 		ID:                  task.ID,
 		UserID:              task.UserID,
 		ShortID:             task.ShortID,
+		Number:              task.Number,
+		Key:                 task.Key,
 		TaskListID:          task.TaskListID,
 		Position:            task.Position,
 		SpentTime:           task.SpentTime,
@@ -157,7 +201,12 @@ func taskToDTO(task *store.Task) *TaskDTO {
 		Archived:            task.Archived,
 		DateCreated:         task.DateCreated,
 		DateStartedTracking: task.DateStartedTracking,
+		StartDate:           task.StartDate,
 		DueDate:             task.DueDate,
+		SprintID:            task.SprintID,
+		Estimate:            task.Estimate,
+		Priority:            task.Priority,
+		LaneID:              task.LaneID,
 	}
 
 	if len(task.Comments) > 0 {
@@ -229,3 +278,461 @@ func publicUserToDTO(user *store.User) *PublicUserDTO {
 
 	return dto
 }
+
+func timesheetToDTO(report *timesheet.Report) *TimesheetDTO {
+	return &TimesheetDTO{
+		From:         report.From,
+		To:           report.To,
+		GroupBy:      string(report.GroupBy),
+		TimeZone:     report.TimeZone,
+		TotalSeconds: report.TotalSeconds,
+		Rows: lo.Map(report.Rows, func(row timesheet.Row, _ int) *TimesheetRowDTO {
+			return &TimesheetRowDTO{
+				Key:     row.Key,
+				Name:    row.Name,
+				Seconds: row.Seconds,
+			}
+		}),
+	}
+}
+
+func archivedItemToDTO(item *userservice.ArchivedItem) *ArchivedItemDTO {
+	return &ArchivedItemDTO{
+		Type:         item.Type,
+		ID:           item.ID,
+		Name:         item.Name,
+		BoardID:      item.BoardID,
+		BoardName:    item.BoardName,
+		TaskListID:   item.TaskListID,
+		TaskListName: item.TaskListName,
+		DateArchived: item.DateArchived,
+	}
+}
+
+func trashedItemToDTO(item *userservice.TrashedItem) *TrashedItemDTO {
+	return &TrashedItemDTO{
+		Type:         item.Type,
+		ID:           item.ID,
+		Name:         item.Name,
+		ProjectID:    item.ProjectID,
+		ProjectName:  item.ProjectName,
+		BoardID:      item.BoardID,
+		BoardName:    item.BoardName,
+		TaskListID:   item.TaskListID,
+		TaskListName: item.TaskListName,
+		DateDeleted:  item.DateDeleted,
+		DateExpires:  item.DateExpires,
+	}
+}
+
+func taskRevisionToDTO(revision *userservice.TaskRevisionWithDiff) *TaskRevisionDTO {
+	dto := &TaskRevisionDTO{
+		Number:      revision.Number,
+		UserID:      revision.UserID,
+		Text:        revision.Text,
+		Diff:        revision.Diff,
+		DateCreated: revision.DateCreated,
+		DateUpdated: revision.DateUpdated,
+	}
+
+	if revision.Author != nil {
+		dto.Author = publicUserToDTO(revision.Author)
+	}
+
+	return dto
+}
+
+func flowMetricsToDTO(metrics *userservice.BoardFlowMetrics, from, to time.Time) *FlowMetricsDTO {
+	names := make(map[string]string, len(metrics.TaskLists))
+	for _, taskList := range metrics.TaskLists {
+		names[taskList.ID] = taskList.Name
+	}
+
+	dto := &FlowMetricsDTO{
+		From:        from,
+		To:          to,
+		StartListID: metrics.StartListID,
+		EndListID:   metrics.EndListID,
+		LeadTime:    distributionToDTO(metrics.LeadTime),
+		CycleTime:   distributionToDTO(metrics.CycleTime),
+		Lists:       make([]*ListTimeDTO, len(metrics.Lists)),
+	}
+
+	for i, list := range metrics.Lists {
+		dto.Lists[i] = &ListTimeDTO{
+			TaskListID:      list.ListID,
+			TaskListName:    names[list.ListID],
+			DistributionDTO: *distributionToDTO(list.Distribution),
+		}
+	}
+
+	return dto
+}
+
+func distributionToDTO(distribution flowmetrics.Distribution) *DistributionDTO {
+	dto := &DistributionDTO{
+		Count:       distribution.Count,
+		Percentiles: make(map[string]int64, len(distribution.Percentiles)),
+	}
+
+	for _, p := range distribution.Percentiles {
+		dto.Percentiles[fmt.Sprintf("p%g", p.P)] = p.Seconds
+	}
+
+	return dto
+}
+
+func cumulativeFlowToDTO(flow *userservice.CumulativeFlow) *CumulativeFlowDTO {
+	dto := &CumulativeFlowDTO{
+		Dates: lo.Map(flow.Dates, func(date time.Time, _ int) string {
+			return date.Format(reportDateLayout)
+		}),
+		Lists: make([]*CumulativeFlowListDTO, len(flow.TaskLists)),
+	}
+
+	for i, taskList := range flow.TaskLists {
+		dto.Lists[i] = &CumulativeFlowListDTO{
+			TaskListID:   taskList.ID,
+			TaskListName: taskList.Name,
+			Archived:     taskList.Archived,
+			Counts:       flow.Counts[taskList.ID],
+		}
+	}
+
+	return dto
+}
+
+func burndownToDTO(burndown *userservice.Burndown) *BurndownDTO {
+	return &BurndownDTO{
+		DoneListID: burndown.DoneListID,
+		Points: lo.Map(burndown.Points, func(point userservice.BurndownPoint, _ int) *BurndownPointDTO {
+			return &BurndownPointDTO{
+				Date:              point.Date.Format(reportDateLayout),
+				Remaining:         point.Remaining,
+				Done:              point.Done,
+				RemainingEstimate: point.RemainingEstimate,
+				DoneEstimate:      point.DoneEstimate,
+			}
+		}),
+	}
+}
+
+func sprintToDTO(sprint *store.Sprint) *SprintDTO {
+	return &SprintDTO{
+		ID:          sprint.ID,
+		BoardID:     sprint.BoardID,
+		Name:        sprint.Name,
+		Goal:        sprint.Goal,
+		DateStart:   sprint.DateStart.Format(reportDateLayout),
+		DateEnd:     sprint.DateEnd.Format(reportDateLayout),
+		DateCreated: sprint.DateCreated,
+		DateClosed:  sprint.DateClosed,
+	}
+}
+
+func sprintWithSummaryToDTO(sprint *userservice.SprintWithSummary) *SprintDTO {
+	dto := sprintToDTO(sprint.Sprint)
+	dto.Summary = &SprintSummaryDTO{
+		Committed:  sprint.Summary.Committed,
+		Added:      sprint.Summary.Added,
+		Removed:    sprint.Summary.Removed,
+		Completed:  sprint.Summary.Completed,
+		Unfinished: sprint.Summary.Unfinished,
+
+		CommittedEstimate: sprint.Summary.CommittedEstimate,
+		CompletedEstimate: sprint.Summary.CompletedEstimate,
+	}
+
+	return dto
+}
+
+func estimateReportToDTO(report *userservice.EstimateReport) *EstimateReportDTO {
+	return &EstimateReportDTO{
+		ProjectID: report.ProjectID,
+		Boards: lo.Map(report.Boards, func(board *userservice.EstimateReportBoard, _ int) *EstimateReportBoardDTO {
+			return &EstimateReportBoardDTO{
+				ID:                 board.ID,
+				Name:               board.Name,
+				EstimateUnit:       board.EstimateUnit,
+				Estimate:           board.Estimate,
+				EstimatedSpentTime: board.EstimatedSpentTime,
+				SpentTime:          board.SpentTime,
+				EstimatedTasks:     board.EstimatedTasks,
+				UnestimatedTasks:   board.UnestimatedTasks,
+				Ratio:              board.Ratio,
+				Tasks: lo.Map(board.Tasks, func(task *userservice.EstimateReportTask, _ int) *EstimateReportTaskDTO {
+					return &EstimateReportTaskDTO{
+						ID:        task.ID,
+						Key:       task.Key,
+						Name:      task.Name,
+						Archived:  task.Archived,
+						Estimate:  task.Estimate,
+						SpentTime: task.SpentTime,
+					}
+				}),
+			}
+		}),
+	}
+}
+
+func agendaToDTO(agenda *userservice.Agenda) *AgendaDTO {
+	tasksToDTO := func(tasks []*userservice.AgendaTask) []*AgendaTaskDTO {
+		return lo.Map(tasks, func(task *userservice.AgendaTask, _ int) *AgendaTaskDTO {
+			return &AgendaTaskDTO{
+				ID:           task.ID,
+				Key:          task.Key,
+				Name:         task.Name,
+				Priority:     task.Priority,
+				DueDate:      task.DueDate,
+				Estimate:     task.Estimate,
+				DateCreated:  task.DateCreated,
+				TaskListID:   task.TaskListID,
+				TaskListName: task.TaskListName,
+				BoardID:      task.BoardID,
+				BoardName:    task.BoardName,
+				ProjectID:    task.ProjectID,
+				ProjectName:  task.ProjectName,
+			}
+		})
+	}
+
+	return &AgendaDTO{
+		Date:         agenda.Today.Format(reportDateLayout),
+		TimeZone:     agenda.Today.Location().String(),
+		Overdue:      tasksToDTO(agenda.Overdue),
+		Today:        tasksToDTO(agenda.DueToday),
+		Upcoming:     tasksToDTO(agenda.Upcoming),
+		HighPriority: tasksToDTO(agenda.HighPriority),
+	}
+}
+
+func timelineToDTO(timeline *userservice.Timeline) *TimelineDTO {
+	return &TimelineDTO{
+		ProjectID:    timeline.ProjectID,
+		SkipWeekends: timeline.SkipWeekends,
+		Boards: lo.Map(timeline.Boards, func(board *userservice.TimelineBoard, _ int) *TimelineBoardDTO {
+			return &TimelineBoardDTO{
+				ID:   board.ID,
+				Name: board.Name,
+				TaskLists: lo.Map(board.TaskLists, func(taskList *userservice.TimelineTaskList, _ int) *TimelineTaskListDTO {
+					return &TimelineTaskListDTO{
+						ID:   taskList.ID,
+						Name: taskList.Name,
+						Tasks: lo.Map(taskList.Tasks, func(task *userservice.TimelineTask, _ int) *TimelineTaskDTO {
+							return &TimelineTaskDTO{
+								ID:        task.ID,
+								Key:       task.Key,
+								Name:      task.Name,
+								Priority:  task.Priority,
+								StartDate: task.StartDate,
+								DueDate:   task.DueDate,
+							}
+						}),
+					}
+				}),
+			}
+		}),
+		Dependencies: lo.Map(timeline.Dependencies, func(dependency *store.TaskDependency, _ int) *TaskDependencyDTO {
+			return &TaskDependencyDTO{
+				TaskID:      dependency.TaskID,
+				DependsOnID: dependency.DependsOnID,
+			}
+		}),
+	}
+}
+
+func laneToDTO(lane *store.Lane) *LaneDTO {
+	dto := &LaneDTO{
+		ID:       lane.ID,
+		BoardID:  lane.BoardID,
+		Name:     lane.Name,
+		Position: lane.Position,
+		LabelID:  lane.LabelID,
+	}
+
+	if len(lane.Cells) > 0 {
+		dto.Cells = lo.Map(lane.Cells, func(cell *store.LaneCell, _ int) *LaneCellDTO {
+			return &LaneCellDTO{
+				TaskListID: cell.TaskListID,
+				TaskIDs:    cell.TaskIDs,
+			}
+		})
+	}
+
+	return dto
+}
+
+func viewToDTO(view *store.View) *ViewDTO {
+	filter := view.Filter
+
+	return &ViewDTO{
+		ID:        view.ID,
+		BoardID:   view.BoardID,
+		ProjectID: view.ProjectID,
+		Name:      view.Name,
+		Filter: &ViewFilterDTO{
+			LabelIDs:      filter.LabelIDs,
+			DueFrom:       filter.DueFrom,
+			DueTo:         filter.DueTo,
+			DueWithinDays: filter.DueWithinDays,
+			NoDueDate:     filter.NoDueDate,
+			Text:          filter.Text,
+			Completion:    filter.Completion,
+			Archived:      filter.Archived,
+			MinPriority:   filter.MinPriority,
+			GroupBy:       filter.GroupBy,
+			SortBy:        filter.SortBy,
+			SortDesc:      filter.SortDesc,
+		},
+		DateCreated: view.DateCreated,
+	}
+}
+
+func automationRuleToDTO(rule *store.AutomationRule) *AutomationRuleDTO {
+	trigger := rule.Trigger
+
+	return &AutomationRuleDTO{
+		ID:      rule.ID,
+		BoardID: rule.BoardID,
+		Name:    rule.Name,
+		Enabled: rule.Enabled,
+		Trigger: &AutomationTriggerDTO{
+			Type:           trigger.Type,
+			TaskListID:     trigger.TaskListID,
+			FromTaskListID: trigger.FromTaskListID,
+			LabelID:        trigger.LabelID,
+			IntervalHours:  trigger.IntervalHours,
+		},
+		Conditions: lo.Map(rule.Conditions, func(condition *store.AutomationCondition, _ int) *AutomationConditionDTO {
+			return &AutomationConditionDTO{
+				Type:       condition.Type,
+				TaskListID: condition.TaskListID,
+				LabelID:    condition.LabelID,
+				Priority:   condition.Priority,
+				Days:       condition.Days,
+			}
+		}),
+		Actions: lo.Map(rule.Actions, func(action *store.AutomationAction, _ int) *AutomationActionDTO {
+			return &AutomationActionDTO{
+				Type:       action.Type,
+				TaskListID: action.TaskListID,
+				LabelID:    action.LabelID,
+				Days:       action.Days,
+				Text:       action.Text,
+			}
+		}),
+		LastRunAt:   rule.LastRunAt,
+		DateCreated: rule.DateCreated,
+	}
+}
+
+func automationRunToDTO(run *store.AutomationRun) *AutomationRunDTO {
+	return &AutomationRunDTO{
+		ID:          run.ID,
+		RuleID:      run.RuleID,
+		TaskID:      run.TaskID,
+		Trigger:     run.Trigger,
+		Status:      run.Status,
+		Error:       run.Error,
+		Depth:       run.Depth,
+		DateCreated: run.DateCreated,
+	}
+}
+
+func feedTokenToDTO(token *store.FeedToken) *FeedTokenDTO {
+	return &FeedTokenDTO{
+		ID:               token.ID,
+		BoardID:          token.BoardID,
+		Name:             token.Name,
+		Component:        token.Component,
+		IncludeCompleted: token.IncludeCompleted,
+		DateCreated:      token.DateCreated,
+		DateLastUsed:     token.DateLastUsed,
+	}
+}
+
+func webhookToDTO(hook *store.Webhook) *WebhookDTO {
+	return &WebhookDTO{
+		ID:          hook.ID,
+		ProjectID:   hook.ProjectID,
+		URL:         hook.URL,
+		Events:      hook.Events,
+		Enabled:     hook.Enabled,
+		DateCreated: hook.DateCreated,
+	}
+}
+
+func webhookDeliveryToDTO(delivery *store.WebhookDelivery) *WebhookDeliveryDTO {
+	return &WebhookDeliveryDTO{
+		ID:            delivery.ID,
+		WebhookID:     delivery.WebhookID,
+		Event:         delivery.Event,
+		Payload:       delivery.Payload,
+		Status:        delivery.Status,
+		Attempts:      delivery.Attempts,
+		NextAttemptAt: lo.Ternary(delivery.Status == store.WebhookDeliveryPending, &delivery.NextAttemptAt, nil),
+		ResponseCode:  delivery.ResponseCode,
+		Error:         delivery.Error,
+		DurationMS:    delivery.DurationMS,
+		DateCreated:   delivery.DateCreated,
+		DateDelivered: delivery.DateDelivered,
+	}
+}
+
+func incomingWebhookToDTO(hook *store.IncomingWebhook) *IncomingWebhookDTO {
+	return &IncomingWebhookDTO{
+		ID:           hook.ID,
+		TaskListID:   hook.TaskListID,
+		Name:         hook.Name,
+		DateCreated:  hook.DateCreated,
+		DateLastUsed: hook.DateLastUsed,
+	}
+}
+
+func forgeHookToDTO(hook *store.ForgeHook) *ForgeHookDTO {
+	return &ForgeHookDTO{
+		ID:           hook.ID,
+		ProjectID:    hook.ProjectID,
+		Name:         hook.Name,
+		DateCreated:  hook.DateCreated,
+		DateLastUsed: hook.DateLastUsed,
+	}
+}
+
+func taskLinkToDTO(link *store.TaskLink) *TaskLinkDTO {
+	return &TaskLinkDTO{
+		ID:          link.ID,
+		TaskID:      link.TaskID,
+		Kind:        link.Kind,
+		URL:         link.URL,
+		ExternalID:  link.ExternalID,
+		Repository:  link.Repository,
+		Title:       link.Title,
+		Author:      link.Author,
+		State:       link.State,
+		Completed:   link.Completed,
+		DateCreated: link.DateCreated,
+		DateUpdated: link.DateUpdated,
+	}
+}
+
+func searchHitToDTO(hit *userservice.SearchHit) *SearchHitDTO {
+	return &SearchHitDTO{
+		Kind:         hit.Kind,
+		Rank:         hit.Rank,
+		Snippet:      hit.Snippet,
+		Date:         hit.Date,
+		CommentID:    hit.CommentID,
+		FileID:       hit.FileID,
+		FileName:     hit.FileName,
+		TaskID:       hit.TaskID,
+		TaskKey:      hit.TaskKey,
+		TaskName:     hit.TaskName,
+		TaskListID:   hit.TaskListID,
+		TaskListName: hit.TaskListName,
+		BoardID:      hit.BoardID,
+		BoardName:    hit.BoardName,
+		ProjectID:    hit.ProjectID,
+		ProjectName:  hit.ProjectName,
+	}
+}
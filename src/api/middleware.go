@@ -2,11 +2,17 @@ package api
 
 import (
 	"errors"
+	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/go-playground/validator"
 	"github.com/labstack/echo/v4"
+	"github.com/lesnoi-kot/karten-backend/src/modules/boardarchive"
+	"github.com/lesnoi-kot/karten-backend/src/modules/email"
+	"github.com/lesnoi-kot/karten-backend/src/modules/forge"
+	"github.com/lesnoi-kot/karten-backend/src/modules/taskcsv"
+	"github.com/lesnoi-kot/karten-backend/src/modules/trello"
 	"github.com/lesnoi-kot/karten-backend/src/store"
 	"github.com/lesnoi-kot/karten-backend/src/userservice"
 )
@@ -61,6 +67,63 @@ func parseError(next echo.HandlerFunc) echo.HandlerFunc {
 		if errors.Is(err, store.ErrNotFound) {
 			return echo.ErrNotFound
 		}
+		if errors.Is(err, userservice.ErrPermissionDenied) {
+			return echo.ErrForbidden
+		}
+		if errors.Is(err, boardarchive.ErrInvalidArchive) || errors.Is(err, boardarchive.ErrUnsupportedVersion) {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		if errors.Is(err, taskcsv.ErrInvalidCSV) || errors.Is(err, taskcsv.ErrUnknownColumn) {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		if errors.Is(err, email.ErrInvalidMessage) {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		if errors.Is(err, forge.ErrInvalidPayload) {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		if errors.Is(err, userservice.ErrInvalidSignature) {
+			return echo.ErrUnauthorized
+		}
+		if errors.Is(err, trello.ErrInvalidExport) {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		if errors.Is(err, userservice.ErrOriginalContainerGone) {
+			return echo.NewHTTPError(http.StatusConflict, err.Error())
+		}
+		if errors.Is(err, userservice.ErrInvalidKeyPrefix) || errors.Is(err, userservice.ErrInvalidAutomationRule) {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		if errors.Is(err, userservice.ErrInvalidSearchLanguage) {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		if errors.Is(err, userservice.ErrInvalidWebhook) {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		if errors.Is(err, userservice.ErrKeyPrefixTaken) {
+			return echo.NewHTTPError(http.StatusConflict, err.Error())
+		}
+		if errors.Is(err, userservice.ErrParentInTrash) {
+			return echo.NewHTTPError(http.StatusConflict, err.Error())
+		}
+		if errors.Is(err, userservice.ErrSprintClosed) || errors.Is(err, userservice.ErrNoNextSprint) {
+			return echo.NewHTTPError(http.StatusConflict, err.Error())
+		}
+		if errors.Is(err, userservice.ErrNoInboxList) {
+			return echo.NewHTTPError(http.StatusConflict, err.Error())
+		}
+		if errors.Is(err, userservice.ErrDependencyCycle) || errors.Is(err, userservice.ErrNoLanes) {
+			return echo.NewHTTPError(http.StatusConflict, err.Error())
+		}
+		if errors.Is(err, userservice.ErrWIPLimitExceeded) {
+			return echo.NewHTTPError(http.StatusConflict, echo.Map{
+				"message": err.Error(),
+				"code":    "wip_limit_exceeded",
+			})
+		}
+		if errors.Is(err, userservice.ErrNoActiveSprint) {
+			return echo.NewHTTPError(http.StatusNotFound, err.Error())
+		}
 
 		return err
 	}
@@ -15,8 +15,10 @@ type ProjectDTO struct {
 	UserID             int         `json:"user_id"`
 	ShortID            string      `json:"short_id"`
 	Name               string      `json:"name"`
+	KeyPrefix          string      `json:"key_prefix"`
 	AvatarURL          string      `json:"avatar_url,omitempty"`
 	AvatarThumbnailURL string      `json:"avatar_thumbnail_url,omitempty"`
+	SkipWeekends       bool        `json:"skip_weekends"`
 	Boards             []*BoardDTO `json:"boards,omitempty"`
 }
 
@@ -50,8 +52,9 @@ func (api *APIService) getProject(c echo.Context) error {
 
 func (api *APIService) addProject(c echo.Context) error {
 	var body struct {
-		Name     string        `json:"name" validate:"required,min=1,max=32"`
-		AvatarID *store.FileID `json:"avatar_id"`
+		Name      string        `json:"name" validate:"required,min=1,max=32"`
+		KeyPrefix *string       `json:"key_prefix" validate:"omitempty,min=1,max=10"`
+		AvatarID  *store.FileID `json:"avatar_id"`
 	}
 	if err := c.Bind(&body); err != nil {
 		return err
@@ -64,8 +67,9 @@ func (api *APIService) addProject(c echo.Context) error {
 
 	userService := api.mustGetUserService(c)
 	project, err := userService.AddProject(&userservice.AddProjectOptions{
-		Name:     body.Name,
-		AvatarID: body.AvatarID,
+		Name:      body.Name,
+		KeyPrefix: body.KeyPrefix,
+		AvatarID:  body.AvatarID,
 	})
 	if err != nil {
 		return err
@@ -107,8 +111,11 @@ func (api *APIService) clearProject(c echo.Context) error {
 
 func (api *APIService) editProject(c echo.Context) error {
 	var body struct {
-		Name     *string       `json:"name" validate:"omitempty,min=1,max=32"`
-		AvatarID *store.FileID `json:"avatar_id"`
+		Name      *string       `json:"name" validate:"omitempty,min=1,max=32"`
+		KeyPrefix *string       `json:"key_prefix" validate:"omitempty,min=1,max=10"`
+		AvatarID  *store.FileID `json:"avatar_id"`
+
+		SkipWeekends *bool `json:"skip_weekends"`
 	}
 	if err := c.Bind(&body); err != nil {
 		return err
@@ -123,7 +130,10 @@ func (api *APIService) editProject(c echo.Context) error {
 	err := userService.EditProject(&userservice.EditProjectOptions{
 		ProjectID: projectID,
 		Name:      body.Name,
+		KeyPrefix: body.KeyPrefix,
 		AvatarID:  body.AvatarID,
+
+		SkipWeekends: body.SkipWeekends,
 	})
 	if err != nil {
 		return err
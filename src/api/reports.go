@@ -0,0 +1,192 @@
+package api
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/lesnoi-kot/karten-backend/src/modules/timesheet"
+	"github.com/lesnoi-kot/karten-backend/src/userservice"
+)
+
+const reportDateLayout = "2006-01-02"
+
+type TimesheetRowDTO struct {
+	Key     string `json:"key"`
+	Name    string `json:"name"`
+	Seconds int64  `json:"seconds"`
+}
+
+type TimesheetDTO struct {
+	From         time.Time          `json:"from"`
+	To           time.Time          `json:"to"`
+	GroupBy      string             `json:"group_by"`
+	TimeZone     string             `json:"time_zone"`
+	TotalSeconds int64              `json:"total_seconds"`
+	Rows         []*TimesheetRowDTO `json:"rows"`
+}
+
+type EstimateReportTaskDTO struct {
+	ID        string   `json:"id"`
+	Key       string   `json:"key"`
+	Name      string   `json:"name"`
+	Archived  bool     `json:"archived"`
+	Estimate  *float64 `json:"estimate"`
+	SpentTime int64    `json:"spent_time"`
+}
+
+type EstimateReportBoardDTO struct {
+	ID                 string                   `json:"id"`
+	Name               string                   `json:"name"`
+	EstimateUnit       string                   `json:"estimate_unit"`
+	Estimate           float64                  `json:"estimate"`
+	EstimatedSpentTime int64                    `json:"estimated_spent_time"`
+	SpentTime          int64                    `json:"spent_time"`
+	EstimatedTasks     int                      `json:"estimated_tasks"`
+	UnestimatedTasks   int                      `json:"unestimated_tasks"`
+	Ratio              *float64                 `json:"ratio"`
+	Tasks              []*EstimateReportTaskDTO `json:"tasks"`
+}
+
+type EstimateReportDTO struct {
+	ProjectID string                    `json:"project_id"`
+	Boards    []*EstimateReportBoardDTO `json:"boards"`
+}
+
+func (api *APIService) getTimesheet(c echo.Context) error {
+	var query struct {
+		From         string `query:"from" validate:"required"`
+		To           string `query:"to" validate:"required"`
+		TimeZone     string `query:"tz"`
+		GroupBy      string `query:"group_by"`
+		RoundMinutes int    `query:"round_minutes" validate:"min=0,max=1440"`
+		RoundMode    string `query:"round_mode"`
+		ProjectID    string `query:"project_id"`
+		BoardID      string `query:"board_id"`
+		Format       string `query:"format" validate:"omitempty,oneof=json csv"`
+	}
+	if err := c.Bind(&query); err != nil {
+		return err
+	}
+	if err := c.Validate(&query); err != nil {
+		return err
+	}
+
+	from, to, loc, err := parseReportRange(query.From, query.To, query.TimeZone)
+	if err != nil {
+		return err
+	}
+
+	groupBy, err := timesheet.ParseGroupBy(query.GroupBy)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	roundMode, err := timesheet.ParseRoundingMode(query.RoundMode)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	userService := api.mustGetUserService(c)
+	report, err := userService.GetTimesheet(&userservice.GetTimesheetOptions{
+		From:      from.UTC(),
+		To:        to.UTC(),
+		ProjectID: query.ProjectID,
+		BoardID:   query.BoardID,
+		GroupBy:   groupBy,
+		Location:  loc,
+		Rounding: timesheet.Rounding{
+			Step: time.Duration(query.RoundMinutes) * time.Minute,
+			Mode: roundMode,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	if query.Format == "csv" {
+		filename := fmt.Sprintf("timesheet_%s_%s.csv", query.From, query.To)
+		return writeTimesheetCSV(c, report, filename)
+	}
+
+	return c.JSON(http.StatusOK, OK(timesheetToDTO(report)))
+}
+
+// Both dates are calendar days in the given time zone, "to" is inclusive.
+// The returned range is half-open.
+func parseReportRange(fromDate, toDate, timeZone string) (time.Time, time.Time, *time.Location, error) {
+	loc, err := time.LoadLocation(timeZone)
+	if err != nil {
+		return time.Time{}, time.Time{}, nil, echo.NewHTTPError(http.StatusBadRequest, "Unknown time zone")
+	}
+
+	from, err := time.ParseInLocation(reportDateLayout, fromDate, loc)
+	if err != nil {
+		return time.Time{}, time.Time{}, nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid from date")
+	}
+	to, err := time.ParseInLocation(reportDateLayout, toDate, loc)
+	if err != nil {
+		return time.Time{}, time.Time{}, nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid to date")
+	}
+	to = to.AddDate(0, 0, 1)
+
+	if !to.After(from) {
+		return time.Time{}, time.Time{}, nil, echo.NewHTTPError(http.StatusBadRequest, "Empty date range")
+	}
+
+	return from, to, loc, nil
+}
+
+func writeTimesheetCSV(c echo.Context, report *timesheet.Report, filename string) error {
+	c.Response().Header().Set(echo.HeaderContentType, "text/csv; charset=utf-8")
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", filename))
+	c.Response().WriteHeader(http.StatusOK)
+
+	w := csv.NewWriter(c.Response())
+	records := [][]string{{string(report.GroupBy), "name", "seconds", "hours"}}
+
+	for _, row := range report.Rows {
+		records = append(records, []string{
+			row.Key,
+			row.Name,
+			strconv.FormatInt(row.Seconds, 10),
+			formatHours(row.Seconds),
+		})
+	}
+
+	records = append(records, []string{
+		"total",
+		"",
+		strconv.FormatInt(report.TotalSeconds, 10),
+		formatHours(report.TotalSeconds),
+	})
+
+	return w.WriteAll(records)
+}
+
+func formatHours(seconds int64) string {
+	return strconv.FormatFloat(float64(seconds)/3600, 'f', 2, 64)
+}
+
+func (api *APIService) getEstimateReport(c echo.Context) error {
+	var query struct {
+		ProjectID string `query:"project_id" validate:"required"`
+	}
+	if err := c.Bind(&query); err != nil {
+		return err
+	}
+	if err := c.Validate(&query); err != nil {
+		return err
+	}
+
+	report, err := api.mustGetUserService(c).GetEstimateReport(&userservice.GetEstimateReportOptions{
+		ProjectID: query.ProjectID,
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, OK(estimateReportToDTO(report)))
+}
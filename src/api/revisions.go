@@ -0,0 +1,90 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/lesnoi-kot/karten-backend/src/userservice"
+	"github.com/samber/lo"
+)
+
+type TaskRevisionDTO struct {
+	Number      int       `json:"number"`
+	UserID      int       `json:"user_id,omitempty"`
+	Text        string    `json:"text"`
+	Diff        string    `json:"diff"`
+	DateCreated time.Time `json:"date_created"`
+	DateUpdated time.Time `json:"date_updated"`
+
+	Author *PublicUserDTO `json:"author,omitempty"`
+}
+
+type TaskRevisionsDTO struct {
+	Items []*TaskRevisionDTO `json:"items"`
+	Total int                `json:"total"`
+}
+
+func (api *APIService) getTaskRevisions(c echo.Context) error {
+	var query struct {
+		Limit  int `query:"limit" validate:"min=0,max=100"`
+		Offset int `query:"offset" validate:"min=0"`
+	}
+	if err := c.Bind(&query); err != nil {
+		return err
+	}
+	if err := c.Validate(&query); err != nil {
+		return err
+	}
+	if query.Limit == 0 {
+		query.Limit = 20
+	}
+
+	taskID := c.Param("id")
+	revisions, total, err := api.mustGetUserService(c).GetTaskRevisions(&userservice.GetTaskRevisionsOptions{
+		TaskID: taskID,
+		Limit:  query.Limit,
+		Offset: query.Offset,
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, OK(&TaskRevisionsDTO{
+		Items: lo.Map(revisions, func(revision *userservice.TaskRevisionWithDiff, _ int) *TaskRevisionDTO {
+			return taskRevisionToDTO(revision)
+		}),
+		Total: total,
+	}))
+}
+
+func (api *APIService) restoreTaskRevision(c echo.Context) error {
+	number, err := strconv.Atoi(c.Param("rev"))
+	if err != nil || number < 1 {
+		return echo.ErrNotFound
+	}
+
+	taskID := c.Param("id")
+	user := api.mustGetUserService(c)
+	err = user.RestoreTaskRevision(&userservice.RestoreTaskRevisionOptions{
+		TaskID: taskID,
+		Number: number,
+	})
+	if err != nil {
+		return err
+	}
+
+	task, err := user.GetTask(&userservice.GetTaskOptions{
+		TaskID:                taskID,
+		IncludeComments:       true,
+		IncludeLabels:         true,
+		IncludeAttachments:    true,
+		SkipCommentTextRender: true,
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, OK(taskToDTO(task)))
+}
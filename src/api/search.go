@@ -0,0 +1,63 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/samber/lo"
+
+	"github.com/lesnoi-kot/karten-backend/src/userservice"
+)
+
+type SearchHitDTO struct {
+	Kind      string    `json:"kind"`
+	Rank      float64   `json:"rank"`
+	Snippet   string    `json:"snippet"` // HTML with the matches in <mark> tags
+	Date      time.Time `json:"date"`
+	CommentID string    `json:"comment_id,omitempty"`
+	FileID    string    `json:"file_id,omitempty"`
+	FileName  string    `json:"file_name,omitempty"`
+
+	TaskID       string `json:"task_id"`
+	TaskKey      string `json:"task_key"`
+	TaskName     string `json:"task_name"`
+	TaskListID   string `json:"task_list_id"`
+	TaskListName string `json:"task_list_name"`
+	BoardID      string `json:"board_id"`
+	BoardName    string `json:"board_name"`
+	ProjectID    string `json:"project_id"`
+	ProjectName  string `json:"project_name"`
+}
+
+func (api *APIService) search(c echo.Context) error {
+	query := struct {
+		Query    string `query:"q" validate:"required,max=256"`
+		Language string `query:"lang"`
+		Limit    int    `query:"limit" validate:"min=1,max=100"`
+	}{
+		Limit: 20,
+	}
+	if err := c.Bind(&query); err != nil {
+		return err
+	}
+
+	query.Query = strings.TrimSpace(query.Query)
+	if err := c.Validate(&query); err != nil {
+		return err
+	}
+
+	hits, err := api.mustGetUserService(c).Search(&userservice.SearchOptions{
+		Query:    query.Query,
+		Language: query.Language,
+		Limit:    query.Limit,
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, OK(lo.Map(hits, func(hit *userservice.SearchHit, _ int) *SearchHitDTO {
+		return searchHitToDTO(hit)
+	})))
+}
@@ -0,0 +1,195 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/lesnoi-kot/karten-backend/src/store"
+	"github.com/lesnoi-kot/karten-backend/src/userservice"
+	"github.com/samber/lo"
+)
+
+type SprintSummaryDTO struct {
+	Committed  int `json:"committed"`
+	Added      int `json:"added"`
+	Removed    int `json:"removed"`
+	Completed  int `json:"completed"`
+	Unfinished int `json:"unfinished"`
+
+	CommittedEstimate float64 `json:"committed_estimate"`
+	CompletedEstimate float64 `json:"completed_estimate"`
+}
+
+type SprintDTO struct {
+	ID          string     `json:"id"`
+	BoardID     string     `json:"board_id"`
+	Name        string     `json:"name"`
+	Goal        string     `json:"goal"`
+	DateStart   string     `json:"date_start"`
+	DateEnd     string     `json:"date_end"`
+	DateCreated time.Time  `json:"date_created"`
+	DateClosed  *time.Time `json:"date_closed"`
+
+	Summary *SprintSummaryDTO `json:"summary,omitempty"`
+}
+
+func parseSprintDate(value string) (time.Time, error) {
+	date, err := time.Parse(reportDateLayout, value)
+	if err != nil {
+		return time.Time{}, echo.NewHTTPError(http.StatusBadRequest, "Invalid sprint date")
+	}
+
+	return date, nil
+}
+
+func (api *APIService) getSprints(c echo.Context) error {
+	boardID := c.Param("id")
+	sprints, err := api.mustGetUserService(c).GetSprints(boardID)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, OK(lo.Map(sprints, func(sprint *store.Sprint, _ int) *SprintDTO {
+		return sprintToDTO(sprint)
+	})))
+}
+
+func (api *APIService) addSprint(c echo.Context) error {
+	var body struct {
+		Name      string `json:"name" validate:"required,min=1,max=64"`
+		Goal      string `json:"goal"`
+		DateStart string `json:"date_start" validate:"required"`
+		DateEnd   string `json:"date_end" validate:"required"`
+	}
+	if err := c.Bind(&body); err != nil {
+		return err
+	}
+
+	body.Name = strings.TrimSpace(body.Name)
+	body.Goal = strings.TrimSpace(body.Goal)
+	if err := c.Validate(&body); err != nil {
+		return err
+	}
+
+	dateStart, err := parseSprintDate(body.DateStart)
+	if err != nil {
+		return err
+	}
+	dateEnd, err := parseSprintDate(body.DateEnd)
+	if err != nil {
+		return err
+	}
+	if dateEnd.Before(dateStart) {
+		return echo.NewHTTPError(http.StatusBadRequest, "Sprint ends before it starts")
+	}
+
+	boardID := c.Param("id")
+	sprint, err := api.mustGetUserService(c).AddSprint(&userservice.AddSprintOptions{
+		BoardID:   boardID,
+		Name:      body.Name,
+		Goal:      body.Goal,
+		DateStart: dateStart,
+		DateEnd:   dateEnd,
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, OK(sprintToDTO(sprint)))
+}
+
+func (api *APIService) getSprint(c echo.Context) error {
+	sprintID := c.Param("id")
+	sprint, err := api.mustGetUserService(c).GetSprint(sprintID)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, OK(sprintWithSummaryToDTO(sprint)))
+}
+
+func (api *APIService) editSprint(c echo.Context) error {
+	var body struct {
+		Name      *string `json:"name" validate:"omitempty,min=1,max=64"`
+		Goal      *string `json:"goal"`
+		DateStart *string `json:"date_start"`
+		DateEnd   *string `json:"date_end"`
+	}
+	if err := c.Bind(&body); err != nil {
+		return err
+	}
+
+	if body.Name != nil {
+		*body.Name = strings.TrimSpace(*body.Name)
+	}
+	if body.Goal != nil {
+		*body.Goal = strings.TrimSpace(*body.Goal)
+	}
+	if err := c.Validate(&body); err != nil {
+		return err
+	}
+
+	opts := &userservice.EditSprintOptions{
+		SprintID: c.Param("id"),
+		Name:     body.Name,
+		Goal:     body.Goal,
+	}
+	if body.DateStart != nil {
+		date, err := parseSprintDate(*body.DateStart)
+		if err != nil {
+			return err
+		}
+		opts.DateStart = &date
+	}
+	if body.DateEnd != nil {
+		date, err := parseSprintDate(*body.DateEnd)
+		if err != nil {
+			return err
+		}
+		opts.DateEnd = &date
+	}
+
+	user := api.mustGetUserService(c)
+	if err := user.EditSprint(opts); err != nil {
+		return err
+	}
+
+	sprint, err := user.GetSprint(opts.SprintID)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, OK(sprintWithSummaryToDTO(sprint)))
+}
+
+func (api *APIService) deleteSprint(c echo.Context) error {
+	sprintID := c.Param("id")
+	if err := api.mustGetUserService(c).DeleteSprint(sprintID); err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (api *APIService) closeSprint(c echo.Context) error {
+	var body struct {
+		Rollover     bool    `json:"rollover"`
+		NextSprintID *string `json:"next_sprint_id"`
+	}
+	if err := c.Bind(&body); err != nil {
+		return err
+	}
+
+	sprint, err := api.mustGetUserService(c).CloseSprint(&userservice.CloseSprintOptions{
+		SprintID:     c.Param("id"),
+		Rollover:     body.Rollover,
+		NextSprintID: body.NextSprintID,
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, OK(sprintWithSummaryToDTO(sprint)))
+}
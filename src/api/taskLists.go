@@ -19,6 +19,11 @@ type TaskListDTO struct {
 	Position    int64       `json:"position"`
 	DateCreated time.Time   `json:"date_created"`
 	Color       store.Color `json:"color"`
+	Estimate    float64     `json:"estimate"`
+	SpentTime   int64       `json:"spent_time"`
+	TaskCount   int         `json:"task_count"`
+	WIPLimit    *int        `json:"wip_limit"`
+	WIPMode     string      `json:"wip_mode"`
 
 	Tasks []*TaskDTO `json:"tasks,omitempty"`
 }
@@ -43,6 +48,8 @@ func (api *APIService) addTaskList(c echo.Context) error {
 		Name     string `json:"name" validate:"required,min=1,max=32"`
 		Color    int    `json:"color"`
 		Position int64  `json:"position"`
+		WIPLimit *int   `json:"wip_limit" validate:"omitempty,min=1"`
+		WIPMode  string `json:"wip_mode" validate:"omitempty,oneof=soft hard"`
 	}
 	if err := c.Bind(&body); err != nil {
 		return err
@@ -60,6 +67,8 @@ func (api *APIService) addTaskList(c echo.Context) error {
 		Name:     body.Name,
 		Color:    body.Color,
 		Position: body.Position,
+		WIPLimit: body.WIPLimit,
+		WIPMode:  body.WIPMode,
 	})
 	if err != nil {
 		return err
@@ -74,6 +83,8 @@ func (api *APIService) editTaskList(c echo.Context) error {
 		Archived *bool        `json:"archived"`
 		Position *int64       `json:"position"`
 		Color    *store.Color `json:"color"`
+		WIPLimit *int         `json:"wip_limit" validate:"omitempty,min=0"`
+		WIPMode  *string      `json:"wip_mode" validate:"omitempty,oneof=soft hard"`
 	}
 	if err := c.Bind(&body); err != nil {
 		return err
@@ -95,6 +106,8 @@ func (api *APIService) editTaskList(c echo.Context) error {
 		Archived:   body.Archived,
 		Color:      body.Color,
 		Position:   body.Position,
+		WIPLimit:   body.WIPLimit,
+		WIPMode:    body.WIPMode,
 	})
 	if err != nil {
 		return err
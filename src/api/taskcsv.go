@@ -0,0 +1,107 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/samber/lo"
+
+	"github.com/lesnoi-kot/karten-backend/src/modules/taskcsv"
+	"github.com/lesnoi-kot/karten-backend/src/userservice"
+)
+
+type TaskCSVRowErrorDTO struct {
+	Line    int    `json:"line"`
+	Column  string `json:"column"`
+	Message string `json:"message"`
+}
+
+type TaskCSVImportDTO struct {
+	DryRun  bool                  `json:"dry_run"`
+	Rows    int                   `json:"rows"`
+	Created int                   `json:"created"`
+	Errors  []*TaskCSVRowErrorDTO `json:"errors"`
+}
+
+func (api *APIService) exportBoardCSV(c echo.Context) error {
+	return api.exportTasksCSV(c, &userservice.GetTaskCSVRowsOptions{BoardID: c.Param("id")}, "board")
+}
+
+func (api *APIService) exportProjectCSV(c echo.Context) error {
+	return api.exportTasksCSV(c, &userservice.GetTaskCSVRowsOptions{ProjectID: c.Param("id")}, "project")
+}
+
+func (api *APIService) exportTasksCSV(c echo.Context, args *userservice.GetTaskCSVRowsOptions, kind string) error {
+	columns, err := taskcsv.ParseColumns(c.QueryParam("columns"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	rows, err := api.mustGetUserService(c).GetTaskCSVRows(args)
+	if err != nil {
+		return err
+	}
+
+	filename := fmt.Sprintf("%s-%s-tasks.csv", kind, c.Param("id"))
+	c.Response().Header().Set(echo.HeaderContentType, "text/csv; charset=utf-8")
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", filename))
+	c.Response().WriteHeader(http.StatusOK)
+
+	return taskcsv.Write(c.Response(), columns, rows)
+}
+
+// Takes a multipart form: the "file", the "mapping" of task fields to the
+// CSV headers as a JSON object, an optional default "task_list_id" and
+// "dry_run".
+func (api *APIService) importBoardCSV(c echo.Context) error {
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "No CSV file")
+	}
+
+	var mapping taskcsv.Mapping
+	if err := json.Unmarshal([]byte(c.FormValue("mapping")), &mapping); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid column mapping")
+	}
+
+	dryRun := false
+	if value := c.FormValue("dry_run"); value != "" {
+		if dryRun, err = strconv.ParseBool(value); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid dry_run")
+		}
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	result, err := api.mustGetUserService(c).ImportTasksCSV(&userservice.ImportTasksCSVOptions{
+		BoardID:    c.Param("id"),
+		TaskListID: strings.TrimSpace(c.FormValue("task_list_id")),
+		Mapping:    mapping,
+		Data:       file,
+		DryRun:     dryRun,
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, OK(TaskCSVImportDTO{
+		DryRun:  dryRun,
+		Rows:    result.Rows,
+		Created: result.Created,
+		Errors: lo.Map(result.Errors, func(rowError *taskcsv.RowError, _ int) *TaskCSVRowErrorDTO {
+			return &TaskCSVRowErrorDTO{
+				Line:    rowError.Line,
+				Column:  string(rowError.Column),
+				Message: rowError.Message,
+			}
+		}),
+	}))
+}
@@ -19,10 +19,14 @@ type LabelDTO struct {
 	Color   int    `json:"color"`
 }
 
+const wipLimitWarningHeader = "X-WIP-Limit-Exceeded"
+
 type TaskDTO struct {
 	ID                  string     `json:"id"`
 	UserID              int        `json:"user_id"`
 	ShortID             string     `json:"short_id"`
+	Number              int64      `json:"number,omitempty"`
+	Key                 string     `json:"key,omitempty"`
 	TaskListID          string     `json:"task_list_id"`
 	Name                string     `json:"name"`
 	Text                string     `json:"text"`
@@ -32,7 +36,12 @@ type TaskDTO struct {
 	Archived            bool       `json:"archived"`
 	DateCreated         time.Time  `json:"date_created"`
 	DateStartedTracking *time.Time `json:"date_started_tracking"`
+	StartDate           *time.Time `json:"start_date"`
 	DueDate             *time.Time `json:"due_date"`
+	SprintID            *string    `json:"sprint_id"`
+	Estimate            *float64   `json:"estimate"`
+	Priority            int16      `json:"priority"`
+	LaneID              *string    `json:"lane_id"`
 
 	Comments    []*CommentDTO `json:"comments,omitempty"`
 	Attachments []*FileDTO    `json:"attachments,omitempty"`
@@ -56,12 +65,27 @@ func (api *APIService) getTask(c echo.Context) error {
 	return c.JSON(http.StatusOK, OK(taskToDTO(task)))
 }
 
+func (api *APIService) getTaskByKey(c echo.Context) error {
+	key := c.Param("key")
+	user := api.mustGetUserService(c)
+	task, err := user.GetTaskByKey(&userservice.GetTaskByKeyOptions{Key: key})
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, OK(taskToDTO(task)))
+}
+
 func (api *APIService) addTask(c echo.Context) error {
 	var body struct {
-		Name     string     `json:"name" validate:"required,min=1"`
-		Text     string     `json:"text"`
-		Position int64      `json:"position"`
-		DueDate  *time.Time `json:"due_date"`
+		Name      string     `json:"name" validate:"required,min=1"`
+		Text      string     `json:"text"`
+		Position  int64      `json:"position"`
+		StartDate *time.Time `json:"start_date"`
+		DueDate   *time.Time `json:"due_date"`
+		Estimate  *float64   `json:"estimate" validate:"omitempty,gt=0"`
+		Priority  int16      `json:"priority" validate:"min=0,max=4"`
+		LaneID    string     `json:"lane_id"`
 	}
 	if err := c.Bind(&body); err != nil {
 		return err
@@ -79,12 +103,20 @@ func (api *APIService) addTask(c echo.Context) error {
 		Name:       body.Name,
 		Text:       body.Text,
 		Position:   body.Position,
+		StartDate:  body.StartDate,
 		DueDate:    body.DueDate,
+		Estimate:   body.Estimate,
+		Priority:   body.Priority,
+		LaneID:     body.LaneID,
 	})
 	if err != nil {
 		return err
 	}
 
+	if err := setWIPLimitWarning(c, user, taskListID); err != nil {
+		return err
+	}
+
 	return c.JSON(http.StatusOK, OK(taskToDTO(task)))
 }
 
@@ -94,7 +126,12 @@ func (api *APIService) editTask(c echo.Context) error {
 		Name       *string    `json:"name" validate:"omitempty,min=1,max=32"`
 		Text       *string    `json:"text"`
 		Position   *int64     `json:"position"`
+		StartDate  *time.Time `json:"start_date"`
 		DueDate    *time.Time `json:"due_date"`
+		SprintID   *string    `json:"sprint_id"` // Empty string takes the task out of its sprint
+		Estimate   *float64   `json:"estimate" validate:"omitempty,min=0"`
+		Priority   *int16     `json:"priority" validate:"omitempty,min=0,max=4"`
+		LaneID     *string    `json:"lane_id"`
 	}
 	if err := c.Bind(&body); err != nil {
 		return err
@@ -118,7 +155,12 @@ func (api *APIService) editTask(c echo.Context) error {
 		Name:       body.Name,
 		Text:       body.Text,
 		Position:   body.Position,
+		StartDate:  body.StartDate,
 		DueDate:    body.DueDate,
+		SprintID:   body.SprintID,
+		Estimate:   body.Estimate,
+		Priority:   body.Priority,
+		LaneID:     body.LaneID,
 	})
 	if err != nil {
 		return err
@@ -135,6 +177,12 @@ func (api *APIService) editTask(c echo.Context) error {
 		return err
 	}
 
+	if body.TaskListID != nil {
+		if err := setWIPLimitWarning(c, user, task.TaskListID); err != nil {
+			return err
+		}
+	}
+
 	return c.JSON(http.StatusOK, OK(taskToDTO(task)))
 }
 
@@ -203,24 +251,7 @@ func (api *APIService) startTaskTracking(c echo.Context) error {
 	taskID := c.Param("id")
 	user := api.mustGetUserService(c)
 
-	task, err := user.GetTask(&userservice.GetTaskOptions{
-		TaskID:                taskID,
-		SkipTextRender:        true,
-		SkipCommentTextRender: true,
-	})
-	if err != nil {
-		return err
-	}
-
-	if task.DateStartedTracking != nil {
-		return c.NoContent(http.StatusOK)
-	}
-
-	now := time.Now()
-	err = user.EditTask(&userservice.EditTaskOptions{
-		TaskID:              taskID,
-		DateStartedTracking: &now,
-	})
+	err := user.StartTaskTracking(&userservice.StartTaskTrackingOptions{TaskID: taskID})
 	if err != nil {
 		return err
 	}
@@ -232,28 +263,7 @@ func (api *APIService) stopTaskTracking(c echo.Context) error {
 	taskID := c.Param("id")
 	user := api.mustGetUserService(c)
 
-	task, err := user.GetTask(&userservice.GetTaskOptions{
-		TaskID:                taskID,
-		SkipTextRender:        true,
-		SkipCommentTextRender: true,
-	})
-	if err != nil {
-		return err
-	}
-
-	if task.DateStartedTracking == nil {
-		return c.NoContent(http.StatusOK)
-	}
-
-	now := time.Now()
-	var nullTime time.Time
-	timeSpentSec := task.SpentTime + now.Unix() - task.DateStartedTracking.Unix()
-
-	err = user.EditTask(&userservice.EditTaskOptions{
-		TaskID:              taskID,
-		DateStartedTracking: &nullTime,
-		SpentTime:           &timeSpentSec,
-	})
+	err := user.StopTaskTracking(&userservice.StopTaskTrackingOptions{TaskID: taskID})
 	if err != nil {
 		return err
 	}
@@ -310,3 +320,18 @@ func (api *APIService) deleteLabelFromTask(c echo.Context) error {
 
 	return c.NoContent(http.StatusOK)
 }
+
+// Lists over their soft WIP limit still take tasks, the client is told about
+// it with a header.
+func setWIPLimitWarning(c echo.Context, user *userservice.UserService, taskListID string) error {
+	over, err := user.IsOverSoftWIPLimit(taskListID)
+	if err != nil {
+		return err
+	}
+
+	if over {
+		c.Response().Header().Set(wipLimitWarningHeader, taskListID)
+	}
+
+	return nil
+}
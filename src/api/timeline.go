@@ -0,0 +1,128 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/lesnoi-kot/karten-backend/src/userservice"
+)
+
+type TimelineTaskDTO struct {
+	ID        string     `json:"id"`
+	Key       string     `json:"key"`
+	Name      string     `json:"name"`
+	Priority  int16      `json:"priority"`
+	StartDate *time.Time `json:"start_date"`
+	DueDate   *time.Time `json:"due_date"`
+}
+
+type TimelineTaskListDTO struct {
+	ID    string             `json:"id"`
+	Name  string             `json:"name"`
+	Tasks []*TimelineTaskDTO `json:"tasks"`
+}
+
+type TimelineBoardDTO struct {
+	ID        string                 `json:"id"`
+	Name      string                 `json:"name"`
+	TaskLists []*TimelineTaskListDTO `json:"task_lists"`
+}
+
+type TaskDependencyDTO struct {
+	TaskID      string `json:"task_id"`
+	DependsOnID string `json:"depends_on_id"`
+}
+
+type TimelineDTO struct {
+	ProjectID    string               `json:"project_id"`
+	SkipWeekends bool                 `json:"skip_weekends"`
+	Boards       []*TimelineBoardDTO  `json:"boards"`
+	Dependencies []*TaskDependencyDTO `json:"dependencies"`
+}
+
+func (api *APIService) getProjectTimeline(c echo.Context) error {
+	projectID := c.Param("id")
+	timeline, err := api.mustGetUserService(c).GetProjectTimeline(&userservice.GetProjectTimelineOptions{
+		ProjectID: projectID,
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, OK(timelineToDTO(timeline)))
+}
+
+func (api *APIService) shiftProjectTasks(c echo.Context) error {
+	var body struct {
+		TaskIDs  []string `json:"task_ids" validate:"required,min=1,max=500"`
+		Days     int      `json:"days" validate:"min=-3650,max=3650"`
+		TimeZone string   `json:"tz"`
+	}
+	if err := c.Bind(&body); err != nil {
+		return err
+	}
+	if err := c.Validate(&body); err != nil {
+		return err
+	}
+
+	loc, err := time.LoadLocation(body.TimeZone)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Unknown time zone")
+	}
+
+	projectID := c.Param("id")
+	userService := api.mustGetUserService(c)
+	err = userService.ShiftTasks(&userservice.ShiftTasksOptions{
+		ProjectID: projectID,
+		TaskIDs:   body.TaskIDs,
+		Days:      body.Days,
+		Location:  loc,
+	})
+	if err != nil {
+		return err
+	}
+
+	timeline, err := userService.GetProjectTimeline(&userservice.GetProjectTimelineOptions{
+		ProjectID: projectID,
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, OK(timelineToDTO(timeline)))
+}
+
+func (api *APIService) addTaskDependency(c echo.Context) error {
+	var body struct {
+		DependsOnID string `json:"depends_on_id" validate:"required"`
+	}
+	if err := c.Bind(&body); err != nil {
+		return err
+	}
+	if err := c.Validate(&body); err != nil {
+		return err
+	}
+
+	err := api.mustGetUserService(c).AddTaskDependency(&userservice.AddTaskDependencyOptions{
+		TaskID:      c.Param("id"),
+		DependsOnID: body.DependsOnID,
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusOK)
+}
+
+func (api *APIService) deleteTaskDependency(c echo.Context) error {
+	err := api.mustGetUserService(c).DeleteTaskDependency(&userservice.DeleteTaskDependencyOptions{
+		TaskID:      c.Param("id"),
+		DependsOnID: c.Param("depends_on_id"),
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
@@ -0,0 +1,89 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/lesnoi-kot/karten-backend/src/userservice"
+	"github.com/samber/lo"
+)
+
+type TrashedItemDTO struct {
+	Type         string    `json:"type"`
+	ID           string    `json:"id"`
+	Name         string    `json:"name"`
+	ProjectID    string    `json:"project_id"`
+	ProjectName  string    `json:"project_name"`
+	BoardID      string    `json:"board_id,omitempty"`
+	BoardName    string    `json:"board_name,omitempty"`
+	TaskListID   string    `json:"task_list_id,omitempty"`
+	TaskListName string    `json:"task_list_name,omitempty"`
+	DateDeleted  time.Time `json:"date_deleted"`
+	DateExpires  time.Time `json:"date_expires"`
+}
+
+type TrashDTO struct {
+	Items []*TrashedItemDTO `json:"items"`
+	Total int               `json:"total"`
+}
+
+func (api *APIService) getTrash(c echo.Context) error {
+	var query struct {
+		Type   string `query:"type" validate:"omitempty,oneof=project board task_list task"`
+		Limit  int    `query:"limit" validate:"min=0,max=100"`
+		Offset int    `query:"offset" validate:"min=0"`
+	}
+	if err := c.Bind(&query); err != nil {
+		return err
+	}
+	if err := c.Validate(&query); err != nil {
+		return err
+	}
+	if query.Limit == 0 {
+		query.Limit = 20
+	}
+
+	userService := api.mustGetUserService(c)
+	items, total, err := userService.GetTrash(&userservice.GetTrashOptions{
+		Type:   query.Type,
+		Limit:  query.Limit,
+		Offset: query.Offset,
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, OK(&TrashDTO{
+		Items: lo.Map(items, func(item *userservice.TrashedItem, _ int) *TrashedItemDTO {
+			return trashedItemToDTO(item)
+		}),
+		Total: total,
+	}))
+}
+
+func (api *APIService) restoreFromTrash(c echo.Context) error {
+	var body struct {
+		Type string `json:"type" validate:"required,oneof=project board task_list task"`
+		ID   string `json:"id" validate:"required"`
+	}
+	if err := c.Bind(&body); err != nil {
+		return err
+	}
+
+	body.ID = strings.TrimSpace(body.ID)
+	if err := c.Validate(&body); err != nil {
+		return err
+	}
+
+	err := api.mustGetUserService(c).RestoreFromTrash(&userservice.RestoreFromTrashOptions{
+		Type: body.Type,
+		ID:   body.ID,
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusOK)
+}
@@ -0,0 +1,172 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/lesnoi-kot/karten-backend/src/store"
+	"github.com/lesnoi-kot/karten-backend/src/userservice"
+	"github.com/samber/lo"
+)
+
+type ViewFilterDTO struct {
+	LabelIDs      []int      `json:"label_ids,omitempty"`
+	DueFrom       *time.Time `json:"due_from,omitempty"`
+	DueTo         *time.Time `json:"due_to,omitempty"`
+	DueWithinDays *int       `json:"due_within_days,omitempty" validate:"omitempty,min=0,max=366"`
+	NoDueDate     bool       `json:"no_due_date,omitempty"`
+	Text          string     `json:"text,omitempty" validate:"max=256"`
+	Completion    string     `json:"completion,omitempty" validate:"omitempty,oneof=open done"`
+	Archived      string     `json:"archived,omitempty" validate:"omitempty,oneof=include only"`
+	MinPriority   int16      `json:"min_priority,omitempty" validate:"min=0,max=4"`
+	GroupBy       string     `json:"group_by,omitempty" validate:"omitempty,oneof=list label priority due_date"`
+	SortBy        string     `json:"sort_by,omitempty" validate:"omitempty,oneof=position due_date priority date_created name"`
+	SortDesc      bool       `json:"sort_desc,omitempty"`
+}
+
+type ViewDTO struct {
+	ID          string         `json:"id"`
+	BoardID     *string        `json:"board_id"`
+	ProjectID   *string        `json:"project_id"`
+	Name        string         `json:"name"`
+	Filter      *ViewFilterDTO `json:"filter"`
+	DateCreated time.Time      `json:"date_created"`
+}
+
+type TaskGroupDTO struct {
+	Key     string   `json:"key"` // Empty for the tasks without the grouping value
+	Name    string   `json:"name"`
+	TaskIDs []string `json:"task_ids"`
+}
+
+func (api *APIService) getBoardViews(c echo.Context) error {
+	views, err := api.mustGetUserService(c).GetBoardViews(c.Param("id"))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, OK(lo.Map(views, func(view *store.View, _ int) *ViewDTO {
+		return viewToDTO(view)
+	})))
+}
+
+func (api *APIService) getProjectViews(c echo.Context) error {
+	views, err := api.mustGetUserService(c).GetProjectViews(c.Param("id"))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, OK(lo.Map(views, func(view *store.View, _ int) *ViewDTO {
+		return viewToDTO(view)
+	})))
+}
+
+func (api *APIService) addBoardView(c echo.Context) error {
+	return api.addView(c, &userservice.AddViewOptions{BoardID: c.Param("id")})
+}
+
+func (api *APIService) addProjectView(c echo.Context) error {
+	return api.addView(c, &userservice.AddViewOptions{ProjectID: c.Param("id")})
+}
+
+func (api *APIService) addView(c echo.Context, opts *userservice.AddViewOptions) error {
+	var body struct {
+		Name   string        `json:"name" validate:"required,min=1,max=64"`
+		Filter ViewFilterDTO `json:"filter"`
+	}
+	if err := c.Bind(&body); err != nil {
+		return err
+	}
+
+	body.Name = strings.TrimSpace(body.Name)
+	body.Filter.Text = strings.TrimSpace(body.Filter.Text)
+	if err := c.Validate(&body); err != nil {
+		return err
+	}
+
+	opts.Name = body.Name
+	opts.Filter = viewFilterFromDTO(&body.Filter)
+	view, err := api.mustGetUserService(c).AddView(opts)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, OK(viewToDTO(view)))
+}
+
+func (api *APIService) getView(c echo.Context) error {
+	view, err := api.mustGetUserService(c).GetView(c.Param("id"))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, OK(viewToDTO(view)))
+}
+
+func (api *APIService) editView(c echo.Context) error {
+	var body struct {
+		Name   *string        `json:"name" validate:"omitempty,min=1,max=64"`
+		Filter *ViewFilterDTO `json:"filter"`
+	}
+	if err := c.Bind(&body); err != nil {
+		return err
+	}
+
+	if body.Name != nil {
+		*body.Name = strings.TrimSpace(*body.Name)
+	}
+	if body.Filter != nil {
+		body.Filter.Text = strings.TrimSpace(body.Filter.Text)
+	}
+	if err := c.Validate(&body); err != nil {
+		return err
+	}
+
+	opts := &userservice.EditViewOptions{
+		ViewID: c.Param("id"),
+		Name:   body.Name,
+	}
+	if body.Filter != nil {
+		filter := viewFilterFromDTO(body.Filter)
+		opts.Filter = &filter
+	}
+
+	userService := api.mustGetUserService(c)
+	if err := userService.EditView(opts); err != nil {
+		return err
+	}
+
+	view, err := userService.GetView(opts.ViewID)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, OK(viewToDTO(view)))
+}
+
+func (api *APIService) deleteView(c echo.Context) error {
+	if err := api.mustGetUserService(c).DeleteView(c.Param("id")); err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func viewFilterFromDTO(dto *ViewFilterDTO) store.ViewFilter {
+	return store.ViewFilter{
+		LabelIDs:      dto.LabelIDs,
+		DueFrom:       dto.DueFrom,
+		DueTo:         dto.DueTo,
+		DueWithinDays: dto.DueWithinDays,
+		NoDueDate:     dto.NoDueDate,
+		Text:          dto.Text,
+		Completion:    dto.Completion,
+		Archived:      dto.Archived,
+		MinPriority:   dto.MinPriority,
+		GroupBy:       dto.GroupBy,
+		SortBy:        dto.SortBy,
+		SortDesc:      dto.SortDesc,
+	}
+}
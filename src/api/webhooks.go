@@ -0,0 +1,176 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/samber/lo"
+
+	"github.com/lesnoi-kot/karten-backend/src/store"
+	"github.com/lesnoi-kot/karten-backend/src/userservice"
+)
+
+type WebhookDTO struct {
+	ID          string    `json:"id"`
+	ProjectID   string    `json:"project_id"`
+	URL         string    `json:"url"`
+	Events      []string  `json:"events"`
+	Enabled     bool      `json:"enabled"`
+	DateCreated time.Time `json:"date_created"`
+
+	// Only on creation, it is not shown later.
+	Secret string `json:"secret,omitempty"`
+}
+
+type WebhookDeliveryDTO struct {
+	ID            string          `json:"id"`
+	WebhookID     string          `json:"webhook_id"`
+	Event         string          `json:"event"`
+	Payload       json.RawMessage `json:"payload"`
+	Status        string          `json:"status"`
+	Attempts      int             `json:"attempts"`
+	NextAttemptAt *time.Time      `json:"next_attempt_at"`
+	ResponseCode  *int            `json:"response_code"`
+	Error         string          `json:"error,omitempty"`
+	DurationMS    int             `json:"duration_ms"`
+	DateCreated   time.Time       `json:"date_created"`
+	DateDelivered *time.Time      `json:"date_delivered"`
+}
+
+func (api *APIService) getWebhooks(c echo.Context) error {
+	hooks, err := api.mustGetUserService(c).GetWebhooks(c.Param("id"))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, OK(lo.Map(hooks, func(hook *store.Webhook, _ int) *WebhookDTO {
+		return webhookToDTO(hook)
+	})))
+}
+
+func (api *APIService) addWebhook(c echo.Context) error {
+	var body struct {
+		URL     string   `json:"url" validate:"required,url,max=2048"`
+		Secret  string   `json:"secret" validate:"omitempty,min=16,max=128"`
+		Events  []string `json:"events" validate:"required,min=1"`
+		Enabled *bool    `json:"enabled"`
+	}
+	if err := c.Bind(&body); err != nil {
+		return err
+	}
+
+	body.URL = strings.TrimSpace(body.URL)
+	if err := c.Validate(&body); err != nil {
+		return err
+	}
+
+	hook, err := api.mustGetUserService(c).AddWebhook(&userservice.AddWebhookOptions{
+		ProjectID: c.Param("id"),
+		URL:       body.URL,
+		Secret:    body.Secret,
+		Events:    body.Events,
+		Enabled:   body.Enabled == nil || *body.Enabled,
+	})
+	if err != nil {
+		return err
+	}
+
+	dto := webhookToDTO(hook)
+	dto.Secret = hook.Secret
+
+	return c.JSON(http.StatusOK, OK(dto))
+}
+
+func (api *APIService) getWebhook(c echo.Context) error {
+	hook, err := api.mustGetUserService(c).GetWebhook(c.Param("id"))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, OK(webhookToDTO(hook)))
+}
+
+func (api *APIService) editWebhook(c echo.Context) error {
+	var body struct {
+		URL     *string   `json:"url" validate:"omitempty,url,max=2048"`
+		Secret  *string   `json:"secret" validate:"omitempty,min=16,max=128"`
+		Events  *[]string `json:"events" validate:"omitempty,min=1"`
+		Enabled *bool     `json:"enabled"`
+	}
+	if err := c.Bind(&body); err != nil {
+		return err
+	}
+
+	if body.URL != nil {
+		*body.URL = strings.TrimSpace(*body.URL)
+	}
+	if err := c.Validate(&body); err != nil {
+		return err
+	}
+
+	userService := api.mustGetUserService(c)
+	err := userService.EditWebhook(&userservice.EditWebhookOptions{
+		WebhookID: c.Param("id"),
+		URL:       body.URL,
+		Secret:    body.Secret,
+		Events:    body.Events,
+		Enabled:   body.Enabled,
+	})
+	if err != nil {
+		return err
+	}
+
+	hook, err := userService.GetWebhook(c.Param("id"))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, OK(webhookToDTO(hook)))
+}
+
+func (api *APIService) deleteWebhook(c echo.Context) error {
+	if err := api.mustGetUserService(c).DeleteWebhook(c.Param("id")); err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (api *APIService) getWebhookDeliveries(c echo.Context) error {
+	limit := 50
+	if value := c.QueryParam("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > 500 {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid limit")
+		}
+		limit = parsed
+	}
+
+	deliveries, err := api.mustGetUserService(c).GetWebhookDeliveries(&userservice.GetWebhookDeliveriesOptions{
+		WebhookID: c.Param("id"),
+		Limit:     limit,
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, OK(lo.Map(deliveries, func(delivery *store.WebhookDelivery, _ int) *WebhookDeliveryDTO {
+		return webhookDeliveryToDTO(delivery)
+	})))
+}
+
+func (api *APIService) redeliverWebhook(c echo.Context) error {
+	delivery, err := api.mustGetUserService(c).RedeliverWebhook(&userservice.RedeliverWebhookOptions{
+		WebhookID:  c.Param("id"),
+		DeliveryID: c.Param("delivery_id"),
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, OK(webhookDeliveryToDTO(delivery)))
+}
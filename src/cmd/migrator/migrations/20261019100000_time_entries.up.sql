@@ -0,0 +1,16 @@
+CREATE TABLE time_entries (
+  id              uuid DEFAULT gen_random_uuid() PRIMARY KEY,
+  task_id         uuid NOT NULL REFERENCES tasks ON DELETE CASCADE,
+  user_id         integer NOT NULL REFERENCES users ON DELETE CASCADE,
+  date_started    timestamp NOT NULL,
+  date_ended      timestamp NOT NULL CHECK (date_ended >= date_started)
+);
+
+CREATE INDEX time_entries_user_id_date_started_idx ON time_entries (user_id, date_started);
+
+-- Time tracked before the entries existed has no dates, so it is attributed
+-- to the task creation moment.
+INSERT INTO time_entries (task_id, user_id, date_started, date_ended)
+SELECT id, user_id, date_created, date_created + make_interval(secs => spent_time)
+FROM tasks
+WHERE spent_time > 0;
@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS tasks_number_idx;
+
+ALTER TABLE tasks DROP COLUMN IF EXISTS number;
+
+ALTER TABLE projects
+  DROP CONSTRAINT IF EXISTS projects_user_id_key_prefix_key,
+  DROP CONSTRAINT IF EXISTS projects_key_prefix_check,
+  DROP COLUMN IF EXISTS next_task_number,
+  DROP COLUMN IF EXISTS key_prefix;
@@ -0,0 +1,47 @@
+ALTER TABLE projects
+  ADD COLUMN key_prefix         varchar(10),
+  ADD COLUMN next_task_number   bigint DEFAULT 1 NOT NULL;
+
+ALTER TABLE tasks ADD COLUMN number bigint;
+
+-- Derive prefixes for existing projects from their names, numbering
+-- duplicates within a user.
+WITH prefixes AS (
+  SELECT
+    id,
+    user_id,
+    coalesce(nullif(upper(left(regexp_replace(name, '[^A-Za-z]', '', 'g'), 3)), ''), 'PRJ') AS prefix
+  FROM projects
+), numbered AS (
+  SELECT id, prefix, row_number() OVER (PARTITION BY user_id, prefix ORDER BY id) AS n
+  FROM prefixes
+)
+UPDATE projects
+SET key_prefix = CASE WHEN numbered.n = 1 THEN numbered.prefix ELSE numbered.prefix || numbered.n END
+FROM numbered
+WHERE numbered.id = projects.id;
+
+ALTER TABLE projects
+  ALTER COLUMN key_prefix SET NOT NULL,
+  ADD CONSTRAINT projects_key_prefix_check CHECK (key_prefix ~ '^[A-Z][A-Z0-9]{0,9}$'),
+  ADD CONSTRAINT projects_user_id_key_prefix_key UNIQUE (user_id, key_prefix);
+
+WITH numbered AS (
+  SELECT
+    tasks.id,
+    row_number() OVER (PARTITION BY boards.project_id ORDER BY tasks.date_created, tasks.id) AS n
+  FROM tasks
+  JOIN task_lists ON task_lists.id = tasks.task_list_id
+  JOIN boards ON boards.id = task_lists.board_id
+)
+UPDATE tasks SET number = numbered.n FROM numbered WHERE numbered.id = tasks.id;
+
+UPDATE projects SET next_task_number = coalesce((
+  SELECT max(tasks.number)
+  FROM tasks
+  JOIN task_lists ON task_lists.id = tasks.task_list_id
+  JOIN boards ON boards.id = task_lists.board_id
+  WHERE boards.project_id = projects.id
+), 0) + 1;
+
+CREATE INDEX tasks_number_idx ON tasks (number);
@@ -0,0 +1,3 @@
+ALTER TABLE tasks DROP COLUMN IF EXISTS date_archived;
+ALTER TABLE task_lists DROP COLUMN IF EXISTS date_archived;
+ALTER TABLE boards DROP COLUMN IF EXISTS date_archived;
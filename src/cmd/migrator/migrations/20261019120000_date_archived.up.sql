@@ -0,0 +1,7 @@
+ALTER TABLE boards ADD COLUMN date_archived timestamp;
+ALTER TABLE task_lists ADD COLUMN date_archived timestamp;
+ALTER TABLE tasks ADD COLUMN date_archived timestamp;
+
+UPDATE boards SET date_archived = CURRENT_TIMESTAMP WHERE archived;
+UPDATE task_lists SET date_archived = CURRENT_TIMESTAMP WHERE archived;
+UPDATE tasks SET date_archived = CURRENT_TIMESTAMP WHERE archived;
@@ -0,0 +1,9 @@
+DELETE FROM tasks WHERE deleted_at IS NOT NULL;
+DELETE FROM task_lists WHERE deleted_at IS NOT NULL;
+DELETE FROM boards WHERE deleted_at IS NOT NULL;
+DELETE FROM projects WHERE deleted_at IS NOT NULL;
+
+ALTER TABLE tasks DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE task_lists DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE boards DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE projects DROP COLUMN IF EXISTS deleted_at;
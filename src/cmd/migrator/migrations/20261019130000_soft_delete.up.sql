@@ -0,0 +1,9 @@
+ALTER TABLE projects ADD COLUMN deleted_at timestamp;
+ALTER TABLE boards ADD COLUMN deleted_at timestamp;
+ALTER TABLE task_lists ADD COLUMN deleted_at timestamp;
+ALTER TABLE tasks ADD COLUMN deleted_at timestamp;
+
+CREATE INDEX projects_deleted_at_idx ON projects (deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX boards_deleted_at_idx ON boards (deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX task_lists_deleted_at_idx ON task_lists (deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX tasks_deleted_at_idx ON tasks (deleted_at) WHERE deleted_at IS NOT NULL;
@@ -0,0 +1,15 @@
+CREATE TABLE task_revisions (
+  id              uuid DEFAULT gen_random_uuid() PRIMARY KEY,
+  task_id         uuid NOT NULL REFERENCES tasks ON DELETE CASCADE,
+  user_id         integer REFERENCES users ON DELETE SET NULL,
+  number          integer NOT NULL CHECK (number > 0),
+  text            text NOT NULL,
+  date_created    timestamp DEFAULT CURRENT_TIMESTAMP NOT NULL,
+  date_updated    timestamp DEFAULT CURRENT_TIMESTAMP NOT NULL,
+  UNIQUE (task_id, number)
+);
+
+-- The current text of existing tasks becomes their first revision.
+INSERT INTO task_revisions (task_id, user_id, number, text, date_created, date_updated)
+SELECT id, user_id, 1, text, date_created, date_created
+FROM tasks;
@@ -0,0 +1,16 @@
+CREATE TABLE task_movements (
+  id                  uuid DEFAULT gen_random_uuid() PRIMARY KEY,
+  task_id             uuid NOT NULL REFERENCES tasks ON DELETE CASCADE,
+  user_id             integer REFERENCES users ON DELETE SET NULL,
+  from_task_list_id   uuid REFERENCES task_lists ON DELETE SET NULL, -- NULL when the task is created
+  to_task_list_id     uuid REFERENCES task_lists ON DELETE SET NULL,
+  date_created        timestamp DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+CREATE INDEX task_movements_task_id_date_created_idx ON task_movements (task_id, date_created);
+
+-- Earlier transitions are unknown, tasks are assumed to be created in their
+-- current list.
+INSERT INTO task_movements (task_id, user_id, to_task_list_id, date_created)
+SELECT id, user_id, task_list_id, date_created
+FROM tasks;
@@ -0,0 +1,32 @@
+CREATE TABLE board_snapshots (
+  board_id        uuid NOT NULL REFERENCES boards ON DELETE CASCADE,
+  task_list_id    uuid NOT NULL REFERENCES task_lists ON DELETE CASCADE,
+  date            date NOT NULL,
+  task_count      integer NOT NULL DEFAULT 0,
+  PRIMARY KEY (board_id, date, task_list_id)
+);
+
+-- Best-effort history for the existing boards: a task is counted in the list
+-- it moved to last before the end of the day. Deleted tasks and tasks
+-- archived by then are skipped.
+INSERT INTO board_snapshots (board_id, task_list_id, date, task_count)
+SELECT tl.board_id, tl.id, d.date, count(m.task_id)
+FROM boards AS b
+JOIN task_lists AS tl ON tl.board_id = b.id AND tl.deleted_at IS NULL
+CROSS JOIN LATERAL (
+  SELECT day::date AS date
+  FROM generate_series(b.date_created::date, current_date - 1, interval '1 day') AS day
+) AS d
+LEFT JOIN LATERAL (
+  SELECT DISTINCT ON (tm.task_id) tm.task_id, tm.to_task_list_id
+  FROM task_movements AS tm
+  JOIN tasks AS t ON t.id = tm.task_id
+  JOIN task_lists AS current_list ON current_list.id = t.task_list_id
+  WHERE current_list.board_id = b.id
+    AND t.deleted_at IS NULL
+    AND (t.date_archived IS NULL OR t.date_archived >= d.date + 1)
+    AND tm.date_created < d.date + 1
+  ORDER BY tm.task_id, tm.date_created DESC
+) AS m ON m.to_task_list_id = tl.id
+WHERE b.deleted_at IS NULL
+GROUP BY tl.board_id, tl.id, d.date;
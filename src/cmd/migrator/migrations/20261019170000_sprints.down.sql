@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS sprint_commitments;
+ALTER TABLE tasks DROP COLUMN IF EXISTS sprint_id;
+DROP TABLE IF EXISTS sprints;
@@ -0,0 +1,27 @@
+CREATE TABLE sprints (
+  id              uuid DEFAULT gen_random_uuid() PRIMARY KEY,
+  board_id        uuid NOT NULL REFERENCES boards ON DELETE CASCADE,
+  user_id         integer NOT NULL REFERENCES users ON DELETE CASCADE,
+  name            varchar(64) NOT NULL CHECK (length("name") > 0),
+  goal            text DEFAULT '' NOT NULL,
+  date_start      date NOT NULL,
+  date_end        date NOT NULL CHECK (date_end >= date_start),
+  date_created    timestamp DEFAULT CURRENT_TIMESTAMP NOT NULL,
+  date_closed     timestamp
+);
+
+CREATE INDEX sprints_board_id_date_start_idx ON sprints (board_id, date_start);
+
+ALTER TABLE tasks ADD COLUMN sprint_id uuid REFERENCES sprints ON DELETE SET NULL;
+CREATE INDEX tasks_sprint_id_idx ON tasks (sprint_id) WHERE sprint_id IS NOT NULL;
+
+-- Every task which has ever been in the sprint. Completion is fixed when the
+-- sprint is closed.
+CREATE TABLE sprint_commitments (
+  sprint_id       uuid NOT NULL REFERENCES sprints ON DELETE CASCADE,
+  task_id         uuid NOT NULL REFERENCES tasks ON DELETE CASCADE,
+  date_added      timestamp DEFAULT CURRENT_TIMESTAMP NOT NULL,
+  date_removed    timestamp,
+  completed       boolean DEFAULT false NOT NULL,
+  PRIMARY KEY (sprint_id, task_id)
+);
@@ -0,0 +1,2 @@
+ALTER TABLE tasks DROP COLUMN estimate;
+ALTER TABLE boards DROP COLUMN estimate_unit;
@@ -0,0 +1,6 @@
+-- Planned effort of a task: seconds for the "time" boards, story points for
+-- the "points" ones.
+ALTER TABLE boards ADD COLUMN estimate_unit varchar(16) DEFAULT 'time' NOT NULL
+  CHECK (estimate_unit IN ('time', 'points'));
+
+ALTER TABLE tasks ADD COLUMN estimate double precision CHECK (estimate > 0);
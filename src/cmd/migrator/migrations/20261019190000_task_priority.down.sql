@@ -0,0 +1,3 @@
+DROP INDEX tasks_user_id_priority_idx;
+DROP INDEX tasks_user_id_due_date_idx;
+ALTER TABLE tasks DROP COLUMN priority;
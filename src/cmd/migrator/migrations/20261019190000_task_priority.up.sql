@@ -0,0 +1,8 @@
+-- 0 is no priority, 4 is urgent.
+ALTER TABLE tasks ADD COLUMN priority smallint DEFAULT 0 NOT NULL
+  CHECK (priority BETWEEN 0 AND 4);
+
+CREATE INDEX tasks_user_id_due_date_idx ON tasks (user_id, due_date)
+  WHERE deleted_at IS NULL AND archived = false AND due_date IS NOT NULL;
+CREATE INDEX tasks_user_id_priority_idx ON tasks (user_id, priority)
+  WHERE deleted_at IS NULL AND archived = false AND priority > 0;
@@ -0,0 +1,3 @@
+DROP TABLE task_dependencies;
+ALTER TABLE projects DROP COLUMN skip_weekends;
+ALTER TABLE tasks DROP COLUMN start_date;
@@ -0,0 +1,15 @@
+ALTER TABLE tasks ADD COLUMN start_date timestamp;
+
+ALTER TABLE projects ADD COLUMN skip_weekends boolean DEFAULT false NOT NULL;
+
+-- The task can't start before the one it depends on is finished.
+CREATE TABLE task_dependencies (
+  task_id         uuid NOT NULL REFERENCES tasks ON DELETE CASCADE,
+  depends_on_id   uuid NOT NULL REFERENCES tasks ON DELETE CASCADE,
+  user_id         integer NOT NULL REFERENCES users ON DELETE CASCADE,
+  date_created    timestamp DEFAULT CURRENT_TIMESTAMP NOT NULL,
+  PRIMARY KEY (task_id, depends_on_id),
+  CHECK (task_id <> depends_on_id)
+);
+
+CREATE INDEX task_dependencies_depends_on_id_idx ON task_dependencies (depends_on_id);
@@ -0,0 +1,2 @@
+ALTER TABLE task_lists DROP COLUMN wip_mode;
+ALTER TABLE task_lists DROP COLUMN wip_limit;
@@ -0,0 +1,3 @@
+ALTER TABLE task_lists ADD COLUMN wip_limit integer CHECK (wip_limit > 0);
+ALTER TABLE task_lists ADD COLUMN wip_mode varchar(8) DEFAULT 'soft' NOT NULL
+  CHECK (wip_mode IN ('soft', 'hard'));
@@ -0,0 +1,3 @@
+ALTER TABLE tasks DROP COLUMN lane_id;
+ALTER TABLE boards DROP COLUMN lane_mode;
+DROP TABLE lanes;
@@ -0,0 +1,18 @@
+-- Horizontal swimlanes of a board. With the "label" mode the board labels
+-- act as the lanes instead.
+CREATE TABLE lanes (
+  id              uuid DEFAULT gen_random_uuid() PRIMARY KEY,
+  board_id        uuid NOT NULL REFERENCES boards ON DELETE CASCADE,
+  user_id         integer NOT NULL REFERENCES users ON DELETE CASCADE,
+  name            varchar(64) NOT NULL CHECK (length("name") > 0),
+  position        bigint DEFAULT 0 NOT NULL,
+  date_created    timestamp DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+CREATE INDEX lanes_board_id_position_idx ON lanes (board_id, position);
+
+ALTER TABLE boards ADD COLUMN lane_mode varchar(16) DEFAULT 'none' NOT NULL
+  CHECK (lane_mode IN ('none', 'explicit', 'label'));
+
+ALTER TABLE tasks ADD COLUMN lane_id uuid REFERENCES lanes ON DELETE SET NULL;
+CREATE INDEX tasks_lane_id_idx ON tasks (lane_id) WHERE lane_id IS NOT NULL;
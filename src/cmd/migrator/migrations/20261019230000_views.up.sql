@@ -0,0 +1,15 @@
+-- Saved task filters. A view belongs either to a board or to a project, the
+-- project ones apply to all of its boards.
+CREATE TABLE views (
+  id              uuid DEFAULT gen_random_uuid() PRIMARY KEY,
+  user_id         integer NOT NULL REFERENCES users ON DELETE CASCADE,
+  board_id        uuid REFERENCES boards ON DELETE CASCADE,
+  project_id      uuid REFERENCES projects ON DELETE CASCADE,
+  name            varchar(64) NOT NULL CHECK (length("name") > 0),
+  filter          jsonb DEFAULT '{}' NOT NULL,
+  date_created    timestamp DEFAULT CURRENT_TIMESTAMP NOT NULL,
+  CHECK ((board_id IS NULL) <> (project_id IS NULL))
+);
+
+CREATE INDEX views_board_id_idx ON views (board_id) WHERE board_id IS NOT NULL;
+CREATE INDEX views_project_id_idx ON views (project_id) WHERE project_id IS NOT NULL;
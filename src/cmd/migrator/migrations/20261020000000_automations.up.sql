@@ -0,0 +1,32 @@
+-- Board automation rules: when the trigger fires and the conditions hold for
+-- the task, the actions are applied to it.
+CREATE TABLE automation_rules (
+  id              uuid DEFAULT gen_random_uuid() PRIMARY KEY,
+  board_id        uuid NOT NULL REFERENCES boards ON DELETE CASCADE,
+  user_id         integer NOT NULL REFERENCES users ON DELETE CASCADE,
+  name            varchar(64) NOT NULL CHECK (length("name") > 0),
+  enabled         boolean DEFAULT true NOT NULL,
+  trigger         jsonb NOT NULL,
+  conditions      jsonb DEFAULT '[]' NOT NULL,
+  actions         jsonb DEFAULT '[]' NOT NULL,
+  last_run_at     timestamp, -- Of the time based triggers
+  date_created    timestamp DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+CREATE INDEX automation_rules_board_id_idx ON automation_rules (board_id);
+CREATE INDEX automation_rules_time_based_idx ON automation_rules ((trigger->>'type'))
+  WHERE enabled AND trigger->>'type' IN ('due_date_passed', 'schedule');
+
+-- Execution log of the rules.
+CREATE TABLE automation_runs (
+  id              bigserial PRIMARY KEY,
+  rule_id         uuid NOT NULL REFERENCES automation_rules ON DELETE CASCADE,
+  task_id         uuid REFERENCES tasks ON DELETE SET NULL,
+  trigger         varchar(32) NOT NULL,
+  status          varchar(16) NOT NULL CHECK (status IN ('success', 'failed', 'skipped')),
+  error           text DEFAULT '' NOT NULL,
+  depth           smallint DEFAULT 0 NOT NULL,
+  date_created    timestamp DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+CREATE INDEX automation_runs_rule_id_date_created_idx ON automation_runs (rule_id, date_created DESC);
@@ -0,0 +1,15 @@
+-- Secret tokens of the iCalendar feeds, only their SHA-256 hashes are kept.
+-- A token without a board is the feed of all boards of the user.
+CREATE TABLE feed_tokens (
+  id              uuid DEFAULT gen_random_uuid() PRIMARY KEY,
+  user_id         integer NOT NULL REFERENCES users ON DELETE CASCADE,
+  board_id        uuid REFERENCES boards ON DELETE CASCADE,
+  name            varchar(64) DEFAULT '' NOT NULL,
+  token_hash      bytea NOT NULL UNIQUE,
+  component       varchar(8) DEFAULT 'event' NOT NULL CHECK (component IN ('event', 'todo')),
+  include_completed boolean DEFAULT false NOT NULL,
+  date_created    timestamp DEFAULT CURRENT_TIMESTAMP NOT NULL,
+  date_last_used  timestamp
+);
+
+CREATE INDEX feed_tokens_user_id_idx ON feed_tokens (user_id);
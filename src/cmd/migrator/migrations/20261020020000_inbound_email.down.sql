@@ -0,0 +1,2 @@
+DROP TABLE email_messages;
+ALTER TABLE boards DROP COLUMN inbound_token;
@@ -0,0 +1,14 @@
+-- Secret local part of the inbound email address of the board.
+ALTER TABLE boards ADD COLUMN inbound_token varchar(32) UNIQUE;
+
+-- Emails turned into tasks and comments, replies are threaded by them.
+CREATE TABLE email_messages (
+  board_id        uuid NOT NULL REFERENCES boards ON DELETE CASCADE,
+  message_id      varchar(998) NOT NULL,
+  task_id         uuid NOT NULL REFERENCES tasks ON DELETE CASCADE,
+  comment_id      uuid REFERENCES comments ON DELETE CASCADE,
+  date_created    timestamp DEFAULT CURRENT_TIMESTAMP NOT NULL,
+  PRIMARY KEY (board_id, message_id)
+);
+
+CREATE INDEX email_messages_task_id_idx ON email_messages (task_id);
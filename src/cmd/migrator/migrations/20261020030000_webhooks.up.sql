@@ -0,0 +1,34 @@
+-- Outgoing webhooks of a project, called on the events listed in "events".
+CREATE TABLE webhooks (
+  id              uuid DEFAULT gen_random_uuid() PRIMARY KEY,
+  project_id      uuid NOT NULL REFERENCES projects ON DELETE CASCADE,
+  user_id         integer NOT NULL REFERENCES users ON DELETE CASCADE,
+  url             varchar(2048) NOT NULL,
+  secret          varchar(128) NOT NULL, -- Key of the HMAC-SHA256 signatures
+  events          varchar(32)[] NOT NULL CHECK (cardinality(events) > 0),
+  enabled         boolean DEFAULT true NOT NULL,
+  date_created    timestamp DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+CREATE INDEX webhooks_project_id_idx ON webhooks (project_id);
+
+-- Queue of the webhook calls and their log. A pending delivery is retried
+-- at next_attempt_at until it succeeds or runs out of attempts.
+CREATE TABLE webhook_deliveries (
+  id              uuid DEFAULT gen_random_uuid() PRIMARY KEY,
+  webhook_id      uuid NOT NULL REFERENCES webhooks ON DELETE CASCADE,
+  event           varchar(32) NOT NULL,
+  payload         jsonb NOT NULL,
+  status          varchar(16) DEFAULT 'pending' NOT NULL CHECK (status IN ('pending', 'succeeded', 'failed')),
+  attempts        smallint DEFAULT 0 NOT NULL,
+  next_attempt_at timestamp DEFAULT CURRENT_TIMESTAMP NOT NULL,
+  response_code   integer, -- Of the last attempt
+  response_body   text DEFAULT '' NOT NULL,
+  error           text DEFAULT '' NOT NULL,
+  duration_ms     integer DEFAULT 0 NOT NULL,
+  date_created    timestamp DEFAULT CURRENT_TIMESTAMP NOT NULL,
+  date_delivered  timestamp
+);
+
+CREATE INDEX webhook_deliveries_webhook_id_date_created_idx ON webhook_deliveries (webhook_id, date_created DESC);
+CREATE INDEX webhook_deliveries_pending_idx ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
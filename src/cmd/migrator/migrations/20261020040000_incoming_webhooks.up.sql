@@ -0,0 +1,13 @@
+-- Secret tokens of the incoming webhook URLs which create tasks in a list,
+-- only their SHA-256 hashes are kept.
+CREATE TABLE incoming_webhooks (
+  id              uuid DEFAULT gen_random_uuid() PRIMARY KEY,
+  task_list_id    uuid NOT NULL REFERENCES task_lists ON DELETE CASCADE,
+  user_id         integer NOT NULL REFERENCES users ON DELETE CASCADE,
+  name            varchar(64) DEFAULT '' NOT NULL,
+  token_hash      bytea NOT NULL UNIQUE,
+  date_created    timestamp DEFAULT CURRENT_TIMESTAMP NOT NULL,
+  date_last_used  timestamp
+);
+
+CREATE INDEX incoming_webhooks_task_list_id_idx ON incoming_webhooks (task_list_id);
@@ -0,0 +1,2 @@
+DROP TABLE task_links;
+DROP TABLE forge_hooks;
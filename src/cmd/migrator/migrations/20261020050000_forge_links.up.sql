@@ -0,0 +1,30 @@
+-- Receivers of the GitHub and Gitea webhooks of a project, "secret" is the
+-- key of their HMAC-SHA256 signatures.
+CREATE TABLE forge_hooks (
+  id              uuid DEFAULT gen_random_uuid() PRIMARY KEY,
+  project_id      uuid NOT NULL REFERENCES projects ON DELETE CASCADE,
+  user_id         integer NOT NULL REFERENCES users ON DELETE CASCADE,
+  name            varchar(64) DEFAULT '' NOT NULL,
+  secret          varchar(128) NOT NULL,
+  date_created    timestamp DEFAULT CURRENT_TIMESTAMP NOT NULL,
+  date_last_used  timestamp
+);
+
+CREATE INDEX forge_hooks_project_id_idx ON forge_hooks (project_id);
+
+-- Commits and pull requests which mention the task.
+CREATE TABLE task_links (
+  id              uuid DEFAULT gen_random_uuid() PRIMARY KEY,
+  task_id         uuid NOT NULL REFERENCES tasks ON DELETE CASCADE,
+  kind            varchar(16) NOT NULL CHECK (kind IN ('commit', 'pull_request')),
+  url             varchar(2048) NOT NULL,
+  external_id     varchar(64) NOT NULL, -- Commit hash or pull request number
+  repository      varchar(256) DEFAULT '' NOT NULL,
+  title           text DEFAULT '' NOT NULL,
+  author          varchar(256) DEFAULT '' NOT NULL,
+  state           varchar(16) DEFAULT '' NOT NULL, -- Of the pull requests
+  completed       boolean DEFAULT false NOT NULL, -- The link has completed the task
+  date_created    timestamp DEFAULT CURRENT_TIMESTAMP NOT NULL,
+  date_updated    timestamp DEFAULT CURRENT_TIMESTAMP NOT NULL,
+  UNIQUE (task_id, url)
+);
@@ -0,0 +1,6 @@
+DROP INDEX files_search_russian_idx;
+DROP INDEX files_search_english_idx;
+DROP INDEX comments_search_russian_idx;
+DROP INDEX comments_search_english_idx;
+DROP INDEX tasks_search_russian_idx;
+DROP INDEX tasks_search_english_idx;
@@ -0,0 +1,16 @@
+-- Full-text search indexes, one per supported text search configuration.
+-- The search queries repeat these expressions to use them.
+CREATE INDEX tasks_search_english_idx ON tasks
+  USING gin (to_tsvector('english'::regconfig, name || ' ' || text));
+CREATE INDEX tasks_search_russian_idx ON tasks
+  USING gin (to_tsvector('russian'::regconfig, name || ' ' || text));
+
+CREATE INDEX comments_search_english_idx ON comments
+  USING gin (to_tsvector('english'::regconfig, text));
+CREATE INDEX comments_search_russian_idx ON comments
+  USING gin (to_tsvector('russian'::regconfig, text));
+
+CREATE INDEX files_search_english_idx ON files
+  USING gin (to_tsvector('english'::regconfig, name));
+CREATE INDEX files_search_russian_idx ON files
+  USING gin (to_tsvector('russian'::regconfig, name));
@@ -0,0 +1,2 @@
+DROP INDEX tasks_project_id_number_key;
+ALTER TABLE tasks DROP COLUMN project_id;
@@ -0,0 +1,32 @@
+-- Tasks keep their project to make the numbers unique within it. Tasks on
+-- the boards without a project have neither.
+ALTER TABLE tasks ADD COLUMN project_id uuid REFERENCES projects ON DELETE CASCADE;
+
+UPDATE tasks SET project_id = boards.project_id
+FROM task_lists
+JOIN boards ON boards.id = task_lists.board_id
+WHERE task_lists.id = tasks.task_list_id;
+
+-- Renumber the tasks which got a taken number, keeping it for the oldest one.
+WITH duplicates AS (
+  SELECT
+    id,
+    project_id,
+    row_number() OVER (PARTITION BY project_id, number ORDER BY date_created, id) AS n
+  FROM tasks
+  WHERE number IS NOT NULL
+), renumbered AS (
+  SELECT
+    duplicates.id,
+    projects.next_task_number - 1 + row_number() OVER (PARTITION BY duplicates.project_id ORDER BY duplicates.id) AS number
+  FROM duplicates
+  JOIN projects ON projects.id = duplicates.project_id
+  WHERE duplicates.n > 1
+)
+UPDATE tasks SET number = renumbered.number FROM renumbered WHERE renumbered.id = tasks.id;
+
+UPDATE projects SET next_task_number = greatest(next_task_number, coalesce((
+  SELECT max(tasks.number) FROM tasks WHERE tasks.project_id = projects.id
+), 0) + 1);
+
+CREATE UNIQUE INDEX tasks_project_id_number_key ON tasks (project_id, number) WHERE project_id IS NOT NULL;
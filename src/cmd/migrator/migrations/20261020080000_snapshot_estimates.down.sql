@@ -0,0 +1 @@
+ALTER TABLE board_snapshots DROP COLUMN estimate;
@@ -0,0 +1,3 @@
+-- Sum of the task estimates in the list. The earlier snapshots have no
+-- estimates and are left NULL, as unknown.
+ALTER TABLE board_snapshots ADD COLUMN estimate double precision;
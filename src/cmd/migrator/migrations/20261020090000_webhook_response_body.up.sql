@@ -0,0 +1,2 @@
+-- Only the status code of a webhook response is kept.
+ALTER TABLE webhook_deliveries DROP COLUMN response_body;
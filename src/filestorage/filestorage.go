@@ -12,6 +12,7 @@ type FileStorage interface {
 	Get(key FileID) ([]byte, error)
 	Set(key FileID, data io.Reader) (int64, error)
 	Add(data io.Reader) (FileID, int64, error)
+	Delete(key FileID) error
 }
 
 func RandomID() FileID {
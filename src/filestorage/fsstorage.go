@@ -49,6 +49,17 @@ func (s FileSystemStorage) Set(key FileID, data io.Reader) (int64, error) {
 	return io.Copy(file, data)
 }
 
+// Removes the object, a missing object is not an error.
+func (s FileSystemStorage) Delete(key FileID) error {
+	path := filepath.Join(s.RootPath, key)
+
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
+
 func NewFileSystemStorage(rootPath string) (*FileSystemStorage, error) {
 	if !filepath.IsAbs(rootPath) {
 		cwd, err := os.Getwd()
@@ -0,0 +1,28 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/lesnoi-kot/karten-backend/src/store"
+	"github.com/lesnoi-kot/karten-backend/src/userservice"
+)
+
+// Fires the automation rules triggered by passed due dates and schedules.
+func NewAutomationsJob(s *store.Store, logger *zap.SugaredLogger) Job {
+	return Job{
+		Name:     "automations",
+		Interval: 5 * time.Minute,
+		Run: func(ctx context.Context) error {
+			count, err := userservice.RunTimedAutomations(ctx, s, time.Now().UTC())
+			if err != nil {
+				return err
+			}
+
+			logger.Debugw("Timed automations run", "rules", count)
+			return nil
+		},
+	}
+}
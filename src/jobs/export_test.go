@@ -0,0 +1,18 @@
+package jobs
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Scheduler whose jobs are run on the ticks sent by the test, by job name,
+// instead of their intervals.
+func NewSchedulerWithTicks(logger *zap.SugaredLogger, ticks map[string]chan time.Time) *Scheduler {
+	s := NewScheduler(logger)
+	s.ticks = func(job Job) (<-chan time.Time, func()) {
+		return ticks[job.Name], func() {}
+	}
+
+	return s
+}
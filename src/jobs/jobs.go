@@ -0,0 +1,88 @@
+package jobs
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Background task run periodically while the server is up.
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Run      func(ctx context.Context) error
+}
+
+type Scheduler struct {
+	logger *zap.SugaredLogger
+	jobs   []Job
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	// Ticks of the job and the function to stop them.
+	ticks func(job Job) (<-chan time.Time, func())
+}
+
+func NewScheduler(logger *zap.SugaredLogger) *Scheduler {
+	return &Scheduler{logger: logger, ticks: intervalTicks}
+}
+
+func intervalTicks(job Job) (<-chan time.Time, func()) {
+	ticker := time.NewTicker(job.Interval)
+	return ticker.C, ticker.Stop
+}
+
+func (s *Scheduler) Add(job Job) {
+	s.jobs = append(s.jobs, job)
+}
+
+// Starts every job right away and then repeats it after its interval.
+func (s *Scheduler) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+
+	for _, job := range s.jobs {
+		s.wg.Add(1)
+		go s.loop(ctx, job)
+	}
+}
+
+// Stops the jobs and waits for the running ones to finish.
+func (s *Scheduler) Stop() {
+	if s.cancel != nil {
+		s.cancel()
+	}
+
+	s.wg.Wait()
+}
+
+func (s *Scheduler) loop(ctx context.Context, job Job) {
+	defer s.wg.Done()
+
+	ticks, stop := s.ticks(job)
+	defer stop()
+
+	for {
+		s.run(ctx, job)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticks:
+		}
+	}
+}
+
+func (s *Scheduler) run(ctx context.Context, job Job) {
+	defer func() {
+		if p := recover(); p != nil {
+			s.logger.Errorw("Job panicked", "job", job.Name, "panic", p)
+		}
+	}()
+
+	if err := job.Run(ctx); err != nil && ctx.Err() == nil {
+		s.logger.Errorw("Job failed", "job", job.Name, "error", err)
+	}
+}
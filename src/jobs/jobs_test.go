@@ -0,0 +1,67 @@
+package jobs_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/lesnoi-kot/karten-backend/src/jobs"
+)
+
+func TestSchedulerRunsJobsUntilStopped(t *testing.T) {
+	var runs, failures atomic.Int32
+	ran := make(chan string, 10)
+	ticks := map[string]chan time.Time{
+		"counter": make(chan time.Time, 1),
+		"failing": make(chan time.Time, 1),
+	}
+
+	scheduler := jobs.NewSchedulerWithTicks(zap.NewNop().Sugar(), ticks)
+	scheduler.Add(jobs.Job{
+		Name:     "counter",
+		Interval: time.Hour,
+		Run: func(ctx context.Context) error {
+			runs.Add(1)
+			ran <- "counter"
+			return nil
+		},
+	})
+	scheduler.Add(jobs.Job{
+		Name:     "failing",
+		Interval: time.Hour,
+		Run: func(ctx context.Context) error {
+			failures.Add(1)
+			ran <- "failing"
+			return errors.New("boom")
+		},
+	})
+
+	waitRuns := func(n int) {
+		for i := 0; i < n; i++ {
+			select {
+			case <-ran:
+			case <-time.After(5 * time.Second):
+				t.Fatal("job did not run")
+			}
+		}
+	}
+
+	scheduler.Start()
+	waitRuns(2) // Both run right away
+
+	ticks["counter"] <- time.Now()
+	ticks["failing"] <- time.Now()
+	waitRuns(2)
+
+	assert.Equal(t, int32(2), runs.Load())
+	assert.Equal(t, int32(2), failures.Load(), "failing job keeps being scheduled")
+
+	scheduler.Stop()
+	ticks["counter"] <- time.Now()
+	assert.Equal(t, int32(2), runs.Load(), "no runs after stop")
+}
@@ -0,0 +1,28 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/lesnoi-kot/karten-backend/src/store"
+)
+
+// Keeps the snapshot of the current day up to date, the last run of a day
+// leaves its final numbers.
+func NewBoardSnapshotsJob(s *store.Store, logger *zap.SugaredLogger) Job {
+	return Job{
+		Name:     "board_snapshots",
+		Interval: time.Hour,
+		Run: func(ctx context.Context) error {
+			count, err := s.TakeBoardSnapshots(ctx, time.Now().UTC())
+			if err != nil {
+				return err
+			}
+
+			logger.Debugw("Board snapshots taken", "lists", count)
+			return nil
+		},
+	}
+}
@@ -0,0 +1,34 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/lesnoi-kot/karten-backend/src/store"
+)
+
+// Deletes for good everything that has stayed in the trash longer than the
+// retention period.
+func NewPurgeTrashJob(s *store.Store, retention time.Duration, logger *zap.SugaredLogger) Job {
+	return Job{
+		Name:     "purge_trash",
+		Interval: time.Hour,
+		Run: func(ctx context.Context) error {
+			result, err := s.PurgeTrash(ctx, time.Now().UTC().Add(-retention))
+			if err != nil {
+				return err
+			}
+
+			logger.Infow("Trash purged",
+				"projects", result.Projects,
+				"boards", result.Boards,
+				"task_lists", result.TaskLists,
+				"tasks", result.Tasks,
+				"files", result.Files,
+			)
+			return nil
+		},
+	}
+}
@@ -0,0 +1,33 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/lesnoi-kot/karten-backend/src/modules/webhook"
+	"github.com/lesnoi-kot/karten-backend/src/store"
+	"github.com/lesnoi-kot/karten-backend/src/userservice"
+)
+
+// Sends the queued webhook deliveries and retries the failed ones.
+func NewWebhooksJob(s *store.Store, logger *zap.SugaredLogger) Job {
+	client := webhook.NewClient()
+
+	return Job{
+		Name:     "webhooks",
+		Interval: 10 * time.Second,
+		Run: func(ctx context.Context) error {
+			count, err := userservice.DeliverWebhooks(ctx, s, client, time.Now().UTC())
+			if err != nil {
+				return err
+			}
+
+			if count > 0 {
+				logger.Debugw("Webhooks delivered", "deliveries", count)
+			}
+			return nil
+		},
+	}
+}
@@ -6,11 +6,15 @@ import (
 	"os/signal"
 	"strings"
 
+	// Time zone database for reports, the alpine image has none.
+	_ "time/tzdata"
+
 	"github.com/caarlos0/env/v6"
 	"go.uber.org/zap"
 
 	"github.com/lesnoi-kot/karten-backend/src/api"
 	"github.com/lesnoi-kot/karten-backend/src/filestorage"
+	"github.com/lesnoi-kot/karten-backend/src/jobs"
 	"github.com/lesnoi-kot/karten-backend/src/settings"
 	"github.com/lesnoi-kot/karten-backend/src/store"
 )
@@ -50,11 +54,21 @@ func main() {
 		Debug:        settings.AppConfig.Debug,
 	})
 
+	scheduler := jobs.NewScheduler(logger)
+	scheduler.Add(jobs.NewPurgeTrashJob(storeService, settings.AppConfig.TrashRetention(), logger))
+	scheduler.Add(jobs.NewBoardSnapshotsJob(storeService, logger))
+	scheduler.Add(jobs.NewAutomationsJob(storeService, logger))
+	scheduler.Add(jobs.NewWebhooksJob(storeService, logger))
+	scheduler.Start()
+
 	go handleSignals(apiService)
 
 	if err := apiService.Start(settings.AppConfig.APIBindAddress); err != nil {
 		logger.Info("API service is stopped")
 
+		scheduler.Stop()
+		logger.Info("Background jobs are stopped")
+
 		if err := storeService.Close(); err != nil {
 			logger.Errorw("Store connection close error", "error", err)
 		} else {
@@ -96,5 +110,6 @@ func printConfigs(logger *zap.SugaredLogger) {
 		"FrontendURL", settings.AppConfig.FrontendURL,
 		"FileStoragePath", settings.AppConfig.FileStoragePath,
 		"AllowOrigins", strings.Join(settings.AppConfig.AllowOrigins, ", "),
+		"TrashRetentionDays", settings.AppConfig.TrashRetentionDays,
 	)
 }
@@ -0,0 +1,295 @@
+// Package boardarchive reads and writes zip archives of a single board: a
+// versioned JSON document plus the blobs of the files it refers to.
+//
+// Entities in the document refer to each other by refs, which are unique
+// within the archive and mean nothing outside of it.
+package boardarchive
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"time"
+)
+
+// Version of the document written by this package. Archives of other
+// versions are rejected.
+const Version = 1
+
+const (
+	documentName = "board.json"
+	filesDir     = "files/"
+)
+
+var (
+	ErrInvalidArchive     = errors.New("Invalid board archive")
+	ErrUnsupportedVersion = errors.New("Unsupported board archive version")
+)
+
+// Limits on the uncompressed sizes read from an archive, so that a small
+// upload can't expand into gigabytes in memory.
+var (
+	MaxEntrySize   int64 = 32 << 20
+	MaxArchiveSize int64 = 128 << 20
+)
+
+type Document struct {
+	Version    int       `json:"version"`
+	ExportedAt time.Time `json:"exported_at"`
+	Board      Board     `json:"board"`
+	Files      []*File   `json:"files"`
+}
+
+type Board struct {
+	Name         string      `json:"name"`
+	Color        int         `json:"color"`
+	EstimateUnit string      `json:"estimate_unit"`
+	CoverRef     string      `json:"cover_ref,omitempty"`
+	Labels       []*Label    `json:"labels"`
+	TaskLists    []*TaskList `json:"task_lists"`
+}
+
+type Label struct {
+	Ref   string `json:"ref"`
+	Name  string `json:"name"`
+	Color int    `json:"color"`
+}
+
+type TaskList struct {
+	Ref      string  `json:"ref"`
+	Name     string  `json:"name"`
+	Position int64   `json:"position"`
+	Color    int     `json:"color"`
+	Archived bool    `json:"archived"`
+	WIPLimit *int    `json:"wip_limit,omitempty"`
+	WIPMode  string  `json:"wip_mode,omitempty"`
+	Tasks    []*Task `json:"tasks"`
+}
+
+// Checklists are markdown task lists in the text.
+type Task struct {
+	Ref            string     `json:"ref"`
+	Name           string     `json:"name"`
+	Text           string     `json:"text"`
+	Position       int64      `json:"position"`
+	Archived       bool       `json:"archived"`
+	SpentTime      int64      `json:"spent_time"`
+	DateCreated    time.Time  `json:"date_created"`
+	StartDate      *time.Time `json:"start_date,omitempty"`
+	DueDate        *time.Time `json:"due_date,omitempty"`
+	Estimate       *float64   `json:"estimate,omitempty"`
+	Priority       int16      `json:"priority"`
+	LabelRefs      []string   `json:"label_refs,omitempty"`
+	AttachmentRefs []string   `json:"attachment_refs,omitempty"`
+	Comments       []*Comment `json:"comments,omitempty"`
+}
+
+type Comment struct {
+	Text           string    `json:"text"`
+	DateCreated    time.Time `json:"date_created"`
+	AttachmentRefs []string  `json:"attachment_refs,omitempty"`
+}
+
+type File struct {
+	Ref      string `json:"ref"`
+	Name     string `json:"name"`
+	MimeType string `json:"mime_type"`
+	Size     int    `json:"size"`
+}
+
+// Writes the archive, the blob of every document file is got from the blob
+// function.
+func Write(w io.Writer, doc *Document, blob func(file *File) ([]byte, error)) error {
+	archive := zip.NewWriter(w)
+
+	documentWriter, err := archive.Create(documentName)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(documentWriter)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return err
+	}
+
+	for _, file := range doc.Files {
+		data, err := blob(file)
+		if err != nil {
+			return err
+		}
+
+		fileWriter, err := archive.CreateHeader(&zip.FileHeader{
+			Name:   filesDir + file.Ref,
+			Method: zip.Deflate,
+		})
+		if err != nil {
+			return err
+		}
+		if _, err := fileWriter.Write(data); err != nil {
+			return err
+		}
+	}
+
+	return archive.Close()
+}
+
+// Reads and validates the archive. Returns the document and the blobs by the
+// file refs.
+func Read(r io.ReaderAt, size int64) (*Document, map[string][]byte, error) {
+	archive, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %s", ErrInvalidArchive, err)
+	}
+
+	var doc *Document
+	blobs := make(map[string][]byte)
+	budget := MaxArchiveSize
+
+	for _, entry := range archive.File {
+		if entry.Name == documentName {
+			data, err := readAll(entry, &budget)
+			if err != nil {
+				return nil, nil, fmt.Errorf("%w: %s", ErrInvalidArchive, err)
+			}
+			doc = new(Document)
+			if err := json.Unmarshal(data, doc); err != nil {
+				return nil, nil, fmt.Errorf("%w: %s", ErrInvalidArchive, err)
+			}
+			continue
+		}
+
+		dir, ref := path.Split(entry.Name)
+		if dir != filesDir || ref == "" {
+			continue
+		}
+
+		data, err := readAll(entry, &budget)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: %s", ErrInvalidArchive, err)
+		}
+		blobs[ref] = data
+	}
+
+	if doc == nil {
+		return nil, nil, fmt.Errorf("%w: no %s", ErrInvalidArchive, documentName)
+	}
+	if err := Validate(doc, blobs); err != nil {
+		return nil, nil, err
+	}
+
+	return doc, blobs, nil
+}
+
+// Checks the version of the document, that the refs are unique and point
+// to existing entities, and that every file has its blob.
+func Validate(doc *Document, blobs map[string][]byte) error {
+	if doc.Version != Version {
+		return fmt.Errorf("%w: %d", ErrUnsupportedVersion, doc.Version)
+	}
+
+	invalid := func(format string, args ...any) error {
+		return fmt.Errorf("%w: "+format, append([]any{ErrInvalidArchive}, args...)...)
+	}
+
+	if doc.Board.Name == "" {
+		return invalid("board has no name")
+	}
+
+	files := make(map[string]bool)
+	for _, file := range doc.Files {
+		if file.Ref == "" || files[file.Ref] {
+			return invalid("duplicate file ref %q", file.Ref)
+		}
+		if _, ok := blobs[file.Ref]; !ok {
+			return invalid("no blob of file %q", file.Ref)
+		}
+		files[file.Ref] = true
+	}
+
+	if doc.Board.CoverRef != "" && !files[doc.Board.CoverRef] {
+		return invalid("unknown cover file %q", doc.Board.CoverRef)
+	}
+
+	labels := make(map[string]bool)
+	for _, label := range doc.Board.Labels {
+		if label.Ref == "" || labels[label.Ref] {
+			return invalid("duplicate label ref %q", label.Ref)
+		}
+		labels[label.Ref] = true
+	}
+
+	refs := make(map[string]bool)
+	for _, taskList := range doc.Board.TaskLists {
+		if taskList.Ref == "" || refs[taskList.Ref] {
+			return invalid("duplicate list ref %q", taskList.Ref)
+		}
+		if taskList.Name == "" {
+			return invalid("list %q has no name", taskList.Ref)
+		}
+		refs[taskList.Ref] = true
+
+		for _, task := range taskList.Tasks {
+			if task.Ref == "" || refs[task.Ref] {
+				return invalid("duplicate task ref %q", task.Ref)
+			}
+			if task.Name == "" {
+				return invalid("task %q has no name", task.Ref)
+			}
+			refs[task.Ref] = true
+
+			for _, ref := range task.LabelRefs {
+				if !labels[ref] {
+					return invalid("task %q refers to unknown label %q", task.Ref, ref)
+				}
+			}
+			for _, ref := range task.AttachmentRefs {
+				if !files[ref] {
+					return invalid("task %q refers to unknown file %q", task.Ref, ref)
+				}
+			}
+			for _, comment := range task.Comments {
+				for _, ref := range comment.AttachmentRefs {
+					if !files[ref] {
+						return invalid("comment of task %q refers to unknown file %q", task.Ref, ref)
+					}
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// Reads the entry, failing if it is larger than MaxEntrySize or than what is
+// left of the budget. The declared size is checked first, but the reading is
+// limited too since the header may lie.
+func readAll(entry *zip.File, budget *int64) ([]byte, error) {
+	limit := MaxEntrySize
+	if *budget < limit {
+		limit = *budget
+	}
+	if entry.UncompressedSize64 > uint64(limit) {
+		return nil, fmt.Errorf("%s is too large", entry.Name)
+	}
+
+	reader, err := entry.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	data, err := io.ReadAll(io.LimitReader(reader, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("%s is too large", entry.Name)
+	}
+
+	*budget -= int64(len(data))
+	return data, nil
+}
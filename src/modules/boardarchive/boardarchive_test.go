@@ -0,0 +1,184 @@
+package boardarchive_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lesnoi-kot/karten-backend/src/modules/boardarchive"
+)
+
+func TestWriteAndRead(t *testing.T) {
+	doc := &boardarchive.Document{
+		Version:    boardarchive.Version,
+		ExportedAt: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+		Board: boardarchive.Board{
+			Name:     "Roadmap",
+			CoverRef: "cover",
+			Labels:   []*boardarchive.Label{{Ref: "l1", Name: "bug", Color: 2}},
+			TaskLists: []*boardarchive.TaskList{{
+				Ref:  "todo",
+				Name: "Todo",
+				Tasks: []*boardarchive.Task{{
+					Ref:            "t1",
+					Name:           "Fix login",
+					Text:           "- [ ] reproduce\n- [x] write a test",
+					LabelRefs:      []string{"l1"},
+					AttachmentRefs: []string{"log"},
+					Comments: []*boardarchive.Comment{
+						{Text: "See the log", AttachmentRefs: []string{"log"}},
+					},
+				}},
+			}},
+		},
+		Files: []*boardarchive.File{
+			{Ref: "cover", Name: "cover.png", MimeType: "image/png", Size: 3},
+			{Ref: "log", Name: "error.log", MimeType: "text/plain", Size: 5},
+		},
+	}
+	blobs := map[string][]byte{
+		"cover": []byte("png"),
+		"log":   []byte("panic"),
+	}
+
+	var buf bytes.Buffer
+	err := boardarchive.Write(&buf, doc, func(file *boardarchive.File) ([]byte, error) {
+		return blobs[file.Ref], nil
+	})
+	require.NoError(t, err)
+
+	readDoc, readBlobs, err := boardarchive.Read(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+
+	assert.Equal(t, doc, readDoc)
+	assert.Equal(t, blobs, readBlobs)
+}
+
+func TestReadRejectsOtherVersions(t *testing.T) {
+	doc := &boardarchive.Document{Version: boardarchive.Version + 1, Board: boardarchive.Board{Name: "Roadmap"}}
+
+	var buf bytes.Buffer
+	require.NoError(t, boardarchive.Write(&buf, doc, nil))
+
+	_, _, err := boardarchive.Read(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.ErrorIs(t, err, boardarchive.ErrUnsupportedVersion)
+}
+
+func TestReadRejectsBrokenArchives(t *testing.T) {
+	_, _, err := boardarchive.Read(bytes.NewReader([]byte("not a zip")), 9)
+	assert.ErrorIs(t, err, boardarchive.ErrInvalidArchive)
+
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	_, err = archive.Create("files/log")
+	require.NoError(t, err)
+	require.NoError(t, archive.Close())
+
+	_, _, err = boardarchive.Read(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.ErrorIs(t, err, boardarchive.ErrInvalidArchive, "no document")
+}
+
+func TestReadLimitsUncompressedSizes(t *testing.T) {
+	log := bytes.Repeat([]byte("panic"), 1000)
+	doc := &boardarchive.Document{
+		Version: boardarchive.Version,
+		Board:   boardarchive.Board{Name: "Roadmap"},
+		Files:   []*boardarchive.File{{Ref: "log", Name: "error.log", MimeType: "text/plain", Size: len(log)}},
+	}
+
+	var buf bytes.Buffer
+	err := boardarchive.Write(&buf, doc, func(file *boardarchive.File) ([]byte, error) {
+		return log, nil
+	})
+	require.NoError(t, err)
+
+	read := func() error {
+		_, _, err := boardarchive.Read(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		return err
+	}
+
+	defer func(entry, archive int64) {
+		boardarchive.MaxEntrySize, boardarchive.MaxArchiveSize = entry, archive
+	}(boardarchive.MaxEntrySize, boardarchive.MaxArchiveSize)
+
+	boardarchive.MaxEntrySize = 4000
+	assert.ErrorIs(t, read(), boardarchive.ErrInvalidArchive, "entry too large")
+
+	boardarchive.MaxEntrySize = 10000
+	boardarchive.MaxArchiveSize = 5100
+	assert.ErrorIs(t, read(), boardarchive.ErrInvalidArchive, "archive too large")
+
+	boardarchive.MaxArchiveSize = 10000
+	assert.NoError(t, read())
+}
+
+func TestValidate(t *testing.T) {
+	valid := &boardarchive.Document{
+		Version: boardarchive.Version,
+		Board: boardarchive.Board{
+			Name:   "Roadmap",
+			Labels: []*boardarchive.Label{{Ref: "l1", Name: "bug"}},
+			TaskLists: []*boardarchive.TaskList{{Ref: "todo", Name: "Todo", Tasks: []*boardarchive.Task{
+				{Ref: "t1", Name: "Fix login", LabelRefs: []string{"l1"}, AttachmentRefs: []string{"log"}},
+			}}},
+		},
+		Files: []*boardarchive.File{{Ref: "log", Name: "error.log"}},
+	}
+	assert.NoError(t, boardarchive.Validate(valid, map[string][]byte{"log": []byte("panic")}))
+
+	tests := []struct {
+		name string
+		doc  *boardarchive.Document
+	}{
+		{
+			name: "missing blob",
+			doc: &boardarchive.Document{
+				Version: boardarchive.Version,
+				Board:   boardarchive.Board{Name: "Roadmap"},
+				Files:   []*boardarchive.File{{Ref: "log", Name: "error.log"}},
+			},
+		},
+		{
+			name: "unknown label",
+			doc: &boardarchive.Document{
+				Version: boardarchive.Version,
+				Board: boardarchive.Board{Name: "Roadmap", TaskLists: []*boardarchive.TaskList{{Ref: "todo", Name: "Todo", Tasks: []*boardarchive.Task{
+					{Ref: "t1", Name: "Fix login", LabelRefs: []string{"l2"}},
+				}}}},
+			},
+		},
+		{
+			name: "duplicate ref",
+			doc: &boardarchive.Document{
+				Version: boardarchive.Version,
+				Board: boardarchive.Board{Name: "Roadmap", TaskLists: []*boardarchive.TaskList{{Ref: "todo", Name: "Todo", Tasks: []*boardarchive.Task{
+					{Ref: "todo", Name: "Fix login"},
+				}}}},
+			},
+		},
+		{
+			name: "unknown cover",
+			doc: &boardarchive.Document{
+				Version: boardarchive.Version,
+				Board:   boardarchive.Board{Name: "Roadmap", CoverRef: "missing"},
+			},
+		},
+		{
+			name: "unknown comment file",
+			doc: &boardarchive.Document{
+				Version: boardarchive.Version,
+				Board: boardarchive.Board{Name: "Roadmap", TaskLists: []*boardarchive.TaskList{{Ref: "todo", Name: "Todo", Tasks: []*boardarchive.Task{
+					{Ref: "t1", Name: "Fix login", Comments: []*boardarchive.Comment{{AttachmentRefs: []string{"trace"}}}},
+				}}}},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		assert.ErrorIs(t, boardarchive.Validate(test.doc, nil), boardarchive.ErrInvalidArchive, test.name)
+	}
+}
@@ -0,0 +1,177 @@
+// Package boarddoc renders a board as a single readable document, either
+// Markdown or a self-contained printable HTML page.
+package boarddoc
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+type Board struct {
+	Name       string
+	Project    string
+	ExportedAt time.Time
+	Lists      []*List
+}
+
+type List struct {
+	Name     string
+	Archived bool
+	Tasks    []*Task
+}
+
+// Checklists are markdown task lists in the text.
+type Task struct {
+	Key         string
+	Name        string
+	Text        string
+	Archived    bool
+	Labels      []string
+	StartDate   *time.Time
+	DueDate     *time.Time
+	Attachments []*Attachment
+	Comments    []*Comment
+}
+
+type Comment struct {
+	Author      string
+	DateCreated time.Time
+	Text        string
+	Attachments []*Attachment
+}
+
+type Attachment struct {
+	Name         string
+	URL          string
+	ThumbnailURL string // Images only, may be a data URL
+}
+
+const (
+	dateLayout     = "2006-01-02"
+	dateTimeLayout = "2006-01-02 15:04"
+)
+
+func WriteMarkdown(w io.Writer, board *Board) error {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "# %s\n\n", escape(board.Name))
+	if board.Project != "" {
+		fmt.Fprintf(&b, "Project %s, exported on %s.\n", escape(board.Project), board.ExportedAt.UTC().Format(dateLayout))
+	} else {
+		fmt.Fprintf(&b, "Exported on %s.\n", board.ExportedAt.UTC().Format(dateLayout))
+	}
+
+	for _, list := range board.Lists {
+		fmt.Fprintf(&b, "\n## %s%s\n", escape(list.Name), archivedMark(list.Archived))
+		if len(list.Tasks) == 0 {
+			b.WriteString("\nNo tasks.\n")
+		}
+
+		for _, task := range list.Tasks {
+			fmt.Fprintf(&b, "\n### %s%s\n", escape(taskTitle(task)), archivedMark(task.Archived))
+
+			if details := taskDetails(task); len(details) > 0 {
+				fmt.Fprintf(&b, "\n%s\n", strings.Join(details, " · "))
+			}
+			if text := strings.TrimSpace(task.Text); text != "" {
+				fmt.Fprintf(&b, "\n%s\n", text)
+			}
+			if len(task.Attachments) > 0 {
+				b.WriteString("\n**Attachments**\n\n")
+				writeMarkdownAttachments(&b, task.Attachments, "")
+			}
+
+			if len(task.Comments) > 0 {
+				b.WriteString("\n**Comments**\n")
+			}
+			for _, comment := range task.Comments {
+				fmt.Fprintf(&b, "\n> **%s**, %s\n>\n", escape(commentAuthor(comment)), comment.DateCreated.UTC().Format(dateTimeLayout))
+				for _, line := range strings.Split(strings.TrimSpace(comment.Text), "\n") {
+					fmt.Fprintf(&b, "> %s\n", line)
+				}
+				if len(comment.Attachments) > 0 {
+					b.WriteString(">\n")
+					writeMarkdownAttachments(&b, comment.Attachments, "> ")
+				}
+			}
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeMarkdownAttachments(b *strings.Builder, attachments []*Attachment, prefix string) {
+	for _, attachment := range attachments {
+		if attachment.ThumbnailURL != "" {
+			fmt.Fprintf(b, "%s- [![%s](%s)](%s)\n", prefix, escape(attachment.Name), attachment.ThumbnailURL, attachment.URL)
+		} else {
+			fmt.Fprintf(b, "%s- [%s](%s)\n", prefix, escape(attachment.Name), attachment.URL)
+		}
+	}
+}
+
+func taskTitle(task *Task) string {
+	if task.Key == "" {
+		return task.Name
+	}
+
+	return task.Key + " " + task.Name
+}
+
+// Labels and dates of the task in one line of Markdown.
+func taskDetails(task *Task) []string {
+	var details []string
+
+	if len(task.Labels) > 0 {
+		labels := make([]string, len(task.Labels))
+		for i, label := range task.Labels {
+			labels[i] = escape(label)
+		}
+		details = append(details, "**Labels:** "+strings.Join(labels, ", "))
+	}
+	if task.StartDate != nil {
+		details = append(details, "**Start:** "+task.StartDate.UTC().Format(dateLayout))
+	}
+	if task.DueDate != nil {
+		details = append(details, "**Due:** "+task.DueDate.UTC().Format(dateLayout))
+	}
+
+	return details
+}
+
+func commentAuthor(comment *Comment) string {
+	if comment.Author == "" {
+		return "Unknown"
+	}
+
+	return comment.Author
+}
+
+func archivedMark(archived bool) string {
+	if archived {
+		return " (archived)"
+	}
+
+	return ""
+}
+
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	"`", "\\`",
+	"*", `\*`,
+	"_", `\_`,
+	"[", `\[`,
+	"]", `\]`,
+	"<", `\<`,
+	">", `\>`,
+	"#", `\#`,
+	"|", `\|`,
+)
+
+// Escapes plain text, such as names, for inline use in Markdown.
+func escape(s string) string {
+	return markdownEscaper.Replace(strings.TrimSpace(s))
+}
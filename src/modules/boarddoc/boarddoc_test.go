@@ -0,0 +1,116 @@
+package boarddoc_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lesnoi-kot/karten-backend/src/modules/boarddoc"
+)
+
+func TestWriteMarkdown(t *testing.T) {
+	due := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	board := &boarddoc.Board{
+		Name:       "Roadmap",
+		Project:    "Karten",
+		ExportedAt: time.Date(2024, 2, 20, 9, 0, 0, 0, time.UTC),
+		Lists: []*boarddoc.List{
+			{
+				Name: "Todo",
+				Tasks: []*boarddoc.Task{{
+					Key:     "KAR-1",
+					Name:    "Fix *login*",
+					Text:    "Steps:\n\n- [x] reproduce\n- [ ] write a test",
+					Labels:  []string{"bug"},
+					DueDate: &due,
+					Attachments: []*boarddoc.Attachment{
+						{Name: "error.log", URL: "https://media/log"},
+						{Name: "screen.png", URL: "https://media/png", ThumbnailURL: "data:image/png;base64,cG5n"},
+					},
+					Comments: []*boarddoc.Comment{{
+						Author:      "Ann",
+						DateCreated: time.Date(2024, 2, 21, 10, 30, 0, 0, time.UTC),
+						Text:        "Seen on iOS\ntoo",
+					}},
+				}},
+			},
+			{Name: "Done", Archived: true},
+		},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, boarddoc.WriteMarkdown(&buf, board))
+
+	assert.Equal(t, strings.Join([]string{
+		"# Roadmap",
+		"",
+		"Project Karten, exported on 2024-02-20.",
+		"",
+		"## Todo",
+		"",
+		`### KAR-1 Fix \*login\*`,
+		"",
+		"**Labels:** bug · **Due:** 2024-03-01",
+		"",
+		"Steps:",
+		"",
+		"- [x] reproduce",
+		"- [ ] write a test",
+		"",
+		"**Attachments**",
+		"",
+		"- [error.log](https://media/log)",
+		"- [![screen.png](data:image/png;base64,cG5n)](https://media/png)",
+		"",
+		"**Comments**",
+		"",
+		"> **Ann**, 2024-02-21 10:30",
+		">",
+		"> Seen on iOS",
+		"> too",
+		"",
+		"## Done (archived)",
+		"",
+		"No tasks.",
+		"",
+	}, "\n"), buf.String())
+}
+
+func TestWriteHTML(t *testing.T) {
+	board := &boarddoc.Board{
+		Name: "Roadmap",
+		Lists: []*boarddoc.List{{
+			Name: "Todo",
+			Tasks: []*boarddoc.Task{{
+				Key:  "KAR-1",
+				Name: "Fix *login*",
+				Text: "- [x] reproduce\n- [ ] write a test\n\n<script>alert(1)</script>",
+				Attachments: []*boarddoc.Attachment{
+					{Name: "error.log", URL: "https://media/log"},
+					{Name: "screen.png", URL: "https://media/png", ThumbnailURL: "data:image/png;base64,cG5n"},
+				},
+				Comments: []*boarddoc.Comment{{
+					Author:      "Ann",
+					DateCreated: time.Date(2024, 2, 21, 10, 30, 0, 0, time.UTC),
+					Text:        "Seen on iOS",
+				}},
+			}},
+		}},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, boarddoc.WriteHTML(&buf, board))
+	html := buf.String()
+
+	assert.Contains(t, html, "<h3>KAR-1 Fix *login*</h3>")
+	assert.Contains(t, html, "<li>☑ reproduce")
+	assert.Contains(t, html, "<li>☐ write a test")
+	assert.Contains(t, html, `<img src="data:image/png;base64,cG5n" alt="screen.png">`)
+	assert.Contains(t, html, `<a href="https://media/log">error.log</a>`)
+	assert.Contains(t, html, "<strong>Ann</strong>, 2024-02-21 10:30")
+	assert.NotContains(t, html, "<script>")
+}
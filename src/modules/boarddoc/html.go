@@ -0,0 +1,106 @@
+package boarddoc
+
+import (
+	"html/template"
+	"io"
+	"regexp"
+	"time"
+
+	"github.com/lesnoi-kot/karten-backend/src/modules/markdown"
+)
+
+// Task list items of Markdown, blackfriday renders them as plain text.
+var checklistItemRegexp = regexp.MustCompile(`(?m)^(\s*[-*+] )\[([ xX])\] `)
+
+var htmlTemplate = template.Must(template.New("board").Funcs(template.FuncMap{
+	"markdown": renderMarkdown,
+	"title":    taskTitle,
+	"author":   commentAuthor,
+	"date": func(t time.Time) string {
+		return t.UTC().Format(dateLayout)
+	},
+	"datetime": func(t time.Time) string {
+		return t.UTC().Format(dateTimeLayout)
+	},
+	// Data URLs of the inlined thumbnails are trusted, they are made by
+	// the server.
+	"src": func(url string) template.URL {
+		return template.URL(url)
+	},
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Name}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; color: #172b4d; max-width: 860px; margin: 2em auto; padding: 0 1em; line-height: 1.45; }
+h1 { margin-bottom: 0; }
+.meta { color: #5e6c84; }
+h2 { border-bottom: 2px solid #dfe1e6; padding-bottom: .2em; margin-top: 2em; }
+.task { border: 1px solid #dfe1e6; border-radius: 4px; padding: .5em 1em; margin: 1em 0; page-break-inside: avoid; }
+.task h3 { margin: .3em 0; }
+.archived { color: #5e6c84; font-weight: normal; font-size: .8em; }
+.label { display: inline-block; background: #ebecf0; border-radius: 3px; padding: 0 .4em; margin-right: .3em; }
+.attachments { list-style: none; padding: 0; }
+.attachments img { max-width: 160px; max-height: 120px; border: 1px solid #dfe1e6; display: block; }
+.comment { border-left: 3px solid #dfe1e6; padding-left: .8em; margin: .8em 0; }
+pre { background: #f4f5f7; padding: .5em; overflow-x: auto; }
+@media print {
+	body { margin: 0; max-width: none; }
+	h2 { page-break-after: avoid; }
+	a { color: inherit; }
+}
+</style>
+</head>
+<body>
+<h1>{{.Name}}</h1>
+<p class="meta">{{if .Project}}Project {{.Project}}, exported{{else}}Exported{{end}} on {{date .ExportedAt}}.</p>
+{{range .Lists}}
+<section>
+<h2>{{.Name}}{{if .Archived}} <span class="archived">archived</span>{{end}}</h2>
+{{if not .Tasks}}<p class="meta">No tasks.</p>{{end}}
+{{range .Tasks}}
+<article class="task">
+<h3>{{title .}}{{if .Archived}} <span class="archived">archived</span>{{end}}</h3>
+{{if or .Labels .StartDate .DueDate}}<p class="meta">
+{{range .Labels}}<span class="label">{{.}}</span>{{end}}
+{{with .StartDate}}Start {{date .}}{{end}}
+{{with .DueDate}}Due {{date .}}{{end}}
+</p>{{end}}
+{{markdown .Text}}
+{{template "attachments" .Attachments}}
+{{range .Comments}}
+<div class="comment">
+<p class="meta"><strong>{{author .}}</strong>, {{datetime .DateCreated}}</p>
+{{markdown .Text}}
+{{template "attachments" .Attachments}}
+</div>
+{{end}}
+</article>
+{{end}}
+</section>
+{{end}}
+</body>
+</html>
+{{define "attachments"}}{{if .}}<ul class="attachments">
+{{range .}}<li>{{if .ThumbnailURL}}<a href="{{.URL}}"><img src="{{src .ThumbnailURL}}" alt="{{.Name}}"></a>{{end}}<a href="{{.URL}}">{{.Name}}</a></li>
+{{end}}</ul>{{end}}{{end}}
+`))
+
+// Writes the board as a printable HTML page with the styles inlined.
+func WriteHTML(w io.Writer, board *Board) error {
+	return htmlTemplate.Execute(w, board)
+}
+
+func renderMarkdown(text string) template.HTML {
+	text = checklistItemRegexp.ReplaceAllStringFunc(text, func(item string) string {
+		match := checklistItemRegexp.FindStringSubmatch(item)
+		if match[2] == " " {
+			return match[1] + "☐ "
+		}
+		return match[1] + "☑ "
+	})
+
+	// Sanitized by the bluemonday policy of the markdown module.
+	return template.HTML(markdown.Render(text))
+}
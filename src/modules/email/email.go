@@ -0,0 +1,293 @@
+// Package email parses raw RFC 5322 messages into what is needed to turn them
+// into tasks and comments: the threading headers, the recipients, the body as
+// Markdown and the attachments.
+package email
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"regexp"
+	"strings"
+
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+var ErrInvalidMessage = errors.New("Invalid email message")
+
+// Nesting of multipart bodies deeper than this is not walked into.
+const maxDepth = 8
+
+var wordDecoder = &mime.WordDecoder{CharsetReader: charsetReader}
+
+type Message struct {
+	MessageID string // Without the angle brackets
+
+	// In-Reply-To followed by References, newest first, without the angle
+	// brackets.
+	References []string
+
+	From       *mail.Address
+	Recipients []string // Lowercased addresses of To, Cc and the delivery headers
+	Subject    string
+	Text       string // Plain text, or Markdown converted from HTML
+
+	Attachments []*Attachment
+}
+
+type Attachment struct {
+	Name     string
+	MimeType string
+	Data     []byte
+}
+
+func Parse(r io.Reader) (*Message, error) {
+	raw, err := mail.ReadMessage(bufio.NewReader(r))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidMessage, err)
+	}
+
+	msg := &Message{
+		MessageID:  firstMessageID(raw.Header.Get("Message-Id")),
+		References: messageIDs(raw.Header.Get("In-Reply-To") + " " + reverse(raw.Header.Get("References"))),
+		Subject:    decodeHeader(raw.Header.Get("Subject")),
+	}
+
+	addressParser := &mail.AddressParser{WordDecoder: wordDecoder}
+	if from, err := addressParser.Parse(raw.Header.Get("From")); err == nil {
+		msg.From = from
+	}
+
+	seen := make(map[string]bool)
+	for _, name := range []string{"To", "Cc", "Delivered-To", "X-Original-To", "Envelope-To"} {
+		for _, value := range raw.Header[name] {
+			addresses, err := addressParser.ParseList(value)
+			if err != nil {
+				continue
+			}
+			for _, address := range addresses {
+				recipient := strings.ToLower(address.Address)
+				if !seen[recipient] {
+					seen[recipient] = true
+					msg.Recipients = append(msg.Recipients, recipient)
+				}
+			}
+		}
+	}
+
+	body := &bodyParts{}
+	if err := body.walk(textproto.MIMEHeader(raw.Header), raw.Body, 0); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidMessage, err)
+	}
+
+	if body.plain != "" {
+		msg.Text = normalizeText(body.plain)
+	} else if body.html != "" {
+		msg.Text = HTMLToMarkdown(body.html)
+	}
+	msg.Attachments = body.attachments
+
+	return msg, nil
+}
+
+type bodyParts struct {
+	plain       string
+	html        string
+	attachments []*Attachment
+}
+
+// Takes the first plain and HTML texts as the body, the rest are
+// attachments.
+func (b *bodyParts) walk(header textproto.MIMEHeader, body io.Reader, depth int) error {
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		mediaType, params = "text/plain", map[string]string{}
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		if depth >= maxDepth || params["boundary"] == "" {
+			return nil
+		}
+
+		reader := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := reader.NextRawPart()
+			if err == io.EOF {
+				return nil
+			} else if err != nil {
+				return err
+			}
+
+			if err := b.walk(part.Header, part, depth+1); err != nil {
+				return err
+			}
+		}
+	}
+
+	data, err := io.ReadAll(decodeTransfer(header.Get("Content-Transfer-Encoding"), body))
+	if err != nil {
+		return err
+	}
+
+	disposition, dispositionParams, _ := mime.ParseMediaType(header.Get("Content-Disposition"))
+	name := decodeHeader(dispositionParams["filename"])
+	if name == "" {
+		name = decodeHeader(params["name"])
+	}
+
+	isText := mediaType == "text/plain" || mediaType == "text/html"
+	if isText && disposition != "attachment" && name == "" {
+		text := decodeCharset(params["charset"], data)
+		if mediaType == "text/plain" && b.plain == "" {
+			b.plain = text
+			return nil
+		}
+		if mediaType == "text/html" && b.html == "" {
+			b.html = text
+			return nil
+		}
+	}
+
+	if name == "" {
+		name = "attachment"
+		if extensions, _ := mime.ExtensionsByType(mediaType); len(extensions) > 0 {
+			name += extensions[0]
+		}
+	}
+
+	b.attachments = append(b.attachments, &Attachment{
+		Name:     name,
+		MimeType: mediaType,
+		Data:     data,
+	})
+
+	return nil
+}
+
+func decodeTransfer(encoding string, body io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, body)
+	case "quoted-printable":
+		return quotedprintable.NewReader(body)
+	}
+
+	return body
+}
+
+func charsetReader(charset string, input io.Reader) (io.Reader, error) {
+	encoding, err := htmlindex.Get(charset)
+	if err != nil {
+		return nil, err
+	}
+
+	return encoding.NewDecoder().Reader(input), nil
+}
+
+// Converts the text to UTF-8, it is left as is if the charset is unknown.
+func decodeCharset(charset string, data []byte) string {
+	if charset == "" || strings.EqualFold(charset, "utf-8") || strings.EqualFold(charset, "us-ascii") {
+		return string(data)
+	}
+
+	reader, err := charsetReader(charset, bytes.NewReader(data))
+	if err != nil {
+		return string(data)
+	}
+
+	decoded, err := io.ReadAll(reader)
+	if err != nil {
+		return string(data)
+	}
+
+	return string(decoded)
+}
+
+func decodeHeader(value string) string {
+	decoded, err := wordDecoder.DecodeHeader(value)
+	if err != nil {
+		return strings.TrimSpace(value)
+	}
+
+	return strings.TrimSpace(decoded)
+}
+
+var messageIDRegexp = regexp.MustCompile(`<([^<>\s]+)>`)
+
+func messageIDs(value string) []string {
+	var ids []string
+	seen := make(map[string]bool)
+	for _, match := range messageIDRegexp.FindAllStringSubmatch(value, -1) {
+		if !seen[match[1]] {
+			seen[match[1]] = true
+			ids = append(ids, match[1])
+		}
+	}
+
+	return ids
+}
+
+func firstMessageID(value string) string {
+	if ids := messageIDs(value); len(ids) > 0 {
+		return ids[0]
+	}
+
+	return strings.Trim(strings.TrimSpace(value), "<>")
+}
+
+// References lists the oldest message first.
+func reverse(references string) string {
+	fields := strings.Fields(references)
+	for i, j := 0, len(fields)-1; i < j; i, j = i+1, j-1 {
+		fields[i], fields[j] = fields[j], fields[i]
+	}
+
+	return strings.Join(fields, " ")
+}
+
+var (
+	quoteHeaderRegexp = regexp.MustCompile(`^On .+ wrote:$`)
+	blankLinesRegexp  = regexp.MustCompile(`\n{3,}`)
+)
+
+func normalizeText(text string) string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = blankLinesRegexp.ReplaceAllString(text, "\n\n")
+
+	return strings.TrimSpace(text)
+}
+
+// Cuts the quoted message and the signature off the end of a reply.
+func StripQuotedReply(text string) string {
+	lines := strings.Split(text, "\n")
+
+	for i, line := range lines {
+		if strings.TrimRight(line, " ") == "--" {
+			lines = lines[:i]
+			break
+		}
+	}
+
+	end := len(lines)
+	for end > 0 {
+		line := strings.TrimSpace(lines[end-1])
+		if line == "" || strings.HasPrefix(line, ">") {
+			end--
+			continue
+		}
+		if quoteHeaderRegexp.MatchString(line) {
+			end--
+		}
+		break
+	}
+
+	return strings.TrimSpace(strings.Join(lines[:end], "\n"))
+}
@@ -0,0 +1,78 @@
+package email_test
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lesnoi-kot/karten-backend/src/modules/email"
+)
+
+func parseFile(t *testing.T, name string) *email.Message {
+	file, err := os.Open("testdata/" + name)
+	require.NoError(t, err)
+	defer file.Close()
+
+	msg, err := email.Parse(file)
+	require.NoError(t, err)
+
+	return msg
+}
+
+func TestParseMultipart(t *testing.T) {
+	msg := parseFile(t, "multipart.eml")
+
+	assert.Equal(t, "first@mail.example.com", msg.MessageID)
+	assert.Empty(t, msg.References)
+	assert.Equal(t, "ann@example.com", msg.From.Address)
+	assert.Equal(t, "Ann Smith", msg.From.Name)
+	assert.Equal(t, []string{"b7xkq2@inbound.karten.test", "other@example.com"}, msg.Recipients)
+	assert.Equal(t, "Ошибка входа", msg.Subject)
+	assert.Equal(t, "Login fails with a 500.\n\nSteps: open /login", msg.Text)
+
+	require.Len(t, msg.Attachments, 1)
+	assert.Equal(t, "screen.png", msg.Attachments[0].Name)
+	assert.Equal(t, "image/png", msg.Attachments[0].MimeType)
+	assert.Equal(t, []byte("fake png data"), msg.Attachments[0].Data)
+}
+
+func TestParseHTMLReply(t *testing.T) {
+	msg := parseFile(t, "reply.eml")
+
+	assert.Equal(t, "André", msg.From.Name)
+	assert.Equal(t, []string{"second@mail.example.com", "first@mail.example.com"}, msg.References)
+	assert.Equal(t, strings.Join([]string{
+		"Fixed in [the PR](https://example.com/pr/1), café is on me.",
+		"",
+		"- one",
+		"- two",
+		"",
+		"> On Monday Ann wrote:",
+		"> Login fails",
+	}, "\n"), msg.Text)
+	assert.Empty(t, msg.Attachments)
+}
+
+func TestParseRejectsGarbage(t *testing.T) {
+	_, err := email.Parse(strings.NewReader("not an email"))
+	assert.ErrorIs(t, err, email.ErrInvalidMessage)
+}
+
+func TestStripQuotedReply(t *testing.T) {
+	text := strings.Join([]string{
+		"Thanks, works now.",
+		"",
+		"On Mon, 4 Mar 2024 at 10:00, Ann <ann@example.com> wrote:",
+		"> Login fails",
+		">",
+	}, "\n")
+	assert.Equal(t, "Thanks, works now.", email.StripQuotedReply(text))
+
+	withSignature := "Deployed.\n\n-- \nBob\nSupport team"
+	assert.Equal(t, "Deployed.", email.StripQuotedReply(withSignature))
+
+	assert.Empty(t, email.StripQuotedReply("> only a quote"))
+}
@@ -0,0 +1,188 @@
+package email
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+var spacesRegexp = regexp.MustCompile(`[ \t\r\n]+`)
+
+// Converts the HTML body of an email to Markdown. Only the common formatting
+// is kept, styles and scripts are dropped.
+func HTMLToMarkdown(source string) string {
+	doc, err := html.Parse(strings.NewReader(source))
+	if err != nil {
+		return ""
+	}
+
+	c := &htmlConverter{}
+	c.node(doc)
+
+	text := strings.Join(c.lines(), "\n")
+	return normalizeText(text)
+}
+
+type htmlConverter struct {
+	b         strings.Builder
+	listDepth int
+	pre       bool
+}
+
+func (c *htmlConverter) node(n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		if c.pre {
+			c.b.WriteString(n.Data)
+			return
+		}
+		c.b.WriteString(spacesRegexp.ReplaceAllString(n.Data, " "))
+		return
+	case html.ElementNode:
+	default:
+		c.children(n)
+		return
+	}
+
+	switch n.Data {
+	case "head", "script", "style", "title":
+		return
+	case "br":
+		c.b.WriteString("\n")
+	case "p", "div", "table", "tr":
+		c.block()
+		c.children(n)
+		c.block()
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		c.block()
+		c.b.WriteString(strings.Repeat("#", int(n.Data[1]-'0')) + " ")
+		c.children(n)
+		c.block()
+	case "strong", "b":
+		c.wrap(n, "**")
+	case "em", "i":
+		c.wrap(n, "*")
+	case "code":
+		if c.pre {
+			c.children(n)
+		} else {
+			c.wrap(n, "`")
+		}
+	case "pre":
+		c.block()
+		c.b.WriteString("```\n")
+		c.pre = true
+		c.children(n)
+		c.pre = false
+		c.b.WriteString("\n```")
+		c.block()
+	case "ul", "ol":
+		c.block()
+		c.listDepth++
+		c.children(n)
+		c.listDepth--
+		c.block()
+	case "li":
+		c.b.WriteString("\n" + strings.Repeat("  ", max0(c.listDepth-1)) + "- ")
+		c.children(n)
+	case "blockquote":
+		inner := &htmlConverter{}
+		inner.children(n)
+		c.block()
+		for _, line := range inner.lines() {
+			c.b.WriteString("> " + line + "\n")
+		}
+		c.block()
+	case "a":
+		href := attr(n, "href")
+		inner := &htmlConverter{}
+		inner.children(n)
+		text := strings.TrimSpace(inner.b.String())
+		if href == "" || strings.HasPrefix(href, "mailto:") || text == href {
+			c.b.WriteString(orDefault(text, href))
+		} else {
+			fmt.Fprintf(&c.b, "[%s](%s)", orDefault(text, href), href)
+		}
+	case "img":
+		if alt := attr(n, "alt"); alt != "" {
+			c.b.WriteString(alt)
+		}
+	case "td", "th":
+		c.children(n)
+		c.b.WriteString(" ")
+	case "hr":
+		c.block()
+		c.b.WriteString("---")
+		c.block()
+	default:
+		c.children(n)
+	}
+}
+
+func (c *htmlConverter) children(n *html.Node) {
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		c.node(child)
+	}
+}
+
+func (c *htmlConverter) wrap(n *html.Node, mark string) {
+	inner := &htmlConverter{pre: c.pre}
+	inner.children(n)
+	if text := strings.TrimSpace(inner.b.String()); text != "" {
+		c.b.WriteString(mark + text + mark)
+	}
+}
+
+func (c *htmlConverter) block() {
+	c.b.WriteString("\n\n")
+}
+
+// Lines of the output with the stray spaces trimmed, except in code blocks
+// and the indentation of nested list items.
+func (c *htmlConverter) lines() []string {
+	lines := strings.Split(c.b.String(), "\n")
+	inCode := false
+	for i, line := range lines {
+		if strings.TrimSpace(line) == "```" {
+			inCode = !inCode
+		}
+		if inCode {
+			continue
+		}
+
+		lines[i] = strings.TrimRight(line, " ")
+		if !strings.HasPrefix(strings.TrimSpace(lines[i]), "- ") {
+			lines[i] = strings.TrimLeft(lines[i], " ")
+		}
+	}
+
+	return lines
+}
+
+func attr(n *html.Node, name string) string {
+	for _, a := range n.Attr {
+		if a.Key == name {
+			return strings.TrimSpace(a.Val)
+		}
+	}
+
+	return ""
+}
+
+func orDefault(text, fallback string) string {
+	if text == "" {
+		return fallback
+	}
+
+	return text
+}
+
+func max0(n int) int {
+	if n < 0 {
+		return 0
+	}
+
+	return n
+}
@@ -0,0 +1,155 @@
+// Package flowmetrics computes lead time, cycle time and time in list from
+// the history of task movements between lists.
+package flowmetrics
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+var DefaultPercentiles = []float64{50, 75, 85, 95}
+
+type Movement struct {
+	ToListID string // Empty if the list does not exist anymore
+	At       time.Time
+}
+
+type Task struct {
+	ID        string
+	Created   time.Time
+	Movements []Movement // Ordered by time, the first one is the creation
+}
+
+type Options struct {
+	// Tasks finishing, or stays starting, within [From, To) are counted.
+	From time.Time
+	To   time.Time
+	Now  time.Time
+
+	StartListID string
+	EndListID   string
+	ListIDs     []string
+
+	Percentiles []float64
+}
+
+type Percentile struct {
+	P       float64
+	Seconds int64
+}
+
+type Distribution struct {
+	Count       int
+	Percentiles []Percentile
+}
+
+type ListTime struct {
+	ListID string
+	Distribution
+}
+
+type Report struct {
+	LeadTime  Distribution
+	CycleTime Distribution
+	Lists     []ListTime
+}
+
+func Build(tasks []Task, opts Options) *Report {
+	if len(opts.Percentiles) == 0 {
+		opts.Percentiles = DefaultPercentiles
+	}
+
+	inRange := func(t time.Time) bool {
+		return !t.Before(opts.From) && t.Before(opts.To)
+	}
+
+	var lead, cycle []float64
+	stays := make(map[string][]float64)
+
+	for _, task := range tasks {
+		var started, finished time.Time
+
+		for i, move := range task.Movements {
+			if started.IsZero() && move.ToListID == opts.StartListID {
+				started = move.At
+			}
+			if finished.IsZero() && move.ToListID == opts.EndListID {
+				finished = move.At
+			}
+
+			if move.ToListID == "" || !inRange(move.At) {
+				continue
+			}
+
+			left := opts.Now
+			if i+1 < len(task.Movements) {
+				left = task.Movements[i+1].At
+			}
+			stays[move.ToListID] = append(stays[move.ToListID], left.Sub(move.At).Seconds())
+		}
+
+		if finished.IsZero() || !inRange(finished) {
+			continue
+		}
+
+		lead = append(lead, finished.Sub(task.Created).Seconds())
+		if !started.IsZero() && !started.After(finished) {
+			cycle = append(cycle, finished.Sub(started).Seconds())
+		}
+	}
+
+	report := &Report{
+		LeadTime:  distribution(lead, opts.Percentiles),
+		CycleTime: distribution(cycle, opts.Percentiles),
+		Lists:     make([]ListTime, len(opts.ListIDs)),
+	}
+
+	for i, listID := range opts.ListIDs {
+		report.Lists[i] = ListTime{
+			ListID:       listID,
+			Distribution: distribution(stays[listID], opts.Percentiles),
+		}
+	}
+
+	return report
+}
+
+func distribution(values []float64, percentiles []float64) Distribution {
+	sort.Float64s(values)
+
+	result := Distribution{
+		Count:       len(values),
+		Percentiles: make([]Percentile, len(percentiles)),
+	}
+
+	for i, p := range percentiles {
+		result.Percentiles[i] = Percentile{
+			P:       p,
+			Seconds: int64(math.Round(PercentileOf(values, p))),
+		}
+	}
+
+	return result
+}
+
+// Percentile of sorted values, linearly interpolated between the closest
+// ranks. Zero for no values.
+func PercentileOf(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+
+	rank := p / 100 * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+
+	if lower < 0 {
+		return sorted[0]
+	}
+	if upper >= len(sorted) {
+		return sorted[len(sorted)-1]
+	}
+
+	return sorted[lower] + (sorted[upper]-sorted[lower])*(rank-float64(lower))
+}
@@ -0,0 +1,113 @@
+package flowmetrics_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lesnoi-kot/karten-backend/src/modules/flowmetrics"
+)
+
+var base = time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)
+
+func at(hours int) time.Time {
+	return base.Add(time.Duration(hours) * time.Hour)
+}
+
+func TestPercentileOf(t *testing.T) {
+	values := []float64{1, 2, 3, 4}
+
+	assert.Equal(t, 0.0, flowmetrics.PercentileOf(nil, 50))
+	assert.Equal(t, 1.0, flowmetrics.PercentileOf(values, 0))
+	assert.Equal(t, 2.5, flowmetrics.PercentileOf(values, 50))
+	assert.Equal(t, 4.0, flowmetrics.PercentileOf(values, 100))
+	assert.InDelta(t, 3.55, flowmetrics.PercentileOf(values, 85), 1e-9)
+}
+
+func TestBuild(t *testing.T) {
+	tasks := []flowmetrics.Task{
+		{
+			ID:      "a",
+			Created: at(0),
+			Movements: []flowmetrics.Movement{
+				{ToListID: "todo", At: at(0)},
+				{ToListID: "doing", At: at(2)},
+				{ToListID: "done", At: at(6)},
+			},
+		},
+		{
+			// Skips "doing", has no cycle time.
+			ID:      "b",
+			Created: at(1),
+			Movements: []flowmetrics.Movement{
+				{ToListID: "todo", At: at(1)},
+				{ToListID: "done", At: at(3)},
+			},
+		},
+		{
+			// Not finished yet, stays in "doing" until now.
+			ID:      "c",
+			Created: at(0),
+			Movements: []flowmetrics.Movement{
+				{ToListID: "todo", At: at(0)},
+				{ToListID: "doing", At: at(4)},
+			},
+		},
+	}
+
+	report := flowmetrics.Build(tasks, flowmetrics.Options{
+		From:        at(0),
+		To:          at(24),
+		Now:         at(10),
+		StartListID: "doing",
+		EndListID:   "done",
+		ListIDs:     []string{"todo", "doing", "done"},
+		Percentiles: []float64{50, 100},
+	})
+
+	assert.Equal(t, flowmetrics.Distribution{
+		Count: 2,
+		Percentiles: []flowmetrics.Percentile{
+			{P: 50, Seconds: 4 * 3600},
+			{P: 100, Seconds: 6 * 3600},
+		},
+	}, report.LeadTime)
+
+	assert.Equal(t, flowmetrics.Distribution{
+		Count: 1,
+		Percentiles: []flowmetrics.Percentile{
+			{P: 50, Seconds: 4 * 3600},
+			{P: 100, Seconds: 4 * 3600},
+		},
+	}, report.CycleTime)
+
+	assert.Equal(t, "doing", report.Lists[1].ListID)
+	assert.Equal(t, 2, report.Lists[1].Count)
+	// Stays of 4h (a) and 6h (c, still there).
+	assert.Equal(t, int64(5*3600), report.Lists[1].Percentiles[0].Seconds)
+}
+
+func TestBuildRespectsRange(t *testing.T) {
+	tasks := []flowmetrics.Task{{
+		ID:      "a",
+		Created: at(0),
+		Movements: []flowmetrics.Movement{
+			{ToListID: "todo", At: at(0)},
+			{ToListID: "done", At: at(30)},
+		},
+	}}
+
+	report := flowmetrics.Build(tasks, flowmetrics.Options{
+		From:      at(0),
+		To:        at(24),
+		Now:       at(48),
+		EndListID: "done",
+		ListIDs:   []string{"todo", "done"},
+	})
+
+	assert.Equal(t, 0, report.LeadTime.Count)
+	assert.Equal(t, 1, report.Lists[0].Count)
+	assert.Equal(t, 0, report.Lists[1].Count)
+	assert.Len(t, report.LeadTime.Percentiles, len(flowmetrics.DefaultPercentiles))
+}
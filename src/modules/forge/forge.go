@@ -0,0 +1,248 @@
+// Package forge reads the push and pull request webhooks of GitHub and Gitea
+// and finds the task references in the commit messages and pull requests.
+package forge
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+var ErrInvalidPayload = errors.New("Invalid forge webhook payload")
+
+type Provider string
+
+const (
+	GitHub Provider = "github"
+	Gitea  Provider = "gitea"
+)
+
+const (
+	EventPush        = "push"
+	EventPullRequest = "pull_request"
+	EventPing        = "ping"
+)
+
+type ChangeKind string
+
+const (
+	ChangeCommit      ChangeKind = "commit"
+	ChangePullRequest ChangeKind = "pull_request"
+)
+
+// States of the pull requests.
+const (
+	StateOpen   = "open"
+	StateClosed = "closed"
+	StateMerged = "merged"
+)
+
+type Event struct {
+	Type       string
+	Repository string // Full name, e.g. owner/repo
+	Changes    []*Change
+}
+
+// Commit or pull request which may refer to tasks.
+type Change struct {
+	Kind   ChangeKind
+	ID     string // Commit hash or pull request number
+	URL    string
+	Title  string // First line of the commit message
+	Author string
+	State  string // Of the pull requests
+
+	// Text searched for the references.
+	Text string
+
+	// Closing references, as in "fixes KAR-1", complete the tasks. Only
+	// commits pushed to the default branch and merged pull requests do.
+	Closes bool
+}
+
+// Task key (KAR-12) or short ID (12 hex digits) mentioned in a text.
+type Reference struct {
+	ID      string // Keys are upper case, short IDs are lower case
+	Closing bool
+}
+
+// Tells the forge by the headers it sends. Gitea sends the GitHub headers
+// too, so it is checked first.
+func DetectProvider(header http.Header) Provider {
+	if header.Get("X-Gitea-Event") != "" {
+		return Gitea
+	}
+
+	return GitHub
+}
+
+func EventType(provider Provider, header http.Header) string {
+	if provider == Gitea {
+		return header.Get("X-Gitea-Event")
+	}
+
+	return header.Get("X-GitHub-Event")
+}
+
+// Checks the HMAC-SHA256 signature of the body made with the secret.
+func VerifySignature(provider Provider, header http.Header, body []byte, secret string) bool {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	expected := hex.EncodeToString(mac.Sum(nil))
+
+	var signature string
+	if provider == Gitea {
+		signature = header.Get("X-Gitea-Signature")
+	} else {
+		signature = strings.TrimPrefix(header.Get("X-Hub-Signature-256"), "sha256=")
+	}
+
+	return signature != "" && hmac.Equal([]byte(expected), []byte(strings.ToLower(signature)))
+}
+
+type payloadUser struct {
+	Login    string `json:"login"`
+	Username string `json:"username"`
+	Name     string `json:"name"`
+}
+
+func (u *payloadUser) String() string {
+	if u == nil {
+		return ""
+	}
+
+	for _, name := range []string{u.Name, u.Username, u.Login} {
+		if name != "" {
+			return name
+		}
+	}
+
+	return ""
+}
+
+// GitHub and Gitea payloads share the fields used here.
+type payload struct {
+	Ref        string `json:"ref"`
+	Action     string `json:"action"`
+	Repository struct {
+		FullName      string `json:"full_name"`
+		DefaultBranch string `json:"default_branch"`
+	} `json:"repository"`
+	Commits []struct {
+		ID      string       `json:"id"`
+		Message string       `json:"message"`
+		URL     string       `json:"url"`
+		Author  *payloadUser `json:"author"`
+	} `json:"commits"`
+	PullRequest *struct {
+		Number  int          `json:"number"`
+		HTMLURL string       `json:"html_url"`
+		Title   string       `json:"title"`
+		Body    string       `json:"body"`
+		State   string       `json:"state"`
+		Merged  bool         `json:"merged"`
+		User    *payloadUser `json:"user"`
+		Head    struct {
+			Ref string `json:"ref"`
+		} `json:"head"`
+	} `json:"pull_request"`
+}
+
+// Reads the commits of a push or the pull request of a pull request event.
+// Other events have no changes.
+func Parse(eventType string, body []byte) (*Event, error) {
+	var data payload
+	if err := json.Unmarshal(body, &data); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidPayload, err)
+	}
+
+	event := &Event{
+		Type:       eventType,
+		Repository: data.Repository.FullName,
+		Changes:    make([]*Change, 0),
+	}
+
+	switch eventType {
+	case EventPush:
+		toDefaultBranch := data.Repository.DefaultBranch != "" &&
+			data.Ref == "refs/heads/"+data.Repository.DefaultBranch
+
+		for _, commit := range data.Commits {
+			title, _, _ := strings.Cut(strings.TrimSpace(commit.Message), "\n")
+			event.Changes = append(event.Changes, &Change{
+				Kind:   ChangeCommit,
+				ID:     commit.ID,
+				URL:    commit.URL,
+				Title:  strings.TrimSpace(title),
+				Author: commit.Author.String(),
+				Text:   commit.Message,
+				Closes: toDefaultBranch,
+			})
+		}
+
+	case EventPullRequest:
+		pr := data.PullRequest
+		if pr == nil {
+			return nil, fmt.Errorf("%w: no pull request", ErrInvalidPayload)
+		}
+
+		state := StateOpen
+		if pr.Merged {
+			state = StateMerged
+		} else if pr.State == StateClosed {
+			state = StateClosed
+		}
+
+		event.Changes = append(event.Changes, &Change{
+			Kind:   ChangePullRequest,
+			ID:     fmt.Sprint(pr.Number),
+			URL:    pr.HTMLURL,
+			Title:  strings.TrimSpace(pr.Title),
+			Author: pr.User.String(),
+			State:  state,
+			Text:   strings.Join([]string{pr.Title, pr.Body, pr.Head.Ref}, "\n"),
+			Closes: state == StateMerged,
+		})
+	}
+
+	return event, nil
+}
+
+var (
+	referenceRegexp = regexp.MustCompile(
+		`(?i)(?:\b(close[sd]?|fix(?:e[sd])?|resolve[sd]?):?\s+|^|\W)` +
+			`#?([a-z][a-z0-9]{0,9}-[1-9][0-9]*|[0-9a-f]{12})\b`,
+	)
+	shortIDRegexp = regexp.MustCompile(`^[0-9a-f]{12}$`)
+)
+
+// Finds the task references in the text, each once. A reference is closing
+// if a closing keyword precedes it anywhere in the text.
+func FindReferences(text string) []Reference {
+	var refs []Reference
+	index := make(map[string]int)
+
+	for _, match := range referenceRegexp.FindAllStringSubmatch(text, -1) {
+		id := strings.ToLower(match[2])
+		if !shortIDRegexp.MatchString(id) {
+			id = strings.ToUpper(id)
+		}
+		closing := match[1] != ""
+
+		if i, ok := index[id]; ok {
+			refs[i].Closing = refs[i].Closing || closing
+			continue
+		}
+
+		index[id] = len(refs)
+		refs = append(refs, Reference{ID: id, Closing: closing})
+	}
+
+	return refs
+}
@@ -0,0 +1,155 @@
+package forge_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lesnoi-kot/karten-backend/src/modules/forge"
+)
+
+func readFixture(t *testing.T, name string) []byte {
+	t.Helper()
+
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	require.NoError(t, err)
+	return data
+}
+
+func sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifySignature(t *testing.T) {
+	body := readFixture(t, "github_push.json")
+
+	header := http.Header{}
+	header.Set("X-GitHub-Event", "push")
+	header.Set("X-Hub-Signature-256", "sha256="+sign("s3cret", body))
+	require.Equal(t, forge.GitHub, forge.DetectProvider(header))
+	assert.Equal(t, forge.EventPush, forge.EventType(forge.GitHub, header))
+	assert.True(t, forge.VerifySignature(forge.GitHub, header, body, "s3cret"))
+	assert.False(t, forge.VerifySignature(forge.GitHub, header, body, "other"))
+	assert.False(t, forge.VerifySignature(forge.GitHub, header, append(body, ' '), "s3cret"))
+
+	header = http.Header{}
+	header.Set("X-GitHub-Event", "pull_request")
+	header.Set("X-Gitea-Event", "pull_request")
+	header.Set("X-Gitea-Signature", sign("s3cret", body))
+	require.Equal(t, forge.Gitea, forge.DetectProvider(header))
+	assert.Equal(t, forge.EventPullRequest, forge.EventType(forge.Gitea, header))
+	assert.True(t, forge.VerifySignature(forge.Gitea, header, body, "s3cret"))
+	assert.False(t, forge.VerifySignature(forge.GitHub, header, body, "s3cret"))
+
+	assert.False(t, forge.VerifySignature(forge.GitHub, http.Header{}, body, "s3cret"))
+}
+
+func TestParseGitHubPush(t *testing.T) {
+	event, err := forge.Parse(forge.EventPush, readFixture(t, "github_push.json"))
+	require.NoError(t, err)
+
+	assert.Equal(t, "octocat/karten-backend", event.Repository)
+	require.Len(t, event.Changes, 2)
+
+	commit := event.Changes[0]
+	assert.Equal(t, forge.ChangeCommit, commit.Kind)
+	assert.Equal(t, "1f2e3d4c5b6a79880123456789abcdef01234567", commit.ID)
+	assert.Equal(t, "https://github.com/octocat/karten-backend/commit/1f2e3d4c5b6a79880123456789abcdef01234567", commit.URL)
+	assert.Equal(t, "Fixes KAR-12: trim the board names", commit.Title)
+	assert.Equal(t, "The Octocat", commit.Author)
+	assert.True(t, commit.Closes, "pushed to the default branch")
+	assert.Equal(t, []forge.Reference{{ID: "KAR-12", Closing: true}}, forge.FindReferences(commit.Text))
+
+	commit = event.Changes[1]
+	assert.Equal(t, "Mona Lisa", commit.Author)
+	assert.Equal(t, []forge.Reference{
+		{ID: "KAR-7"},
+		{ID: "3f9a0c5e21d4"},
+	}, forge.FindReferences(commit.Text))
+}
+
+func TestParseGitHubPullRequest(t *testing.T) {
+	event, err := forge.Parse(forge.EventPullRequest, readFixture(t, "github_pull_request.json"))
+	require.NoError(t, err)
+	require.Len(t, event.Changes, 1)
+
+	pr := event.Changes[0]
+	assert.Equal(t, forge.ChangePullRequest, pr.Kind)
+	assert.Equal(t, "42", pr.ID)
+	assert.Equal(t, "https://github.com/octocat/karten-backend/pull/42", pr.URL)
+	assert.Equal(t, "Add the CSV export", pr.Title)
+	assert.Equal(t, "monalisa", pr.Author)
+	assert.Equal(t, forge.StateMerged, pr.State)
+	assert.True(t, pr.Closes)
+	assert.Equal(t, []forge.Reference{
+		{ID: "KAR-31", Closing: true},
+		{ID: "KAR-5"},
+	}, forge.FindReferences(pr.Text))
+}
+
+func TestParseGitea(t *testing.T) {
+	event, err := forge.Parse(forge.EventPush, readFixture(t, "gitea_push.json"))
+	require.NoError(t, err)
+
+	assert.Equal(t, "team/karten", event.Repository)
+	require.Len(t, event.Changes, 1)
+	assert.Equal(t, "Gitea User", event.Changes[0].Author)
+	assert.False(t, event.Changes[0].Closes, "pushed to a feature branch")
+	assert.Equal(t, []forge.Reference{
+		{ID: "KAR-3", Closing: true},
+		{ID: "KAR-4", Closing: true},
+	}, forge.FindReferences(event.Changes[0].Text))
+
+	event, err = forge.Parse(forge.EventPullRequest, readFixture(t, "gitea_pull_request.json"))
+	require.NoError(t, err)
+	require.Len(t, event.Changes, 1)
+
+	pr := event.Changes[0]
+	assert.Equal(t, "7", pr.ID)
+	assert.Equal(t, "https://gitea.example.com/team/karten/pulls/7", pr.URL)
+	assert.Equal(t, "gituser", pr.Author)
+	assert.Equal(t, forge.StateOpen, pr.State)
+	assert.False(t, pr.Closes, "not merged yet")
+}
+
+func TestParseOtherEvents(t *testing.T) {
+	event, err := forge.Parse(forge.EventPing, []byte(`{"zen": "Keep it logically awesome."}`))
+	require.NoError(t, err)
+	assert.Empty(t, event.Changes)
+
+	_, err = forge.Parse(forge.EventPush, []byte(`not json`))
+	assert.ErrorIs(t, err, forge.ErrInvalidPayload)
+
+	_, err = forge.Parse(forge.EventPullRequest, []byte(`{}`))
+	assert.ErrorIs(t, err, forge.ErrInvalidPayload)
+}
+
+func TestFindReferences(t *testing.T) {
+	cases := []struct {
+		text string
+		refs []forge.Reference
+	}{
+		{"nothing here", nil},
+		{"fix: KAR-1", []forge.Reference{{ID: "KAR-1", Closing: true}}},
+		{"Closed #kar-2, see (KAR-3)", []forge.Reference{{ID: "KAR-2", Closing: true}, {ID: "KAR-3"}}},
+		{"branch fix/kar-4-login", []forge.Reference{{ID: "KAR-4"}}},
+		{"KAR-5 then resolves KAR-5", []forge.Reference{{ID: "KAR-5", Closing: true}}},
+		{"short id 0123456789AB", []forge.Reference{{ID: "0123456789ab"}}},
+		{"full hash 1f2e3d4c5b6a79880123456789abcdef01234567 is not one", nil},
+		{"prefix fixes without space:KAR-6", []forge.Reference{{ID: "KAR-6"}}},
+		{"KAR-0 is not a key", nil},
+	}
+
+	for _, c := range cases {
+		assert.Equal(t, c.refs, forge.FindReferences(c.text), c.text)
+	}
+}
@@ -0,0 +1,165 @@
+// Package ical writes iCalendar (RFC 5545) feeds of task due dates.
+package ical
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+type Component string
+
+const (
+	ComponentEvent Component = "event"
+	ComponentTodo  Component = "todo"
+)
+
+type Calendar struct {
+	Name  string
+	Items []*Item
+}
+
+type Item struct {
+	UID         string
+	Summary     string
+	Description string
+	URL         string
+	Categories  []string
+	Due         time.Time // Midnight in UTC is a whole day
+	Completed   bool
+}
+
+const (
+	dateLayout     = "20060102"
+	dateTimeLayout = "20060102T150405Z"
+
+	// Lines are folded after this many octets.
+	maxLineLength = 75
+)
+
+var textEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	";", `\;`,
+	",", `\,`,
+	"\r\n", `\n`,
+	"\n", `\n`,
+)
+
+// Writes the calendar with each item as the given component.
+func Write(w io.Writer, calendar *Calendar, component Component, now time.Time) error {
+	var b strings.Builder
+	line := func(name, value string) {
+		writeLine(&b, name+":"+value)
+	}
+	text := func(name, value string) {
+		line(name, textEscaper.Replace(value))
+	}
+
+	line("BEGIN", "VCALENDAR")
+	line("VERSION", "2.0")
+	line("PRODID", "-//Karten//Due dates//EN")
+	line("CALSCALE", "GREGORIAN")
+	line("METHOD", "PUBLISH")
+	text("X-WR-CALNAME", calendar.Name)
+
+	stamp := now.UTC().Format(dateTimeLayout)
+	for _, item := range calendar.Items {
+		due, allDay := formatDue(item.Due)
+		dueParam := ""
+		if allDay {
+			dueParam = ";VALUE=DATE"
+		}
+
+		if component == ComponentTodo {
+			line("BEGIN", "VTODO")
+		} else {
+			line("BEGIN", "VEVENT")
+		}
+
+		text("UID", item.UID)
+		line("DTSTAMP", stamp)
+
+		if component == ComponentTodo {
+			text("SUMMARY", item.Summary)
+			line("DUE"+dueParam, due)
+			if item.Completed {
+				line("STATUS", "COMPLETED")
+			} else {
+				line("STATUS", "NEEDS-ACTION")
+			}
+		} else {
+			summary := item.Summary
+			if item.Completed {
+				summary = "✓ " + summary
+			}
+			text("SUMMARY", summary)
+			line("DTSTART"+dueParam, due)
+			if allDay {
+				line("DTEND"+dueParam, item.Due.UTC().AddDate(0, 0, 1).Format(dateLayout))
+			} else {
+				line("DTEND", due)
+			}
+			line("TRANSP", "TRANSPARENT")
+		}
+
+		if item.Description != "" {
+			text("DESCRIPTION", item.Description)
+		}
+		if item.URL != "" {
+			line("URL", item.URL)
+		}
+		if len(item.Categories) > 0 {
+			categories := make([]string, len(item.Categories))
+			for i, category := range item.Categories {
+				categories[i] = textEscaper.Replace(category)
+			}
+			line("CATEGORIES", strings.Join(categories, ","))
+		}
+
+		if component == ComponentTodo {
+			line("END", "VTODO")
+		} else {
+			line("END", "VEVENT")
+		}
+	}
+
+	line("END", "VCALENDAR")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func formatDue(due time.Time) (string, bool) {
+	due = due.UTC()
+	if due.Hour() == 0 && due.Minute() == 0 && due.Second() == 0 {
+		return due.Format(dateLayout), true
+	}
+
+	return due.Format(dateTimeLayout), false
+}
+
+// Writes the content line folded to lines of at most 75 octets, without
+// splitting UTF-8 sequences.
+func writeLine(b *strings.Builder, content string) {
+	limit := maxLineLength
+	for len(content) > limit {
+		cut := limit
+		for cut > 0 && !isRuneStart(content[cut]) {
+			cut--
+		}
+
+		fmt.Fprintf(b, "%s\r\n ", content[:cut])
+		content = content[cut:]
+
+		// The leading space of the continuation counts too.
+		limit = maxLineLength - 1
+	}
+
+	b.WriteString(content)
+	b.WriteString("\r\n")
+}
+
+func isRuneStart(c byte) bool {
+	return c&0xC0 != 0x80
+}
@@ -0,0 +1,120 @@
+package ical_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lesnoi-kot/karten-backend/src/modules/ical"
+)
+
+var now = time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC)
+
+func TestWriteEvents(t *testing.T) {
+	calendar := &ical.Calendar{
+		Name: "Karten, Roadmap",
+		Items: []*ical.Item{
+			{
+				UID:         "t1@karten",
+				Summary:     "KAR-1 Fix login; again",
+				Description: "Steps:\n- reproduce",
+				URL:         "https://karten.test/task/t1",
+				Categories:  []string{"bug", "web,ui"},
+				Due:         time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC),
+			},
+			{
+				UID:       "t2@karten",
+				Summary:   "Release",
+				Due:       time.Date(2024, 3, 6, 15, 0, 0, 0, time.UTC),
+				Completed: true,
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, ical.Write(&buf, calendar, ical.ComponentEvent, now))
+
+	assert.Equal(t, strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//Karten//Due dates//EN",
+		"CALSCALE:GREGORIAN",
+		"METHOD:PUBLISH",
+		`X-WR-CALNAME:Karten\, Roadmap`,
+		"BEGIN:VEVENT",
+		"UID:t1@karten",
+		"DTSTAMP:20240301T093000Z",
+		`SUMMARY:KAR-1 Fix login\; again`,
+		"DTSTART;VALUE=DATE:20240305",
+		"DTEND;VALUE=DATE:20240306",
+		"TRANSP:TRANSPARENT",
+		`DESCRIPTION:Steps:\n- reproduce`,
+		"URL:https://karten.test/task/t1",
+		`CATEGORIES:bug,web\,ui`,
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:t2@karten",
+		"DTSTAMP:20240301T093000Z",
+		"SUMMARY:✓ Release",
+		"DTSTART:20240306T150000Z",
+		"DTEND:20240306T150000Z",
+		"TRANSP:TRANSPARENT",
+		"END:VEVENT",
+		"END:VCALENDAR",
+		"",
+	}, "\r\n"), buf.String())
+}
+
+func TestWriteTodos(t *testing.T) {
+	calendar := &ical.Calendar{
+		Name: "Roadmap",
+		Items: []*ical.Item{
+			{UID: "t1@karten", Summary: "Fix login", Due: time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC)},
+			{UID: "t2@karten", Summary: "Release", Due: time.Date(2024, 3, 6, 15, 0, 0, 0, time.UTC), Completed: true},
+		},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, ical.Write(&buf, calendar, ical.ComponentTodo, now))
+	out := buf.String()
+
+	assert.Contains(t, out, "BEGIN:VTODO\r\nUID:t1@karten\r\n")
+	assert.Contains(t, out, "DUE;VALUE=DATE:20240305\r\nSTATUS:NEEDS-ACTION\r\n")
+	assert.Contains(t, out, "SUMMARY:Release\r\nDUE:20240306T150000Z\r\nSTATUS:COMPLETED\r\n")
+	assert.NotContains(t, out, "VEVENT")
+}
+
+func TestWriteFoldsLongLines(t *testing.T) {
+	calendar := &ical.Calendar{
+		Name: "Board",
+		Items: []*ical.Item{{
+			UID:     "t1",
+			Summary: strings.Repeat("ж", 100),
+			Due:     now,
+		}},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, ical.Write(&buf, calendar, ical.ComponentTodo, now))
+
+	var summary strings.Builder
+	inSummary := false
+	for _, line := range strings.Split(buf.String(), "\r\n") {
+		assert.LessOrEqual(t, len(line), 75)
+
+		if strings.HasPrefix(line, "SUMMARY:") {
+			inSummary = true
+			summary.WriteString(strings.TrimPrefix(line, "SUMMARY:"))
+		} else if inSummary && strings.HasPrefix(line, " ") {
+			summary.WriteString(line[1:])
+		} else {
+			inSummary = false
+		}
+	}
+
+	assert.Equal(t, strings.Repeat("ж", 100), summary.String())
+}
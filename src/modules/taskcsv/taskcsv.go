@@ -0,0 +1,360 @@
+// Package taskcsv writes tasks as CSV and reads tasks from CSV files made in
+// spreadsheets, with the columns mapped to the task fields by their headers.
+package taskcsv
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Column string
+
+const (
+	ColumnKey       Column = "key"
+	ColumnName      Column = "name"
+	ColumnBoard     Column = "board"
+	ColumnList      Column = "list"
+	ColumnLabels    Column = "labels"
+	ColumnText      Column = "text"
+	ColumnStartDate Column = "start_date"
+	ColumnDueDate   Column = "due_date"
+	ColumnSpentTime Column = "spent_time"
+	ColumnEstimate  Column = "estimate"
+	ColumnPriority  Column = "priority"
+	ColumnArchived  Column = "archived"
+)
+
+var DefaultColumns = []Column{
+	ColumnKey,
+	ColumnName,
+	ColumnList,
+	ColumnLabels,
+	ColumnDueDate,
+	ColumnSpentTime,
+	ColumnArchived,
+}
+
+// Columns which can be mapped on import. The rest is derived by the server.
+var importColumns = map[Column]bool{
+	ColumnName:      true,
+	ColumnList:      true,
+	ColumnLabels:    true,
+	ColumnText:      true,
+	ColumnStartDate: true,
+	ColumnDueDate:   true,
+	ColumnEstimate:  true,
+	ColumnPriority:  true,
+}
+
+const (
+	dateLayout     = "2006-01-02"
+	labelSeparator = ";"
+	maxPriority    = 4
+)
+
+var (
+	ErrUnknownColumn = errors.New("Unknown CSV column")
+	ErrInvalidCSV    = errors.New("Invalid CSV")
+)
+
+// Task as exported, one per CSV record.
+type Row struct {
+	Key       string
+	Name      string
+	Board     string
+	List      string
+	Labels    []string
+	Text      string
+	StartDate *time.Time
+	DueDate   *time.Time
+	SpentTime int64 // Seconds
+	Estimate  *float64
+	Priority  int16
+	Archived  bool
+}
+
+// Task fields by the headers of the CSV columns holding them.
+type Mapping map[Column]string
+
+// Task read from the CSV. List and labels are names, resolving them is left to
+// the caller.
+type ImportRow struct {
+	Line      int // Line of the record in the file, the header is line 1
+	Name      string
+	List      string
+	Labels    []string
+	Text      string
+	StartDate *time.Time
+	DueDate   *time.Time
+	Estimate  *float64
+	Priority  int16
+}
+
+type RowError struct {
+	Line    int
+	Column  Column
+	Message string
+}
+
+// Parses a comma separated list of columns, empty means the default ones.
+func ParseColumns(s string) ([]Column, error) {
+	if strings.TrimSpace(s) == "" {
+		return DefaultColumns, nil
+	}
+
+	var columns []Column
+	for _, name := range strings.Split(s, ",") {
+		column := Column(strings.TrimSpace(name))
+		switch column {
+		case ColumnKey, ColumnName, ColumnBoard, ColumnList, ColumnLabels, ColumnText,
+			ColumnStartDate, ColumnDueDate, ColumnSpentTime, ColumnEstimate, ColumnPriority, ColumnArchived:
+			columns = append(columns, column)
+		default:
+			return nil, fmt.Errorf("%w: %s", ErrUnknownColumn, column)
+		}
+	}
+
+	return columns, nil
+}
+
+func Write(w io.Writer, columns []Column, rows []*Row) error {
+	writer := csv.NewWriter(w)
+
+	header := make([]string, len(columns))
+	for i, column := range columns {
+		header[i] = string(column)
+	}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+
+	record := make([]string, len(columns))
+	for _, row := range rows {
+		for i, column := range columns {
+			record[i] = row.field(column)
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+func (row *Row) field(column Column) string {
+	switch column {
+	case ColumnKey:
+		return escapeFormula(row.Key)
+	case ColumnName:
+		return escapeFormula(row.Name)
+	case ColumnBoard:
+		return escapeFormula(row.Board)
+	case ColumnList:
+		return escapeFormula(row.List)
+	case ColumnLabels:
+		return escapeFormula(strings.Join(row.Labels, labelSeparator+" "))
+	case ColumnText:
+		return escapeFormula(row.Text)
+	case ColumnStartDate:
+		return formatDate(row.StartDate)
+	case ColumnDueDate:
+		return formatDate(row.DueDate)
+	case ColumnSpentTime:
+		return strconv.FormatInt(row.SpentTime, 10)
+	case ColumnEstimate:
+		if row.Estimate == nil {
+			return ""
+		}
+		return strconv.FormatFloat(*row.Estimate, 'f', -1, 64)
+	case ColumnPriority:
+		return strconv.Itoa(int(row.Priority))
+	case ColumnArchived:
+		return strconv.FormatBool(row.Archived)
+	}
+
+	return ""
+}
+
+// Prefixes the text cells which spreadsheets would take for formulas with a
+// quote, so that an exported task name can't run anything when opened.
+func escapeFormula(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+
+	return value
+}
+
+func formatDate(date *time.Time) string {
+	if date == nil {
+		return ""
+	}
+
+	return date.UTC().Format(time.RFC3339)
+}
+
+// Reads the tasks, the first record is the header. Records with invalid
+// values are reported by RowError and left out of the rows. An error is
+// returned only if the file or the mapping is unusable as a whole.
+func Read(r io.Reader, mapping Mapping) ([]*ImportRow, []*RowError, error) {
+	for column := range mapping {
+		if !importColumns[column] {
+			return nil, nil, fmt.Errorf("%w: %s can't be imported", ErrUnknownColumn, column)
+		}
+	}
+	if mapping[ColumnName] == "" {
+		return nil, nil, fmt.Errorf("%w: the name column is not mapped", ErrInvalidCSV)
+	}
+
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil, fmt.Errorf("%w: no header", ErrInvalidCSV)
+	} else if err != nil {
+		return nil, nil, fmt.Errorf("%w: %s", ErrInvalidCSV, err)
+	}
+
+	indexes := make(map[Column]int, len(mapping))
+	for column, name := range mapping {
+		index := indexOf(header, name)
+		if index < 0 {
+			return nil, nil, fmt.Errorf("%w: no %q column for %s", ErrInvalidCSV, name, column)
+		}
+		indexes[column] = index
+	}
+
+	rows := make([]*ImportRow, 0)
+	rowErrors := make([]*RowError, 0)
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, nil, fmt.Errorf("%w: %s", ErrInvalidCSV, err)
+		}
+
+		line, _ := reader.FieldPos(0)
+		if isBlank(record) {
+			continue
+		}
+
+		value := func(column Column) string {
+			index, ok := indexes[column]
+			if !ok || index >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[index])
+		}
+
+		row, errs := parseRow(line, value)
+		if len(errs) > 0 {
+			rowErrors = append(rowErrors, errs...)
+			continue
+		}
+		rows = append(rows, row)
+	}
+
+	return rows, rowErrors, nil
+}
+
+func parseRow(line int, value func(column Column) string) (*ImportRow, []*RowError) {
+	var errs []*RowError
+	fail := func(column Column, format string, args ...any) {
+		errs = append(errs, &RowError{Line: line, Column: column, Message: fmt.Sprintf(format, args...)})
+	}
+
+	row := &ImportRow{
+		Line: line,
+		Name: value(ColumnName),
+		List: value(ColumnList),
+		Text: value(ColumnText),
+	}
+	if row.Name == "" {
+		fail(ColumnName, "Name is empty")
+	}
+
+	for _, label := range strings.Split(value(ColumnLabels), labelSeparator) {
+		if label = strings.TrimSpace(label); label != "" {
+			row.Labels = append(row.Labels, label)
+		}
+	}
+
+	var err error
+	if row.StartDate, err = parseDate(value(ColumnStartDate)); err != nil {
+		fail(ColumnStartDate, "Invalid date %q", value(ColumnStartDate))
+	}
+	if row.DueDate, err = parseDate(value(ColumnDueDate)); err != nil {
+		fail(ColumnDueDate, "Invalid date %q", value(ColumnDueDate))
+	}
+	if row.StartDate != nil && row.DueDate != nil && row.DueDate.Before(*row.StartDate) {
+		fail(ColumnDueDate, "Due date is before the start date")
+	}
+
+	if s := value(ColumnEstimate); s != "" {
+		estimate, err := strconv.ParseFloat(s, 64)
+		if err != nil || estimate < 0 {
+			fail(ColumnEstimate, "Invalid estimate %q", s)
+		} else if estimate > 0 {
+			row.Estimate = &estimate
+		}
+	}
+
+	if s := value(ColumnPriority); s != "" {
+		priority, err := strconv.Atoi(s)
+		if err != nil || priority < 0 || priority > maxPriority {
+			fail(ColumnPriority, "Priority must be from 0 to %d", maxPriority)
+		} else {
+			row.Priority = int16(priority)
+		}
+	}
+
+	return row, errs
+}
+
+// Dates are either calendar days, taken as UTC, or RFC 3339 timestamps.
+func parseDate(s string) (*time.Time, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	date, err := time.Parse(dateLayout, s)
+	if err != nil {
+		date, err = time.Parse(time.RFC3339, s)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	date = date.UTC()
+	return &date, nil
+}
+
+func indexOf(header []string, name string) int {
+	for i, column := range header {
+		if strings.EqualFold(strings.TrimSpace(column), strings.TrimSpace(name)) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func isBlank(record []string) bool {
+	for _, field := range record {
+		if strings.TrimSpace(field) != "" {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,117 @@
+package taskcsv_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lesnoi-kot/karten-backend/src/modules/taskcsv"
+)
+
+func TestParseColumns(t *testing.T) {
+	columns, err := taskcsv.ParseColumns("")
+	require.NoError(t, err)
+	assert.Equal(t, taskcsv.DefaultColumns, columns)
+
+	columns, err = taskcsv.ParseColumns("name, due_date,archived")
+	require.NoError(t, err)
+	assert.Equal(t, []taskcsv.Column{taskcsv.ColumnName, taskcsv.ColumnDueDate, taskcsv.ColumnArchived}, columns)
+
+	_, err = taskcsv.ParseColumns("name,color")
+	assert.ErrorIs(t, err, taskcsv.ErrUnknownColumn)
+}
+
+func TestWrite(t *testing.T) {
+	due := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	rows := []*taskcsv.Row{
+		{
+			Key:       "KAR-1",
+			Name:      "Fix login, again",
+			List:      "Todo",
+			Labels:    []string{"bug", "web"},
+			DueDate:   &due,
+			SpentTime: 3600,
+			Estimate:  lo.ToPtr(1.5),
+		},
+		{Key: "KAR-2", Name: "Write docs", List: "Done", Archived: true},
+		{Key: "KAR-3", Name: `=HYPERLINK("http://evil","x")`, List: "-Todo", Labels: []string{"@bug"}, Estimate: lo.ToPtr(-1.0)},
+	}
+
+	var buf bytes.Buffer
+	columns := []taskcsv.Column{
+		taskcsv.ColumnKey, taskcsv.ColumnName, taskcsv.ColumnList, taskcsv.ColumnLabels,
+		taskcsv.ColumnDueDate, taskcsv.ColumnSpentTime, taskcsv.ColumnEstimate, taskcsv.ColumnArchived,
+	}
+	require.NoError(t, taskcsv.Write(&buf, columns, rows))
+
+	assert.Equal(t, strings.Join([]string{
+		"key,name,list,labels,due_date,spent_time,estimate,archived",
+		`KAR-1,"Fix login, again",Todo,bug; web,2024-03-01T12:00:00Z,3600,1.5,false`,
+		"KAR-2,Write docs,Done,,,0,,true",
+		`KAR-3,"'=HYPERLINK(""http://evil"",""x"")",'-Todo,'@bug,,0,-1,false`,
+		"",
+	}, "\n"), buf.String())
+}
+
+func TestRead(t *testing.T) {
+	data := strings.Join([]string{
+		"Title,Status,Tags,Due,Points,Notes",
+		"Fix login,Todo,bug; web,2024-03-01,2,",
+		`"Write docs",,,2024-03-05T10:00:00+02:00,,"multi`,
+		`line"`,
+		",,,,",
+		",Todo,,,,",
+		"Deploy,Done,,tomorrow,-1,",
+	}, "\n")
+
+	rows, rowErrors, err := taskcsv.Read(strings.NewReader(data), taskcsv.Mapping{
+		taskcsv.ColumnName:     "title",
+		taskcsv.ColumnList:     "Status",
+		taskcsv.ColumnLabels:   "Tags",
+		taskcsv.ColumnDueDate:  "Due",
+		taskcsv.ColumnEstimate: "Points",
+		taskcsv.ColumnText:     "Notes",
+	})
+	require.NoError(t, err)
+
+	require.Len(t, rows, 2)
+	assert.Equal(t, &taskcsv.ImportRow{
+		Line:     2,
+		Name:     "Fix login",
+		List:     "Todo",
+		Labels:   []string{"bug", "web"},
+		DueDate:  lo.ToPtr(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)),
+		Estimate: lo.ToPtr(2.0),
+	}, rows[0])
+	assert.Equal(t, 3, rows[1].Line)
+	assert.Equal(t, "multi\nline", rows[1].Text)
+	assert.Equal(t, time.Date(2024, 3, 5, 8, 0, 0, 0, time.UTC), *rows[1].DueDate)
+
+	assert.Equal(t, []*taskcsv.RowError{
+		{Line: 6, Column: taskcsv.ColumnName, Message: "Name is empty"},
+		{Line: 7, Column: taskcsv.ColumnDueDate, Message: `Invalid date "tomorrow"`},
+		{Line: 7, Column: taskcsv.ColumnEstimate, Message: `Invalid estimate "-1"`},
+	}, rowErrors)
+}
+
+func TestReadRejectsUnusableInput(t *testing.T) {
+	_, _, err := taskcsv.Read(strings.NewReader("Title\nA"), taskcsv.Mapping{taskcsv.ColumnList: "Title"})
+	assert.ErrorIs(t, err, taskcsv.ErrInvalidCSV, "name is not mapped")
+
+	_, _, err = taskcsv.Read(strings.NewReader("Title\nA"), taskcsv.Mapping{taskcsv.ColumnName: "Name"})
+	assert.ErrorIs(t, err, taskcsv.ErrInvalidCSV, "no such header")
+
+	_, _, err = taskcsv.Read(strings.NewReader(""), taskcsv.Mapping{taskcsv.ColumnName: "Name"})
+	assert.ErrorIs(t, err, taskcsv.ErrInvalidCSV)
+
+	_, _, err = taskcsv.Read(strings.NewReader("Title\nA"), taskcsv.Mapping{
+		taskcsv.ColumnName:     "Title",
+		taskcsv.ColumnArchived: "Title",
+	})
+	assert.ErrorIs(t, err, taskcsv.ErrUnknownColumn)
+}
@@ -0,0 +1,83 @@
+package textdiff_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lesnoi-kot/karten-backend/src/modules/textdiff"
+)
+
+func TestUnifiedEqual(t *testing.T) {
+	assert.Equal(t, "", textdiff.Unified("a", "b", "same\ntext", "same\ntext", 3))
+}
+
+func TestUnifiedSingleChange(t *testing.T) {
+	oldText := "one\ntwo\nthree\nfour\nfive\nsix\nseven\neight"
+	newText := "one\ntwo\nthree\nfour\n5\nsix\nseven\neight"
+
+	expected := strings.Join([]string{
+		"--- r1",
+		"+++ r2",
+		"@@ -3,5 +3,5 @@",
+		" three",
+		" four",
+		"-five",
+		"+5",
+		" six",
+		" seven",
+		"",
+	}, "\n")
+
+	assert.Equal(t, expected, textdiff.Unified("r1", "r2", oldText, newText, 2))
+}
+
+func TestUnifiedSeparateHunks(t *testing.T) {
+	oldText := "a\nb\nc\nd\ne\nf\ng\nh\ni"
+	newText := "A\nb\nc\nd\ne\nf\ng\nh\nI"
+
+	expected := strings.Join([]string{
+		"--- old",
+		"+++ new",
+		"@@ -1,2 +1,2 @@",
+		"-a",
+		"+A",
+		" b",
+		"@@ -8,2 +8,2 @@",
+		" h",
+		"-i",
+		"+I",
+		"",
+	}, "\n")
+
+	assert.Equal(t, expected, textdiff.Unified("old", "new", oldText, newText, 1))
+}
+
+func TestUnifiedFromEmpty(t *testing.T) {
+	expected := strings.Join([]string{
+		"--- old",
+		"+++ new",
+		"@@ -0,0 +1,2 @@",
+		"+first",
+		"+second",
+		"",
+	}, "\n")
+
+	assert.Equal(t, expected, textdiff.Unified("old", "new", "", "first\nsecond\n", 3))
+}
+
+func TestLinesKeepsCommonSubsequence(t *testing.T) {
+	ops := textdiff.Lines(
+		[]string{"x", "a", "b", "c"},
+		[]string{"a", "y", "b", "c"},
+	)
+
+	assert.Equal(t, []textdiff.Op{
+		{Kind: textdiff.Delete, Line: "x"},
+		{Kind: textdiff.Equal, Line: "a"},
+		{Kind: textdiff.Insert, Line: "y"},
+		{Kind: textdiff.Equal, Line: "b"},
+		{Kind: textdiff.Equal, Line: "c"},
+	}, ops)
+}
@@ -0,0 +1,250 @@
+package timesheet
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+type GroupBy string
+
+const (
+	GroupByDay     GroupBy = "day"
+	GroupByWeek    GroupBy = "week"
+	GroupByProject GroupBy = "project"
+	GroupByBoard   GroupBy = "board"
+	GroupByLabel   GroupBy = "label"
+	GroupByTask    GroupBy = "task"
+)
+
+func ParseGroupBy(s string) (GroupBy, error) {
+	switch g := GroupBy(s); g {
+	case GroupByDay, GroupByWeek, GroupByProject, GroupByBoard, GroupByLabel, GroupByTask:
+		return g, nil
+	case "":
+		return GroupByDay, nil
+	default:
+		return "", fmt.Errorf("Unknown grouping: %s", s)
+	}
+}
+
+type RoundingMode string
+
+const (
+	RoundNearest RoundingMode = "nearest"
+	RoundUp      RoundingMode = "up"
+	RoundDown    RoundingMode = "down"
+)
+
+func ParseRoundingMode(s string) (RoundingMode, error) {
+	switch m := RoundingMode(s); m {
+	case RoundNearest, RoundUp, RoundDown:
+		return m, nil
+	case "":
+		return RoundNearest, nil
+	default:
+		return "", fmt.Errorf("Unknown rounding mode: %s", s)
+	}
+}
+
+// Rounding is applied to every tracked interval separately, after it has been
+// clipped to the report range and split by the grouping buckets.
+type Rounding struct {
+	Step time.Duration // Zero disables rounding
+	Mode RoundingMode
+}
+
+func (r Rounding) Apply(d time.Duration) time.Duration {
+	if r.Step <= 0 {
+		return d
+	}
+
+	switch r.Mode {
+	case RoundUp:
+		if rem := d % r.Step; rem != 0 {
+			return d - rem + r.Step
+		}
+		return d
+	case RoundDown:
+		return d.Truncate(r.Step)
+	default:
+		return d.Round(r.Step)
+	}
+}
+
+type Label struct {
+	ID   int
+	Name string
+}
+
+// Entry is a single tracked interval together with its place in the hierarchy.
+type Entry struct {
+	TaskID      string
+	TaskName    string
+	BoardID     string
+	BoardName   string
+	ProjectID   string
+	ProjectName string
+	Labels      []Label
+	Start       time.Time
+	End         time.Time
+}
+
+type Options struct {
+	From     time.Time
+	To       time.Time
+	GroupBy  GroupBy
+	Location *time.Location
+	Rounding Rounding
+}
+
+type Row struct {
+	Key     string
+	Name    string
+	Seconds int64
+}
+
+type Report struct {
+	From         time.Time
+	To           time.Time
+	GroupBy      GroupBy
+	TimeZone     string
+	Rows         []Row
+	TotalSeconds int64
+}
+
+func Build(entries []Entry, opts Options) *Report {
+	loc := opts.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	rows := make(map[string]*Row)
+	add := func(key, name string, d time.Duration) {
+		row, ok := rows[key]
+		if !ok {
+			row = &Row{Key: key, Name: name}
+			rows[key] = row
+		}
+		row.Seconds += int64(d / time.Second)
+	}
+
+	var total int64
+
+	for _, entry := range entries {
+		start, end := clip(entry.Start, entry.End, opts.From, opts.To)
+		if !end.After(start) {
+			continue
+		}
+
+		switch opts.GroupBy {
+		case GroupByDay, GroupByWeek:
+			for _, piece := range splitByDays(start, end, loc) {
+				d := opts.Rounding.Apply(piece.end.Sub(piece.start))
+				key, name := calendarKey(piece.start.In(loc), opts.GroupBy)
+				add(key, name, d)
+				total += int64(d / time.Second)
+			}
+		case GroupByLabel:
+			d := opts.Rounding.Apply(end.Sub(start))
+			if len(entry.Labels) == 0 {
+				add("", "", d)
+			}
+			for _, label := range entry.Labels {
+				add(fmt.Sprint(label.ID), label.Name, d)
+			}
+			total += int64(d / time.Second)
+		default:
+			d := opts.Rounding.Apply(end.Sub(start))
+			key, name := entityKey(&entry, opts.GroupBy)
+			add(key, name, d)
+			total += int64(d / time.Second)
+		}
+	}
+
+	report := &Report{
+		From:         opts.From,
+		To:           opts.To,
+		GroupBy:      opts.GroupBy,
+		TimeZone:     loc.String(),
+		Rows:         make([]Row, 0, len(rows)),
+		TotalSeconds: total,
+	}
+
+	for _, row := range rows {
+		report.Rows = append(report.Rows, *row)
+	}
+
+	sort.Slice(report.Rows, func(i, j int) bool {
+		a, b := report.Rows[i], report.Rows[j]
+
+		if opts.GroupBy == GroupByDay || opts.GroupBy == GroupByWeek {
+			return a.Key < b.Key
+		}
+		if a.Seconds != b.Seconds {
+			return a.Seconds > b.Seconds
+		}
+		return a.Name < b.Name
+	})
+
+	return report
+}
+
+type interval struct {
+	start, end time.Time
+}
+
+func clip(start, end, from, to time.Time) (time.Time, time.Time) {
+	if !from.IsZero() && start.Before(from) {
+		start = from
+	}
+	if !to.IsZero() && end.After(to) {
+		end = to
+	}
+
+	return start, end
+}
+
+// Splits the interval at every local midnight.
+func splitByDays(start, end time.Time, loc *time.Location) []interval {
+	var pieces []interval
+
+	for start.Before(end) {
+		local := start.In(loc)
+		midnight := time.Date(local.Year(), local.Month(), local.Day()+1, 0, 0, 0, 0, loc)
+
+		if !midnight.Before(end) {
+			pieces = append(pieces, interval{start, end})
+			break
+		}
+
+		pieces = append(pieces, interval{start, midnight})
+		start = midnight
+	}
+
+	return pieces
+}
+
+func calendarKey(t time.Time, groupBy GroupBy) (string, string) {
+	if groupBy == GroupByWeek {
+		// Weeks start on Monday, as in ISO 8601.
+		offset := (int(t.Weekday()) + 6) % 7
+		monday := time.Date(t.Year(), t.Month(), t.Day()-offset, 0, 0, 0, 0, t.Location())
+		year, week := monday.ISOWeek()
+		return monday.Format("2006-01-02"), fmt.Sprintf("%d-W%02d", year, week)
+	}
+
+	day := t.Format("2006-01-02")
+	return day, day
+}
+
+func entityKey(entry *Entry, groupBy GroupBy) (string, string) {
+	switch groupBy {
+	case GroupByProject:
+		return entry.ProjectID, entry.ProjectName
+	case GroupByBoard:
+		return entry.BoardID, entry.BoardName
+	default:
+		return entry.TaskID, entry.TaskName
+	}
+}
@@ -0,0 +1,95 @@
+package timesheet_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lesnoi-kot/karten-backend/src/modules/timesheet"
+)
+
+func date(s string) time.Time {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestBuildByDay(t *testing.T) {
+	moscow, err := time.LoadLocation("Europe/Moscow")
+	require.NoError(t, err)
+
+	entries := []timesheet.Entry{
+		// 20:00 - 23:00 UTC, crosses midnight in Moscow (UTC+3).
+		{TaskID: "a", Start: date("2023-06-01T20:00:00Z"), End: date("2023-06-01T23:00:00Z")},
+		// Starts before the report range.
+		{TaskID: "b", Start: date("2023-05-31T20:00:00Z"), End: date("2023-05-31T22:00:00Z")},
+	}
+
+	report := timesheet.Build(entries, timesheet.Options{
+		From:     date("2023-05-31T21:00:00Z"),
+		To:       date("2023-06-02T21:00:00Z"),
+		GroupBy:  timesheet.GroupByDay,
+		Location: moscow,
+	})
+
+	assert.Equal(t, []timesheet.Row{
+		{Key: "2023-06-01", Name: "2023-06-01", Seconds: 2 * 3600},
+		{Key: "2023-06-02", Name: "2023-06-02", Seconds: 2 * 3600},
+	}, report.Rows)
+	assert.Equal(t, int64(4*3600), report.TotalSeconds)
+}
+
+func TestBuildByLabelWithRounding(t *testing.T) {
+	entries := []timesheet.Entry{
+		{
+			TaskID: "a",
+			Labels: []timesheet.Label{{ID: 1, Name: "bug"}, {ID: 2, Name: "client"}},
+			Start:  date("2023-06-01T10:00:00Z"),
+			End:    date("2023-06-01T10:07:00Z"),
+		},
+		{
+			TaskID: "b",
+			Start:  date("2023-06-01T11:00:00Z"),
+			End:    date("2023-06-01T11:01:00Z"),
+		},
+	}
+
+	report := timesheet.Build(entries, timesheet.Options{
+		GroupBy:  timesheet.GroupByLabel,
+		Rounding: timesheet.Rounding{Step: 15 * time.Minute, Mode: timesheet.RoundUp},
+	})
+
+	assert.Equal(t, []timesheet.Row{
+		{Key: "", Name: "", Seconds: 900},
+		{Key: "1", Name: "bug", Seconds: 900},
+		{Key: "2", Name: "client", Seconds: 900},
+	}, report.Rows)
+	assert.Equal(t, int64(1800), report.TotalSeconds)
+}
+
+func TestBuildByWeek(t *testing.T) {
+	entries := []timesheet.Entry{
+		{TaskID: "a", Start: date("2023-06-04T10:00:00Z"), End: date("2023-06-04T11:00:00Z")}, // Sunday
+		{TaskID: "a", Start: date("2023-06-05T10:00:00Z"), End: date("2023-06-05T11:00:00Z")}, // Monday
+	}
+
+	report := timesheet.Build(entries, timesheet.Options{GroupBy: timesheet.GroupByWeek})
+
+	assert.Equal(t, []timesheet.Row{
+		{Key: "2023-05-29", Name: "2023-W22", Seconds: 3600},
+		{Key: "2023-06-05", Name: "2023-W23", Seconds: 3600},
+	}, report.Rows)
+}
+
+func TestRounding(t *testing.T) {
+	step := 15 * time.Minute
+
+	assert.Equal(t, 15*time.Minute, timesheet.Rounding{Step: step, Mode: timesheet.RoundUp}.Apply(time.Minute))
+	assert.Equal(t, time.Duration(0), timesheet.Rounding{Step: step, Mode: timesheet.RoundDown}.Apply(14*time.Minute))
+	assert.Equal(t, 15*time.Minute, timesheet.Rounding{Step: step, Mode: timesheet.RoundNearest}.Apply(8*time.Minute))
+	assert.Equal(t, 7*time.Minute, timesheet.Rounding{}.Apply(7*time.Minute))
+}
@@ -0,0 +1,415 @@
+// Package trello converts Trello board JSON exports into board archive
+// documents. What has no counterpart in Karten is listed in the report
+// instead of failing the conversion.
+package trello
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"mime"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lesnoi-kot/karten-backend/src/modules/boardarchive"
+)
+
+var ErrInvalidExport = errors.New("Invalid Trello export")
+
+// Label colors of Trello in RGB, the variants fall back to the base color.
+var labelColors = map[string]int{
+	"green":  0x61BD4F,
+	"yellow": 0xF2D600,
+	"orange": 0xFF9F1A,
+	"red":    0xEB5A46,
+	"purple": 0xC377E0,
+	"blue":   0x0079BF,
+	"sky":    0x00C2E0,
+	"lime":   0x51E898,
+	"pink":   0xFF78CB,
+	"black":  0x344563,
+
+	"green_dark":  0x519839,
+	"yellow_dark": 0xD9B51C,
+	"orange_dark": 0xCD8313,
+	"red_dark":    0xB04632,
+	"purple_dark": 0x89609E,
+	"blue_dark":   0x055A8C,
+	"sky_dark":    0x0098B7,
+	"lime_dark":   0x4BBF6B,
+	"pink_dark":   0xCD5A91,
+	"black_dark":  0x091E42,
+
+	"green_light":  0xB7DDB0,
+	"yellow_light": 0xF5EA92,
+	"orange_light": 0xFAD29C,
+	"red_light":    0xEFB3AB,
+	"purple_light": 0xDFC0EB,
+	"blue_light":   0x8BBDD9,
+	"sky_light":    0x8FDFEB,
+	"lime_light":   0xB3F1D0,
+	"pink_light":   0xF9C2E4,
+	"black_light":  0x505F79,
+}
+
+type Export struct {
+	Name       string       `json:"name"`
+	Labels     []*Label     `json:"labels"`
+	Lists      []*List      `json:"lists"`
+	Cards      []*Card      `json:"cards"`
+	Checklists []*Checklist `json:"checklists"`
+	Actions    []*Action    `json:"actions"`
+
+	CustomFields []json.RawMessage `json:"customFields"`
+}
+
+type Label struct {
+	ID    string  `json:"id"`
+	Name  string  `json:"name"`
+	Color *string `json:"color"`
+}
+
+type List struct {
+	ID     string  `json:"id"`
+	Name   string  `json:"name"`
+	Closed bool    `json:"closed"`
+	Pos    float64 `json:"pos"`
+}
+
+type Card struct {
+	ID          string        `json:"id"`
+	Name        string        `json:"name"`
+	Desc        string        `json:"desc"`
+	Closed      bool          `json:"closed"`
+	IDList      string        `json:"idList"`
+	Pos         float64       `json:"pos"`
+	Start       *time.Time    `json:"start"`
+	Due         *time.Time    `json:"due"`
+	IDLabels    []string      `json:"idLabels"`
+	IDMembers   []string      `json:"idMembers"`
+	Attachments []*Attachment `json:"attachments"`
+}
+
+type Attachment struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	URL      string `json:"url"`
+	MimeType string `json:"mimeType"`
+}
+
+type Checklist struct {
+	ID         string       `json:"id"`
+	Name       string       `json:"name"`
+	IDCard     string       `json:"idCard"`
+	Pos        float64      `json:"pos"`
+	CheckItems []*CheckItem `json:"checkItems"`
+}
+
+type CheckItem struct {
+	Name  string  `json:"name"`
+	State string  `json:"state"` // "complete" or "incomplete"
+	Pos   float64 `json:"pos"`
+}
+
+type Action struct {
+	Type string    `json:"type"`
+	Date time.Time `json:"date"`
+	Data struct {
+		Text string `json:"text"`
+		Card struct {
+			ID string `json:"id"`
+		} `json:"card"`
+	} `json:"data"`
+	MemberCreator *struct {
+		FullName string `json:"fullName"`
+	} `json:"memberCreator"`
+}
+
+// What could not be carried over, one human readable line per thing.
+type Report struct {
+	Unmapped []string
+}
+
+func (r *Report) add(format string, args ...any) {
+	r.Unmapped = append(r.Unmapped, fmt.Sprintf(format, args...))
+}
+
+// Converts the Trello board export. Blobs of the inline attachments are
+// returned by their file refs.
+func Convert(data []byte) (*boardarchive.Document, map[string][]byte, *Report, error) {
+	var export Export
+	if err := json.Unmarshal(data, &export); err != nil {
+		return nil, nil, nil, fmt.Errorf("%w: %s", ErrInvalidExport, err)
+	}
+
+	name := strings.TrimSpace(export.Name)
+	if name == "" {
+		return nil, nil, nil, fmt.Errorf("%w: board has no name", ErrInvalidExport)
+	}
+
+	report := &Report{}
+	blobs := make(map[string][]byte)
+	doc := &boardarchive.Document{
+		Version:    boardarchive.Version,
+		ExportedAt: time.Now().UTC(),
+		Board: boardarchive.Board{
+			Name:      name,
+			Labels:    make([]*boardarchive.Label, 0, len(export.Labels)),
+			TaskLists: make([]*boardarchive.TaskList, 0, len(export.Lists)),
+		},
+		Files: make([]*boardarchive.File, 0),
+	}
+
+	labels := make(map[string]bool)
+	for _, label := range export.Labels {
+		labelName := strings.TrimSpace(label.Name)
+		if labelName == "" {
+			labelName = colorName(label.Color)
+		}
+
+		doc.Board.Labels = append(doc.Board.Labels, &boardarchive.Label{
+			Ref:   label.ID,
+			Name:  labelName,
+			Color: LabelColor(label.Color),
+		})
+		labels[label.ID] = true
+	}
+
+	lists := make(map[string]*boardarchive.TaskList)
+	sort.SliceStable(export.Lists, func(i, j int) bool { return export.Lists[i].Pos < export.Lists[j].Pos })
+	for _, list := range export.Lists {
+		taskList := &boardarchive.TaskList{
+			Ref:      list.ID,
+			Name:     strings.TrimSpace(list.Name),
+			Position: position(list.Pos),
+			Archived: list.Closed,
+			Tasks:    make([]*boardarchive.Task, 0),
+		}
+		if taskList.Name == "" {
+			taskList.Name = "Untitled"
+		}
+
+		doc.Board.TaskLists = append(doc.Board.TaskLists, taskList)
+		lists[list.ID] = taskList
+	}
+
+	checklists := make(map[string][]*Checklist)
+	for _, checklist := range export.Checklists {
+		checklists[checklist.IDCard] = append(checklists[checklist.IDCard], checklist)
+	}
+
+	comments := make(map[string][]*boardarchive.Comment)
+	for _, action := range export.Actions {
+		if action.Type != "commentCard" {
+			continue
+		}
+
+		text := action.Data.Text
+		if action.MemberCreator != nil && action.MemberCreator.FullName != "" {
+			text = fmt.Sprintf("**%s:** %s", action.MemberCreator.FullName, text)
+		}
+
+		cardID := action.Data.Card.ID
+		comments[cardID] = append(comments[cardID], &boardarchive.Comment{
+			Text:        text,
+			DateCreated: action.Date,
+		})
+	}
+
+	sort.SliceStable(export.Cards, func(i, j int) bool { return export.Cards[i].Pos < export.Cards[j].Pos })
+	for _, card := range export.Cards {
+		taskList, ok := lists[card.IDList]
+		if !ok {
+			report.add("Card %q is in an unknown list", card.Name)
+			continue
+		}
+
+		task := &boardarchive.Task{
+			Ref:         card.ID,
+			Name:        strings.TrimSpace(card.Name),
+			Text:        cardText(card.Desc, checklists[card.ID]),
+			Position:    position(card.Pos),
+			Archived:    card.Closed,
+			DateCreated: creationDate(card.ID),
+			StartDate:   card.Start,
+			DueDate:     card.Due,
+		}
+		if task.Name == "" {
+			task.Name = "Untitled"
+		}
+
+		for _, labelID := range card.IDLabels {
+			if labels[labelID] {
+				task.LabelRefs = append(task.LabelRefs, labelID)
+			} else {
+				report.add("Unknown label of card %q", card.Name)
+			}
+		}
+
+		if len(card.IDMembers) > 0 {
+			report.add("Members of card %q", card.Name)
+		}
+
+		for _, attachment := range card.Attachments {
+			data, mimeType, ok := inlineData(attachment.URL)
+			if !ok {
+				report.add("Attachment %q of card %q is not inline", attachment.Name, card.Name)
+				continue
+			}
+			if attachment.MimeType != "" {
+				mimeType = attachment.MimeType
+			}
+
+			ref := card.ID + "-" + attachment.ID
+			doc.Files = append(doc.Files, &boardarchive.File{
+				Ref:      ref,
+				Name:     attachmentName(attachment, mimeType),
+				MimeType: mimeType,
+				Size:     len(data),
+			})
+			blobs[ref] = data
+			task.AttachmentRefs = append(task.AttachmentRefs, ref)
+		}
+
+		task.Comments = comments[card.ID]
+		delete(comments, card.ID)
+
+		taskList.Tasks = append(taskList.Tasks, task)
+	}
+
+	if len(comments) > 0 {
+		count := 0
+		for _, cardComments := range comments {
+			count += len(cardComments)
+		}
+		report.add("%d comments of unknown cards", count)
+	}
+	if len(export.CustomFields) > 0 {
+		report.add("%d custom fields", len(export.CustomFields))
+	}
+
+	if err := boardarchive.Validate(doc, blobs); err != nil {
+		return nil, nil, nil, fmt.Errorf("%w: %s", ErrInvalidExport, err)
+	}
+
+	return doc, blobs, report, nil
+}
+
+// Closest RGB color of a Trello label color name, zero for no color.
+func LabelColor(name *string) int {
+	if name == nil {
+		return 0
+	}
+
+	if color, ok := labelColors[*name]; ok {
+		return color
+	}
+
+	base, _, _ := strings.Cut(*name, "_")
+	return labelColors[base]
+}
+
+func colorName(name *string) string {
+	if name == nil || *name == "" {
+		return "Label"
+	}
+
+	return strings.ReplaceAll(*name, "_", " ")
+}
+
+// Checklists become markdown task lists after the description.
+func cardText(desc string, checklists []*Checklist) string {
+	sort.SliceStable(checklists, func(i, j int) bool { return checklists[i].Pos < checklists[j].Pos })
+
+	var b strings.Builder
+	b.WriteString(strings.TrimSpace(desc))
+
+	for _, checklist := range checklists {
+		if b.Len() > 0 {
+			b.WriteString("\n\n")
+		}
+		fmt.Fprintf(&b, "### %s\n", checklist.Name)
+
+		items := checklist.CheckItems
+		sort.SliceStable(items, func(i, j int) bool { return items[i].Pos < items[j].Pos })
+		for _, item := range items {
+			mark := " "
+			if item.State == "complete" {
+				mark = "x"
+			}
+			fmt.Fprintf(&b, "\n- [%s] %s", mark, item.Name)
+		}
+	}
+
+	return b.String()
+}
+
+func position(pos float64) int64 {
+	return int64(math.Round(pos))
+}
+
+// Trello IDs are Mongo object IDs, they start with the creation time.
+func creationDate(id string) time.Time {
+	if len(id) < 8 {
+		return time.Time{}
+	}
+
+	seconds, err := strconv.ParseInt(id[:8], 16, 64)
+	if err != nil {
+		return time.Time{}
+	}
+
+	return time.Unix(seconds, 0).UTC()
+}
+
+// Decodes a data URL.
+func inlineData(rawURL string) ([]byte, string, bool) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme != "data" {
+		return nil, "", false
+	}
+
+	meta, payload, ok := strings.Cut(u.Opaque, ",")
+	if !ok {
+		return nil, "", false
+	}
+
+	isBase64 := strings.HasSuffix(meta, ";base64")
+	mimeType := strings.TrimSuffix(meta, ";base64")
+	if mimeType == "" {
+		mimeType = "text/plain"
+	}
+
+	if isBase64 {
+		data, err := base64.StdEncoding.DecodeString(payload)
+		if err != nil {
+			return nil, "", false
+		}
+		return data, mimeType, true
+	}
+
+	text, err := url.PathUnescape(payload)
+	if err != nil {
+		return nil, "", false
+	}
+
+	return []byte(text), mimeType, true
+}
+
+func attachmentName(attachment *Attachment, mimeType string) string {
+	if name := strings.TrimSpace(attachment.Name); name != "" {
+		return name
+	}
+
+	name := "attachment"
+	if extensions, _ := mime.ExtensionsByType(mimeType); len(extensions) > 0 {
+		name += extensions[0]
+	}
+
+	return name
+}
@@ -0,0 +1,75 @@
+package trello_test
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lesnoi-kot/karten-backend/src/modules/trello"
+)
+
+func TestConvert(t *testing.T) {
+	data, err := os.ReadFile("testdata/board.json")
+	require.NoError(t, err)
+
+	doc, blobs, report, err := trello.Convert(data)
+	require.NoError(t, err)
+
+	assert.Equal(t, "Website", doc.Board.Name)
+
+	require.Len(t, doc.Board.Labels, 3)
+	assert.Equal(t, 0xEB5A46, doc.Board.Labels[0].Color)
+	assert.Equal(t, "green dark", doc.Board.Labels[1].Name)
+	assert.Equal(t, 0, doc.Board.Labels[2].Color)
+
+	require.Len(t, doc.Board.TaskLists, 2)
+	todo := doc.Board.TaskLists[0]
+	assert.Equal(t, "Todo", todo.Name)
+	assert.Empty(t, doc.Board.TaskLists[1].Tasks)
+
+	require.Len(t, todo.Tasks, 1)
+	task := todo.Tasks[0]
+	assert.Equal(t, "Fix the footer", task.Name)
+	assert.Equal(t, int64(65536), task.Position)
+	assert.Equal(t, "It overlaps on mobile.\n\n### Steps\n\n- [x] Reproduce\n- [ ] Check Safari", task.Text)
+	assert.Equal(t, time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC), task.DueDate.UTC())
+	assert.Equal(t, time.Unix(0x5f1a2b3c, 0).UTC(), task.DateCreated)
+	assert.Equal(t, []string{"lab1", "lab2"}, task.LabelRefs)
+
+	require.Len(t, task.Comments, 1)
+	assert.Equal(t, "**Ann:** Seen on iOS too", task.Comments[0].Text)
+
+	require.Len(t, doc.Files, 1)
+	assert.Equal(t, "note.txt", doc.Files[0].Name)
+	assert.Equal(t, "text/plain", doc.Files[0].MimeType)
+	assert.Equal(t, []string{doc.Files[0].Ref}, task.AttachmentRefs)
+	assert.Equal(t, []byte("hello"), blobs[doc.Files[0].Ref])
+
+	assert.ElementsMatch(t, []string{
+		`Card "Lost card" is in an unknown list`,
+		`Unknown label of card "Fix the footer"`,
+		`Members of card "Fix the footer"`,
+		`Attachment "screenshot.png" of card "Fix the footer" is not inline`,
+		"1 custom fields",
+	}, report.Unmapped)
+}
+
+func TestConvertRejectsBrokenExports(t *testing.T) {
+	_, _, _, err := trello.Convert([]byte("{"))
+	assert.ErrorIs(t, err, trello.ErrInvalidExport)
+
+	_, _, _, err = trello.Convert([]byte(`{"name": " "}`))
+	assert.ErrorIs(t, err, trello.ErrInvalidExport)
+}
+
+func TestLabelColor(t *testing.T) {
+	assert.Equal(t, 0x0079BF, trello.LabelColor(lo.ToPtr("blue")))
+	assert.Equal(t, 0xB04632, trello.LabelColor(lo.ToPtr("red_dark")))
+	assert.Equal(t, 0xC377E0, trello.LabelColor(lo.ToPtr("purple_darker")), "unknown variants fall back to the base color")
+	assert.Equal(t, 0, trello.LabelColor(nil))
+	assert.Equal(t, 0, trello.LabelColor(lo.ToPtr("chartreuse")))
+}
@@ -0,0 +1,242 @@
+// Package webhook signs and sends the JSON payloads of outgoing webhooks.
+//
+// Receivers verify a delivery by computing the HMAC-SHA256 of the raw request
+// body with the webhook secret and comparing its hex digest with the
+// X-Karten-Signature-256 header, which looks like "sha256=<digest>".
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	EventHeader     = "X-Karten-Event"
+	DeliveryHeader  = "X-Karten-Delivery"
+	SignatureHeader = "X-Karten-Signature-256"
+
+	userAgent = "Karten-Webhooks/1.0"
+
+	// The response body is not kept, but read this far to reuse the
+	// connection.
+	maxDrainedBody = 64 << 10
+)
+
+var (
+	ErrInvalidURL       = errors.New("Webhook URL must be an absolute http or https URL")
+	ErrForbiddenAddress = errors.New("Webhook address is not public")
+)
+
+const (
+	// Attempts of a delivery before it is given up.
+	MaxAttempts = 8
+
+	firstRetryDelay = time.Minute
+	maxRetryDelay   = 6 * time.Hour
+)
+
+type Request struct {
+	URL        string
+	Secret     string
+	Event      string
+	DeliveryID string
+	Payload    []byte
+}
+
+type Result struct {
+	StatusCode int // Zero if no response came
+	Error      string
+	Duration   time.Duration
+}
+
+func (r *Result) OK() bool {
+	return r.Error == "" && r.StatusCode >= 200 && r.StatusCode < 300
+}
+
+// Client for the deliveries. Redirects are not followed, the receiver is
+// expected to answer at the registered URL. Only public addresses are
+// dialed: the check is done on the resolved address of every connection, so
+// a host name can't point the server at itself or its network.
+func NewClient() *http.Client {
+	return newClient(IsPublicIP)
+}
+
+func newClient(allowed func(net.IP) bool) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 5 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !allowed(ip) {
+				return fmt.Errorf("%w: %s", ErrForbiddenAddress, host)
+			}
+
+			return nil
+		},
+	}
+
+	return &http.Client{
+		Timeout: 10 * time.Second,
+		Transport: &http.Transport{
+			// No proxy, it would be the one dialed and checked.
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: 5 * time.Second,
+			MaxIdleConns:        10,
+			IdleConnTimeout:     90 * time.Second,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// Address ranges that are not reachable from the internet or lead back into
+// the local network.
+var nonPublicNets = parseCIDRs(
+	"0.0.0.0/8",      // This network
+	"10.0.0.0/8",     // Private
+	"100.64.0.0/10",  // Carrier-grade NAT
+	"127.0.0.0/8",    // Loopback
+	"169.254.0.0/16", // Link-local
+	"172.16.0.0/12",  // Private
+	"192.0.0.0/24",   // Protocol assignments
+	"192.168.0.0/16", // Private
+	"198.18.0.0/15",  // Benchmarking
+	"224.0.0.0/4",    // Multicast
+	"240.0.0.0/4",    // Reserved and broadcast
+	"::/128",         // Unspecified
+	"::1/128",        // Loopback
+	"64:ff9b::/96",   // NAT64 of any IPv4 address
+	"64:ff9b:1::/48", // Local NAT64
+	"fc00::/7",       // Unique local
+	"fe80::/10",      // Link-local
+	"ff00::/8",       // Multicast
+)
+
+func parseCIDRs(cidrs ...string) []*net.IPNet {
+	nets := make([]*net.IPNet, len(cidrs))
+	for i, cidr := range cidrs {
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		nets[i] = ipNet
+	}
+
+	return nets
+}
+
+// Tells if the address is reachable from the internet. IPv4-mapped IPv6
+// addresses are checked as the IPv4 ones.
+func IsPublicIP(ip net.IP) bool {
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	} else if len(ip) != net.IPv6len {
+		return false
+	}
+
+	for _, ipNet := range nonPublicNets {
+		if ipNet.Contains(ip) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Checks that the URL can be delivered to. Host names are resolved only when
+// dialing, here just the literal addresses and localhost are rejected.
+func ValidateURL(rawURL string) error {
+	target, err := url.Parse(rawURL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Hostname() == "" {
+		return ErrInvalidURL
+	}
+
+	host := strings.ToLower(strings.TrimSuffix(target.Hostname(), "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return ErrForbiddenAddress
+	}
+	if ip := net.ParseIP(host); ip != nil && !IsPublicIP(ip) {
+		return ErrForbiddenAddress
+	}
+
+	return nil
+}
+
+// Returns the signature header value of the payload.
+func Sign(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func Verify(secret string, payload []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, payload)), []byte(strings.TrimSpace(signature)))
+}
+
+// Delay before the next attempt after the given number of failed ones,
+// doubling each time.
+func Backoff(attempts int) time.Duration {
+	delay := firstRetryDelay
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= maxRetryDelay {
+			return maxRetryDelay
+		}
+	}
+
+	return delay
+}
+
+// Posts the signed payload. Failures to connect or read the response end up
+// in the result, not in an error.
+func Deliver(ctx context.Context, client *http.Client, req *Request) *Result {
+	result := new(Result)
+	started := time.Now()
+	defer func() {
+		result.Duration = time.Since(started)
+	}()
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, req.URL, bytes.NewReader(req.Payload))
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("User-Agent", userAgent)
+	httpReq.Header.Set(EventHeader, req.Event)
+	httpReq.Header.Set(DeliveryHeader, req.DeliveryID)
+	httpReq.Header.Set(SignatureHeader, Sign(req.Secret, req.Payload))
+
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	defer resp.Body.Close()
+
+	result.StatusCode = resp.StatusCode
+
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxDrainedBody))
+
+	if !result.OK() {
+		result.Error = fmt.Sprintf("Unexpected response status %d", resp.StatusCode)
+	}
+
+	return result
+}
@@ -0,0 +1,145 @@
+package webhook_test
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lesnoi-kot/karten-backend/src/modules/webhook"
+)
+
+func TestSign(t *testing.T) {
+	// echo -n 'The quick brown fox jumps over the lazy dog' | openssl dgst -sha256 -hmac key
+	signature := webhook.Sign("key", []byte("The quick brown fox jumps over the lazy dog"))
+	assert.Equal(t, "sha256=f7bc83f430538424b13298e6aa6fb143ef4d59a14946175997479dbc2d1a3cd8", signature)
+
+	assert.True(t, webhook.Verify("key", []byte("The quick brown fox jumps over the lazy dog"), signature))
+	assert.False(t, webhook.Verify("other", []byte("The quick brown fox jumps over the lazy dog"), signature))
+	assert.False(t, webhook.Verify("key", []byte("The quick brown fox"), signature))
+}
+
+func TestBackoff(t *testing.T) {
+	assert.Equal(t, time.Minute, webhook.Backoff(0))
+	assert.Equal(t, time.Minute, webhook.Backoff(1))
+	assert.Equal(t, 2*time.Minute, webhook.Backoff(2))
+	assert.Equal(t, 64*time.Minute, webhook.Backoff(7))
+	assert.Equal(t, 6*time.Hour, webhook.Backoff(20))
+}
+
+func TestDeliver(t *testing.T) {
+	payload := []byte(`{"event":"task.created"}`)
+
+	var received *http.Request
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusAccepted)
+		io.WriteString(w, "thanks")
+	}))
+	defer server.Close()
+
+	result := webhook.Deliver(context.Background(), webhook.NewTestClient(), &webhook.Request{
+		URL:        server.URL,
+		Secret:     "s3cret",
+		Event:      "task.created",
+		DeliveryID: "d1",
+		Payload:    payload,
+	})
+
+	require.True(t, result.OK(), result.Error)
+	assert.Equal(t, http.StatusAccepted, result.StatusCode)
+
+	require.NotNil(t, received)
+	assert.Equal(t, http.MethodPost, received.Method)
+	assert.Equal(t, "application/json", received.Header.Get("Content-Type"))
+	assert.Equal(t, "task.created", received.Header.Get(webhook.EventHeader))
+	assert.Equal(t, "d1", received.Header.Get(webhook.DeliveryHeader))
+	assert.Equal(t, payload, body)
+	assert.True(t, webhook.Verify("s3cret", body, received.Header.Get(webhook.SignatureHeader)))
+}
+
+func TestDeliverFailures(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, "/", http.StatusFound)
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		io.WriteString(w, strings.Repeat("x", 10000))
+	}))
+	defer server.Close()
+
+	result := webhook.Deliver(context.Background(), webhook.NewTestClient(), &webhook.Request{URL: server.URL})
+	assert.False(t, result.OK())
+	assert.Equal(t, http.StatusInternalServerError, result.StatusCode)
+	assert.NotEmpty(t, result.Error)
+
+	result = webhook.Deliver(context.Background(), webhook.NewTestClient(), &webhook.Request{URL: server.URL + "/redirect"})
+	assert.False(t, result.OK())
+	assert.Equal(t, http.StatusFound, result.StatusCode)
+
+	server.Close()
+	result = webhook.Deliver(context.Background(), webhook.NewTestClient(), &webhook.Request{URL: server.URL})
+	assert.False(t, result.OK())
+	assert.Zero(t, result.StatusCode)
+	assert.NotEmpty(t, result.Error)
+}
+
+func TestDeliverOnlyToPublicAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	result := webhook.Deliver(context.Background(), webhook.NewClient(), &webhook.Request{URL: server.URL})
+	assert.False(t, result.OK())
+	assert.Zero(t, result.StatusCode)
+	assert.Contains(t, result.Error, webhook.ErrForbiddenAddress.Error())
+
+	localhost := strings.Replace(server.URL, "127.0.0.1", "localhost", 1)
+	result = webhook.Deliver(context.Background(), webhook.NewClient(), &webhook.Request{URL: localhost})
+	assert.False(t, result.OK())
+	assert.Contains(t, result.Error, webhook.ErrForbiddenAddress.Error())
+}
+
+func TestIsPublicIP(t *testing.T) {
+	for _, ip := range []string{
+		"93.184.216.34", "2606:2800:220:1:248:1893:25c8:1946", "::ffff:93.184.216.34", "100.128.0.1", "198.20.0.1",
+	} {
+		assert.True(t, webhook.IsPublicIP(net.ParseIP(ip)), ip)
+	}
+
+	for _, ip := range []string{
+		"127.0.0.1", "::1", "10.1.2.3", "172.16.0.1", "192.168.1.1", "fd00::1",
+		"169.254.169.254", "fe80::1", "0.0.0.0", "::", "::ffff:127.0.0.1", "224.0.0.1",
+		"100.64.0.1", "100.127.255.254", "0.1.2.3", "192.0.0.170", "198.18.0.1", "198.19.255.255",
+		"240.0.0.1", "255.255.255.255", "64:ff9b::7f00:1", "64:ff9b::a00:1", "64:ff9b:1::1",
+		"::ffff:10.0.0.1", "::ffff:100.64.0.1", "::ffff:169.254.169.254", "ff02::1",
+	} {
+		assert.False(t, webhook.IsPublicIP(net.ParseIP(ip)), ip)
+	}
+
+	assert.False(t, webhook.IsPublicIP(nil))
+}
+
+func TestValidateURL(t *testing.T) {
+	assert.NoError(t, webhook.ValidateURL("https://example.com/hooks"))
+	assert.NoError(t, webhook.ValidateURL("http://93.184.216.34:8080/"))
+
+	assert.ErrorIs(t, webhook.ValidateURL("ftp://example.com"), webhook.ErrInvalidURL)
+	assert.ErrorIs(t, webhook.ValidateURL("/hooks"), webhook.ErrInvalidURL)
+	assert.ErrorIs(t, webhook.ValidateURL("http://localhost:8080/"), webhook.ErrForbiddenAddress)
+	assert.ErrorIs(t, webhook.ValidateURL("http://api.localhost/"), webhook.ErrForbiddenAddress)
+	assert.ErrorIs(t, webhook.ValidateURL("http://127.0.0.1/"), webhook.ErrForbiddenAddress)
+	assert.ErrorIs(t, webhook.ValidateURL("http://[::1]/"), webhook.ErrForbiddenAddress)
+	assert.ErrorIs(t, webhook.ValidateURL("http://169.254.169.254/latest/meta-data"), webhook.ErrForbiddenAddress)
+}
@@ -0,0 +1,33 @@
+// Package workdays does calendar arithmetic that can skip the weekends.
+package workdays
+
+import "time"
+
+func IsWeekend(t time.Time) bool {
+	weekday := t.Weekday()
+	return weekday == time.Saturday || weekday == time.Sunday
+}
+
+// Moves t by the given number of days, negative ones go back. With
+// skipWeekends only Monday to Friday are counted, so a task due on Friday
+// shifted by one day becomes due on Monday. The weekday and the time of day
+// are taken in the location of t.
+func Shift(t time.Time, days int, skipWeekends bool) time.Time {
+	if !skipWeekends {
+		return t.AddDate(0, 0, days)
+	}
+
+	step := 1
+	if days < 0 {
+		step, days = -1, -days
+	}
+
+	for days > 0 {
+		t = t.AddDate(0, 0, step)
+		if !IsWeekend(t) {
+			days--
+		}
+	}
+
+	return t
+}
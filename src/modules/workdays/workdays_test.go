@@ -0,0 +1,50 @@
+package workdays_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lesnoi-kot/karten-backend/src/modules/workdays"
+)
+
+func date(s string) time.Time {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestShiftCalendarDays(t *testing.T) {
+	friday := date("2024-03-01T15:00:00Z")
+
+	assert.Equal(t, date("2024-03-02T15:00:00Z"), workdays.Shift(friday, 1, false))
+	assert.Equal(t, date("2024-02-28T15:00:00Z"), workdays.Shift(friday, -2, false))
+	assert.Equal(t, friday, workdays.Shift(friday, 0, true))
+}
+
+func TestShiftSkipsWeekends(t *testing.T) {
+	friday := date("2024-03-01T15:00:00Z")
+	monday := date("2024-03-04T15:00:00Z")
+	saturday := date("2024-03-02T15:00:00Z")
+
+	assert.Equal(t, monday, workdays.Shift(friday, 1, true))
+	assert.Equal(t, date("2024-03-08T15:00:00Z"), workdays.Shift(friday, 5, true))
+	assert.Equal(t, friday, workdays.Shift(monday, -1, true))
+	assert.Equal(t, monday, workdays.Shift(saturday, 1, true))
+	assert.Equal(t, friday, workdays.Shift(saturday, -1, true))
+}
+
+func TestShiftUsesLocation(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	require.NoError(t, err)
+
+	// Friday 23:00 UTC is already Saturday in Tokyo.
+	due := date("2024-03-01T23:00:00Z").In(tokyo)
+
+	// Monday 08:00 in Tokyo.
+	assert.Equal(t, date("2024-03-03T23:00:00Z"), workdays.Shift(due, 1, true).UTC())
+}
@@ -1,5 +1,7 @@
 package settings
 
+import "time"
+
 var AppConfig appConfig
 
 type appConfig struct {
@@ -21,6 +23,25 @@ type appConfig struct {
 
 	SessionsSecretKey string `env:"SESSIONS_SECRET_KEY,notEmpty,unset"`
 	SessionsStorePath string `env:"SESSIONS_STORE_PATH,notEmpty"`
+
+	TrashRetentionDays int `env:"TRASH_RETENTION_DAYS" envDefault:"30"`
+
+	// Inbound email is accepted only with the secret, it is off without one.
+	InboundEmailSecret string `env:"INBOUND_EMAIL_SECRET,unset"`
+	InboundEmailDomain string `env:"INBOUND_EMAIL_DOMAIN"`
+
+	// Text search configuration used when a search doesn't choose one.
+	SearchLanguage string `env:"SEARCH_LANGUAGE" envDefault:"english"`
+}
+
+// How long deleted items stay restorable before they are purged.
+func (c appConfig) TrashRetention() time.Duration {
+	days := c.TrashRetentionDays
+	if days <= 0 {
+		days = 30
+	}
+
+	return time.Duration(days) * 24 * time.Hour
 }
 
 type projectsConfig struct {
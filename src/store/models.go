@@ -1,6 +1,10 @@
 package store
 
 import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
@@ -15,6 +19,36 @@ type (
 	LabelID    = int
 	DateString = string
 	Color      = int
+
+	TaskPriority = int16
+)
+
+// Units of the task estimates, chosen per board.
+const (
+	EstimateUnitTime   = "time"   // Seconds
+	EstimateUnitPoints = "points" // Story points
+)
+
+// Sources of the board swimlanes. There are no custom fields on tasks yet, so
+// lanes can't be derived from them.
+const (
+	LaneModeNone     = "none"
+	LaneModeExplicit = "explicit" // Lanes are created by hand
+	LaneModeLabel    = "label"    // Every board label is a lane
+)
+
+// Modes of the work in progress limits of the lists.
+const (
+	WIPModeSoft = "soft" // Only warns
+	WIPModeHard = "hard" // Rejects tasks over the limit
+)
+
+const (
+	TaskPriorityNone TaskPriority = iota
+	TaskPriorityLow
+	TaskPriorityMedium
+	TaskPriorityHigh
+	TaskPriorityUrgent
 )
 
 type User struct {
@@ -69,22 +103,45 @@ type Board struct {
 	Favorite       bool
 	DateCreated    time.Time
 	DateLastViewed time.Time
+	DateArchived   *time.Time `bun:",nullzero"`
+	DeletedAt      time.Time  `bun:",soft_delete,nullzero"`
 	Color          Color
 	CoverID        *FileID `bun:"cover_id,nullzero"`
+	EstimateUnit   string
+	LaneMode       string
+	InboundToken   string `bun:",nullzero"` // Local part of the inbound email address
 
 	TaskLists []*TaskList `bun:"rel:has-many,join:id=board_id"`
 	Labels    []*Label    `bun:"rel:has-many,join:id=board_id"`
 	Project   *Project    `bun:"rel:belongs-to,join:project_id=id"`
 	Cover     *File       `bun:"rel:has-one,join:cover_id=id"`
+
+	// Sprint the tasks are filtered by
+	Sprint *Sprint `bun:"-"`
+
+	// Rollups of the non-archived tasks
+	Estimate  float64 `bun:"-"`
+	SpentTime int64   `bun:"-"`
+
+	// Lane by list matrix of the loaded tasks
+	Lanes []*Lane `bun:"-"`
+
+	// View the tasks are filtered by and the groups of its GroupBy
+	View   *View        `bun:"-"`
+	Groups []*TaskGroup `bun:"-"`
 }
 
 type Project struct {
 	bun.BaseModel `bun:"table:projects"`
 
-	ID      EntityID `bun:",pk,autoincrement"`
-	UserID  UserID
-	ShortID string
-	Name    string
+	ID             EntityID `bun:",pk,autoincrement"`
+	UserID         UserID
+	ShortID        string
+	Name           string
+	KeyPrefix      string
+	NextTaskNumber int64
+	SkipWeekends   bool
+	DeletedAt      time.Time `bun:",soft_delete,nullzero"`
 
 	AvatarID FileID     `bun:",nullzero"`
 	Avatar   *ImageFile `bun:"rel:has-one,join:avatar_id=id"`
@@ -98,6 +155,8 @@ type Task struct {
 	ID                  EntityID `bun:",pk"`
 	UserID              UserID
 	ShortID             string
+	Number              int64    `bun:",nullzero"`
+	ProjectID           EntityID `bun:",nullzero"`
 	TaskListID          EntityID
 	Name                string
 	Text                string
@@ -105,8 +164,15 @@ type Task struct {
 	SpentTime           int64
 	Archived            bool
 	DateCreated         time.Time
+	DateArchived        *time.Time `bun:",nullzero"`
+	DeletedAt           time.Time  `bun:",soft_delete,nullzero"`
 	DateStartedTracking *time.Time `bun:",nullzero"`
+	StartDate           *time.Time `bun:",nullzero"`
 	DueDate             *time.Time `bun:",nullzero"`
+	SprintID            *EntityID  `bun:",nullzero"`
+	Estimate            *float64
+	Priority            TaskPriority
+	LaneID              *EntityID `bun:",nullzero"`
 
 	Comments    []*Comment `bun:"rel:has-many,join:id=task_id"`
 	Attachments []*File    `bun:"m2m:task_files,join:Task=File"`
@@ -114,21 +180,407 @@ type Task struct {
 
 	// Rendered Text markdown
 	HTML string `bun:"-"`
+
+	// Human-readable key, e.g. KAR-142
+	Key string `bun:"-"`
+}
+
+var taskKeyRegexp = regexp.MustCompile(`^([A-Z][A-Z0-9]{0,9})-([1-9][0-9]*)$`)
+
+func FormatTaskKey(prefix string, number int64) string {
+	if prefix == "" || number == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf("%s-%d", prefix, number)
+}
+
+func ParseTaskKey(key string) (string, int64, bool) {
+	match := taskKeyRegexp.FindStringSubmatch(strings.ToUpper(key))
+	if match == nil {
+		return "", 0, false
+	}
+
+	number, err := strconv.ParseInt(match[2], 10, 64)
+	if err != nil {
+		return "", 0, false
+	}
+
+	return match[1], number, true
+}
+
+type TimeEntry struct {
+	bun.BaseModel `bun:"table:time_entries"`
+
+	ID          EntityID `bun:",pk"`
+	TaskID      EntityID
+	UserID      UserID
+	DateStarted time.Time
+	DateEnded   time.Time
+}
+
+type TaskRevision struct {
+	bun.BaseModel `bun:"table:task_revisions"`
+
+	ID          EntityID `bun:",pk"`
+	TaskID      EntityID
+	UserID      UserID `bun:",nullzero"`
+	Number      int
+	Text        string
+	DateCreated time.Time
+	DateUpdated time.Time
+
+	Author *User `bun:"rel:belongs-to,join:user_id=id"`
+}
+
+type TaskMovement struct {
+	bun.BaseModel `bun:"table:task_movements"`
+
+	ID             EntityID `bun:",pk"`
+	TaskID         EntityID
+	UserID         UserID   `bun:",nullzero"`
+	FromTaskListID EntityID `bun:",nullzero"`
+	ToTaskListID   EntityID `bun:",nullzero"`
+	DateCreated    time.Time
 }
 
 type TaskList struct {
 	bun.BaseModel `bun:"table:task_lists"`
 
-	ID          EntityID  `bun:",pk" json:"-"`
-	UserID      UserID    `json:"-"`
-	BoardID     EntityID  `bun:"board_id" json:"-"`
-	Name        string    `json:"-"`
-	Archived    bool      `json:"-"`
-	Position    int64     `json:"-"`
-	DateCreated time.Time `json:"-"`
-	Color       Color     `json:"-"`
+	ID           EntityID   `bun:",pk" json:"-"`
+	UserID       UserID     `json:"-"`
+	BoardID      EntityID   `bun:"board_id" json:"-"`
+	Name         string     `json:"-"`
+	Archived     bool       `json:"-"`
+	Position     int64      `json:"-"`
+	DateCreated  time.Time  `json:"-"`
+	DateArchived *time.Time `bun:",nullzero" json:"-"`
+	DeletedAt    time.Time  `bun:",soft_delete,nullzero" json:"-"`
+	Color        Color      `json:"-"`
+	WIPLimit     *int       `bun:"wip_limit" json:"-"`
+	WIPMode      string     `bun:"wip_mode" json:"-"`
 
 	Tasks []*Task `bun:"rel:has-many,join:id=task_list_id" json:"tasks,omitempty"`
+
+	// Rollups of the non-archived tasks
+	TaskCount int     `bun:"-" json:"-"`
+	Estimate  float64 `bun:"-" json:"-"`
+	SpentTime int64   `bun:"-" json:"-"`
+}
+
+type Lane struct {
+	bun.BaseModel `bun:"table:lanes"`
+
+	ID          EntityID `bun:",pk"`
+	BoardID     EntityID
+	UserID      UserID
+	Name        string
+	Position    int64
+	DateCreated time.Time
+
+	// Set for the lanes derived from labels
+	LabelID *LabelID `bun:"-"`
+
+	Cells []*LaneCell `bun:"-"`
+}
+
+// Tasks of a lane within one list.
+type LaneCell struct {
+	TaskListID EntityID
+	TaskIDs    []EntityID
+}
+
+type View struct {
+	bun.BaseModel `bun:"table:views"`
+
+	ID          EntityID `bun:",pk"`
+	UserID      UserID
+	BoardID     *EntityID `bun:",nullzero"`
+	ProjectID   *EntityID `bun:",nullzero"`
+	Name        string
+	Filter      ViewFilter `bun:"type:jsonb"`
+	DateCreated time.Time
+}
+
+// Saved filter of a view. Zero values don't filter anything. Tasks have no
+// custom fields yet, so there is nothing to filter by for them.
+type ViewFilter struct {
+	LabelIDs      []LabelID    `json:"label_ids,omitempty"` // Any of
+	DueFrom       *time.Time   `json:"due_from,omitempty"`
+	DueTo         *time.Time   `json:"due_to,omitempty"`
+	DueWithinDays *int         `json:"due_within_days,omitempty"` // Due from today till N days later
+	NoDueDate     bool         `json:"no_due_date,omitempty"`
+	Text          string       `json:"text,omitempty"`
+	Completion    string       `json:"completion,omitempty"` // "open" or "done"
+	Archived      string       `json:"archived,omitempty"`   // "include" or "only"
+	MinPriority   TaskPriority `json:"min_priority,omitempty"`
+
+	GroupBy  string `json:"group_by,omitempty"` // "list", "label", "priority" or "due_date"
+	SortBy   string `json:"sort_by,omitempty"`  // "position", "due_date", "priority", "date_created" or "name"
+	SortDesc bool   `json:"sort_desc,omitempty"`
+}
+
+// Tasks of a board grouped by something other than the list.
+type TaskGroup struct {
+	Key     string
+	Name    string
+	TaskIDs []EntityID
+}
+
+// Kinds of the automation triggers.
+const (
+	AutomationTriggerTaskMoved     = "task_moved"
+	AutomationTriggerLabelAdded    = "label_added"
+	AutomationTriggerDueDatePassed = "due_date_passed"
+	AutomationTriggerSchedule      = "schedule" // Runs over all the board tasks
+)
+
+// Kinds of the automation conditions.
+const (
+	AutomationConditionInList        = "in_list"
+	AutomationConditionNotInList     = "not_in_list"
+	AutomationConditionHasLabel      = "has_label"
+	AutomationConditionLacksLabel    = "lacks_label"
+	AutomationConditionMinPriority   = "min_priority"
+	AutomationConditionInListForDays = "in_list_for_days" // Since the task entered its list
+	AutomationConditionOverdue       = "overdue"
+)
+
+// Kinds of the automation actions.
+const (
+	AutomationActionMove        = "move"
+	AutomationActionAddLabel    = "add_label"
+	AutomationActionRemoveLabel = "remove_label"
+	AutomationActionSetDueDate  = "set_due_date"
+	AutomationActionComment     = "comment"
+	AutomationActionArchive     = "archive"
+)
+
+// Statuses of the automation runs.
+const (
+	AutomationRunSuccess = "success"
+	AutomationRunFailed  = "failed"
+	AutomationRunSkipped = "skipped"
+)
+
+type AutomationRule struct {
+	bun.BaseModel `bun:"table:automation_rules"`
+
+	ID          EntityID `bun:",pk"`
+	BoardID     EntityID
+	UserID      UserID
+	Name        string
+	Enabled     bool
+	Trigger     AutomationTrigger      `bun:"type:jsonb"`
+	Conditions  []*AutomationCondition `bun:"type:jsonb"`
+	Actions     []*AutomationAction    `bun:"type:jsonb"`
+	LastRunAt   *time.Time             `bun:",nullzero"`
+	DateCreated time.Time
+}
+
+type AutomationTrigger struct {
+	Type           string   `json:"type"`
+	TaskListID     EntityID `json:"task_list_id,omitempty"`      // task_moved: to this list, any if empty
+	FromTaskListID EntityID `json:"from_task_list_id,omitempty"` // task_moved: from this list, any if empty
+	LabelID        LabelID  `json:"label_id,omitempty"`          // label_added: this label, any if zero
+	IntervalHours  int      `json:"interval_hours,omitempty"`    // schedule
+}
+
+type AutomationCondition struct {
+	Type       string       `json:"type"`
+	TaskListID EntityID     `json:"task_list_id,omitempty"`
+	LabelID    LabelID      `json:"label_id,omitempty"`
+	Priority   TaskPriority `json:"priority,omitempty"`
+	Days       int          `json:"days,omitempty"`
+}
+
+type AutomationAction struct {
+	Type       string   `json:"type"`
+	TaskListID EntityID `json:"task_list_id,omitempty"`
+	LabelID    LabelID  `json:"label_id,omitempty"`
+	Days       *int     `json:"days,omitempty"` // set_due_date: days from now, clears the date if null
+	Text       string   `json:"text,omitempty"`
+}
+
+type AutomationRun struct {
+	bun.BaseModel `bun:"table:automation_runs"`
+
+	ID          int64 `bun:",pk,autoincrement"`
+	RuleID      EntityID
+	TaskID      *EntityID `bun:",nullzero"`
+	Trigger     string
+	Status      string
+	Error       string
+	Depth       int
+	DateCreated time.Time
+}
+
+// Secret token of an iCalendar feed of due dates. Only the hash of the token
+// is stored, the token itself is shown once on creation.
+type FeedToken struct {
+	bun.BaseModel `bun:"table:feed_tokens"`
+
+	ID               EntityID `bun:",pk"`
+	UserID           UserID
+	BoardID          *EntityID `bun:",nullzero"` // Nil for all boards of the user
+	Name             string
+	TokenHash        []byte
+	Component        string
+	IncludeCompleted bool
+	DateCreated      time.Time
+	DateLastUsed     *time.Time `bun:",nullzero"`
+}
+
+// Email which became the task or one of its comments.
+type EmailMessage struct {
+	bun.BaseModel `bun:"table:email_messages"`
+
+	BoardID     EntityID `bun:",pk"`
+	MessageID   string   `bun:",pk"`
+	TaskID      EntityID
+	CommentID   *EntityID `bun:",nullzero"`
+	DateCreated time.Time
+}
+
+// Events the webhooks can be called on.
+const (
+	WebhookEventTaskCreated  = "task.created"
+	WebhookEventTaskUpdated  = "task.updated"
+	WebhookEventTaskMoved    = "task.moved"
+	WebhookEventTaskDeleted  = "task.deleted"
+	WebhookEventCommentAdded = "comment.added"
+)
+
+var WebhookEvents = []string{
+	WebhookEventTaskCreated,
+	WebhookEventTaskUpdated,
+	WebhookEventTaskMoved,
+	WebhookEventTaskDeleted,
+	WebhookEventCommentAdded,
+}
+
+type Webhook struct {
+	bun.BaseModel `bun:"table:webhooks"`
+
+	ID          EntityID `bun:",pk"`
+	ProjectID   EntityID
+	UserID      UserID
+	URL         string
+	Secret      string
+	Events      []string `bun:",array"`
+	Enabled     bool
+	DateCreated time.Time
+}
+
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliverySucceeded = "succeeded"
+	WebhookDeliveryFailed    = "failed"
+)
+
+type WebhookDelivery struct {
+	bun.BaseModel `bun:"table:webhook_deliveries"`
+
+	ID            EntityID `bun:",pk"`
+	WebhookID     EntityID
+	Event         string
+	Payload       json.RawMessage `bun:"type:jsonb"`
+	Status        string
+	Attempts      int
+	NextAttemptAt time.Time
+	ResponseCode  *int `bun:",nullzero"`
+	Error         string
+	DurationMS    int `bun:"duration_ms"`
+	DateCreated   time.Time
+	DateDelivered *time.Time `bun:",nullzero"`
+}
+
+// Secret token of an incoming webhook URL which creates tasks in the list.
+// Only the hash of the token is stored.
+type IncomingWebhook struct {
+	bun.BaseModel `bun:"table:incoming_webhooks"`
+
+	ID           EntityID `bun:",pk"`
+	TaskListID   EntityID
+	UserID       UserID
+	Name         string
+	TokenHash    []byte
+	DateCreated  time.Time
+	DateLastUsed *time.Time `bun:",nullzero"`
+}
+
+// Receiver of the GitHub and Gitea webhooks of a project.
+type ForgeHook struct {
+	bun.BaseModel `bun:"table:forge_hooks"`
+
+	ID           EntityID `bun:",pk"`
+	ProjectID    EntityID
+	UserID       UserID
+	Name         string
+	Secret       string
+	DateCreated  time.Time
+	DateLastUsed *time.Time `bun:",nullzero"`
+}
+
+// Commit or pull request which mentions the task.
+type TaskLink struct {
+	bun.BaseModel `bun:"table:task_links"`
+
+	ID          EntityID `bun:",pk"`
+	TaskID      EntityID
+	Kind        string
+	URL         string
+	ExternalID  string
+	Repository  string
+	Title       string
+	Author      string
+	State       string
+	Completed   bool
+	DateCreated time.Time
+	DateUpdated time.Time
+}
+
+type TaskDependency struct {
+	bun.BaseModel `bun:"table:task_dependencies"`
+
+	TaskID      EntityID `bun:",pk"`
+	DependsOnID EntityID `bun:",pk"`
+	UserID      UserID
+	DateCreated time.Time
+}
+
+type BoardSnapshot struct {
+	bun.BaseModel `bun:"table:board_snapshots"`
+
+	BoardID    EntityID  `bun:",pk"`
+	TaskListID EntityID  `bun:",pk"`
+	Date       time.Time `bun:",pk"`
+	TaskCount  int
+	Estimate   *float64 // Sum of the estimates, nil in the snapshots taken before them
+}
+
+type Sprint struct {
+	bun.BaseModel `bun:"table:sprints"`
+
+	ID          EntityID `bun:",pk"`
+	BoardID     EntityID
+	UserID      UserID
+	Name        string
+	Goal        string
+	DateStart   time.Time
+	DateEnd     time.Time
+	DateCreated time.Time
+	DateClosed  *time.Time `bun:",nullzero"`
+}
+
+type SprintCommitment struct {
+	bun.BaseModel `bun:"table:sprint_commitments"`
+
+	SprintID    EntityID `bun:",pk"`
+	TaskID      EntityID `bun:",pk"`
+	DateAdded   time.Time
+	DateRemoved *time.Time `bun:",nullzero"`
+	Completed   bool
 }
 
 type Comment struct {
@@ -0,0 +1,38 @@
+package store
+
+import (
+	"context"
+	"time"
+)
+
+// Records the current amount of tasks and the sum of their estimates in every
+// list of every board as the snapshot of the given day. Repeated calls within
+// a day overwrite it, so the last one wins.
+func (s *Store) TakeBoardSnapshots(ctx context.Context, date time.Time) (int, error) {
+	counts := s.ORM.NewSelect().
+		TableExpr("task_lists AS tl").
+		ColumnExpr("tl.board_id, tl.id AS task_list_id, ?::date AS date", date.Format("2006-01-02")).
+		ColumnExpr("count(t.id) AS task_count, coalesce(sum(t.estimate), 0) AS estimate").
+		Join("JOIN boards AS b ON b.id = tl.board_id").
+		Join("LEFT JOIN tasks AS t ON t.task_list_id = tl.id AND NOT t.archived AND t.deleted_at IS NULL").
+		Where("NOT tl.archived").
+		Where("tl.deleted_at IS NULL").
+		Where("b.deleted_at IS NULL").
+		GroupExpr("tl.board_id, tl.id")
+
+	result, err := s.ORM.NewInsert().
+		Model((*BoardSnapshot)(nil)).
+		Column("board_id", "task_list_id", "date", "task_count", "estimate").
+		With("counts", counts).
+		TableExpr("counts").
+		On("CONFLICT (board_id, date, task_list_id) DO UPDATE").
+		Set("task_count = EXCLUDED.task_count").
+		Set("estimate = EXCLUDED.estimate").
+		Exec(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	affected, _ := result.RowsAffected()
+	return int(affected), nil
+}
@@ -0,0 +1,146 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+type PurgeTrashResult struct {
+	Tasks     int
+	TaskLists int
+	Boards    int
+	Projects  int
+	Files     int
+}
+
+// Permanently deletes projects, boards, task lists and tasks moved to the trash
+// before the given moment, then removes the files which were attached to them
+// and are not referenced by anything else.
+func (s *Store) PurgeTrash(ctx context.Context, deletedBefore time.Time) (*PurgeTrashResult, error) {
+	result := new(PurgeTrashResult)
+	var orphans []File
+
+	err := s.ORM.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		candidates, err := trashedFileIDs(ctx, tx, deletedBefore)
+		if err != nil {
+			return err
+		}
+
+		// Children first, so the counters are not swallowed by cascades.
+		purges := []struct {
+			model   any
+			counter *int
+		}{
+			{(*Task)(nil), &result.Tasks},
+			{(*TaskList)(nil), &result.TaskLists},
+			{(*Board)(nil), &result.Boards},
+			{(*Project)(nil), &result.Projects},
+		}
+
+		for _, purge := range purges {
+			deleteResult, err := tx.NewDelete().
+				Model(purge.model).
+				ForceDelete().
+				WhereDeleted().
+				Where("deleted_at < ?", deletedBefore).
+				Exec(ctx)
+			if err != nil {
+				return err
+			}
+
+			affected, _ := deleteResult.RowsAffected()
+			*purge.counter = int(affected)
+		}
+
+		if len(candidates) == 0 {
+			return nil
+		}
+
+		err = tx.NewSelect().
+			Model(&orphans).
+			Where("id IN (?)", bun.In(candidates)).
+			Where("NOT EXISTS (SELECT 1 FROM task_files WHERE file_id = file.id)").
+			Where("NOT EXISTS (SELECT 1 FROM comment_files WHERE file_id = file.id)").
+			Where("NOT EXISTS (SELECT 1 FROM users WHERE avatar_id = file.id)").
+			Where("NOT EXISTS (SELECT 1 FROM projects WHERE avatar_id = file.id)").
+			Where("NOT EXISTS (SELECT 1 FROM boards WHERE cover_id = file.id)").
+			Where("NOT EXISTS (SELECT 1 FROM default_cover_images WHERE id = file.id)").
+			Scan(ctx)
+		if err != nil || len(orphans) == 0 {
+			return err
+		}
+
+		// Thumbnails go away along with their original image.
+		var thumbnails []File
+		err = tx.NewSelect().
+			Model(&thumbnails).
+			Where("id IN (SELECT id FROM image_thumbnails WHERE image_id IN (?))", bun.In(fileIDs(orphans))).
+			Scan(ctx)
+		if err != nil {
+			return err
+		}
+		orphans = append(orphans, thumbnails...)
+
+		_, err = tx.NewDelete().
+			Model(&orphans).
+			WherePK().
+			Exec(ctx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Storage objects are removed only after the rows are gone for good.
+	for _, file := range orphans {
+		if err := s.fileStorage.Delete(file.StorageObjectID); err != nil {
+			return result, err
+		}
+		result.Files++
+	}
+
+	return result, nil
+}
+
+// Collects files attached to the trashed entities which are due to be purged.
+func trashedFileIDs(ctx context.Context, db bun.IDB, deletedBefore time.Time) ([]FileID, error) {
+	trashedTasks := db.NewSelect().
+		TableExpr("tasks").
+		Column("id").
+		Where("deleted_at < ?", deletedBefore)
+
+	var ids []FileID
+	err := db.NewSelect().
+		TableExpr("task_files").
+		ColumnExpr("file_id AS id").
+		Where("task_id IN (?)", trashedTasks).
+		Union(db.NewSelect().
+			TableExpr("comment_files AS cf").
+			ColumnExpr("cf.file_id AS id").
+			Join("JOIN comments AS c ON c.id = cf.comment_id").
+			Where("c.task_id IN (?)", trashedTasks)).
+		Union(db.NewSelect().
+			TableExpr("boards").
+			ColumnExpr("cover_id AS id").
+			Where("deleted_at < ?", deletedBefore).
+			Where("cover_id IS NOT NULL")).
+		Union(db.NewSelect().
+			TableExpr("projects").
+			ColumnExpr("avatar_id AS id").
+			Where("deleted_at < ?", deletedBefore).
+			Where("avatar_id IS NOT NULL")).
+		Scan(ctx, &ids)
+
+	return ids, err
+}
+
+func fileIDs(files []File) []FileID {
+	ids := make([]FileID, len(files))
+	for i, file := range files {
+		ids[i] = file.ID
+	}
+
+	return ids
+}
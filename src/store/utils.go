@@ -1,6 +1,11 @@
 package store
 
-import "database/sql"
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/uptrace/bun/driver/pgdriver"
+)
 
 func NoRowsAffected(result sql.Result) bool {
 	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
@@ -9,3 +14,12 @@ func NoRowsAffected(result sql.Result) bool {
 
 	return false
 }
+
+func IsUniqueViolation(err error) bool {
+	var pgErr pgdriver.Error
+	if errors.As(err, &pgErr) {
+		return pgErr.Field('C') == "23505"
+	}
+
+	return false
+}
@@ -18,3 +18,12 @@ func GetFileURL(file *store.File) string {
 	}
 	return url
 }
+
+// Link to the task in the frontend app.
+func GetTaskURL(taskID store.EntityID) string {
+	url, err := url.JoinPath(settings.AppConfig.FrontendURL, "task", taskID)
+	if err != nil {
+		return ""
+	}
+	return url
+}
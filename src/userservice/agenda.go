@@ -0,0 +1,144 @@
+package userservice
+
+import (
+	"time"
+
+	"github.com/uptrace/bun"
+
+	"github.com/lesnoi-kot/karten-backend/src/store"
+)
+
+// Agenda buckets, in the order they are shown.
+const (
+	AgendaOverdue      = "overdue"
+	AgendaToday        = "today"
+	AgendaUpcoming     = "upcoming"
+	AgendaHighPriority = "high_priority"
+)
+
+type GetAgendaOptions struct {
+	Now          time.Time
+	Location     *time.Location
+	UpcomingDays int // Days after today counted as upcoming
+	Limit        int // Tasks per bucket
+}
+
+type AgendaTask struct {
+	ID           store.EntityID     `bun:"id"`
+	Number       int64              `bun:"number"`
+	Key          string             `bun:"-"`
+	Name         string             `bun:"name"`
+	Priority     store.TaskPriority `bun:"priority"`
+	DueDate      *time.Time         `bun:"due_date"`
+	Estimate     *float64           `bun:"estimate"`
+	DateCreated  time.Time          `bun:"date_created"`
+	TaskListID   store.EntityID     `bun:"task_list_id"`
+	TaskListName string             `bun:"task_list_name"`
+	BoardID      store.EntityID     `bun:"board_id"`
+	BoardName    string             `bun:"board_name"`
+	ProjectID    store.EntityID     `bun:"project_id"`
+	ProjectName  string             `bun:"project_name"`
+	KeyPrefix    string             `bun:"key_prefix"`
+	Bucket       string             `bun:"bucket"`
+	Rank         int                `bun:"rank"` // Position in the bucket
+}
+
+type Agenda struct {
+	Today        time.Time // Start of the day in the requested location
+	Overdue      []*AgendaTask
+	DueToday     []*AgendaTask
+	Upcoming     []*AgendaTask
+	HighPriority []*AgendaTask
+}
+
+// Collects the open tasks worth attention from all the boards in one query.
+// Tasks in the last list of a board count as done and are left out, unless
+// the board has a single list.
+func (user UserService) GetAgenda(args *GetAgendaOptions) (*Agenda, error) {
+	now := args.Now.In(args.Location)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, args.Location)
+	tomorrow := today.AddDate(0, 0, 1)
+	upcomingEnd := tomorrow.AddDate(0, 0, args.UpcomingDays)
+
+	candidates := user.Store.ORM.NewSelect().
+		TableExpr("tasks AS t").
+		ColumnExpr("t.id, t.number, t.name, t.priority, t.due_date, t.estimate, t.date_created").
+		ColumnExpr("tl.id AS task_list_id, tl.name AS task_list_name").
+		ColumnExpr("b.id AS board_id, b.name AS board_name").
+		ColumnExpr("p.id AS project_id, p.name AS project_name, p.key_prefix").
+		ColumnExpr(`CASE
+			WHEN t.due_date < ? THEN ?
+			WHEN t.due_date < ? THEN ?
+			WHEN t.due_date < ? THEN ?
+			ELSE ?
+		END AS bucket`,
+			today.UTC(), AgendaOverdue,
+			tomorrow.UTC(), AgendaToday,
+			upcomingEnd.UTC(), AgendaUpcoming,
+			AgendaHighPriority).
+		Join("JOIN task_lists AS tl ON tl.id = t.task_list_id").
+		Join("JOIN boards AS b ON b.id = tl.board_id").
+		Join("JOIN projects AS p ON p.id = b.project_id").
+		Join("LEFT JOIN LATERAL (?) AS done ON true",
+			doneListQuery(user.Store.ORM, bun.Ident("b.id")).ColumnExpr("count(*) OVER () AS lists")).
+		Where("t.user_id = ?", user.UserID).
+		Where("t.archived = ?", false).
+		Where("t.deleted_at IS NULL").
+		Where("tl.archived = ?", false).
+		Where("tl.deleted_at IS NULL").
+		Where("b.archived = ?", false).
+		Where("b.deleted_at IS NULL").
+		Where("p.deleted_at IS NULL").
+		Where("done.lists = 1 OR done.id <> tl.id").
+		Where("t.due_date < ? OR t.priority >= ?", upcomingEnd.UTC(), store.TaskPriorityHigh)
+
+	ranked := user.Store.ORM.NewSelect().
+		TableExpr("candidates AS c").
+		ColumnExpr("c.*").
+		ColumnExpr(`row_number() OVER (
+			PARTITION BY c.bucket
+			ORDER BY
+				CASE WHEN c.bucket = ? THEN c.priority END DESC,
+				c.due_date ASC NULLS LAST,
+				c.priority DESC,
+				c.date_created
+		) AS rank`, AgendaHighPriority)
+
+	tasks := make([]*AgendaTask, 0)
+	err := user.Store.ORM.NewSelect().
+		With("candidates", candidates).
+		With("ranked", ranked).
+		TableExpr("ranked").
+		ColumnExpr("ranked.*").
+		Where("ranked.rank <= ?", args.Limit).
+		OrderExpr("ranked.rank").
+		Scan(user.Context, &tasks)
+	if err != nil {
+		return nil, err
+	}
+
+	agenda := &Agenda{
+		Today:        today,
+		Overdue:      make([]*AgendaTask, 0),
+		DueToday:     make([]*AgendaTask, 0),
+		Upcoming:     make([]*AgendaTask, 0),
+		HighPriority: make([]*AgendaTask, 0),
+	}
+
+	for _, task := range tasks {
+		task.Key = store.FormatTaskKey(task.KeyPrefix, task.Number)
+
+		switch task.Bucket {
+		case AgendaOverdue:
+			agenda.Overdue = append(agenda.Overdue, task)
+		case AgendaToday:
+			agenda.DueToday = append(agenda.DueToday, task)
+		case AgendaUpcoming:
+			agenda.Upcoming = append(agenda.Upcoming, task)
+		case AgendaHighPriority:
+			agenda.HighPriority = append(agenda.HighPriority, task)
+		}
+	}
+
+	return agenda, nil
+}
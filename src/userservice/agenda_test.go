@@ -0,0 +1,83 @@
+package userservice
+
+import (
+	"time"
+
+	"github.com/samber/lo"
+
+	"github.com/lesnoi-kot/karten-backend/src/store"
+)
+
+func (s *userServiceSuite) TestGetAgenda() {
+	_, lists := s.addBoard("Todo", "Doing", "Done")
+
+	location := time.FixedZone("UTC+3", 3*60*60)
+	now := time.Date(2026, 10, 19, 10, 0, 0, 0, location)
+	today := time.Date(2026, 10, 19, 0, 0, 0, 0, location)
+
+	add := func(taskListID store.EntityID, name string, dueDate *time.Time, priority store.TaskPriority) {
+		if dueDate != nil {
+			dueDate = lo.ToPtr(dueDate.UTC())
+		}
+		_, err := s.user.AddTask(&AddTaskOptions{
+			TaskListID: taskListID,
+			Name:       name,
+			DueDate:    dueDate,
+			Priority:   priority,
+		})
+		s.Require().NoError(err)
+	}
+
+	add(lists[0].ID, "Overdue", lo.ToPtr(today.Add(-time.Hour)), store.TaskPriorityNone)
+	add(lists[1].ID, "Long overdue", lo.ToPtr(today.AddDate(0, 0, -3)), store.TaskPriorityNone)
+	add(lists[0].ID, "Today", lo.ToPtr(today), store.TaskPriorityNone)
+	add(lists[0].ID, "Tonight", lo.ToPtr(today.Add(23*time.Hour)), store.TaskPriorityLow)
+	add(lists[0].ID, "Upcoming", lo.ToPtr(today.AddDate(0, 0, 1)), store.TaskPriorityNone)
+	add(lists[0].ID, "Last upcoming", lo.ToPtr(today.AddDate(0, 0, 3).Add(-time.Minute)), store.TaskPriorityNone)
+	add(lists[0].ID, "Later", lo.ToPtr(today.AddDate(0, 0, 3)), store.TaskPriorityMedium)
+	add(lists[0].ID, "Later but urgent", lo.ToPtr(today.AddDate(0, 0, 10)), store.TaskPriorityUrgent)
+	add(lists[1].ID, "High", nil, store.TaskPriorityHigh)
+	add(lists[2].ID, "Done", lo.ToPtr(today.AddDate(0, 0, -1)), store.TaskPriorityUrgent)
+
+	agenda, err := s.user.GetAgenda(&GetAgendaOptions{
+		Now:          now,
+		Location:     location,
+		UpcomingDays: 2,
+		Limit:        10,
+	})
+	s.Require().NoError(err)
+
+	names := func(tasks []*AgendaTask) []string {
+		return lo.Map(tasks, func(task *AgendaTask, _ int) string { return task.Name })
+	}
+
+	s.True(agenda.Today.Equal(today))
+	s.Equal([]string{"Long overdue", "Overdue"}, names(agenda.Overdue))
+	s.Equal([]string{"Today", "Tonight"}, names(agenda.DueToday))
+	s.Equal([]string{"Upcoming", "Last upcoming"}, names(agenda.Upcoming))
+	s.Equal([]string{"Later but urgent", "High"}, names(agenda.HighPriority), "the done list is left out")
+	s.NotEmpty(agenda.Overdue[0].Key)
+	s.Equal("Doing", agenda.Overdue[0].TaskListName)
+
+	agenda, err = s.user.GetAgenda(&GetAgendaOptions{
+		Now:          now,
+		Location:     location,
+		UpcomingDays: 2,
+		Limit:        1,
+	})
+	s.Require().NoError(err)
+	s.Equal([]string{"Long overdue"}, names(agenda.Overdue))
+	s.Equal([]string{"Today"}, names(agenda.DueToday))
+	s.Equal([]string{"Upcoming"}, names(agenda.Upcoming))
+	s.Equal([]string{"Later but urgent"}, names(agenda.HighPriority))
+}
+
+func (s *userServiceSuite) TestGetAgendaOfSingleListBoard() {
+	_, lists := s.addBoard("Todo")
+	_, err := s.user.AddTask(&AddTaskOptions{TaskListID: lists[0].ID, Name: "Urgent", Priority: store.TaskPriorityUrgent})
+	s.Require().NoError(err)
+
+	agenda, err := s.user.GetAgenda(&GetAgendaOptions{Now: time.Now(), Location: time.UTC, UpcomingDays: 7, Limit: 10})
+	s.Require().NoError(err)
+	s.Len(agenda.HighPriority, 1, "the only list of a board is not the done one")
+}
@@ -0,0 +1,319 @@
+package userservice
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/uptrace/bun"
+
+	"github.com/lesnoi-kot/karten-backend/src/store"
+)
+
+var ErrOriginalContainerGone = errors.New("Original container is not available, choose another one")
+
+const (
+	ArchivedBoard    = "board"
+	ArchivedTaskList = "task_list"
+	ArchivedTask     = "task"
+)
+
+type ArchivedItem struct {
+	Type         string    `bun:"type"`
+	ID           string    `bun:"id"`
+	Name         string    `bun:"name"`
+	BoardID      string    `bun:"board_id"`
+	BoardName    string    `bun:"board_name"`
+	TaskListID   string    `bun:"task_list_id"`
+	TaskListName string    `bun:"task_list_name"`
+	DateArchived time.Time `bun:"date_archived"`
+}
+
+type GetProjectArchiveOptions struct {
+	ProjectID store.EntityID
+	Query     string
+	Type      string
+	Limit     int
+	Offset    int
+}
+
+type UnarchiveTaskListOptions struct {
+	TaskListID store.EntityID
+	BoardID    *store.EntityID // Target board if the original one is archived
+}
+
+type UnarchiveTaskOptions struct {
+	TaskID     store.EntityID
+	TaskListID *store.EntityID // Target list if the original one is archived
+}
+
+func setArchived(q *bun.UpdateQuery, archived bool) *bun.UpdateQuery {
+	if archived {
+		return q.
+			Set("archived = ?", true).
+			Set("date_archived = coalesce(date_archived, ?)", time.Now().UTC())
+	}
+
+	return q.
+		Set("archived = ?", false).
+		Set("date_archived = ?", nil)
+}
+
+// Escapes LIKE wildcards in a user input.
+func likePattern(query string) string {
+	query = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(query)
+	return "%" + query + "%"
+}
+
+func (user UserService) GetProjectArchive(args *GetProjectArchiveOptions) ([]*ArchivedItem, int, error) {
+	if owns, err := user.OwnsProject(args.ProjectID); err != nil {
+		return nil, 0, err
+	} else if !owns {
+		return nil, 0, store.ErrNotFound
+	}
+
+	db := user.Store.ORM
+	var archive *bun.SelectQuery
+
+	union := func(q *bun.SelectQuery) {
+		if archive == nil {
+			archive = q
+		} else {
+			archive = archive.UnionAll(q)
+		}
+	}
+
+	if args.Type == "" || args.Type == ArchivedBoard {
+		union(db.NewSelect().
+			TableExpr("boards AS b").
+			ColumnExpr("? AS type", ArchivedBoard).
+			ColumnExpr("b.id, b.name, b.id AS board_id, b.name AS board_name").
+			ColumnExpr("NULL::uuid AS task_list_id, NULL::varchar AS task_list_name").
+			ColumnExpr("b.date_archived").
+			Where("b.project_id = ?", args.ProjectID).
+			Where("b.archived").
+			Where("b.deleted_at IS NULL"))
+	}
+
+	if args.Type == "" || args.Type == ArchivedTaskList {
+		union(db.NewSelect().
+			TableExpr("task_lists AS tl").
+			ColumnExpr("? AS type", ArchivedTaskList).
+			ColumnExpr("tl.id, tl.name, b.id AS board_id, b.name AS board_name").
+			ColumnExpr("NULL::uuid AS task_list_id, NULL::varchar AS task_list_name").
+			ColumnExpr("tl.date_archived").
+			Join("JOIN boards AS b ON b.id = tl.board_id").
+			Where("b.project_id = ?", args.ProjectID).
+			Where("tl.archived").
+			Where("tl.deleted_at IS NULL"))
+	}
+
+	if args.Type == "" || args.Type == ArchivedTask {
+		union(db.NewSelect().
+			TableExpr("tasks AS t").
+			ColumnExpr("? AS type", ArchivedTask).
+			ColumnExpr("t.id, t.name, b.id AS board_id, b.name AS board_name").
+			ColumnExpr("tl.id AS task_list_id, tl.name AS task_list_name").
+			ColumnExpr("t.date_archived").
+			Join("JOIN task_lists AS tl ON tl.id = t.task_list_id").
+			Join("JOIN boards AS b ON b.id = tl.board_id").
+			Where("b.project_id = ?", args.ProjectID).
+			Where("t.archived").
+			Where("t.deleted_at IS NULL"))
+	}
+
+	if archive == nil {
+		return nil, 0, errors.New("Unknown archived item type")
+	}
+
+	filter := func(q *bun.SelectQuery) *bun.SelectQuery {
+		if args.Query != "" {
+			q = q.Where("archive.name ILIKE ?", likePattern(args.Query))
+		}
+		return q
+	}
+
+	var total int
+	err := db.NewSelect().
+		With("archive", archive).
+		TableExpr("archive").
+		ColumnExpr("count(*)").
+		Apply(filter).
+		Scan(user.Context, &total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	items := make([]*ArchivedItem, 0)
+	err = db.NewSelect().
+		With("archive", archive).
+		TableExpr("archive").
+		ColumnExpr("archive.*").
+		Apply(filter).
+		OrderExpr("archive.date_archived DESC NULLS LAST, archive.id").
+		Limit(args.Limit).
+		Offset(args.Offset).
+		Scan(user.Context, &items)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return items, total, nil
+}
+
+func (user UserService) ArchiveBoard(boardID store.EntityID) error {
+	archived := true
+	return user.EditBoard(&EditBoardOptions{BoardID: boardID, Archived: &archived})
+}
+
+func (user UserService) UnarchiveBoard(boardID store.EntityID) error {
+	archived := false
+	return user.EditBoard(&EditBoardOptions{BoardID: boardID, Archived: &archived})
+}
+
+func (user UserService) ArchiveTaskList(taskListID store.EntityID) error {
+	archived := true
+	return user.EditTaskList(&EditTaskListOptions{TaskListID: taskListID, Archived: &archived})
+}
+
+func (user UserService) ArchiveTask(taskID store.EntityID) error {
+	archived := true
+	return user.EditTask(&EditTaskOptions{TaskID: taskID, Archived: &archived})
+}
+
+func (user UserService) isBoardAvailable(boardID store.EntityID) (bool, error) {
+	return user.Store.ORM.NewSelect().
+		Model((*store.Board)(nil)).
+		Where("id = ?", boardID).
+		Where("user_id = ?", user.UserID).
+		Where("archived = ?", false).
+		Exists(user.Context)
+}
+
+func (user UserService) isTaskListAvailable(taskListID store.EntityID) (bool, error) {
+	return user.Store.ORM.NewSelect().
+		TableExpr("task_lists AS tl").
+		Join("JOIN boards AS b ON b.id = tl.board_id").
+		Where("tl.id = ?", taskListID).
+		Where("tl.user_id = ?", user.UserID).
+		Where("NOT tl.archived").
+		Where("NOT b.archived").
+		Where("tl.deleted_at IS NULL").
+		Where("b.deleted_at IS NULL").
+		Exists(user.Context)
+}
+
+func (user UserService) boardProjectID(boardID store.EntityID) (store.EntityID, error) {
+	board := new(store.Board)
+	err := user.Store.ORM.NewSelect().
+		Model(board).
+		Column("project_id").
+		Where("id = ?", boardID).
+		Where("user_id = ?", user.UserID).
+		Scan(user.Context)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", store.ErrNotFound
+	} else if err != nil {
+		return "", err
+	}
+
+	return board.ProjectID, nil
+}
+
+// Restores the list to its board or, if the board is archived, to another
+// board of the same project.
+func (user UserService) UnarchiveTaskList(args *UnarchiveTaskListOptions) error {
+	taskList, err := user.getTaskListRow(args.TaskListID)
+	if err != nil {
+		return err
+	}
+
+	q := user.Store.ORM.NewUpdate().
+		Model((*store.TaskList)(nil)).
+		Where("id = ?", taskList.ID).
+		Where("user_id = ?", user.UserID)
+	q = setArchived(q, false)
+
+	if args.BoardID != nil && *args.BoardID != taskList.BoardID {
+		sourceProjectID, err := user.boardProjectID(taskList.BoardID)
+		if err != nil {
+			return err
+		}
+		targetProjectID, err := user.boardProjectID(*args.BoardID)
+		if err != nil {
+			return err
+		}
+		if sourceProjectID != targetProjectID {
+			return ErrPermissionDenied
+		}
+
+		q = q.Set("board_id = ?", *args.BoardID)
+	}
+
+	targetBoardID := taskList.BoardID
+	if args.BoardID != nil {
+		targetBoardID = *args.BoardID
+	}
+
+	if available, err := user.isBoardAvailable(targetBoardID); err != nil {
+		return err
+	} else if !available && args.BoardID == nil {
+		return ErrOriginalContainerGone
+	} else if !available {
+		return store.ErrNotFound
+	}
+
+	_, err = q.Exec(user.Context)
+	return err
+}
+
+// Restores the task to its list or, if the list is not available anymore,
+// to the chosen one.
+func (user UserService) UnarchiveTask(args *UnarchiveTaskOptions) error {
+	task, err := user.GetTask(&GetTaskOptions{
+		TaskID:                args.TaskID,
+		SkipTextRender:        true,
+		SkipCommentTextRender: true,
+	})
+	if err != nil {
+		return err
+	}
+
+	targetListID := task.TaskListID
+	if args.TaskListID != nil {
+		targetListID = *args.TaskListID
+	}
+
+	if available, err := user.isTaskListAvailable(targetListID); err != nil {
+		return err
+	} else if !available && args.TaskListID == nil {
+		return ErrOriginalContainerGone
+	} else if !available {
+		return store.ErrNotFound
+	}
+
+	archived := false
+	opts := &EditTaskOptions{TaskID: task.ID, Archived: &archived}
+	if targetListID != task.TaskListID {
+		opts.TaskListID = &targetListID
+	}
+
+	return user.EditTask(opts)
+}
+
+func (user UserService) getTaskListRow(taskListID store.EntityID) (*store.TaskList, error) {
+	taskList := new(store.TaskList)
+	err := user.Store.ORM.NewSelect().
+		Model(taskList).
+		Where("id = ?", taskListID).
+		Where("user_id = ?", user.UserID).
+		Scan(user.Context)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, store.ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	return taskList, nil
+}
@@ -0,0 +1,90 @@
+package userservice
+
+import (
+	"github.com/samber/lo"
+
+	"github.com/lesnoi-kot/karten-backend/src/store"
+)
+
+func (s *userServiceSuite) TestGetProjectArchive() {
+	board, lists := s.addBoard("Todo", "Done")
+	project, err := s.user.boardProjectID(board.ID)
+	s.Require().NoError(err)
+
+	task := s.addTask(lists[0].ID, "Write 100% of the docs")
+	s.addTask(lists[0].ID, "Stays active")
+	s.Require().NoError(s.user.ArchiveTask(task.ID))
+	s.Require().NoError(s.user.ArchiveTaskList(lists[1].ID))
+
+	items, total, err := s.user.GetProjectArchive(&GetProjectArchiveOptions{ProjectID: project, Limit: 10})
+	s.Require().NoError(err)
+	s.Equal(2, total)
+	s.ElementsMatch(
+		[]string{ArchivedTask + ":" + task.ID, ArchivedTaskList + ":" + lists[1].ID},
+		lo.Map(items, func(item *ArchivedItem, _ int) string { return item.Type + ":" + item.ID }),
+	)
+
+	items, total, err = s.user.GetProjectArchive(&GetProjectArchiveOptions{ProjectID: project, Type: ArchivedTask, Limit: 10})
+	s.Require().NoError(err)
+	s.Equal(1, total)
+	s.Equal(lists[0].ID, items[0].TaskListID)
+	s.Equal("Todo", items[0].TaskListName)
+
+	// Wildcards in the query are taken literally.
+	_, total, err = s.user.GetProjectArchive(&GetProjectArchiveOptions{ProjectID: project, Query: "100%", Limit: 10})
+	s.Require().NoError(err)
+	s.Equal(1, total)
+	_, total, err = s.user.GetProjectArchive(&GetProjectArchiveOptions{ProjectID: project, Query: "1_0", Limit: 10})
+	s.Require().NoError(err)
+	s.Zero(total)
+
+	_, _, err = s.user.GetProjectArchive(&GetProjectArchiveOptions{ProjectID: project, Type: "comment", Limit: 10})
+	s.Error(err)
+}
+
+func (s *userServiceSuite) TestUnarchiveTaskToChosenList() {
+	_, lists := s.addBoard("Todo", "Doing")
+	task := s.addTask(lists[0].ID, "Task")
+
+	s.Require().NoError(s.user.ArchiveTask(task.ID))
+	s.Require().NoError(s.user.ArchiveTaskList(lists[0].ID))
+
+	err := s.user.UnarchiveTask(&UnarchiveTaskOptions{TaskID: task.ID})
+	s.ErrorIs(err, ErrOriginalContainerGone)
+
+	err = s.user.UnarchiveTask(&UnarchiveTaskOptions{TaskID: task.ID, TaskListID: &lists[0].ID})
+	s.ErrorIs(err, store.ErrNotFound, "the chosen list is archived too")
+
+	err = s.user.UnarchiveTask(&UnarchiveTaskOptions{TaskID: task.ID, TaskListID: &lists[1].ID})
+	s.Require().NoError(err)
+
+	restored := s.getTask(task.ID)
+	s.False(restored.Archived)
+	s.Nil(restored.DateArchived)
+	s.Equal(lists[1].ID, restored.TaskListID)
+}
+
+func (s *userServiceSuite) TestUnarchiveTaskListToChosenBoard() {
+	board, lists := s.addBoard("Todo")
+	projectID, err := s.user.boardProjectID(board.ID)
+	s.Require().NoError(err)
+	otherBoard, _ := s.addProjectBoard(projectID)
+	foreignBoard, _ := s.addBoard()
+
+	s.Require().NoError(s.user.ArchiveTaskList(lists[0].ID))
+	s.Require().NoError(s.user.ArchiveBoard(board.ID))
+
+	err = s.user.UnarchiveTaskList(&UnarchiveTaskListOptions{TaskListID: lists[0].ID})
+	s.ErrorIs(err, ErrOriginalContainerGone)
+
+	err = s.user.UnarchiveTaskList(&UnarchiveTaskListOptions{TaskListID: lists[0].ID, BoardID: &foreignBoard.ID})
+	s.ErrorIs(err, ErrPermissionDenied, "boards of other projects are not offered")
+
+	err = s.user.UnarchiveTaskList(&UnarchiveTaskListOptions{TaskListID: lists[0].ID, BoardID: &otherBoard.ID})
+	s.Require().NoError(err)
+
+	taskList, err := s.user.getTaskListRow(lists[0].ID)
+	s.Require().NoError(err)
+	s.False(taskList.Archived)
+	s.Equal(otherBoard.ID, taskList.BoardID)
+}
@@ -0,0 +1,710 @@
+package userservice
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/samber/lo"
+	"github.com/uptrace/bun"
+
+	"github.com/lesnoi-kot/karten-backend/src/store"
+)
+
+var ErrInvalidAutomationRule = errors.New("Invalid automation rule")
+
+// Rules triggered by the actions of other rules run up to this depth.
+const maxAutomationDepth = 5
+
+// Tasks of a timed rule loaded at once, a run goes through all of them page
+// by page.
+const timedAutomationPageSize = 500
+
+type AddAutomationRuleOptions struct {
+	BoardID    store.EntityID
+	Name       string
+	Enabled    bool
+	Trigger    store.AutomationTrigger
+	Conditions []*store.AutomationCondition
+	Actions    []*store.AutomationAction
+}
+
+type EditAutomationRuleOptions struct {
+	RuleID     store.EntityID
+	Name       *string
+	Enabled    *bool
+	Trigger    *store.AutomationTrigger
+	Conditions *[]*store.AutomationCondition
+	Actions    *[]*store.AutomationAction
+}
+
+type GetAutomationRunsOptions struct {
+	RuleID store.EntityID
+	Limit  int
+}
+
+// Something that happened to a task, rules of the board with the same
+// trigger get a chance to run.
+type AutomationEvent struct {
+	Trigger        string
+	BoardID        store.EntityID // The board of the task if empty
+	TaskID         store.EntityID
+	TaskListID     store.EntityID // The list the task is in after the event
+	FromTaskListID store.EntityID
+	LabelID        store.LabelID
+}
+
+// State of a task the conditions are checked against.
+type automationTask struct {
+	ID            store.EntityID     `bun:"id"`
+	TaskListID    store.EntityID     `bun:"task_list_id"`
+	BoardID       store.EntityID     `bun:"board_id"`
+	Priority      store.TaskPriority `bun:"priority"`
+	DueDate       *time.Time         `bun:"due_date"`
+	DateCreated   time.Time          `bun:"date_created"`
+	EnteredListAt time.Time          `bun:"entered_list_at"`
+	LabelIDs      []store.LabelID    `bun:"label_ids,array"`
+}
+
+// Rules applied in the current chain, carried by the context to the
+// mutations the actions make.
+type automationChain struct {
+	ruleIDs []store.EntityID
+}
+
+type automationChainKey struct{}
+
+func automationChainFrom(ctx context.Context) automationChain {
+	chain, _ := ctx.Value(automationChainKey{}).(automationChain)
+	return chain
+}
+
+func (user UserService) GetAutomationRules(boardID store.EntityID) ([]*store.AutomationRule, error) {
+	if owns, err := user.OwnsBoard(boardID); err != nil {
+		return nil, err
+	} else if !owns {
+		return nil, store.ErrNotFound
+	}
+
+	rules := make([]*store.AutomationRule, 0)
+	err := user.Store.ORM.NewSelect().
+		Model(&rules).
+		Where("board_id = ?", boardID).
+		Order("date_created").
+		Scan(user.Context)
+	if err != nil {
+		return nil, err
+	}
+
+	return rules, nil
+}
+
+func (user UserService) GetAutomationRule(ruleID store.EntityID) (*store.AutomationRule, error) {
+	rule := new(store.AutomationRule)
+	err := user.Store.ORM.NewSelect().
+		Model(rule).
+		Where("id = ?", ruleID).
+		Where("user_id = ?", user.UserID).
+		Scan(user.Context)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, store.ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	return rule, nil
+}
+
+func (user UserService) AddAutomationRule(args *AddAutomationRuleOptions) (*store.AutomationRule, error) {
+	if owns, err := user.OwnsBoard(args.BoardID); err != nil {
+		return nil, err
+	} else if !owns {
+		return nil, store.ErrNotFound
+	}
+
+	rule := &store.AutomationRule{
+		BoardID:    args.BoardID,
+		UserID:     user.UserID,
+		Name:       args.Name,
+		Enabled:    args.Enabled,
+		Trigger:    args.Trigger,
+		Conditions: lo.Ternary(args.Conditions != nil, args.Conditions, []*store.AutomationCondition{}),
+		Actions:    args.Actions,
+	}
+	if err := user.validateAutomationRule(rule); err != nil {
+		return nil, err
+	}
+
+	_, err := user.Store.ORM.NewInsert().
+		Model(rule).
+		Column("board_id", "user_id", "name", "enabled", "trigger", "conditions", "actions").
+		Returning("*").
+		Exec(user.Context)
+	if err != nil {
+		return nil, err
+	}
+
+	return rule, nil
+}
+
+func (user UserService) EditAutomationRule(args *EditAutomationRuleOptions) error {
+	rule, err := user.GetAutomationRule(args.RuleID)
+	if err != nil {
+		return err
+	}
+
+	if args.Name != nil && *args.Name != "" {
+		rule.Name = *args.Name
+	}
+	if args.Trigger != nil {
+		rule.Trigger = *args.Trigger
+	}
+	if args.Conditions != nil {
+		rule.Conditions = lo.Ternary(*args.Conditions != nil, *args.Conditions, []*store.AutomationCondition{})
+	}
+	if args.Actions != nil {
+		rule.Actions = *args.Actions
+	}
+	if err := user.validateAutomationRule(rule); err != nil {
+		return err
+	}
+
+	q := user.Store.ORM.NewUpdate().
+		Model((*store.AutomationRule)(nil)).
+		Set("name = ?", rule.Name).
+		Set("trigger = ?", rule.Trigger).
+		Set("conditions = ?", rule.Conditions).
+		Set("actions = ?", rule.Actions).
+		Where("id = ?", rule.ID)
+
+	if args.Enabled != nil {
+		q = q.Set("enabled = ?", *args.Enabled)
+
+		// Time based triggers don't catch up on what happened while the
+		// rule was off.
+		if *args.Enabled && !rule.Enabled {
+			q = q.Set("last_run_at = ?", time.Now().UTC())
+		}
+	}
+
+	_, err = q.Exec(user.Context)
+	return err
+}
+
+func (user UserService) DeleteAutomationRule(ruleID store.EntityID) error {
+	result, err := user.Store.ORM.NewDelete().
+		Model((*store.AutomationRule)(nil)).
+		Where("id = ?", ruleID).
+		Where("user_id = ?", user.UserID).
+		Exec(user.Context)
+	if err != nil {
+		return err
+	} else if store.NoRowsAffected(result) {
+		return store.ErrNotFound
+	}
+
+	return nil
+}
+
+// The execution log of the rule, latest runs first.
+func (user UserService) GetAutomationRuns(args *GetAutomationRunsOptions) ([]*store.AutomationRun, error) {
+	if _, err := user.GetAutomationRule(args.RuleID); err != nil {
+		return nil, err
+	}
+
+	runs := make([]*store.AutomationRun, 0)
+	err := user.Store.ORM.NewSelect().
+		Model(&runs).
+		Where("rule_id = ?", args.RuleID).
+		Order("date_created DESC", "id DESC").
+		Limit(args.Limit).
+		Scan(user.Context)
+	if err != nil {
+		return nil, err
+	}
+
+	return runs, nil
+}
+
+// Checks the parameters each kind of trigger, condition and action needs and
+// that the lists and labels they refer to are on the rule board.
+func (user UserService) validateAutomationRule(rule *store.AutomationRule) error {
+	invalid := func(format string, args ...any) error {
+		return fmt.Errorf("%w: "+format, append([]any{ErrInvalidAutomationRule}, args...)...)
+	}
+
+	var taskListIDs []store.EntityID
+	var labelIDs []store.LabelID
+
+	trigger := rule.Trigger
+	switch trigger.Type {
+	case store.AutomationTriggerTaskMoved:
+		taskListIDs = append(taskListIDs, trigger.TaskListID, trigger.FromTaskListID)
+	case store.AutomationTriggerLabelAdded:
+		labelIDs = append(labelIDs, trigger.LabelID)
+	case store.AutomationTriggerDueDatePassed:
+	case store.AutomationTriggerSchedule:
+		if trigger.IntervalHours <= 0 {
+			return invalid("schedule needs a positive interval")
+		}
+	default:
+		return invalid("unknown trigger %q", trigger.Type)
+	}
+
+	for _, condition := range rule.Conditions {
+		switch condition.Type {
+		case store.AutomationConditionInList, store.AutomationConditionNotInList:
+			if condition.TaskListID == "" {
+				return invalid("%s needs a list", condition.Type)
+			}
+			taskListIDs = append(taskListIDs, condition.TaskListID)
+		case store.AutomationConditionHasLabel, store.AutomationConditionLacksLabel:
+			if condition.LabelID == 0 {
+				return invalid("%s needs a label", condition.Type)
+			}
+			labelIDs = append(labelIDs, condition.LabelID)
+		case store.AutomationConditionMinPriority:
+		case store.AutomationConditionInListForDays:
+			if condition.Days <= 0 {
+				return invalid("%s needs a positive number of days", condition.Type)
+			}
+		case store.AutomationConditionOverdue:
+		default:
+			return invalid("unknown condition %q", condition.Type)
+		}
+	}
+
+	if len(rule.Actions) == 0 {
+		return invalid("no actions")
+	}
+	for _, action := range rule.Actions {
+		switch action.Type {
+		case store.AutomationActionMove:
+			if action.TaskListID == "" {
+				return invalid("%s needs a list", action.Type)
+			}
+			taskListIDs = append(taskListIDs, action.TaskListID)
+		case store.AutomationActionAddLabel, store.AutomationActionRemoveLabel:
+			if action.LabelID == 0 {
+				return invalid("%s needs a label", action.Type)
+			}
+			labelIDs = append(labelIDs, action.LabelID)
+		case store.AutomationActionSetDueDate, store.AutomationActionArchive:
+		case store.AutomationActionComment:
+			if action.Text == "" {
+				return invalid("%s needs a text", action.Type)
+			}
+		default:
+			return invalid("unknown action %q", action.Type)
+		}
+	}
+
+	taskListIDs = lo.Uniq(lo.Compact(taskListIDs))
+	if len(taskListIDs) > 0 {
+		count, err := user.Store.ORM.NewSelect().
+			Model((*store.TaskList)(nil)).
+			Where("id IN (?)", bun.In(taskListIDs)).
+			Where("board_id = ?", rule.BoardID).
+			Where("deleted_at IS NULL").
+			Count(user.Context)
+		if err != nil {
+			return err
+		} else if count != len(taskListIDs) {
+			return invalid("unknown list")
+		}
+	}
+
+	labelIDs = lo.Uniq(lo.Compact(labelIDs))
+	if len(labelIDs) > 0 {
+		count, err := user.Store.ORM.NewSelect().
+			Model((*store.Label)(nil)).
+			Where("id IN (?)", bun.In(labelIDs)).
+			Where("board_id = ?", rule.BoardID).
+			Count(user.Context)
+		if err != nil {
+			return err
+		} else if count != len(labelIDs) {
+			return invalid("unknown label")
+		}
+	}
+
+	return nil
+}
+
+// Runs the automations of a mutation which is already saved. Failing the call
+// would hide that it went through, so their errors are only logged.
+func (user UserService) runSavedAutomations(event *AutomationEvent) {
+	if err := user.runAutomations(event); err != nil {
+		user.logger().Errorw("Automations failed",
+			"trigger", event.Trigger,
+			"task_id", event.TaskID,
+			"error", err,
+		)
+	}
+}
+
+// Runs the enabled rules of the board the event trigger. Failed actions end
+// up in the execution log instead of failing the mutation that caused them.
+func (user UserService) runAutomations(event *AutomationEvent) error {
+	if event.BoardID == "" {
+		boardID, err := user.taskBoardID(event.TaskID)
+		if err != nil {
+			return err
+		}
+		event.BoardID = boardID
+	}
+
+	var rules []*store.AutomationRule
+	err := user.Store.ORM.NewSelect().
+		Model(&rules).
+		Where("board_id = ?", event.BoardID).
+		Where("enabled = ?", true).
+		Where("trigger->>'type' = ?", event.Trigger).
+		Order("date_created").
+		Scan(user.Context)
+	if err != nil || len(rules) == 0 {
+		return err
+	}
+
+	rules = lo.Filter(rules, func(rule *store.AutomationRule, _ int) bool {
+		return automationTriggerMatches(&rule.Trigger, event)
+	})
+	if len(rules) == 0 {
+		return nil
+	}
+
+	task, err := user.getAutomationTask(event.TaskID)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	for _, rule := range rules {
+		if err := user.applyAutomationRule(rule, task, time.Now().UTC()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func automationTriggerMatches(trigger *store.AutomationTrigger, event *AutomationEvent) bool {
+	switch trigger.Type {
+	case store.AutomationTriggerTaskMoved:
+		return (trigger.TaskListID == "" || trigger.TaskListID == event.TaskListID) &&
+			(trigger.FromTaskListID == "" || trigger.FromTaskListID == event.FromTaskListID)
+	case store.AutomationTriggerLabelAdded:
+		return trigger.LabelID == 0 || trigger.LabelID == event.LabelID
+	}
+
+	return true
+}
+
+func automationConditionsHold(
+	conditions []*store.AutomationCondition,
+	task *automationTask,
+	now time.Time,
+) bool {
+	for _, condition := range conditions {
+		var holds bool
+
+		switch condition.Type {
+		case store.AutomationConditionInList:
+			holds = task.TaskListID == condition.TaskListID
+		case store.AutomationConditionNotInList:
+			holds = task.TaskListID != condition.TaskListID
+		case store.AutomationConditionHasLabel:
+			holds = lo.Contains(task.LabelIDs, condition.LabelID)
+		case store.AutomationConditionLacksLabel:
+			holds = !lo.Contains(task.LabelIDs, condition.LabelID)
+		case store.AutomationConditionMinPriority:
+			holds = task.Priority >= condition.Priority
+		case store.AutomationConditionInListForDays:
+			holds = !task.EnteredListAt.AddDate(0, 0, condition.Days).After(now)
+		case store.AutomationConditionOverdue:
+			holds = task.DueDate != nil && task.DueDate.Before(now)
+		}
+
+		if !holds {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Applies the actions of the rule to the task if its conditions hold, and
+// logs the run.
+func (user UserService) applyAutomationRule(
+	rule *store.AutomationRule,
+	task *automationTask,
+	now time.Time,
+) error {
+	if !automationConditionsHold(rule.Conditions, task, now) {
+		return nil
+	}
+
+	chain := automationChainFrom(user.Context)
+	depth := len(chain.ruleIDs)
+
+	if lo.Contains(chain.ruleIDs, rule.ID) {
+		return user.logAutomationRun(rule, task.ID, depth, now, store.AutomationRunSkipped,
+			"The rule has already run in this chain")
+	}
+	if depth >= maxAutomationDepth {
+		return user.logAutomationRun(rule, task.ID, depth, now, store.AutomationRunSkipped,
+			fmt.Sprintf("Rules can't trigger each other deeper than %d levels", maxAutomationDepth))
+	}
+
+	actor := user
+	actor.Context = context.WithValue(user.Context, automationChainKey{}, automationChain{
+		ruleIDs: append(append([]store.EntityID(nil), chain.ruleIDs...), rule.ID),
+	})
+
+	for _, action := range rule.Actions {
+		if err := actor.applyAutomationAction(action, task, now); err != nil {
+			return user.logAutomationRun(rule, task.ID, depth, now, store.AutomationRunFailed, err.Error())
+		}
+	}
+
+	return user.logAutomationRun(rule, task.ID, depth, now, store.AutomationRunSuccess, "")
+}
+
+func (user UserService) applyAutomationAction(
+	action *store.AutomationAction,
+	task *automationTask,
+	now time.Time,
+) error {
+	switch action.Type {
+	case store.AutomationActionMove:
+		if task.TaskListID == action.TaskListID {
+			return nil
+		}
+
+		err := user.EditTask(&EditTaskOptions{TaskID: task.ID, TaskListID: &action.TaskListID})
+		if err == nil {
+			task.TaskListID = action.TaskListID
+			task.EnteredListAt = now
+		}
+		return err
+
+	case store.AutomationActionAddLabel:
+		if lo.Contains(task.LabelIDs, action.LabelID) {
+			return nil
+		}
+
+		err := user.AddLabelToTask(&AddLabelToTaskOptions{TaskID: task.ID, LabelID: action.LabelID})
+		if err == nil {
+			task.LabelIDs = append(task.LabelIDs, action.LabelID)
+		}
+		return err
+
+	case store.AutomationActionRemoveLabel:
+		err := user.DeleteLabelFromTask(&AddLabelToTaskOptions{TaskID: task.ID, LabelID: action.LabelID})
+		if err == nil {
+			task.LabelIDs = lo.Without(task.LabelIDs, action.LabelID)
+		}
+		return err
+
+	case store.AutomationActionSetDueDate:
+		// No days clear the due date.
+		var dueDate time.Time
+		if action.Days != nil {
+			dueDate = now.AddDate(0, 0, *action.Days)
+		}
+
+		err := user.EditTask(&EditTaskOptions{TaskID: task.ID, DueDate: &dueDate})
+		if err == nil {
+			task.DueDate = lo.Ternary(dueDate.IsZero(), nil, &dueDate)
+		}
+		return err
+
+	case store.AutomationActionComment:
+		_, err := user.AddComment(&AddCommentOptions{TaskID: task.ID, Text: action.Text})
+		return err
+
+	case store.AutomationActionArchive:
+		return user.ArchiveTask(task.ID)
+	}
+
+	return fmt.Errorf("%w: unknown action %q", ErrInvalidAutomationRule, action.Type)
+}
+
+func (user UserService) logAutomationRun(
+	rule *store.AutomationRule,
+	taskID store.EntityID,
+	depth int,
+	now time.Time,
+	status string,
+	message string,
+) error {
+	_, err := user.Store.ORM.NewInsert().
+		Model(&store.AutomationRun{
+			RuleID:      rule.ID,
+			TaskID:      lo.ToPtr(taskID),
+			Trigger:     rule.Trigger.Type,
+			Status:      status,
+			Error:       message,
+			Depth:       depth,
+			DateCreated: now,
+		}).
+		Column("rule_id", "task_id", "trigger", "status", "error", "depth", "date_created").
+		Exec(user.Context)
+
+	return err
+}
+
+func automationTasksQuery(db bun.IDB) *bun.SelectQuery {
+	return db.NewSelect().
+		TableExpr("tasks AS t").
+		ColumnExpr("t.id, t.task_list_id, tl.board_id, t.priority, t.due_date, t.date_created").
+		ColumnExpr(`coalesce((
+			SELECT max(m.date_created) FROM task_movements AS m
+			WHERE m.task_id = t.id AND m.to_task_list_id = t.task_list_id
+		), t.date_created) AS entered_list_at`).
+		ColumnExpr("array(SELECT tlb.label_id FROM task_labels AS tlb WHERE tlb.task_id = t.id) AS label_ids").
+		Join("JOIN task_lists AS tl ON tl.id = t.task_list_id").
+		Where("t.deleted_at IS NULL")
+}
+
+func (user UserService) getAutomationTask(taskID store.EntityID) (*automationTask, error) {
+	task := new(automationTask)
+	err := automationTasksQuery(user.Store.ORM).
+		Where("t.id = ?", taskID).
+		Where("t.user_id = ?", user.UserID).
+		Scan(user.Context, task)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, store.ErrNotFound
+	}
+
+	return task, err
+}
+
+// Fires the due date and schedule rules of all users whose time has come.
+// Returns the number of rules run.
+func RunTimedAutomations(ctx context.Context, s *store.Store, now time.Time) (int, error) {
+	var rules []*store.AutomationRule
+	err := s.ORM.NewSelect().
+		Model(&rules).
+		Where("enabled = ?", true).
+		Where("trigger->>'type' IN (?)", bun.In([]string{
+			store.AutomationTriggerDueDatePassed,
+			store.AutomationTriggerSchedule,
+		})).
+		Order("date_created").
+		Scan(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	ran := 0
+	for _, rule := range rules {
+		if rule.Trigger.Type == store.AutomationTriggerSchedule && rule.LastRunAt != nil &&
+			now.Sub(*rule.LastRunAt) < time.Duration(rule.Trigger.IntervalHours)*time.Hour {
+			continue
+		}
+
+		claimed, err := runTimedAutomationRule(ctx, s, rule, now)
+		if err != nil {
+			return ran, err
+		}
+		if claimed {
+			ran++
+		}
+	}
+
+	return ran, nil
+}
+
+// Runs the rule over all the matching tasks of its board. The rule row stays
+// locked during the run, so concurrent servers skip it, and last_run_at moves
+// only after every task is handled. The actions commit on their own, so when
+// a run fails the next one picks up the tasks without a logged run since
+// last_run_at. Returns false if another server has the rule or has already
+// run it.
+func runTimedAutomationRule(ctx context.Context, s *store.Store, rule *store.AutomationRule, now time.Time) (bool, error) {
+	claimed := false
+
+	err := s.ORM.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		// Unlike FOR UPDATE, this lock lets the run log reference the rule.
+		var ruleID store.EntityID
+		err := tx.NewSelect().
+			Model((*store.AutomationRule)(nil)).
+			Column("id").
+			Where("id = ?", rule.ID).
+			Where("last_run_at IS NOT DISTINCT FROM ?", rule.LastRunAt).
+			For("NO KEY UPDATE SKIP LOCKED").
+			Scan(ctx, &ruleID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		claimed = true
+
+		user := UserService{Context: ctx, UserID: rule.UserID, Store: s}
+		var last *automationTask
+
+		for {
+			q := automationTasksQuery(tx).
+				Join("JOIN boards AS b ON b.id = tl.board_id").
+				Where("tl.board_id = ?", rule.BoardID).
+				Where("t.archived = ?", false).
+				Where("tl.archived = ?", false).
+				Where("tl.deleted_at IS NULL").
+				Where("b.archived = ?", false).
+				Where("b.deleted_at IS NULL").
+				OrderExpr("t.date_created, t.id").
+				Limit(timedAutomationPageSize)
+
+			if rule.Trigger.Type == store.AutomationTriggerDueDatePassed {
+				since := rule.DateCreated
+				if rule.LastRunAt != nil {
+					since = *rule.LastRunAt
+				}
+				q = q.Where("t.due_date > ?", since).Where("t.due_date <= ?", now)
+			}
+			if last != nil {
+				q = q.Where("(t.date_created, t.id) > (?, ?)", last.DateCreated, last.ID)
+			}
+
+			handled := tx.NewSelect().
+				Model((*store.AutomationRun)(nil)).
+				Where("rule_id = ?", rule.ID).
+				Where("task_id = t.id").
+				Where("trigger = ?", rule.Trigger.Type)
+			if rule.LastRunAt != nil {
+				handled = handled.Where("date_created > ?", *rule.LastRunAt)
+			}
+			q = q.Where("NOT EXISTS (?)", handled)
+
+			var tasks []*automationTask
+			if err := q.Scan(ctx, &tasks); err != nil {
+				return err
+			}
+
+			for _, task := range tasks {
+				if err := user.applyAutomationRule(rule, task, now); err != nil {
+					return err
+				}
+			}
+
+			if len(tasks) < timedAutomationPageSize {
+				break
+			}
+			last = tasks[len(tasks)-1]
+		}
+
+		_, err = tx.NewUpdate().
+			Model(rule).
+			Set("last_run_at = ?", now).
+			WherePK().
+			Exec(ctx)
+		return err
+	})
+
+	return claimed, err
+}
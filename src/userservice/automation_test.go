@@ -0,0 +1,211 @@
+package userservice
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lesnoi-kot/karten-backend/src/store"
+)
+
+func TestAutomationTriggerMatches(t *testing.T) {
+	moved := &AutomationEvent{Trigger: store.AutomationTriggerTaskMoved, TaskListID: "done", FromTaskListID: "todo"}
+	labeled := &AutomationEvent{Trigger: store.AutomationTriggerLabelAdded, LabelID: 7}
+
+	tests := []struct {
+		name    string
+		trigger store.AutomationTrigger
+		event   *AutomationEvent
+		matches bool
+	}{
+		{"moved anywhere", store.AutomationTrigger{Type: store.AutomationTriggerTaskMoved}, moved, true},
+		{"moved to the list", store.AutomationTrigger{Type: store.AutomationTriggerTaskMoved, TaskListID: "done"}, moved, true},
+		{"moved to another list", store.AutomationTrigger{Type: store.AutomationTriggerTaskMoved, TaskListID: "todo"}, moved, false},
+		{"moved from the list", store.AutomationTrigger{Type: store.AutomationTriggerTaskMoved, FromTaskListID: "todo"}, moved, true},
+		{"moved from another list", store.AutomationTrigger{Type: store.AutomationTriggerTaskMoved, FromTaskListID: "done"}, moved, false},
+		{"moved between the lists", store.AutomationTrigger{Type: store.AutomationTriggerTaskMoved, FromTaskListID: "todo", TaskListID: "done"}, moved, true},
+		{"any label", store.AutomationTrigger{Type: store.AutomationTriggerLabelAdded}, labeled, true},
+		{"the label", store.AutomationTrigger{Type: store.AutomationTriggerLabelAdded, LabelID: 7}, labeled, true},
+		{"another label", store.AutomationTrigger{Type: store.AutomationTriggerLabelAdded, LabelID: 8}, labeled, false},
+		{"due date passed", store.AutomationTrigger{Type: store.AutomationTriggerDueDatePassed}, &AutomationEvent{}, true},
+		{"schedule", store.AutomationTrigger{Type: store.AutomationTriggerSchedule, IntervalHours: 1}, &AutomationEvent{}, true},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.matches, automationTriggerMatches(&test.trigger, test.event), test.name)
+	}
+}
+
+func TestAutomationConditionsHold(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	task := &automationTask{
+		TaskListID:    "doing",
+		Priority:      store.TaskPriorityHigh,
+		DueDate:       lo.ToPtr(now.Add(-time.Minute)),
+		EnteredListAt: now.AddDate(0, 0, -3),
+		LabelIDs:      []store.LabelID{1, 2},
+	}
+
+	inList := &store.AutomationCondition{Type: store.AutomationConditionInList, TaskListID: "doing"}
+	notInList := &store.AutomationCondition{Type: store.AutomationConditionNotInList, TaskListID: "doing"}
+	hasLabel := &store.AutomationCondition{Type: store.AutomationConditionHasLabel, LabelID: 2}
+	lacksLabel := &store.AutomationCondition{Type: store.AutomationConditionLacksLabel, LabelID: 3}
+	highPriority := &store.AutomationCondition{Type: store.AutomationConditionMinPriority, Priority: store.TaskPriorityHigh}
+	urgent := &store.AutomationCondition{Type: store.AutomationConditionMinPriority, Priority: store.TaskPriorityUrgent}
+	threeDays := &store.AutomationCondition{Type: store.AutomationConditionInListForDays, Days: 3}
+	fourDays := &store.AutomationCondition{Type: store.AutomationConditionInListForDays, Days: 4}
+	overdue := &store.AutomationCondition{Type: store.AutomationConditionOverdue}
+
+	tests := []struct {
+		name       string
+		conditions []*store.AutomationCondition
+		holds      bool
+	}{
+		{"no conditions", nil, true},
+		{"in list", []*store.AutomationCondition{inList}, true},
+		{"not in list", []*store.AutomationCondition{notInList}, false},
+		{"has label", []*store.AutomationCondition{hasLabel}, true},
+		{"lacks label", []*store.AutomationCondition{lacksLabel}, true},
+		{"has a missing label", []*store.AutomationCondition{{Type: store.AutomationConditionHasLabel, LabelID: 3}}, false},
+		{"lacks a present label", []*store.AutomationCondition{{Type: store.AutomationConditionLacksLabel, LabelID: 1}}, false},
+		{"priority at the minimum", []*store.AutomationCondition{highPriority}, true},
+		{"priority below the minimum", []*store.AutomationCondition{urgent}, false},
+		{"in list exactly for the days", []*store.AutomationCondition{threeDays}, true},
+		{"in list for fewer days", []*store.AutomationCondition{fourDays}, false},
+		{"overdue", []*store.AutomationCondition{overdue}, true},
+		{"all hold", []*store.AutomationCondition{inList, hasLabel, lacksLabel, highPriority, threeDays, overdue}, true},
+		{"one fails", []*store.AutomationCondition{inList, hasLabel, urgent, overdue}, false},
+		{"unknown condition", []*store.AutomationCondition{{Type: "unknown"}}, false},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.holds, automationConditionsHold(test.conditions, task, now), test.name)
+	}
+
+	assert.False(t, automationConditionsHold([]*store.AutomationCondition{overdue}, &automationTask{}, now),
+		"no due date")
+	assert.False(t, automationConditionsHold([]*store.AutomationCondition{overdue}, &automationTask{DueDate: &now}, now),
+		"due right now")
+}
+
+func (s *userServiceSuite) addTimedRule(boardID store.EntityID, trigger store.AutomationTrigger, actions ...*store.AutomationAction) *store.AutomationRule {
+	rule, err := s.user.AddAutomationRule(&AddAutomationRuleOptions{
+		BoardID: boardID,
+		Name:    "Rule",
+		Enabled: true,
+		Trigger: trigger,
+		Actions: actions,
+	})
+	s.Require().NoError(err)
+
+	return rule
+}
+
+func (s *userServiceSuite) countComments(taskID store.EntityID) int {
+	count, err := s.store.ORM.NewSelect().
+		Model((*store.Comment)(nil)).
+		Where("task_id = ?", taskID).
+		Count(context.Background())
+	s.Require().NoError(err)
+
+	return count
+}
+
+func (s *userServiceSuite) TestDueDatePassedWindow() {
+	board, lists := s.addBoard("Todo")
+	rule := s.addTimedRule(board.ID, store.AutomationTrigger{Type: store.AutomationTriggerDueDatePassed},
+		&store.AutomationAction{Type: store.AutomationActionComment, Text: "Overdue"})
+
+	since := time.Now().UTC().Truncate(time.Second).Add(-time.Hour)
+	now := since.Add(time.Hour)
+	_, err := s.store.ORM.NewUpdate().
+		Model(rule).
+		Set("last_run_at = ?", since).
+		WherePK().
+		Exec(context.Background())
+	s.Require().NoError(err)
+
+	due := func(name string, date time.Time) *store.Task {
+		task, err := s.user.AddTask(&AddTaskOptions{TaskListID: lists[0].ID, Name: name, DueDate: &date})
+		s.Require().NoError(err)
+		return task
+	}
+	atSince := due("At the window start", since)
+	afterSince := due("After the window start", since.Add(time.Second))
+	atNow := due("At the window end", now)
+	afterNow := due("After the window end", now.Add(time.Second))
+
+	_, err = RunTimedAutomations(context.Background(), s.store, now)
+	s.Require().NoError(err)
+
+	s.Zero(s.countComments(atSince.ID), "the previous run has seen it")
+	s.Equal(1, s.countComments(afterSince.ID))
+	s.Equal(1, s.countComments(atNow.ID))
+	s.Zero(s.countComments(afterNow.ID))
+
+	// The next run starts where this one ended.
+	_, err = RunTimedAutomations(context.Background(), s.store, now.Add(time.Second))
+	s.Require().NoError(err)
+
+	s.Equal(1, s.countComments(atNow.ID))
+	s.Equal(1, s.countComments(afterNow.ID))
+}
+
+func (s *userServiceSuite) TestTimedRuleResumesFailedRun() {
+	board, lists := s.addBoard("Todo")
+	rule := s.addTimedRule(board.ID, store.AutomationTrigger{Type: store.AutomationTriggerSchedule, IntervalHours: 1},
+		&store.AutomationAction{Type: store.AutomationActionComment, Text: "Ping"})
+
+	first := s.addTask(lists[0].ID, "First")
+	second := s.addTask(lists[0].ID, "Second")
+
+	// A run that handled the first task and failed on the second one.
+	now := time.Now().UTC()
+	task, err := s.user.getAutomationTask(first.ID)
+	s.Require().NoError(err)
+	s.Require().NoError(s.user.applyAutomationRule(rule, task, now.Add(-time.Minute)))
+
+	_, err = RunTimedAutomations(context.Background(), s.store, now)
+	s.Require().NoError(err)
+	s.Equal(1, s.countComments(first.ID), "the first task is not acted on twice")
+	s.Equal(1, s.countComments(second.ID))
+
+	rule, err = s.user.GetAutomationRule(rule.ID)
+	s.Require().NoError(err)
+	s.Require().NotNil(rule.LastRunAt)
+	s.True(rule.LastRunAt.Equal(now.Truncate(time.Microsecond)))
+
+	// The next interval is a new run over all the tasks.
+	_, err = RunTimedAutomations(context.Background(), s.store, now.Add(time.Hour))
+	s.Require().NoError(err)
+	s.Equal(2, s.countComments(first.ID))
+	s.Equal(2, s.countComments(second.ID))
+}
+
+func (s *userServiceSuite) TestSetDueDateAction() {
+	board, lists := s.addBoard("Todo", "Done")
+	task := s.addTask(lists[0].ID, "Task")
+	rule := s.addTimedRule(board.ID, store.AutomationTrigger{Type: store.AutomationTriggerSchedule, IntervalHours: 1},
+		&store.AutomationAction{Type: store.AutomationActionSetDueDate, Days: lo.ToPtr(2)})
+
+	now := time.Now().UTC().Truncate(time.Second)
+	_, err := RunTimedAutomations(context.Background(), s.store, now)
+	s.Require().NoError(err)
+
+	dueDate := s.getTask(task.ID).DueDate
+	s.Require().NotNil(dueDate)
+	s.True(dueDate.Equal(now.AddDate(0, 0, 2)))
+
+	err = s.user.EditAutomationRule(&EditAutomationRuleOptions{
+		RuleID:  rule.ID,
+		Actions: &[]*store.AutomationAction{{Type: store.AutomationActionSetDueDate}},
+	})
+	s.Require().NoError(err)
+
+	_, err = RunTimedAutomations(context.Background(), s.store, now.Add(time.Hour))
+	s.Require().NoError(err)
+	s.Nil(s.getTask(task.ID).DueDate, "no days clear the due date")
+}
@@ -0,0 +1,193 @@
+package userservice
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"time"
+
+	"github.com/samber/lo"
+	"github.com/uptrace/bun"
+
+	"github.com/lesnoi-kot/karten-backend/src/filestorage"
+	"github.com/lesnoi-kot/karten-backend/src/modules/boarddoc"
+	"github.com/lesnoi-kot/karten-backend/src/store"
+	"github.com/lesnoi-kot/karten-backend/src/urlprovider"
+)
+
+type GetBoardDocumentOptions struct {
+	BoardID         store.EntityID
+	IncludeArchived bool
+
+	// Thumbnails are inlined as data URLs when given, otherwise they are
+	// linked.
+	FileStorage filestorage.FileStorage
+}
+
+// Board with its lists, tasks, comments and attachments for rendering as a
+// document.
+func (user UserService) GetBoardDocument(args *GetBoardDocumentOptions) (*boarddoc.Board, error) {
+	archivedFilter := func(q *bun.SelectQuery, column string) *bun.SelectQuery {
+		if args.IncludeArchived {
+			return q
+		}
+		return q.Where(column+" = ?", false)
+	}
+
+	board := new(store.Board)
+	err := user.Store.ORM.NewSelect().
+		Model(board).
+		Where("board.id = ?", args.BoardID).
+		Where("board.user_id = ?", user.UserID).
+		Relation("Project", func(q *bun.SelectQuery) *bun.SelectQuery {
+			return q.Column("name", "key_prefix")
+		}).
+		Relation("TaskLists", func(q *bun.SelectQuery) *bun.SelectQuery {
+			return archivedFilter(q, "task_list.archived").Order("task_list.position", "task_list.date_created")
+		}).
+		Relation("TaskLists.Tasks", func(q *bun.SelectQuery) *bun.SelectQuery {
+			return archivedFilter(q, "task.archived").Order("task.position", "task.date_created")
+		}).
+		Relation("TaskLists.Tasks.Labels", func(q *bun.SelectQuery) *bun.SelectQuery {
+			return q.Order("label.name")
+		}).
+		Relation("TaskLists.Tasks.Attachments").
+		Relation("TaskLists.Tasks.Comments", func(q *bun.SelectQuery) *bun.SelectQuery {
+			return q.Order("comment.date_created")
+		}).
+		Relation("TaskLists.Tasks.Comments.Author", func(q *bun.SelectQuery) *bun.SelectQuery {
+			return q.Column("name", "login")
+		}).
+		Relation("TaskLists.Tasks.Comments.Attachments").
+		Scan(user.Context)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, store.ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	var imageIDs []store.FileID
+	for _, taskList := range board.TaskLists {
+		for _, task := range taskList.Tasks {
+			for _, file := range task.Attachments {
+				if file.IsImage() {
+					imageIDs = append(imageIDs, file.ID)
+				}
+			}
+			for _, comment := range task.Comments {
+				for _, file := range comment.Attachments {
+					if file.IsImage() {
+						imageIDs = append(imageIDs, file.ID)
+					}
+				}
+			}
+		}
+	}
+
+	thumbnailURLs, err := user.getThumbnailURLs(lo.Uniq(imageIDs), args.FileStorage)
+	if err != nil {
+		return nil, err
+	}
+
+	attachments := func(files []*store.File) []*boarddoc.Attachment {
+		return lo.Map(files, func(file *store.File, _ int) *boarddoc.Attachment {
+			return &boarddoc.Attachment{
+				Name:         file.Name,
+				URL:          urlprovider.GetFileURL(file),
+				ThumbnailURL: thumbnailURLs[file.ID],
+			}
+		})
+	}
+
+	var keyPrefix string
+	doc := &boarddoc.Board{
+		Name:       board.Name,
+		ExportedAt: time.Now().UTC(),
+		Lists:      make([]*boarddoc.List, 0, len(board.TaskLists)),
+	}
+	if board.Project != nil {
+		doc.Project = board.Project.Name
+		keyPrefix = board.Project.KeyPrefix
+	}
+
+	for _, taskList := range board.TaskLists {
+		list := &boarddoc.List{
+			Name:     taskList.Name,
+			Archived: taskList.Archived,
+			Tasks:    make([]*boarddoc.Task, 0, len(taskList.Tasks)),
+		}
+
+		for _, task := range taskList.Tasks {
+			list.Tasks = append(list.Tasks, &boarddoc.Task{
+				Key:       store.FormatTaskKey(keyPrefix, task.Number),
+				Name:      task.Name,
+				Text:      task.Text,
+				Archived:  task.Archived,
+				StartDate: task.StartDate,
+				DueDate:   task.DueDate,
+				Labels: lo.Map(task.Labels, func(label *store.Label, _ int) string {
+					return label.Name
+				}),
+				Attachments: attachments(task.Attachments),
+				Comments: lo.Map(task.Comments, func(comment *store.Comment, _ int) *boarddoc.Comment {
+					author := ""
+					if comment.Author != nil {
+						author = lo.Ternary(comment.Author.Name != "", comment.Author.Name, comment.Author.Login)
+					}
+
+					return &boarddoc.Comment{
+						Author:      author,
+						DateCreated: comment.DateCreated,
+						Text:        comment.Text,
+						Attachments: attachments(comment.Attachments),
+					}
+				}),
+			})
+		}
+
+		doc.Lists = append(doc.Lists, list)
+	}
+
+	return doc, nil
+}
+
+// URLs of a thumbnail of every image which has one, by the image IDs.
+func (user UserService) getThumbnailURLs(
+	imageIDs []store.FileID,
+	fileStorage filestorage.FileStorage,
+) (map[store.FileID]string, error) {
+	urls := make(map[store.FileID]string)
+	if len(imageIDs) == 0 {
+		return urls, nil
+	}
+
+	var thumbnails []*store.ImageThumbnailAssoc
+	err := user.Store.ORM.NewSelect().
+		Model(&thumbnails).
+		Relation("Thumbnail").
+		Where("image_thumbnail_assoc.image_id IN (?)", bun.In(imageIDs)).
+		Scan(user.Context)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, thumbnail := range thumbnails {
+		if thumbnail.Thumbnail == nil || urls[thumbnail.ImageID] != "" {
+			continue
+		}
+
+		if fileStorage == nil {
+			urls[thumbnail.ImageID] = urlprovider.GetFileURL(thumbnail.Thumbnail)
+			continue
+		}
+
+		data, err := fileStorage.Get(thumbnail.Thumbnail.StorageObjectID)
+		if err != nil {
+			return nil, err
+		}
+		urls[thumbnail.ImageID] = "data:" + thumbnail.Thumbnail.MimeType + ";base64," +
+			base64.StdEncoding.EncodeToString(data)
+	}
+
+	return urls, nil
+}
@@ -0,0 +1,442 @@
+package userservice
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/uptrace/bun"
+
+	"github.com/lesnoi-kot/karten-backend/src/filestorage"
+	"github.com/lesnoi-kot/karten-backend/src/modules/boardarchive"
+	"github.com/lesnoi-kot/karten-backend/src/store"
+)
+
+type ExportBoardOptions struct {
+	BoardID     store.EntityID
+	FileStorage filestorage.FileStorage
+	Writer      io.Writer
+}
+
+type ImportBoardOptions struct {
+	ProjectID   store.EntityID
+	FileStorage filestorage.FileStorage
+	Archive     io.ReaderAt
+	Size        int64
+}
+
+// Writes the board with its lists, tasks, labels, comments, attachments and
+// cover as a zip archive. Archived lists and tasks are included.
+func (user UserService) ExportBoard(args *ExportBoardOptions) error {
+	board := new(store.Board)
+	err := user.Store.ORM.NewSelect().
+		Model(board).
+		Where("board.id = ?", args.BoardID).
+		Where("board.user_id = ?", user.UserID).
+		Relation("Cover").
+		Relation("Labels", func(q *bun.SelectQuery) *bun.SelectQuery {
+			return q.Order("label.id")
+		}).
+		Relation("TaskLists", func(q *bun.SelectQuery) *bun.SelectQuery {
+			return q.Order("task_list.position", "task_list.date_created")
+		}).
+		Relation("TaskLists.Tasks", func(q *bun.SelectQuery) *bun.SelectQuery {
+			return q.Order("task.position", "task.date_created")
+		}).
+		Relation("TaskLists.Tasks.Labels").
+		Relation("TaskLists.Tasks.Attachments").
+		Relation("TaskLists.Tasks.Comments", func(q *bun.SelectQuery) *bun.SelectQuery {
+			return q.Order("comment.date_created")
+		}).
+		Relation("TaskLists.Tasks.Comments.Attachments").
+		Scan(user.Context)
+	if errors.Is(err, sql.ErrNoRows) {
+		return store.ErrNotFound
+	} else if err != nil {
+		return err
+	}
+
+	doc := &boardarchive.Document{
+		Version:    boardarchive.Version,
+		ExportedAt: time.Now().UTC(),
+		Board: boardarchive.Board{
+			Name:         board.Name,
+			Color:        board.Color,
+			EstimateUnit: board.EstimateUnit,
+			Labels:       make([]*boardarchive.Label, 0, len(board.Labels)),
+			TaskLists:    make([]*boardarchive.TaskList, 0, len(board.TaskLists)),
+		},
+		Files: make([]*boardarchive.File, 0),
+	}
+
+	// Storage objects of the document files by their refs.
+	storageIDs := make(map[string]filestorage.FileID)
+	fileRef := func(file *store.File) string {
+		if _, ok := storageIDs[file.ID]; !ok {
+			storageIDs[file.ID] = file.StorageObjectID
+			doc.Files = append(doc.Files, &boardarchive.File{
+				Ref:      file.ID,
+				Name:     file.Name,
+				MimeType: file.MimeType,
+				Size:     file.Size,
+			})
+		}
+
+		return file.ID
+	}
+	fileRefs := func(files []*store.File) []string {
+		refs := make([]string, 0, len(files))
+		for _, file := range files {
+			refs = append(refs, fileRef(file))
+		}
+		return refs
+	}
+
+	if board.Cover != nil {
+		doc.Board.CoverRef = fileRef(board.Cover)
+	}
+
+	for _, label := range board.Labels {
+		doc.Board.Labels = append(doc.Board.Labels, &boardarchive.Label{
+			Ref:   strconv.Itoa(label.ID),
+			Name:  label.Name,
+			Color: label.Color,
+		})
+	}
+
+	for _, taskList := range board.TaskLists {
+		docTaskList := &boardarchive.TaskList{
+			Ref:      taskList.ID,
+			Name:     taskList.Name,
+			Position: taskList.Position,
+			Color:    taskList.Color,
+			Archived: taskList.Archived,
+			WIPLimit: taskList.WIPLimit,
+			WIPMode:  taskList.WIPMode,
+			Tasks:    make([]*boardarchive.Task, 0, len(taskList.Tasks)),
+		}
+
+		for _, task := range taskList.Tasks {
+			docTask := &boardarchive.Task{
+				Ref:            task.ID,
+				Name:           task.Name,
+				Text:           task.Text,
+				Position:       task.Position,
+				Archived:       task.Archived,
+				SpentTime:      task.SpentTime,
+				DateCreated:    task.DateCreated,
+				StartDate:      task.StartDate,
+				DueDate:        task.DueDate,
+				Estimate:       task.Estimate,
+				Priority:       task.Priority,
+				AttachmentRefs: fileRefs(task.Attachments),
+			}
+
+			for _, label := range task.Labels {
+				docTask.LabelRefs = append(docTask.LabelRefs, strconv.Itoa(label.ID))
+			}
+
+			for _, comment := range task.Comments {
+				docTask.Comments = append(docTask.Comments, &boardarchive.Comment{
+					Text:           comment.Text,
+					DateCreated:    comment.DateCreated,
+					AttachmentRefs: fileRefs(comment.Attachments),
+				})
+			}
+
+			docTaskList.Tasks = append(docTaskList.Tasks, docTask)
+		}
+
+		doc.Board.TaskLists = append(doc.Board.TaskLists, docTaskList)
+	}
+
+	return boardarchive.Write(args.Writer, doc, func(file *boardarchive.File) ([]byte, error) {
+		return args.FileStorage.Get(storageIDs[file.Ref])
+	})
+}
+
+// Rebuilds the archived board in the project with new IDs. Either the whole
+// board is imported or nothing is, blobs stored for a failed import are
+// removed.
+func (user UserService) ImportBoard(args *ImportBoardOptions) (_ *store.Board, err error) {
+	if owns, err := user.OwnsProject(args.ProjectID); err != nil {
+		return nil, err
+	} else if !owns {
+		return nil, store.ErrNotFound
+	}
+
+	doc, blobs, err := boardarchive.Read(args.Archive, args.Size)
+	if err != nil {
+		return nil, err
+	}
+
+	// Blobs are stored first, the storage can't take part in the transaction.
+	files := make(map[string]*store.File, len(doc.Files))
+	defer func() {
+		if err == nil {
+			return
+		}
+		for _, file := range files {
+			args.FileStorage.Delete(file.StorageObjectID)
+		}
+	}()
+
+	for _, docFile := range doc.Files {
+		file := &store.File{
+			StorageObjectID: filestorage.RandomID() + path.Ext(docFile.Name),
+			Name:            docFile.Name,
+			MimeType:        docFile.MimeType,
+		}
+
+		size, err := args.FileStorage.Set(file.StorageObjectID, bytes.NewReader(blobs[docFile.Ref]))
+		files[docFile.Ref] = file
+		if err != nil {
+			return nil, err
+		}
+		file.Size = int(size)
+	}
+
+	var board *store.Board
+	err = user.Store.ORM.RunInTx(user.Context, nil, func(ctx context.Context, tx bun.Tx) error {
+		for _, file := range files {
+			_, err := tx.NewInsert().
+				Model(file).
+				Column("storage_object_id", "name", "size", "mime_type").
+				Returning("id").
+				Exec(ctx)
+			if err != nil {
+				return err
+			}
+		}
+
+		var err error
+		board, err = user.insertBoardDocument(ctx, tx, args.ProjectID, doc, files)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return board, nil
+}
+
+// Inserts the board of the document into the project. The files must be
+// already inserted, they are looked up by their refs.
+func (user UserService) insertBoardDocument(
+	ctx context.Context,
+	tx bun.Tx,
+	projectID store.EntityID,
+	doc *boardarchive.Document,
+	files map[string]*store.File,
+) (*store.Board, error) {
+	board := &store.Board{
+		ProjectID:    projectID,
+		UserID:       user.UserID,
+		Name:         doc.Board.Name,
+		Color:        doc.Board.Color,
+		EstimateUnit: doc.Board.EstimateUnit,
+	}
+	if board.EstimateUnit != store.EstimateUnitPoints {
+		board.EstimateUnit = store.EstimateUnitTime
+	}
+
+	if doc.Board.CoverRef != "" {
+		board.CoverID = &files[doc.Board.CoverRef].ID
+	}
+
+	_, err := tx.NewInsert().
+		Model(board).
+		Column("project_id", "user_id", "name", "color", "cover_id", "estimate_unit").
+		Returning("*").
+		Exec(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	labelIDs := make(map[string]store.LabelID, len(doc.Board.Labels))
+	for _, docLabel := range doc.Board.Labels {
+		label := &store.Label{
+			BoardID: board.ID,
+			UserID:  user.UserID,
+			Name:    docLabel.Name,
+			Color:   docLabel.Color,
+		}
+		_, err := tx.NewInsert().
+			Model(label).
+			Column("board_id", "user_id", "name", "color").
+			Returning("id").
+			Exec(ctx)
+		if err != nil {
+			return nil, err
+		}
+		labelIDs[docLabel.Ref] = label.ID
+	}
+
+	taskCount := 0
+	for _, docTaskList := range doc.Board.TaskLists {
+		taskCount += len(docTaskList.Tasks)
+	}
+
+	var number int64
+	_, err = tx.NewUpdate().
+		Model((*store.Project)(nil)).
+		Set("next_task_number = next_task_number + ?", taskCount).
+		Where("id = ?", projectID).
+		Returning("next_task_number - ?", taskCount).
+		Exec(ctx, &number)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	archivedAt := func(archived bool) *time.Time {
+		if archived {
+			return &now
+		}
+		return nil
+	}
+
+	for _, docTaskList := range doc.Board.TaskLists {
+		taskList := &store.TaskList{
+			UserID:       user.UserID,
+			BoardID:      board.ID,
+			Name:         docTaskList.Name,
+			Position:     docTaskList.Position,
+			Color:        docTaskList.Color,
+			Archived:     docTaskList.Archived,
+			DateArchived: archivedAt(docTaskList.Archived),
+			WIPLimit:     docTaskList.WIPLimit,
+			WIPMode:      docTaskList.WIPMode,
+		}
+		if taskList.WIPMode != store.WIPModeHard {
+			taskList.WIPMode = store.WIPModeSoft
+		}
+
+		_, err := tx.NewInsert().
+			Model(taskList).
+			Column("user_id", "board_id", "name", "position", "color", "archived", "date_archived", "wip_limit", "wip_mode").
+			Returning("id").
+			Exec(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, docTask := range docTaskList.Tasks {
+			if err := user.importTask(ctx, tx, projectID, taskList, docTask, number, labelIDs, files); err != nil {
+				return nil, err
+			}
+			number++
+		}
+	}
+
+	return board, nil
+}
+
+func (user UserService) importTask(
+	ctx context.Context,
+	tx bun.Tx,
+	projectID store.EntityID,
+	taskList *store.TaskList,
+	docTask *boardarchive.Task,
+	number int64,
+	labelIDs map[string]store.LabelID,
+	files map[string]*store.File,
+) error {
+	task := &store.Task{
+		UserID:      user.UserID,
+		ProjectID:   projectID,
+		TaskListID:  taskList.ID,
+		Number:      number,
+		Name:        docTask.Name,
+		Text:        docTask.Text,
+		Position:    docTask.Position,
+		Archived:    docTask.Archived,
+		SpentTime:   docTask.SpentTime,
+		DateCreated: docTask.DateCreated,
+		StartDate:   docTask.StartDate,
+		DueDate:     docTask.DueDate,
+		Priority:    docTask.Priority,
+	}
+	if task.DateCreated.IsZero() {
+		task.DateCreated = time.Now().UTC()
+	}
+	if task.Archived {
+		now := time.Now().UTC()
+		task.DateArchived = &now
+	}
+	if docTask.Estimate != nil && *docTask.Estimate > 0 {
+		task.Estimate = docTask.Estimate
+	}
+
+	_, err := tx.NewInsert().
+		Model(task).
+		Column("task_list_id", "user_id", "number", "project_id", "name", "text", "position", "archived", "date_archived",
+			"spent_time", "date_created", "start_date", "due_date", "estimate", "priority").
+		Returning("id").
+		Exec(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, ref := range docTask.LabelRefs {
+		_, err := tx.NewInsert().
+			Model(&store.LabelToTaskAssoc{TaskID: task.ID, LabelID: labelIDs[ref]}).
+			On("CONFLICT DO NOTHING").
+			Exec(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, ref := range docTask.AttachmentRefs {
+		_, err := tx.NewInsert().
+			Model(&store.AttachmentToTaskAssoc{TaskID: task.ID, FileID: files[ref].ID}).
+			On("CONFLICT DO NOTHING").
+			Exec(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, docComment := range docTask.Comments {
+		comment := &store.Comment{
+			TaskID:      task.ID,
+			UserID:      user.UserID,
+			Text:        docComment.Text,
+			DateCreated: docComment.DateCreated,
+		}
+		if comment.DateCreated.IsZero() {
+			comment.DateCreated = time.Now().UTC()
+		}
+
+		_, err := tx.NewInsert().
+			Model(comment).
+			Column("task_id", "user_id", "text", "date_created").
+			Returning("id").
+			Exec(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, ref := range docComment.AttachmentRefs {
+			_, err := tx.NewInsert().
+				Model(&store.AttachmentToCommentAssoc{CommentID: comment.ID, FileID: files[ref].ID}).
+				On("CONFLICT DO NOTHING").
+				Exec(ctx)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := user.addTaskMovement(ctx, tx, task.ID, "", task.TaskListID); err != nil {
+		return err
+	}
+
+	return user.addTaskRevision(ctx, tx, task.ID, task.Text, false)
+}
@@ -0,0 +1,222 @@
+package userservice
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/samber/lo"
+	"github.com/uptrace/bun"
+
+	"github.com/lesnoi-kot/karten-backend/src/store"
+)
+
+type GetEstimateReportOptions struct {
+	ProjectID store.EntityID
+}
+
+type EstimateReportTask struct {
+	ID        store.EntityID `bun:"id"`
+	BoardID   store.EntityID `bun:"board_id"`
+	Number    int64          `bun:"number"`
+	Key       string         `bun:"-"`
+	Name      string         `bun:"name"`
+	Archived  bool           `bun:"archived"`
+	Estimate  *float64       `bun:"estimate"`
+	SpentTime int64          `bun:"spent_time"`
+}
+
+type EstimateReportBoard struct {
+	ID           store.EntityID
+	Name         string
+	EstimateUnit string
+
+	Estimate           float64 // Sum of the estimates
+	EstimatedSpentTime int64   // Time spent on the estimated tasks
+	SpentTime          int64   // Time spent on all the tasks
+	EstimatedTasks     int
+	UnestimatedTasks   int
+
+	// Seconds spent per estimated unit, 1 is a perfect time estimate.
+	// Nil when nothing is estimated.
+	Ratio *float64
+
+	Tasks []*EstimateReportTask
+}
+
+type EstimateReport struct {
+	ProjectID store.EntityID
+	Boards    []*EstimateReportBoard
+}
+
+type taskListRollup struct {
+	TaskListID store.EntityID `bun:"task_list_id"`
+	TaskCount  int            `bun:"task_count"`
+	Estimate   float64        `bun:"estimate"`
+	SpentTime  int64          `bun:"spent_time"`
+}
+
+// Counts the non-archived tasks per list and sums their estimates and spent
+// time. With a sprint given, the sums only cover the tasks of the sprint
+// while the count is still about the whole list, as WIP limits need it.
+func (user UserService) getTaskListRollups(
+	sprintID store.EntityID,
+	filter func(q *bun.SelectQuery) *bun.SelectQuery,
+) (map[store.EntityID]taskListRollup, error) {
+	var rollups []taskListRollup
+
+	q := user.Store.ORM.NewSelect().
+		TableExpr("tasks AS t").
+		ColumnExpr("t.task_list_id").
+		ColumnExpr("count(*) AS task_count").
+		Join("JOIN task_lists AS tl ON tl.id = t.task_list_id").
+		Where("tl.user_id = ?", user.UserID).
+		Where("tl.archived = ?", false).
+		Where("tl.deleted_at IS NULL").
+		Where("t.archived = ?", false).
+		Where("t.deleted_at IS NULL").
+		GroupExpr("t.task_list_id")
+
+	if sprintID != "" {
+		q = q.
+			ColumnExpr("coalesce(sum(t.estimate) FILTER (WHERE t.sprint_id = ?), 0) AS estimate", sprintID).
+			ColumnExpr("coalesce(sum(t.spent_time) FILTER (WHERE t.sprint_id = ?), 0) AS spent_time", sprintID)
+	} else {
+		q = q.
+			ColumnExpr("coalesce(sum(t.estimate), 0) AS estimate").
+			ColumnExpr("coalesce(sum(t.spent_time), 0) AS spent_time")
+	}
+
+	if err := filter(q).Scan(user.Context, &rollups); err != nil {
+		return nil, err
+	}
+
+	return lo.KeyBy(rollups, func(rollup taskListRollup) store.EntityID {
+		return rollup.TaskListID
+	}), nil
+}
+
+func (user UserService) fillBoardRollups(board *store.Board) error {
+	var sprintID store.EntityID
+	if board.Sprint != nil {
+		sprintID = board.Sprint.ID
+	}
+
+	rollups, err := user.getTaskListRollups(sprintID, func(q *bun.SelectQuery) *bun.SelectQuery {
+		return q.Where("tl.board_id = ?", board.ID)
+	})
+	if err != nil {
+		return err
+	}
+
+	board.Estimate, board.SpentTime = 0, 0
+	for _, rollup := range rollups {
+		board.Estimate += rollup.Estimate
+		board.SpentTime += rollup.SpentTime
+	}
+
+	for _, taskList := range board.TaskLists {
+		fillTaskListRollup(taskList, rollups[taskList.ID])
+	}
+
+	return nil
+}
+
+func (user UserService) fillTaskListRollups(taskList *store.TaskList) error {
+	rollups, err := user.getTaskListRollups("", func(q *bun.SelectQuery) *bun.SelectQuery {
+		return q.Where("tl.id = ?", taskList.ID)
+	})
+	if err != nil {
+		return err
+	}
+
+	fillTaskListRollup(taskList, rollups[taskList.ID])
+
+	return nil
+}
+
+func fillTaskListRollup(taskList *store.TaskList, rollup taskListRollup) {
+	taskList.TaskCount = rollup.TaskCount
+	taskList.Estimate = rollup.Estimate
+	taskList.SpentTime = rollup.SpentTime
+}
+
+// Compares the estimates with the time actually spent, board by board.
+// Archived tasks are counted too, they are usually the finished ones.
+func (user UserService) GetEstimateReport(args *GetEstimateReportOptions) (*EstimateReport, error) {
+	project := new(store.Project)
+	err := user.Store.ORM.NewSelect().
+		Model(project).
+		Column("id", "key_prefix").
+		Where("project.id = ?", args.ProjectID).
+		Where("project.user_id = ?", user.UserID).
+		Relation("Boards", func(q *bun.SelectQuery) *bun.SelectQuery {
+			return q.Column("id", "project_id", "name", "estimate_unit").Order("date_created")
+		}).
+		Scan(user.Context)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, store.ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	tasks := make([]*EstimateReportTask, 0)
+	err = user.Store.ORM.NewSelect().
+		TableExpr("tasks AS t").
+		ColumnExpr("t.id, tl.board_id, t.number, t.name, t.archived, t.estimate, t.spent_time").
+		Join("JOIN task_lists AS tl ON tl.id = t.task_list_id").
+		Join("JOIN boards AS b ON b.id = tl.board_id").
+		Where("b.project_id = ?", project.ID).
+		Where("t.user_id = ?", user.UserID).
+		Where("t.deleted_at IS NULL").
+		Where("tl.deleted_at IS NULL").
+		Where("t.estimate IS NOT NULL OR t.spent_time > 0").
+		OrderExpr("t.number, t.date_created").
+		Scan(user.Context, &tasks)
+	if err != nil {
+		return nil, err
+	}
+
+	tasksByBoard := lo.GroupBy(tasks, func(task *EstimateReportTask) store.EntityID {
+		return task.BoardID
+	})
+
+	report := &EstimateReport{
+		ProjectID: project.ID,
+		Boards:    make([]*EstimateReportBoard, 0, len(project.Boards)),
+	}
+
+	for _, board := range project.Boards {
+		row := &EstimateReportBoard{
+			ID:           board.ID,
+			Name:         board.Name,
+			EstimateUnit: board.EstimateUnit,
+			Tasks:        tasksByBoard[board.ID],
+		}
+		summarizeEstimates(row, project.KeyPrefix)
+
+		report.Boards = append(report.Boards, row)
+	}
+
+	return report, nil
+}
+
+// Sums up the estimates and the spent time of the board tasks.
+func summarizeEstimates(row *EstimateReportBoard, keyPrefix string) {
+	for _, task := range row.Tasks {
+		task.Key = store.FormatTaskKey(keyPrefix, task.Number)
+		row.SpentTime += task.SpentTime
+
+		if task.Estimate == nil {
+			row.UnestimatedTasks++
+			continue
+		}
+
+		row.EstimatedTasks++
+		row.Estimate += *task.Estimate
+		row.EstimatedSpentTime += task.SpentTime
+	}
+
+	if row.Estimate > 0 {
+		row.Ratio = lo.ToPtr(float64(row.EstimatedSpentTime) / row.Estimate)
+	}
+}
@@ -0,0 +1,113 @@
+package userservice
+
+import (
+	"testing"
+	"time"
+
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lesnoi-kot/karten-backend/src/store"
+)
+
+func TestSummarizeEstimates(t *testing.T) {
+	row := &EstimateReportBoard{Tasks: []*EstimateReportTask{
+		{Number: 1, Estimate: lo.ToPtr(2.0), SpentTime: 9000},
+		{Number: 2, Estimate: lo.ToPtr(1.5), SpentTime: 0},
+		{Number: 3, SpentTime: 600},
+	}}
+	summarizeEstimates(row, "KAR")
+
+	assert.Equal(t, 3.5, row.Estimate)
+	assert.EqualValues(t, 9000, row.EstimatedSpentTime)
+	assert.EqualValues(t, 9600, row.SpentTime)
+	assert.Equal(t, 2, row.EstimatedTasks)
+	assert.Equal(t, 1, row.UnestimatedTasks)
+	assert.Equal(t, lo.ToPtr(9000/3.5), row.Ratio)
+	assert.Equal(t, []string{"KAR-1", "KAR-2", "KAR-3"}, lo.Map(row.Tasks, func(task *EstimateReportTask, _ int) string {
+		return task.Key
+	}))
+
+	unestimated := &EstimateReportBoard{Tasks: []*EstimateReportTask{{SpentTime: 600}}}
+	summarizeEstimates(unestimated, "")
+	assert.Nil(t, unestimated.Ratio)
+	assert.Empty(t, unestimated.Tasks[0].Key)
+}
+
+func (s *userServiceSuite) TestBoardRollups() {
+	board, lists := s.addBoard("Todo", "Done")
+
+	estimated := s.addTask(lists[0].ID, "Estimated")
+	s.Require().NoError(s.user.EditTask(&EditTaskOptions{
+		TaskID:    estimated.ID,
+		Estimate:  lo.ToPtr(3.0),
+		SpentTime: lo.ToPtr(int64(60)),
+	}))
+	s.addTask(lists[0].ID, "Unestimated")
+	archived := s.addTask(lists[0].ID, "Archived")
+	s.Require().NoError(s.user.EditTask(&EditTaskOptions{TaskID: archived.ID, Estimate: lo.ToPtr(8.0)}))
+	s.Require().NoError(s.user.ArchiveTask(archived.ID))
+	done := s.addTask(lists[1].ID, "Done")
+	s.Require().NoError(s.user.EditTask(&EditTaskOptions{TaskID: done.ID, Estimate: lo.ToPtr(1.0)}))
+
+	got, err := s.user.GetBoard(&GetBoardOptions{BoardID: board.ID, IncludeTaskLists: true, SkipDateLastViewedUpdate: true})
+	s.Require().NoError(err)
+	s.Equal(4.0, got.Estimate, "archived tasks are left out")
+	s.EqualValues(60, got.SpentTime)
+	taskLists := lo.KeyBy(got.TaskLists, func(taskList *store.TaskList) store.EntityID { return taskList.ID })
+	s.Equal(2, taskLists[lists[0].ID].TaskCount)
+	s.Equal(3.0, taskLists[lists[0].ID].Estimate)
+	s.Equal(1, taskLists[lists[1].ID].TaskCount)
+	s.Equal(1.0, taskLists[lists[1].ID].Estimate)
+
+	// Within a sprint only its tasks are summed, the counts stay whole.
+	now := time.Now().UTC()
+	sprint, err := s.user.AddSprint(&AddSprintOptions{BoardID: board.ID, Name: "Sprint", DateStart: now, DateEnd: now.AddDate(0, 0, 7)})
+	s.Require().NoError(err)
+	s.Require().NoError(s.user.EditTask(&EditTaskOptions{TaskID: done.ID, SprintID: &sprint.ID}))
+
+	got, err = s.user.GetBoard(&GetBoardOptions{BoardID: board.ID, IncludeTaskLists: true, SprintID: sprint.ID, SkipDateLastViewedUpdate: true})
+	s.Require().NoError(err)
+	s.Equal(1.0, got.Estimate)
+	s.Zero(got.SpentTime)
+	taskLists = lo.KeyBy(got.TaskLists, func(taskList *store.TaskList) store.EntityID { return taskList.ID })
+	s.Equal(2, taskLists[lists[0].ID].TaskCount)
+	s.Zero(taskLists[lists[0].ID].Estimate)
+	s.Equal(1.0, taskLists[lists[1].ID].Estimate)
+
+	taskList, err := s.user.GetTaskList(&GetTaskListOptions{TaskListID: lists[0].ID})
+	s.Require().NoError(err)
+	s.Equal(2, taskList.TaskCount)
+	s.Equal(3.0, taskList.Estimate)
+	s.EqualValues(60, taskList.SpentTime)
+}
+
+func (s *userServiceSuite) TestGetEstimateReport() {
+	board, lists := s.addBoard("Todo")
+	project, err := s.user.boardProjectID(board.ID)
+	s.Require().NoError(err)
+
+	estimated := s.addTask(lists[0].ID, "Estimated")
+	s.Require().NoError(s.user.EditTask(&EditTaskOptions{
+		TaskID:    estimated.ID,
+		Estimate:  lo.ToPtr(2.0),
+		SpentTime: lo.ToPtr(int64(7200)),
+	}))
+	s.Require().NoError(s.user.ArchiveTask(estimated.ID))
+	spent := s.addTask(lists[0].ID, "Spent")
+	s.Require().NoError(s.user.EditTask(&EditTaskOptions{TaskID: spent.ID, SpentTime: lo.ToPtr(int64(300))}))
+	s.addTask(lists[0].ID, "Untouched")
+
+	report, err := s.user.GetEstimateReport(&GetEstimateReportOptions{ProjectID: project})
+	s.Require().NoError(err)
+	s.Require().Len(report.Boards, 1)
+
+	row := report.Boards[0]
+	s.Equal(board.ID, row.ID)
+	s.Len(row.Tasks, 2, "tasks without an estimate and spent time are left out")
+	s.Equal(1, row.EstimatedTasks)
+	s.Equal(1, row.UnestimatedTasks)
+	s.Equal(2.0, row.Estimate)
+	s.EqualValues(7500, row.SpentTime)
+	s.Equal(lo.ToPtr(3600.0), row.Ratio)
+}
@@ -0,0 +1,237 @@
+package userservice
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/uptrace/bun"
+
+	"github.com/lesnoi-kot/karten-backend/src/modules/ical"
+	"github.com/lesnoi-kot/karten-backend/src/store"
+	"github.com/lesnoi-kot/karten-backend/src/urlprovider"
+)
+
+const (
+	// Tasks due earlier than this are left out of the feeds.
+	feedHistory   = 365 * 24 * time.Hour
+	feedTaskLimit = 2000
+)
+
+type AddFeedTokenOptions struct {
+	BoardID          store.EntityID // Empty for all boards
+	Name             string
+	Component        ical.Component
+	IncludeCompleted bool
+}
+
+type feedTask struct {
+	ID        store.EntityID `bun:"id"`
+	KeyPrefix string         `bun:"key_prefix"`
+	Number    int64          `bun:"number"`
+	Name      string         `bun:"name"`
+	Text      string         `bun:"text"`
+	DueDate   time.Time      `bun:"due_date"`
+	BoardName string         `bun:"board_name"`
+	ListName  string         `bun:"list_name"`
+	Labels    []string       `bun:"labels,array"`
+	Completed bool           `bun:"completed"`
+}
+
+func (user UserService) GetFeedTokens() ([]*store.FeedToken, error) {
+	tokens := make([]*store.FeedToken, 0)
+	err := user.Store.ORM.NewSelect().
+		Model(&tokens).
+		ExcludeColumn("token_hash").
+		Where("user_id = ?", user.UserID).
+		Order("date_created").
+		Scan(user.Context)
+	if err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+// Returns the new token along with the secret. The secret can't be got
+// later.
+func (user UserService) AddFeedToken(args *AddFeedTokenOptions) (*store.FeedToken, string, error) {
+	if args.BoardID != "" {
+		if owns, err := user.OwnsBoard(args.BoardID); err != nil {
+			return nil, "", err
+		} else if !owns {
+			return nil, "", store.ErrNotFound
+		}
+	}
+
+	secret, err := newSecretToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	token := &store.FeedToken{
+		UserID:           user.UserID,
+		Name:             args.Name,
+		TokenHash:        hashSecretToken(secret),
+		Component:        string(args.Component),
+		IncludeCompleted: args.IncludeCompleted,
+	}
+	if args.BoardID != "" {
+		token.BoardID = &args.BoardID
+	}
+	if token.Component == "" {
+		token.Component = string(ical.ComponentEvent)
+	}
+
+	_, err = user.Store.ORM.NewInsert().
+		Model(token).
+		Column("user_id", "board_id", "name", "token_hash", "component", "include_completed").
+		Returning("*").
+		Exec(user.Context)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return token, secret, nil
+}
+
+// Revokes the token, its feed URL stops working.
+func (user UserService) DeleteFeedToken(tokenID store.EntityID) error {
+	result, err := user.Store.ORM.NewDelete().
+		Model((*store.FeedToken)(nil)).
+		Where("id = ?", tokenID).
+		Where("user_id = ?", user.UserID).
+		Exec(user.Context)
+	if err != nil {
+		return err
+	} else if store.NoRowsAffected(result) {
+		return store.ErrNotFound
+	}
+
+	return nil
+}
+
+// Builds the calendar of the feed the secret belongs to. Feeds are read by
+// calendar apps without a session, the secret is the only credential.
+func GetFeedCalendar(ctx context.Context, s *store.Store, secret string) (*store.FeedToken, *ical.Calendar, error) {
+	token := new(store.FeedToken)
+	err := s.ORM.NewSelect().
+		Model(token).
+		Where("token_hash = ?", hashSecretToken(secret)).
+		Scan(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil, store.ErrNotFound
+	} else if err != nil {
+		return nil, nil, err
+	}
+
+	calendar := &ical.Calendar{Name: "Karten"}
+	if token.Name != "" {
+		calendar.Name = token.Name
+	}
+
+	q := s.ORM.NewSelect().
+		TableExpr("tasks AS t").
+		ColumnExpr("t.id, p.key_prefix, t.number, t.name, t.text, t.due_date").
+		ColumnExpr("b.name AS board_name, tl.name AS list_name").
+		ColumnExpr(`array(
+			SELECT l.name FROM task_labels AS tlb
+			JOIN labels AS l ON l.id = tlb.label_id
+			WHERE tlb.task_id = t.id
+			ORDER BY l.name
+		) AS labels`).
+		ColumnExpr("t.task_list_id IS NOT DISTINCT FROM (?) AS completed", doneListQuery(s.ORM, bun.Ident("b.id"))).
+		Join("JOIN task_lists AS tl ON tl.id = t.task_list_id").
+		Join("JOIN boards AS b ON b.id = tl.board_id").
+		Join("JOIN projects AS p ON p.id = b.project_id").
+		Where("t.user_id = ?", token.UserID).
+		Where("t.due_date >= ?", time.Now().UTC().Add(-feedHistory)).
+		Where("t.archived = ?", false).
+		Where("t.deleted_at IS NULL").
+		Where("tl.archived = ?", false).
+		Where("tl.deleted_at IS NULL").
+		Where("b.archived = ?", false).
+		Where("b.deleted_at IS NULL").
+		Where("p.deleted_at IS NULL").
+		OrderExpr("t.due_date, t.date_created").
+		Limit(feedTaskLimit)
+
+	if token.BoardID != nil {
+		q = q.Where("b.id = ?", *token.BoardID)
+
+		if token.Name == "" {
+			var boardName string
+			err := s.ORM.NewSelect().
+				Model((*store.Board)(nil)).
+				Column("name").
+				Where("id = ?", *token.BoardID).
+				Scan(ctx, &boardName)
+			if err != nil {
+				return nil, nil, err
+			}
+			calendar.Name = boardName
+		}
+	}
+
+	if !token.IncludeCompleted {
+		q = q.Where("t.task_list_id IS DISTINCT FROM (?)", doneListQuery(s.ORM, bun.Ident("b.id")))
+	}
+
+	tasks := make([]*feedTask, 0)
+	if err := q.Scan(ctx, &tasks); err != nil {
+		return nil, nil, err
+	}
+
+	calendar.Items = make([]*ical.Item, len(tasks))
+	for i, task := range tasks {
+		summary := task.Name
+		if key := store.FormatTaskKey(task.KeyPrefix, task.Number); key != "" {
+			summary = key + " " + summary
+		}
+
+		description := fmt.Sprintf("%s › %s", task.BoardName, task.ListName)
+		if task.Text != "" {
+			description += "\n\n" + task.Text
+		}
+
+		calendar.Items[i] = &ical.Item{
+			UID:         fmt.Sprintf("%s@karten", task.ID),
+			Summary:     summary,
+			Description: description,
+			URL:         urlprovider.GetTaskURL(task.ID),
+			Categories:  task.Labels,
+			Due:         task.DueDate,
+			Completed:   task.Completed,
+		}
+	}
+
+	_, err = s.ORM.NewUpdate().
+		Model(token).
+		Set("date_last_used = ?", time.Now().UTC()).
+		WherePK().
+		Exec(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return token, calendar, nil
+}
+
+func newSecretToken() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(secret), nil
+}
+
+func hashSecretToken(secret string) []byte {
+	hash := sha256.Sum256([]byte(secret))
+	return hash[:]
+}
@@ -0,0 +1,138 @@
+package userservice
+
+import (
+	"context"
+	"time"
+
+	"github.com/uptrace/bun"
+
+	"github.com/lesnoi-kot/karten-backend/src/modules/flowmetrics"
+	"github.com/lesnoi-kot/karten-backend/src/store"
+)
+
+type GetBoardFlowMetricsOptions struct {
+	BoardID     store.EntityID
+	From        time.Time
+	To          time.Time
+	StartListID store.EntityID // The second list by default
+	EndListID   store.EntityID // The last list by default
+}
+
+type BoardFlowMetrics struct {
+	*flowmetrics.Report
+
+	StartListID store.EntityID
+	EndListID   store.EntityID
+	TaskLists   []*store.TaskList
+}
+
+type taskMovementRow struct {
+	TaskID       string    `bun:"task_id"`
+	TaskCreated  time.Time `bun:"task_created"`
+	ToTaskListID string    `bun:"to_task_list_id"`
+	DateCreated  time.Time `bun:"date_created"`
+}
+
+func (user UserService) addTaskMovement(
+	ctx context.Context,
+	tx bun.Tx,
+	taskID, fromTaskListID, toTaskListID store.EntityID,
+) error {
+	movement := &store.TaskMovement{
+		TaskID:         taskID,
+		UserID:         user.UserID,
+		FromTaskListID: fromTaskListID,
+		ToTaskListID:   toTaskListID,
+		DateCreated:    time.Now().UTC(),
+	}
+
+	_, err := tx.NewInsert().
+		Model(movement).
+		Column("task_id", "user_id", "from_task_list_id", "to_task_list_id", "date_created").
+		Exec(ctx)
+	return err
+}
+
+// Lead time, cycle time and time in list percentiles of the board tasks,
+// based on their movement history.
+func (user UserService) GetBoardFlowMetrics(args *GetBoardFlowMetricsOptions) (*BoardFlowMetrics, error) {
+	if owns, err := user.OwnsBoard(args.BoardID); err != nil {
+		return nil, err
+	} else if !owns {
+		return nil, store.ErrNotFound
+	}
+
+	var taskLists []*store.TaskList
+	err := user.Store.ORM.NewSelect().
+		Model(&taskLists).
+		Where("board_id = ?", args.BoardID).
+		Order("position").
+		Scan(user.Context)
+	if err != nil {
+		return nil, err
+	}
+
+	listIDs := make([]string, len(taskLists))
+	for i, taskList := range taskLists {
+		listIDs[i] = taskList.ID
+	}
+
+	startListID, endListID := args.StartListID, args.EndListID
+	if startListID == "" && len(listIDs) > 1 {
+		startListID = listIDs[1]
+	}
+	if endListID == "" && len(listIDs) > 0 {
+		endListID = listIDs[len(listIDs)-1]
+	}
+
+	var rows []taskMovementRow
+	err = user.Store.ORM.NewSelect().
+		TableExpr("task_movements AS tm").
+		ColumnExpr("tm.task_id, t.date_created AS task_created").
+		ColumnExpr("tm.to_task_list_id, tm.date_created").
+		Join("JOIN tasks AS t ON t.id = tm.task_id").
+		Join("JOIN task_lists AS tl ON tl.id = t.task_list_id").
+		Where("tl.board_id = ?", args.BoardID).
+		Where("t.user_id = ?", user.UserID).
+		Where("t.deleted_at IS NULL").
+		Where("tm.date_created < ?", args.To).
+		OrderExpr("tm.task_id, tm.date_created, tm.id").
+		Scan(user.Context, &rows)
+	if err != nil {
+		return nil, err
+	}
+
+	var tasks []flowmetrics.Task
+	for _, row := range rows {
+		if len(tasks) == 0 || tasks[len(tasks)-1].ID != row.TaskID {
+			tasks = append(tasks, flowmetrics.Task{ID: row.TaskID, Created: row.TaskCreated})
+		}
+
+		task := &tasks[len(tasks)-1]
+		task.Movements = append(task.Movements, flowmetrics.Movement{
+			ToListID: row.ToTaskListID,
+			At:       row.DateCreated,
+		})
+	}
+
+	now := time.Now().UTC()
+	if now.After(args.To) {
+		now = args.To
+	}
+
+	report := flowmetrics.Build(tasks, flowmetrics.Options{
+		From:        args.From,
+		To:          args.To,
+		Now:         now,
+		StartListID: startListID,
+		EndListID:   endListID,
+		ListIDs:     listIDs,
+	})
+
+	return &BoardFlowMetrics{
+		Report:      report,
+		StartListID: startListID,
+		EndListID:   endListID,
+		TaskLists:   taskLists,
+	}, nil
+}
@@ -0,0 +1,381 @@
+package userservice
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+
+	"github.com/lesnoi-kot/karten-backend/src/modules/forge"
+	"github.com/lesnoi-kot/karten-backend/src/store"
+)
+
+var ErrInvalidSignature = errors.New("Invalid webhook signature")
+
+type AddForgeHookOptions struct {
+	ProjectID store.EntityID
+	Name      string
+}
+
+type ReceiveForgeEventOptions struct {
+	HookID store.EntityID
+	Header http.Header
+	Body   []byte
+}
+
+type ForgeEventResult struct {
+	Linked    int // New links, an already linked change is only updated
+	Completed int // Tasks moved to the done list
+}
+
+// Task mentioned by a commit or a pull request.
+type referencedTask struct {
+	ID         store.EntityID `bun:"id"`
+	ShortID    string         `bun:"short_id"`
+	Number     int64          `bun:"number"`
+	TaskListID store.EntityID `bun:"task_list_id"`
+	BoardID    store.EntityID `bun:"board_id"`
+}
+
+func (user UserService) GetForgeHooks(projectID store.EntityID) ([]*store.ForgeHook, error) {
+	if owns, err := user.OwnsProject(projectID); err != nil {
+		return nil, err
+	} else if !owns {
+		return nil, store.ErrNotFound
+	}
+
+	hooks := make([]*store.ForgeHook, 0)
+	err := user.Store.ORM.NewSelect().
+		Model(&hooks).
+		ExcludeColumn("secret").
+		Where("project_id = ?", projectID).
+		Order("date_created").
+		Scan(user.Context)
+	if err != nil {
+		return nil, err
+	}
+
+	return hooks, nil
+}
+
+// Returns the new hook with its secret, which is not shown later.
+func (user UserService) AddForgeHook(args *AddForgeHookOptions) (*store.ForgeHook, error) {
+	if owns, err := user.OwnsProject(args.ProjectID); err != nil {
+		return nil, err
+	} else if !owns {
+		return nil, store.ErrNotFound
+	}
+
+	secret, err := newSecretToken()
+	if err != nil {
+		return nil, err
+	}
+
+	hook := &store.ForgeHook{
+		ProjectID: args.ProjectID,
+		UserID:    user.UserID,
+		Name:      args.Name,
+		Secret:    secret,
+	}
+
+	_, err = user.Store.ORM.NewInsert().
+		Model(hook).
+		Column("project_id", "user_id", "name", "secret").
+		Returning("*").
+		Exec(user.Context)
+	if err != nil {
+		return nil, err
+	}
+
+	return hook, nil
+}
+
+func (user UserService) DeleteForgeHook(hookID store.EntityID) error {
+	result, err := user.Store.ORM.NewDelete().
+		Model((*store.ForgeHook)(nil)).
+		Where("id = ?", hookID).
+		Where("user_id = ?", user.UserID).
+		Exec(user.Context)
+	if err != nil {
+		return err
+	} else if store.NoRowsAffected(result) {
+		return store.ErrNotFound
+	}
+
+	return nil
+}
+
+// Returns the commits and pull requests mentioning the task, the latest first.
+func (user UserService) GetTaskLinks(taskID store.EntityID) ([]*store.TaskLink, error) {
+	if _, err := user.GetTask(&GetTaskOptions{TaskID: taskID}); err != nil {
+		return nil, err
+	}
+
+	links := make([]*store.TaskLink, 0)
+	err := user.Store.ORM.NewSelect().
+		Model(&links).
+		Where("task_id = ?", taskID).
+		Order("date_created DESC").
+		Scan(user.Context)
+	if err != nil {
+		return nil, err
+	}
+
+	return links, nil
+}
+
+// Links the commits and pull requests of a forge webhook to the tasks of the
+// hook's project they mention, commenting on the tasks once per change.
+// Closing references of the commits pushed to the default branch and of the
+// merged pull requests move the tasks to the board's done list.
+func ReceiveForgeEvent(ctx context.Context, s *store.Store, args *ReceiveForgeEventOptions) (*ForgeEventResult, error) {
+	if _, err := uuid.Parse(args.HookID); err != nil {
+		return nil, store.ErrNotFound
+	}
+
+	var hook struct {
+		ID        store.EntityID `bun:"id"`
+		ProjectID store.EntityID `bun:"project_id"`
+		Secret    string         `bun:"secret"`
+		KeyPrefix string         `bun:"key_prefix"`
+		OwnerID   store.UserID   `bun:"owner_id"`
+	}
+	err := s.ORM.NewSelect().
+		TableExpr("forge_hooks AS fh").
+		ColumnExpr("fh.id, fh.project_id, fh.secret, p.key_prefix, p.user_id AS owner_id").
+		Join("JOIN projects AS p ON p.id = fh.project_id").
+		Where("fh.id = ?", args.HookID).
+		Where("p.deleted_at IS NULL").
+		Scan(ctx, &hook)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, store.ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	provider := forge.DetectProvider(args.Header)
+	if !forge.VerifySignature(provider, args.Header, args.Body, hook.Secret) {
+		return nil, ErrInvalidSignature
+	}
+
+	event, err := forge.Parse(forge.EventType(provider, args.Header), args.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	user := UserService{Context: ctx, UserID: hook.OwnerID, Store: s}
+	result := &ForgeEventResult{}
+
+	for _, change := range event.Changes {
+		refs := forge.FindReferences(change.Text)
+		if len(refs) == 0 || change.URL == "" {
+			continue
+		}
+
+		tasks, err := user.findReferencedTasks(hook.ProjectID, hook.KeyPrefix, refs)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, ref := range refs {
+			task := matchReference(tasks, hook.KeyPrefix, ref)
+			if task == nil {
+				continue
+			}
+
+			linked, completed, err := user.linkChange(event.Repository, change, ref, task)
+			if err != nil {
+				return nil, err
+			}
+
+			if linked {
+				result.Linked++
+			}
+			if completed {
+				result.Completed++
+			}
+		}
+	}
+
+	_, err = s.ORM.NewUpdate().
+		Model((*store.ForgeHook)(nil)).
+		Set("date_last_used = ?", time.Now().UTC()).
+		Where("id = ?", hook.ID).
+		Exec(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// Finds the tasks of the project by the keys with the project's prefix and by
+// the short IDs.
+func (user UserService) findReferencedTasks(projectID store.EntityID, keyPrefix string, refs []forge.Reference) ([]*referencedTask, error) {
+	var numbers []int64
+	var shortIDs []string
+
+	for _, ref := range refs {
+		if prefix, number, ok := store.ParseTaskKey(ref.ID); ok {
+			if keyPrefix != "" && prefix == keyPrefix {
+				numbers = append(numbers, number)
+			}
+		} else {
+			shortIDs = append(shortIDs, ref.ID)
+		}
+	}
+
+	tasks := make([]*referencedTask, 0)
+	if len(numbers) == 0 && len(shortIDs) == 0 {
+		return tasks, nil
+	}
+
+	err := user.Store.ORM.NewSelect().
+		TableExpr("tasks AS t").
+		ColumnExpr("t.id, t.short_id, t.number, t.task_list_id, tl.board_id").
+		Join("JOIN task_lists AS tl ON tl.id = t.task_list_id").
+		Join("JOIN boards AS b ON b.id = tl.board_id").
+		Where("b.project_id = ?", projectID).
+		Where("t.deleted_at IS NULL").
+		WhereGroup(" AND ", func(q *bun.SelectQuery) *bun.SelectQuery {
+			if len(numbers) > 0 {
+				q = q.WhereOr("t.number IN (?)", bun.In(numbers))
+			}
+			if len(shortIDs) > 0 {
+				q = q.WhereOr("t.short_id IN (?)", bun.In(shortIDs))
+			}
+			return q
+		}).
+		Scan(user.Context, &tasks)
+	if err != nil {
+		return nil, err
+	}
+
+	return tasks, nil
+}
+
+func matchReference(tasks []*referencedTask, keyPrefix string, ref forge.Reference) *referencedTask {
+	for _, task := range tasks {
+		if task.ShortID == ref.ID || (task.Number != 0 && store.FormatTaskKey(keyPrefix, task.Number) == ref.ID) {
+			return task
+		}
+	}
+
+	return nil
+}
+
+// Adds or updates the link of the change to the task. Comments on the task if
+// the link is new and completes the task if the reference closes it and the
+// link has not done it before. The link is marked completed only after the
+// move, so that a redelivered event retries a failed one.
+func (user UserService) linkChange(repository string, change *forge.Change, ref forge.Reference, task *referencedTask) (bool, bool, error) {
+	var link struct {
+		ID        store.EntityID `bun:"id"`
+		Inserted  bool           `bun:"inserted"`
+		Completed bool           `bun:"completed"`
+	}
+	comment := &store.Comment{
+		TaskID: task.ID,
+		UserID: user.UserID,
+		Text:   changeComment(repository, change),
+	}
+
+	err := user.Store.ORM.RunInTx(user.Context, nil, func(ctx context.Context, tx bun.Tx) error {
+		err := tx.NewRaw(`
+			INSERT INTO task_links (task_id, kind, url, external_id, repository, title, author, state)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT (task_id, url) DO UPDATE SET
+				title = EXCLUDED.title,
+				state = EXCLUDED.state,
+				date_updated = CURRENT_TIMESTAMP
+			RETURNING id, (xmax = 0) AS inserted, completed`,
+			task.ID, string(change.Kind), change.URL, change.ID, repository,
+			change.Title, change.Author, change.State,
+		).Scan(ctx, &link)
+		if err != nil || !link.Inserted {
+			return err
+		}
+
+		if err := user.insertComment(ctx, tx, comment); err != nil {
+			return err
+		}
+
+		return user.queueTaskWebhooks(ctx, tx, commentAddedPayload(comment), task.ID)
+	})
+	if err != nil {
+		return false, false, err
+	}
+
+	if !ref.Closing || !change.Closes || link.Completed {
+		return link.Inserted, false, nil
+	}
+
+	moved, err := user.moveToDoneList(task)
+	if err != nil {
+		return false, false, err
+	}
+
+	result, err := user.Store.ORM.NewUpdate().
+		Model((*store.TaskLink)(nil)).
+		Set("completed = ?", true).
+		Where("id = ?", link.ID).
+		Where("completed = ?", false).
+		Exec(user.Context)
+	if err != nil {
+		return false, false, err
+	} else if store.NoRowsAffected(result) {
+		// A concurrent delivery has completed the task.
+		return link.Inserted, false, nil
+	}
+
+	return link.Inserted, moved, nil
+}
+
+// Moves the task to the last active list of its board. Reports false if the
+// task is there already, the board has no lists or the list is full.
+func (user UserService) moveToDoneList(task *referencedTask) (bool, error) {
+	doneListID, err := user.boardDoneListID(user.Store.ORM, task.BoardID)
+	if err != nil {
+		return false, err
+	} else if doneListID == "" || doneListID == task.TaskListID {
+		return false, nil
+	}
+
+	err = user.EditTask(&EditTaskOptions{TaskID: task.ID, TaskListID: &doneListID})
+	if errors.Is(err, ErrWIPLimitExceeded) {
+		// The link and its comment stay, the task is left where it is.
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func changeComment(repository string, change *forge.Change) string {
+	var text strings.Builder
+
+	if change.Kind == forge.ChangePullRequest {
+		fmt.Fprintf(&text, "Mentioned in pull request [#%s](%s)", change.ID, change.URL)
+	} else {
+		fmt.Fprintf(&text, "Mentioned in commit [`%.7s`](%s)", change.ID, change.URL)
+	}
+
+	if repository != "" {
+		fmt.Fprintf(&text, " of %s", repository)
+	}
+	if change.Author != "" {
+		fmt.Fprintf(&text, " by %s", change.Author)
+	}
+	if change.Title != "" {
+		fmt.Fprintf(&text, ": %s", change.Title)
+	}
+
+	return text.String()
+}
@@ -0,0 +1,50 @@
+package userservice
+
+import (
+	"context"
+
+	"github.com/lesnoi-kot/karten-backend/src/modules/forge"
+	"github.com/lesnoi-kot/karten-backend/src/store"
+)
+
+func (s *userServiceSuite) TestLinkChange() {
+	board, lists := s.addBoard("Todo", "Done")
+	task := s.addTask(lists[0].ID, "Task")
+	referenced := &referencedTask{ID: task.ID, TaskListID: lists[0].ID, BoardID: board.ID}
+
+	change := &forge.Change{
+		Kind:  forge.ChangeCommit,
+		ID:    "0123456789abcdef",
+		URL:   "https://example.com/commit/0123456789abcdef",
+		Title: "Trim the names",
+	}
+	linked, completed, err := s.user.linkChange("team/app", change, forge.Reference{ID: task.ID}, referenced)
+	s.Require().NoError(err)
+	s.True(linked)
+	s.False(completed)
+	s.Equal(1, s.countComments(task.ID))
+
+	// A delivery that linked the change and failed to move the task.
+	change.Closes = true
+	linked, completed, err = s.user.linkChange("team/app", change, forge.Reference{ID: task.ID, Closing: true}, referenced)
+	s.Require().NoError(err)
+	s.False(linked)
+	s.True(completed, "the redelivery moves the task")
+	s.Equal(1, s.countComments(task.ID), "the change is commented on once")
+	s.Equal(lists[1].ID, s.getTask(task.ID).TaskListID)
+
+	link := new(store.TaskLink)
+	err = s.store.ORM.NewSelect().
+		Model(link).
+		Where("task_id = ?", task.ID).
+		Scan(context.Background())
+	s.Require().NoError(err)
+	s.True(link.Completed)
+
+	// The task moved back by hand stays there.
+	s.Require().NoError(s.user.EditTask(&EditTaskOptions{TaskID: task.ID, TaskListID: &lists[0].ID}))
+	_, completed, err = s.user.linkChange("team/app", change, forge.Reference{ID: task.ID, Closing: true}, referenced)
+	s.Require().NoError(err)
+	s.False(completed)
+	s.Equal(lists[0].ID, s.getTask(task.ID).TaskListID)
+}
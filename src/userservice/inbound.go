@@ -0,0 +1,351 @@
+package userservice
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/samber/lo"
+	"github.com/uptrace/bun"
+
+	"github.com/lesnoi-kot/karten-backend/src/filestorage"
+	"github.com/lesnoi-kot/karten-backend/src/modules/email"
+	"github.com/lesnoi-kot/karten-backend/src/store"
+)
+
+var ErrNoInboxList = errors.New("Board has no list for the inbound emails")
+
+// Rolls back the email received at the same time as its copy.
+var errDuplicateEmail = errors.New("Email is received already")
+
+var inboundTokenEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+type ReceiveEmailOptions struct {
+	Message     *email.Message
+	Recipients  []string // Envelope recipients, the message headers are searched too
+	Domain      string   // Of the inbound addresses, any if empty
+	FileStorage filestorage.FileStorage
+}
+
+type ReceivedEmail struct {
+	BoardID   store.EntityID
+	TaskID    store.EntityID
+	CommentID *store.EntityID
+	Duplicate bool // The message was received before
+}
+
+// Returns the local part of the inbound address of the board, empty if
+// inbound email is off.
+func (user UserService) GetBoardInboundToken(boardID store.EntityID) (string, error) {
+	board := new(store.Board)
+	err := user.Store.ORM.NewSelect().
+		Model(board).
+		Column("inbound_token").
+		Where("id = ?", boardID).
+		Where("user_id = ?", user.UserID).
+		Scan(user.Context)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", store.ErrNotFound
+	} else if err != nil {
+		return "", err
+	}
+
+	return board.InboundToken, nil
+}
+
+// Turns inbound email on with a new address, the old one stops working.
+func (user UserService) ResetBoardInboundToken(boardID store.EntityID) (string, error) {
+	secret := make([]byte, 15)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	token := strings.ToLower(inboundTokenEncoding.EncodeToString(secret))
+
+	result, err := user.Store.ORM.NewUpdate().
+		Model((*store.Board)(nil)).
+		Set("inbound_token = ?", token).
+		Where("id = ?", boardID).
+		Where("user_id = ?", user.UserID).
+		Exec(user.Context)
+	if err != nil {
+		return "", err
+	} else if store.NoRowsAffected(result) {
+		return "", store.ErrNotFound
+	}
+
+	return token, nil
+}
+
+func (user UserService) DisableBoardInbound(boardID store.EntityID) error {
+	result, err := user.Store.ORM.NewUpdate().
+		Model((*store.Board)(nil)).
+		Set("inbound_token = NULL").
+		Where("id = ?", boardID).
+		Where("user_id = ?", user.UserID).
+		Exec(user.Context)
+	if err != nil {
+		return err
+	} else if store.NoRowsAffected(result) {
+		return store.ErrNotFound
+	}
+
+	return nil
+}
+
+// Turns the email sent to the inbound address of a board into a task in the
+// first list of the board, or into a comment if it replies to an email which
+// already became a task. Everything is done on behalf of the board owner.
+func ReceiveEmail(ctx context.Context, s *store.Store, args *ReceiveEmailOptions) (*ReceivedEmail, error) {
+	msg := args.Message
+
+	board, err := findInboundBoard(ctx, s, append(args.Recipients, msg.Recipients...), args.Domain)
+	if err != nil {
+		return nil, err
+	}
+
+	user := UserService{Context: ctx, UserID: board.UserID, Store: s}
+	received := &ReceivedEmail{BoardID: board.ID}
+
+	if msg.MessageID != "" {
+		known, err := knownEmail(ctx, s, board.ID, msg.MessageID)
+		if err == nil {
+			return known, nil
+		} else if !errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+	}
+
+	var threadTaskID store.EntityID
+	if len(msg.References) > 0 {
+		err := s.ORM.NewSelect().
+			TableExpr("email_messages AS em").
+			ColumnExpr("em.task_id").
+			Join("JOIN tasks AS t ON t.id = em.task_id").
+			Where("em.board_id = ?", board.ID).
+			Where("em.message_id IN (?)", bun.In(msg.References)).
+			Where("t.deleted_at IS NULL").
+			OrderExpr("em.date_created DESC").
+			Limit(1).
+			Scan(ctx, &threadTaskID)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+	}
+
+	files, err := user.addEmailAttachments(msg.Attachments)
+	defer func() {
+		if err != nil {
+			for _, file := range files {
+				user.Store.Files.Delete(ctx, file.ID)
+				args.FileStorage.Delete(file.StorageObjectID)
+			}
+		}
+	}()
+	if err != nil {
+		return nil, err
+	}
+
+	fileIDs := lo.Map(files, func(file *store.File, _ int) store.FileID {
+		return file.ID
+	})
+
+	// The task or the comment, the attachments and the Message-ID are saved
+	// together. The Message-ID is inserted last: if the same email is being
+	// received at once, the insert waits for the other transaction and this
+	// one is rolled back as a duplicate.
+	var comment *store.Comment
+	err = s.ORM.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		if threadTaskID != "" {
+			comment = &store.Comment{
+				TaskID: threadTaskID,
+				UserID: user.UserID,
+				Text:   emailText(msg, email.StripQuotedReply(msg.Text)),
+			}
+			if err := user.insertComment(ctx, tx, comment); err != nil {
+				return err
+			}
+			if err := attachFilesToComment(ctx, tx, comment.ID, fileIDs); err != nil {
+				return err
+			}
+
+			received.TaskID = threadTaskID
+			received.CommentID = &comment.ID
+		} else {
+			taskList, err := inboxTaskList(ctx, s, board.ID)
+			if err != nil {
+				return err
+			}
+
+			task := &store.Task{
+				UserID:     user.UserID,
+				TaskListID: taskList.ID,
+				Name:       lo.Ternary(msg.Subject != "", msg.Subject, "(no subject)"),
+				Text:       emailText(msg, msg.Text),
+				Position:   taskList.Position,
+			}
+			if err := user.insertTask(ctx, tx, task, 0); err != nil {
+				return err
+			}
+			if err := attachFilesToTask(ctx, tx, task.ID, fileIDs); err != nil {
+				return err
+			}
+
+			received.TaskID = task.ID
+		}
+
+		payload := &webhookPayload{Event: store.WebhookEventTaskCreated}
+		if comment != nil {
+			payload = commentAddedPayload(comment)
+		}
+		if err := user.queueTaskWebhooks(ctx, tx, payload, received.TaskID); err != nil {
+			return err
+		}
+
+		if msg.MessageID == "" {
+			return nil
+		}
+
+		result, err := tx.NewInsert().
+			Model(&store.EmailMessage{
+				BoardID:   board.ID,
+				MessageID: msg.MessageID,
+				TaskID:    received.TaskID,
+				CommentID: received.CommentID,
+			}).
+			Column("board_id", "message_id", "task_id", "comment_id").
+			On("CONFLICT DO NOTHING").
+			Exec(ctx)
+		if err != nil {
+			return err
+		} else if store.NoRowsAffected(result) {
+			return errDuplicateEmail
+		}
+
+		return nil
+	})
+	if errors.Is(err, errDuplicateEmail) {
+		// err stays set, so the attachments are deleted.
+		return knownEmail(ctx, s, board.ID, msg.MessageID)
+	} else if err != nil {
+		return nil, err
+	}
+
+	return received, nil
+}
+
+// The task or the comment made of the email received before.
+func knownEmail(ctx context.Context, s *store.Store, boardID store.EntityID, messageID string) (*ReceivedEmail, error) {
+	known := new(store.EmailMessage)
+	err := s.ORM.NewSelect().
+		Model(known).
+		Where("board_id = ?", boardID).
+		Where("message_id = ?", messageID).
+		Scan(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ReceivedEmail{
+		BoardID:   boardID,
+		TaskID:    known.TaskID,
+		CommentID: known.CommentID,
+		Duplicate: true,
+	}, nil
+}
+
+// Finds the board by the local part of the first recipient which is an
+// inbound address. Subaddresses, as in token+support@, are allowed.
+func findInboundBoard(ctx context.Context, s *store.Store, recipients []string, domain string) (*store.Board, error) {
+	var tokens []string
+	for _, recipient := range recipients {
+		local, recipientDomain, ok := strings.Cut(strings.ToLower(strings.TrimSpace(recipient)), "@")
+		if !ok || (domain != "" && recipientDomain != strings.ToLower(domain)) {
+			continue
+		}
+		local, _, _ = strings.Cut(local, "+")
+		tokens = append(tokens, local)
+	}
+	if len(tokens) == 0 {
+		return nil, store.ErrNotFound
+	}
+
+	board := new(store.Board)
+	err := s.ORM.NewSelect().
+		Model(board).
+		Column("id", "user_id").
+		Where("inbound_token IN (?)", bun.In(tokens)).
+		Where("archived = ?", false).
+		Limit(1).
+		Scan(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, store.ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	return board, nil
+}
+
+// The first list of the board gets the new tasks, at its top. Position is
+// of the new task.
+func inboxTaskList(ctx context.Context, s *store.Store, boardID store.EntityID) (*store.TaskList, error) {
+	taskList := new(store.TaskList)
+	err := s.ORM.NewSelect().
+		Model(taskList).
+		Column("id").
+		ColumnExpr(`coalesce((
+			SELECT min(t.position) FROM tasks AS t
+			WHERE t.task_list_id = task_list.id AND t.deleted_at IS NULL
+		), 0) - 1 AS position`).
+		Where("board_id = ?", boardID).
+		Where("archived = ?", false).
+		OrderExpr("task_list.position, task_list.date_created").
+		Limit(1).
+		Scan(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNoInboxList
+	} else if err != nil {
+		return nil, err
+	}
+
+	return taskList, nil
+}
+
+func (user UserService) addEmailAttachments(attachments []*email.Attachment) ([]*store.File, error) {
+	files := make([]*store.File, 0, len(attachments))
+	for _, attachment := range attachments {
+		file, err := user.Store.Files.Add(user.Context, store.AddFileOptions{
+			Name:     attachment.Name,
+			MIMEType: attachment.MimeType,
+			Data:     bytes.NewReader(attachment.Data),
+		})
+		if err != nil {
+			return files, err
+		}
+		files = append(files, file)
+	}
+
+	return files, nil
+}
+
+// Text of the task or the comment, signed by the sender.
+func emailText(msg *email.Message, text string) string {
+	sender := "unknown sender"
+	if msg.From != nil {
+		sender = msg.From.Address
+		if msg.From.Name != "" {
+			sender = fmt.Sprintf("%s <%s>", msg.From.Name, msg.From.Address)
+		}
+	}
+
+	if text == "" {
+		return fmt.Sprintf("*Email from %s*", sender)
+	}
+
+	return fmt.Sprintf("*Email from %s*\n\n%s", sender, text)
+}
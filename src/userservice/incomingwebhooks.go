@@ -0,0 +1,193 @@
+package userservice
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/samber/lo"
+	"github.com/uptrace/bun"
+
+	"github.com/lesnoi-kot/karten-backend/src/store"
+)
+
+type AddIncomingWebhookOptions struct {
+	TaskListID store.EntityID
+	Name       string
+}
+
+// Task sent to an incoming webhook.
+type IncomingTaskOptions struct {
+	Name    string
+	Text    string
+	Labels  []string // Names of the board labels, case insensitive
+	DueDate *time.Time
+}
+
+func (user UserService) GetIncomingWebhooks(taskListID store.EntityID) ([]*store.IncomingWebhook, error) {
+	if _, err := user.GetTaskList(&GetTaskListOptions{TaskListID: taskListID}); err != nil {
+		return nil, err
+	}
+
+	hooks := make([]*store.IncomingWebhook, 0)
+	err := user.Store.ORM.NewSelect().
+		Model(&hooks).
+		ExcludeColumn("token_hash").
+		Where("task_list_id = ?", taskListID).
+		Where("user_id = ?", user.UserID).
+		Order("date_created").
+		Scan(user.Context)
+	if err != nil {
+		return nil, err
+	}
+
+	return hooks, nil
+}
+
+// Returns the new webhook along with its secret token. The token can't be
+// got later.
+func (user UserService) AddIncomingWebhook(args *AddIncomingWebhookOptions) (*store.IncomingWebhook, string, error) {
+	if _, err := user.GetTaskList(&GetTaskListOptions{TaskListID: args.TaskListID}); err != nil {
+		return nil, "", err
+	}
+
+	secret, err := newSecretToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	hook := &store.IncomingWebhook{
+		TaskListID: args.TaskListID,
+		UserID:     user.UserID,
+		Name:       args.Name,
+		TokenHash:  hashSecretToken(secret),
+	}
+
+	_, err = user.Store.ORM.NewInsert().
+		Model(hook).
+		Column("task_list_id", "user_id", "name", "token_hash").
+		Returning("*").
+		Exec(user.Context)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return hook, secret, nil
+}
+
+// Revokes the webhook, its URL stops working.
+func (user UserService) DeleteIncomingWebhook(hookID store.EntityID) error {
+	result, err := user.Store.ORM.NewDelete().
+		Model((*store.IncomingWebhook)(nil)).
+		Where("id = ?", hookID).
+		Where("user_id = ?", user.UserID).
+		Exec(user.Context)
+	if err != nil {
+		return err
+	} else if store.NoRowsAffected(result) {
+		return store.ErrNotFound
+	}
+
+	return nil
+}
+
+// Creates the task at the top of the list of the incoming webhook the secret
+// belongs to, on behalf of the board owner. Returns the names of the labels
+// not found on the board along with the task.
+func ReceiveIncomingWebhook(ctx context.Context, s *store.Store, secret string, args *IncomingTaskOptions) (*store.Task, []string, error) {
+	var target struct {
+		ID         store.EntityID `bun:"id"`
+		TaskListID store.EntityID `bun:"task_list_id"`
+		BoardID    store.EntityID `bun:"board_id"`
+		OwnerID    store.UserID   `bun:"owner_id"`
+		Position   int64          `bun:"position"`
+	}
+	err := s.ORM.NewSelect().
+		TableExpr("incoming_webhooks AS iw").
+		ColumnExpr("iw.id, iw.task_list_id, tl.board_id, b.user_id AS owner_id").
+		ColumnExpr(`coalesce((
+			SELECT min(t.position) FROM tasks AS t
+			WHERE t.task_list_id = tl.id AND t.deleted_at IS NULL
+		), 0) - 1 AS position`).
+		Join("JOIN task_lists AS tl ON tl.id = iw.task_list_id").
+		Join("JOIN boards AS b ON b.id = tl.board_id").
+		Where("iw.token_hash = ?", hashSecretToken(secret)).
+		Where("tl.archived = ?", false).
+		Where("tl.deleted_at IS NULL").
+		Where("b.archived = ?", false).
+		Where("b.deleted_at IS NULL").
+		Scan(ctx, &target)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil, store.ErrNotFound
+	} else if err != nil {
+		return nil, nil, err
+	}
+
+	user := UserService{Context: ctx, UserID: target.OwnerID, Store: s}
+
+	labels, unknownLabels, err := user.findLabelsByName(target.BoardID, args.Labels)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	task := &store.Task{
+		UserID:     user.UserID,
+		TaskListID: target.TaskListID,
+		Name:       args.Name,
+		Text:       args.Text,
+		Position:   target.Position,
+		DueDate:    args.DueDate,
+	}
+
+	err = user.createTask(task, 0, labels, func(ctx context.Context, tx bun.Tx) error {
+		_, err := tx.NewUpdate().
+			Model((*store.IncomingWebhook)(nil)).
+			Set("date_last_used = ?", time.Now().UTC()).
+			Where("id = ?", target.ID).
+			Exec(ctx)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return task, unknownLabels, nil
+}
+
+// Finds the board labels with the names, ignoring the case. The names not
+// matching any label are returned as well.
+func (user UserService) findLabelsByName(boardID store.EntityID, names []string) ([]*store.Label, []string, error) {
+	boardLabels := make([]*store.Label, 0)
+	err := user.Store.ORM.NewSelect().
+		Model(&boardLabels).
+		Where("board_id = ?", boardID).
+		Order("id").
+		Scan(user.Context)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	labels, unknown := matchLabels(boardLabels, names)
+	return labels, unknown, nil
+}
+
+func matchLabels(boardLabels []*store.Label, names []string) ([]*store.Label, []string) {
+	labels := make([]*store.Label, 0, len(names))
+	var unknown []string
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+
+		if label := findLabel(boardLabels, name); label == nil {
+			unknown = append(unknown, name)
+		} else if !lo.Contains(labels, label) {
+			labels = append(labels, label)
+		}
+	}
+
+	return labels, unknown
+}
@@ -0,0 +1,73 @@
+package userservice
+
+import (
+	"context"
+	"crypto/sha256"
+	"testing"
+
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lesnoi-kot/karten-backend/src/store"
+)
+
+func TestHashSecretToken(t *testing.T) {
+	secret, err := newSecretToken()
+	require.NoError(t, err)
+	assert.Len(t, secret, 43, "32 bytes in unpadded base64")
+
+	other, err := newSecretToken()
+	require.NoError(t, err)
+	assert.NotEqual(t, secret, other)
+
+	hash := sha256.Sum256([]byte(secret))
+	assert.Equal(t, hash[:], hashSecretToken(secret))
+	assert.Equal(t, hashSecretToken(secret), hashSecretToken(secret))
+	assert.NotEqual(t, hashSecretToken(secret), hashSecretToken(other))
+}
+
+func TestMatchLabels(t *testing.T) {
+	bug := &store.Label{ID: 1, Name: "Bug"}
+	ui := &store.Label{ID: 2, Name: "UI"}
+
+	labels, unknown := matchLabels([]*store.Label{bug, ui}, []string{"bug", " ui ", "BUG", "", "  ", "Docs", "feature"})
+	assert.Equal(t, []*store.Label{bug, ui}, labels)
+	assert.Equal(t, []string{"Docs", "feature"}, unknown)
+
+	labels, unknown = matchLabels(nil, nil)
+	assert.Empty(t, labels)
+	assert.Nil(t, unknown)
+}
+
+func (s *userServiceSuite) TestReceiveIncomingWebhook() {
+	board, lists := s.addBoard("Inbox")
+	label, err := s.user.AddLabel(&AddLabelOptions{BoardID: board.ID, Name: "Bug"})
+	s.Require().NoError(err)
+	existing := s.addTask(lists[0].ID, "Existing")
+
+	hook, secret, err := s.user.AddIncomingWebhook(&AddIncomingWebhookOptions{TaskListID: lists[0].ID, Name: "CI"})
+	s.Require().NoError(err)
+
+	_, _, err = ReceiveIncomingWebhook(context.Background(), s.store, secret+"x", &IncomingTaskOptions{Name: "Build failed"})
+	s.ErrorIs(err, store.ErrNotFound)
+
+	task, unknown, err := ReceiveIncomingWebhook(context.Background(), s.store, secret, &IncomingTaskOptions{
+		Name:   "Build failed",
+		Labels: []string{"bug", "flaky"},
+	})
+	s.Require().NoError(err)
+	s.Equal([]string{"flaky"}, unknown)
+	s.NotEmpty(task.Key)
+	s.Less(task.Position, existing.Position, "new tasks go on top")
+
+	saved := s.getTask(task.ID)
+	s.Equal(s.user.UserID, saved.UserID)
+	s.Equal([]store.LabelID{label.ID}, lo.Map(saved.Labels, func(label *store.Label, _ int) store.LabelID { return label.ID }))
+
+	hooks, err := s.user.GetIncomingWebhooks(lists[0].ID)
+	s.Require().NoError(err)
+	s.Require().Len(hooks, 1)
+	s.Equal(hook.ID, hooks[0].ID)
+	s.NotNil(hooks[0].DateLastUsed)
+}
@@ -0,0 +1,320 @@
+package userservice
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sort"
+	"strconv"
+
+	"github.com/samber/lo"
+	"github.com/uptrace/bun"
+
+	"github.com/lesnoi-kot/karten-backend/src/store"
+)
+
+var ErrNoLanes = errors.New("Board has no swimlanes")
+
+type AddLaneOptions struct {
+	BoardID  store.EntityID
+	Name     string
+	Position int64
+}
+
+type EditLaneOptions struct {
+	LaneID   store.EntityID
+	Name     *string
+	Position *int64
+}
+
+func (user UserService) GetLanes(boardID store.EntityID) ([]*store.Lane, error) {
+	if owns, err := user.OwnsBoard(boardID); err != nil {
+		return nil, err
+	} else if !owns {
+		return nil, store.ErrNotFound
+	}
+
+	lanes := make([]*store.Lane, 0)
+	err := user.Store.ORM.NewSelect().
+		Model(&lanes).
+		Where("board_id = ?", boardID).
+		Order("position", "date_created").
+		Scan(user.Context)
+	if err != nil {
+		return nil, err
+	}
+
+	return lanes, nil
+}
+
+func (user UserService) AddLane(args *AddLaneOptions) (*store.Lane, error) {
+	if owns, err := user.OwnsBoard(args.BoardID); err != nil {
+		return nil, err
+	} else if !owns {
+		return nil, store.ErrNotFound
+	}
+
+	lane := &store.Lane{
+		BoardID:  args.BoardID,
+		UserID:   user.UserID,
+		Name:     args.Name,
+		Position: args.Position,
+	}
+
+	_, err := user.Store.ORM.NewInsert().
+		Model(lane).
+		Column("board_id", "user_id", "name", "position").
+		Returning("*").
+		Exec(user.Context)
+	if err != nil {
+		return nil, err
+	}
+
+	return lane, nil
+}
+
+func (user UserService) GetLane(laneID store.EntityID) (*store.Lane, error) {
+	lane := new(store.Lane)
+	err := user.Store.ORM.NewSelect().
+		Model(lane).
+		Where("id = ?", laneID).
+		Where("user_id = ?", user.UserID).
+		Scan(user.Context)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, store.ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	return lane, nil
+}
+
+func (user UserService) EditLane(args *EditLaneOptions) error {
+	if args.Name == nil && args.Position == nil {
+		return nil
+	}
+
+	q := user.Store.ORM.NewUpdate().
+		Model((*store.Lane)(nil)).
+		Where("id = ?", args.LaneID).
+		Where("user_id = ?", user.UserID)
+
+	if args.Name != nil && *args.Name != "" {
+		q = q.Set("name = ?", *args.Name)
+	}
+	if args.Position != nil {
+		q = q.Set("position = ?", *args.Position)
+	}
+
+	result, err := q.Exec(user.Context)
+	if err != nil {
+		return err
+	} else if store.NoRowsAffected(result) {
+		return store.ErrNotFound
+	}
+
+	return nil
+}
+
+// Tasks of the deleted lane move to the "no lane" row.
+func (user UserService) DeleteLane(laneID store.EntityID) error {
+	result, err := user.Store.ORM.NewDelete().
+		Model((*store.Lane)(nil)).
+		Where("id = ?", laneID).
+		Where("user_id = ?", user.UserID).
+		Exec(user.Context)
+	if err != nil {
+		return err
+	} else if store.NoRowsAffected(result) {
+		return store.ErrNotFound
+	}
+
+	return nil
+}
+
+func (user UserService) boardLaneMode(boardID store.EntityID) (string, error) {
+	var mode string
+	err := user.Store.ORM.NewSelect().
+		Model((*store.Board)(nil)).
+		Column("lane_mode").
+		Where("id = ?", boardID).
+		Where("user_id = ?", user.UserID).
+		Scan(user.Context, &mode)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", store.ErrNotFound
+	}
+
+	return mode, err
+}
+
+// Checks that the lane belongs to the board and returns the label the lane
+// stands for in the label mode.
+func (user UserService) checkLaneForBoard(
+	laneID store.EntityID,
+	boardID store.EntityID,
+	mode string,
+) (store.LabelID, error) {
+	switch mode {
+	case store.LaneModeExplicit:
+		exists, err := user.Store.ORM.NewSelect().
+			Model((*store.Lane)(nil)).
+			Where("id = ?", laneID).
+			Where("board_id = ?", boardID).
+			Where("user_id = ?", user.UserID).
+			Exists(user.Context)
+		if err != nil {
+			return 0, err
+		} else if !exists {
+			return 0, store.ErrNotFound
+		}
+
+		return 0, nil
+
+	case store.LaneModeLabel:
+		labelID, err := strconv.Atoi(laneID)
+		if err != nil {
+			return 0, store.ErrNotFound
+		}
+
+		exists, err := user.Store.ORM.NewSelect().
+			Model((*store.Label)(nil)).
+			Where("id = ?", labelID).
+			Where("board_id = ?", boardID).
+			Where("user_id = ?", user.UserID).
+			Exists(user.Context)
+		if err != nil {
+			return 0, err
+		} else if !exists {
+			return 0, store.ErrNotFound
+		}
+
+		return labelID, nil
+	}
+
+	return 0, store.ErrNotFound
+}
+
+// In the label mode the lane of a task is its board label with the lowest
+// ID. The task is put into another lane by dropping the board labels coming
+// before the label of the lane, or all of them for the "no lane" row. The
+// labels after it stay, they don't change the lane.
+func (user UserService) setTaskLaneLabel(
+	ctx context.Context,
+	tx bun.Tx,
+	taskID store.EntityID,
+	labelID store.LabelID,
+) error {
+	boardLabels := tx.NewSelect().
+		Model((*store.Label)(nil)).
+		Column("id").
+		Where("board_id = (?)", tx.NewSelect().
+			TableExpr("tasks AS t").
+			ColumnExpr("tl.board_id").
+			Join("JOIN task_lists AS tl ON tl.id = t.task_list_id").
+			Where("t.id = ?", taskID))
+	if labelID != 0 {
+		boardLabels = boardLabels.Where("id < ?", labelID)
+	}
+
+	_, err := tx.NewDelete().
+		Model((*store.LabelToTaskAssoc)(nil)).
+		Where("task_id = ?", taskID).
+		Where("label_id IN (?)", boardLabels).
+		Exec(ctx)
+	if err != nil || labelID == 0 {
+		return err
+	}
+
+	_, err = tx.NewInsert().
+		Model(&store.LabelToTaskAssoc{TaskID: taskID, LabelID: labelID}).
+		On("CONFLICT DO NOTHING").
+		Exec(ctx)
+
+	return err
+}
+
+// Arranges the loaded tasks of the board into lanes, each lane has a cell for
+// every list. Tasks without a lane go to the trailing lane with an empty ID,
+// which is only there if it is not empty.
+func (user UserService) fillLaneMatrix(board *store.Board) error {
+	var lanes []*store.Lane
+	laneOf := func(task *store.Task) store.EntityID {
+		return lo.FromPtr(task.LaneID)
+	}
+
+	switch board.LaneMode {
+	case store.LaneModeExplicit:
+		err := user.Store.ORM.NewSelect().
+			Model(&lanes).
+			Where("board_id = ?", board.ID).
+			Order("position", "date_created").
+			Scan(user.Context)
+		if err != nil {
+			return err
+		}
+
+	case store.LaneModeLabel:
+		labels := append([]*store.Label(nil), board.Labels...)
+		sort.Slice(labels, func(i, j int) bool { return labels[i].ID < labels[j].ID })
+
+		for _, label := range labels {
+			labelID := label.ID
+			lanes = append(lanes, &store.Lane{
+				ID:      strconv.Itoa(label.ID),
+				BoardID: board.ID,
+				UserID:  board.UserID,
+				Name:    label.Name,
+				LabelID: &labelID,
+			})
+		}
+
+		// The first of the board labels decides, labels are ordered by ID.
+		laneOf = func(task *store.Task) store.EntityID {
+			var first *store.Label
+			for _, label := range task.Labels {
+				if label.BoardID == board.ID && (first == nil || label.ID < first.ID) {
+					first = label
+				}
+			}
+			if first == nil {
+				return ""
+			}
+
+			return strconv.Itoa(first.ID)
+		}
+
+	default:
+		return nil
+	}
+
+	noLane := &store.Lane{BoardID: board.ID, UserID: board.UserID}
+	lanesByID := lo.KeyBy(lanes, func(lane *store.Lane) store.EntityID { return lane.ID })
+	lanes = append(lanes, noLane)
+
+	for _, lane := range lanes {
+		lane.Cells = lo.Map(board.TaskLists, func(taskList *store.TaskList, _ int) *store.LaneCell {
+			return &store.LaneCell{TaskListID: taskList.ID, TaskIDs: make([]store.EntityID, 0)}
+		})
+	}
+
+	noLaneTasks := 0
+	for i, taskList := range board.TaskLists {
+		for _, task := range taskList.Tasks {
+			lane, ok := lanesByID[laneOf(task)]
+			if !ok {
+				lane = noLane
+				noLaneTasks++
+			}
+
+			lane.Cells[i].TaskIDs = append(lane.Cells[i].TaskIDs, task.ID)
+		}
+	}
+
+	if noLaneTasks == 0 {
+		lanes = lanes[:len(lanes)-1]
+	}
+
+	board.Lanes = lanes
+
+	return nil
+}
@@ -0,0 +1,110 @@
+package userservice
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lesnoi-kot/karten-backend/src/store"
+)
+
+func TestFillLaneMatrixByLabels(t *testing.T) {
+	bug := &store.Label{ID: 5, BoardID: "board", Name: "Bug"}
+	feature := &store.Label{ID: 2, BoardID: "board", Name: "Feature"}
+	foreign := &store.Label{ID: 1, BoardID: "other"}
+
+	task := func(id store.EntityID, labels ...*store.Label) *store.Task {
+		return &store.Task{ID: id, Labels: labels}
+	}
+
+	tests := []struct {
+		name  string
+		lists [][]*store.Task
+		lanes map[store.EntityID][][]store.EntityID // Task IDs per list by lane ID
+	}{
+		{
+			name:  "lowest label ID wins",
+			lists: [][]*store.Task{{task("a", bug, feature)}, {task("b", feature, bug)}},
+			lanes: map[store.EntityID][][]store.EntityID{
+				"2": {{"a"}, {"b"}},
+				"5": {{}, {}},
+			},
+		},
+		{
+			name:  "labels of other boards are ignored",
+			lists: [][]*store.Task{{task("a", foreign, bug)}, {}},
+			lanes: map[store.EntityID][][]store.EntityID{
+				"2": {{}, {}},
+				"5": {{"a"}, {}},
+			},
+		},
+		{
+			name:  "tasks without board labels go to the trailing lane",
+			lists: [][]*store.Task{{task("a"), task("b", feature)}, {task("c", foreign)}},
+			lanes: map[store.EntityID][][]store.EntityID{
+				"2": {{"b"}, {}},
+				"5": {{}, {}},
+				"":  {{"a"}, {"c"}},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		board := &store.Board{
+			ID:       "board",
+			LaneMode: store.LaneModeLabel,
+			Labels:   []*store.Label{bug, feature},
+		}
+		for i, tasks := range test.lists {
+			board.TaskLists = append(board.TaskLists, &store.TaskList{ID: string(rune('x' + i)), Tasks: tasks})
+		}
+
+		assert.NoError(t, UserService{}.fillLaneMatrix(board), test.name)
+
+		laneIDs := lo.Map(board.Lanes, func(lane *store.Lane, _ int) store.EntityID { return lane.ID })
+		expectedIDs := []store.EntityID{"2", "5"}
+		if _, ok := test.lanes[""]; ok {
+			expectedIDs = append(expectedIDs, "")
+		}
+		assert.Equal(t, expectedIDs, laneIDs, test.name)
+
+		for _, lane := range board.Lanes {
+			cells := lo.Map(lane.Cells, func(cell *store.LaneCell, _ int) []store.EntityID { return cell.TaskIDs })
+			assert.Equal(t, test.lanes[lane.ID], cells, "%s: lane %q", test.name, lane.ID)
+		}
+	}
+
+	board := &store.Board{LaneMode: store.LaneModeNone, TaskLists: []*store.TaskList{{Tasks: []*store.Task{task("a")}}}}
+	assert.NoError(t, UserService{}.fillLaneMatrix(board))
+	assert.Nil(t, board.Lanes)
+}
+
+func (s *userServiceSuite) TestMoveTaskToLabelLane() {
+	board, lists := s.addBoard("Todo")
+	s.Require().NoError(s.user.EditBoard(&EditBoardOptions{BoardID: board.ID, LaneMode: lo.ToPtr(store.LaneModeLabel)}))
+
+	labels := make([]*store.Label, 3)
+	for i, name := range []string{"Bug", "Feature", "Chore"} {
+		var err error
+		labels[i], err = s.user.AddLabel(&AddLabelOptions{BoardID: board.ID, Name: name})
+		s.Require().NoError(err)
+	}
+
+	task := s.addTask(lists[0].ID, "Task")
+	s.Require().NoError(s.user.AddLabelToTask(&AddLabelToTaskOptions{TaskID: task.ID, LabelID: labels[0].ID}))
+	s.Require().NoError(s.user.AddLabelToTask(&AddLabelToTaskOptions{TaskID: task.ID, LabelID: labels[2].ID}))
+
+	taskLabels := func() []store.LabelID {
+		return lo.Map(s.getTask(task.ID).Labels, func(label *store.Label, _ int) store.LabelID { return label.ID })
+	}
+
+	// Labels before the lane label are dropped, the later ones stay.
+	laneID := lo.ToPtr(strconv.Itoa(labels[1].ID))
+	s.Require().NoError(s.user.EditTask(&EditTaskOptions{TaskID: task.ID, LaneID: laneID}))
+	s.ElementsMatch([]store.LabelID{labels[1].ID, labels[2].ID}, taskLabels())
+
+	s.Require().NoError(s.user.EditTask(&EditTaskOptions{TaskID: task.ID, LaneID: lo.ToPtr("")}))
+	s.Empty(taskLabels())
+}
@@ -0,0 +1,195 @@
+package userservice
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/uptrace/bun"
+
+	"github.com/lesnoi-kot/karten-backend/src/modules/textdiff"
+	"github.com/lesnoi-kot/karten-backend/src/store"
+)
+
+// Edits made by the same author within this window go into one revision.
+const revisionSquashWindow = 10 * time.Minute
+
+const revisionDiffContext = 3
+
+type GetTaskRevisionsOptions struct {
+	TaskID store.EntityID
+	Limit  int
+	Offset int
+}
+
+type RestoreTaskRevisionOptions struct {
+	TaskID store.EntityID
+	Number int
+}
+
+type TaskRevisionWithDiff struct {
+	*store.TaskRevision
+
+	// Unified diff against the previous revision
+	Diff string
+}
+
+// Records the new task text. With squash, the latest revision is
+// overwritten if the same user made it a moment ago. The first revision,
+// the text the task was created with, is never overwritten.
+func (user UserService) addTaskRevision(
+	ctx context.Context,
+	tx bun.Tx,
+	taskID store.EntityID,
+	text string,
+	squash bool,
+) error {
+	// Numbers are taken as the latest one plus one, the lock on the task
+	// keeps concurrent edits from taking the same.
+	_, err := tx.ExecContext(ctx, "SELECT id FROM tasks WHERE id = ? FOR UPDATE", taskID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	latest := new(store.TaskRevision)
+
+	err = tx.NewSelect().
+		Model(latest).
+		Where("task_id = ?", taskID).
+		OrderExpr("number DESC").
+		Limit(1).
+		Scan(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		latest = nil
+	} else if err != nil {
+		return err
+	}
+
+	if latest != nil && latest.Text == text {
+		return nil
+	}
+
+	if squash &&
+		latest != nil &&
+		latest.Number > 1 &&
+		latest.UserID == user.UserID &&
+		now.Sub(latest.DateUpdated) < revisionSquashWindow {
+		_, err := tx.NewUpdate().
+			Model(latest).
+			Set("text = ?", text).
+			Set("date_updated = ?", now).
+			WherePK().
+			Exec(ctx)
+		return err
+	}
+
+	revision := &store.TaskRevision{
+		TaskID:      taskID,
+		UserID:      user.UserID,
+		Number:      1,
+		Text:        text,
+		DateCreated: now,
+		DateUpdated: now,
+	}
+	if latest != nil {
+		revision.Number = latest.Number + 1
+	}
+
+	_, err = tx.NewInsert().
+		Model(revision).
+		Column("task_id", "user_id", "number", "text", "date_created", "date_updated").
+		Exec(ctx)
+	return err
+}
+
+// Returns the task revisions, newest first, each one with the diff against
+// its predecessor.
+func (user UserService) GetTaskRevisions(args *GetTaskRevisionsOptions) ([]*TaskRevisionWithDiff, int, error) {
+	if owns, err := user.OwnsTask(args.TaskID); err != nil {
+		return nil, 0, err
+	} else if !owns {
+		return nil, 0, store.ErrNotFound
+	}
+
+	var revisions []*store.TaskRevision
+
+	// One more revision is needed as the base of the oldest diff.
+	total, err := user.Store.ORM.NewSelect().
+		Model(&revisions).
+		Relation("Author").
+		Where("task_id = ?", args.TaskID).
+		OrderExpr("number DESC").
+		Limit(args.Limit + 1).
+		Offset(args.Offset).
+		ScanAndCount(user.Context)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	result := make([]*TaskRevisionWithDiff, 0, len(revisions))
+	for i, revision := range revisions {
+		if i == args.Limit {
+			break
+		}
+
+		base, baseName := "", "/dev/null"
+		if i+1 < len(revisions) {
+			base = revisions[i+1].Text
+			baseName = fmt.Sprintf("r%d", revisions[i+1].Number)
+		}
+
+		result = append(result, &TaskRevisionWithDiff{
+			TaskRevision: revision,
+			Diff: textdiff.Unified(
+				baseName,
+				fmt.Sprintf("r%d", revision.Number),
+				base,
+				revision.Text,
+				revisionDiffContext,
+			),
+		})
+	}
+
+	return result, total, nil
+}
+
+// Sets the task text to the one of the given revision. The restoration is
+// recorded as a new revision.
+func (user UserService) RestoreTaskRevision(args *RestoreTaskRevisionOptions) error {
+	if owns, err := user.OwnsTask(args.TaskID); err != nil {
+		return err
+	} else if !owns {
+		return store.ErrNotFound
+	}
+
+	revision := new(store.TaskRevision)
+	err := user.Store.ORM.NewSelect().
+		Model(revision).
+		Where("task_id = ?", args.TaskID).
+		Where("number = ?", args.Number).
+		Scan(user.Context)
+	if errors.Is(err, sql.ErrNoRows) {
+		return store.ErrNotFound
+	} else if err != nil {
+		return err
+	}
+
+	return user.Store.ORM.RunInTx(user.Context, nil, func(ctx context.Context, tx bun.Tx) error {
+		updateResult, err := tx.NewUpdate().
+			Model((*store.Task)(nil)).
+			Set("text = ?", revision.Text).
+			Where("id = ?", args.TaskID).
+			Where("user_id = ?", user.UserID).
+			Exec(ctx)
+		if err != nil {
+			return err
+		} else if store.NoRowsAffected(updateResult) {
+			return store.ErrNotFound
+		}
+
+		return user.addTaskRevision(ctx, tx, args.TaskID, revision.Text, false)
+	})
+}
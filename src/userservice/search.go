@@ -0,0 +1,177 @@
+package userservice
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"strings"
+	"time"
+
+	"github.com/samber/lo"
+
+	"github.com/lesnoi-kot/karten-backend/src/settings"
+	"github.com/lesnoi-kot/karten-backend/src/store"
+)
+
+var ErrInvalidSearchLanguage = errors.New("Unsupported search language")
+
+// Text search configurations with search indexes.
+var SearchLanguages = []string{"english", "russian"}
+
+// Bounds of the matches in the snippets made by ts_headline, replaced with
+// the <mark> tags after the snippets are escaped.
+const (
+	snippetStart = "\x01"
+	snippetStop  = "\x02"
+)
+
+// Parts of the search query shared by its branches, which find the matches
+// of a task "t" and narrow them down to the user's tasks.
+const (
+	searchTaskColumns = `t.name AS task_name, t.number AS task_number,
+		tl.id AS task_list_id, tl.name AS task_list_name,
+		b.id AS board_id, b.name AS board_name,
+		p.id AS project_id, p.name AS project_name, p.key_prefix`
+
+	searchTaskJoins = `
+		JOIN task_lists AS tl ON tl.id = t.task_list_id
+		JOIN boards AS b ON b.id = tl.board_id
+		JOIN projects AS p ON p.id = b.project_id`
+
+	searchTaskFilter = `p.user_id = ?2
+		AND t.deleted_at IS NULL
+		AND tl.deleted_at IS NULL
+		AND b.deleted_at IS NULL
+		AND p.deleted_at IS NULL`
+)
+
+type SearchOptions struct {
+	Query    string // Web search syntax: quoted phrases, "or", "-" to exclude
+	Language string // One of SearchLanguages, the configured one if empty
+	Limit    int
+}
+
+// Task, comment or attachment matching a search, with its board and list.
+type SearchHit struct {
+	Kind      string         `bun:"kind"` // task, comment or attachment
+	TaskID    store.EntityID `bun:"task_id"`
+	CommentID store.EntityID `bun:"comment_id,nullzero"`
+	FileID    store.FileID   `bun:"file_id,nullzero"`
+	FileName  string         `bun:"file_name,nullzero"`
+	Rank      float64        `bun:"rank"`
+	Date      time.Time      `bun:"date_created"`
+
+	// HTML-escaped excerpt with the matched words in <mark> tags.
+	Snippet string `bun:"snippet"`
+
+	TaskName     string         `bun:"task_name"`
+	TaskNumber   int64          `bun:"task_number,nullzero"`
+	TaskKey      string         `bun:"-"`
+	TaskListID   store.EntityID `bun:"task_list_id"`
+	TaskListName string         `bun:"task_list_name"`
+	BoardID      store.EntityID `bun:"board_id"`
+	BoardName    string         `bun:"board_name"`
+	ProjectID    store.EntityID `bun:"project_id"`
+	ProjectName  string         `bun:"project_name"`
+	KeyPrefix    string         `bun:"key_prefix"`
+}
+
+// Searches the names and texts of the tasks, the comments and the names of the
+// attached files in the user's projects, the best matches first. Trashed
+// items are left out.
+func (user UserService) Search(args *SearchOptions) ([]*SearchHit, error) {
+	language := args.Language
+	if language == "" {
+		language = settings.AppConfig.SearchLanguage
+	}
+	if !lo.Contains(SearchLanguages, language) {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidSearchLanguage, language)
+	}
+
+	// The to_tsvector expressions are the ones of the search indexes. Every
+	// branch is limited to the user's tasks before the hits are ranked.
+	hits := make([]*SearchHit, 0)
+	err := user.Store.ORM.NewRaw(`
+		WITH query AS (SELECT websearch_to_tsquery(?0::regconfig, ?1) AS q),
+		hits AS (
+			SELECT 'task' AS kind, t.id AS task_id, NULL::uuid AS comment_id, NULL::uuid AS file_id,
+				t.name || ' ' || t.text AS document,
+				ts_rank(to_tsvector(?0::regconfig, t.name || ' ' || t.text), query.q) AS rank,
+				t.date_created, `+searchTaskColumns+`
+			FROM tasks AS t`+searchTaskJoins+`, query
+			WHERE to_tsvector(?0::regconfig, t.name || ' ' || t.text) @@ query.q
+				AND `+searchTaskFilter+`
+
+			UNION ALL
+
+			SELECT 'comment', c.task_id, c.id, NULL, c.text,
+				ts_rank(to_tsvector(?0::regconfig, c.text), query.q),
+				c.date_created, `+searchTaskColumns+`
+			FROM comments AS c
+			JOIN tasks AS t ON t.id = c.task_id`+searchTaskJoins+`, query
+			WHERE to_tsvector(?0::regconfig, c.text) @@ query.q
+				AND `+searchTaskFilter+`
+
+			UNION ALL
+
+			SELECT 'attachment', tf.task_id, NULL, f.id, f.name,
+				ts_rank(to_tsvector(?0::regconfig, f.name), query.q),
+				t.date_created, `+searchTaskColumns+`
+			FROM files AS f
+			JOIN task_files AS tf ON tf.file_id = f.id
+			JOIN tasks AS t ON t.id = tf.task_id`+searchTaskJoins+`, query
+			WHERE to_tsvector(?0::regconfig, f.name) @@ query.q
+				AND `+searchTaskFilter+`
+
+			UNION ALL
+
+			SELECT 'attachment', c.task_id, c.id, f.id, f.name,
+				ts_rank(to_tsvector(?0::regconfig, f.name), query.q),
+				c.date_created, `+searchTaskColumns+`
+			FROM files AS f
+			JOIN comment_files AS cf ON cf.file_id = f.id
+			JOIN comments AS c ON c.id = cf.comment_id
+			JOIN tasks AS t ON t.id = c.task_id`+searchTaskJoins+`, query
+			WHERE to_tsvector(?0::regconfig, f.name) @@ query.q
+				AND `+searchTaskFilter+`
+		),
+		best AS (
+			SELECT * FROM hits
+			ORDER BY rank DESC, date_created DESC
+			LIMIT ?3
+		)
+		SELECT
+			best.kind, best.task_id, best.comment_id, best.file_id,
+			CASE WHEN best.file_id IS NOT NULL THEN best.document END AS file_name,
+			best.rank, best.date_created,
+			ts_headline(?0::regconfig, best.document, query.q, ?4) AS snippet,
+			best.task_name, best.task_number, best.task_list_id, best.task_list_name,
+			best.board_id, best.board_name, best.project_id, best.project_name, best.key_prefix
+		FROM best, query
+		ORDER BY best.rank DESC, best.date_created DESC`,
+		language,
+		args.Query,
+		user.UserID,
+		args.Limit,
+		`StartSel="`+snippetStart+`", StopSel="`+snippetStop+`", MaxWords=24, MinWords=8, MaxFragments=2, FragmentDelimiter=" … "`,
+	).Scan(user.Context, &hits)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, hit := range hits {
+		hit.Snippet = formatSnippet(hit.Snippet)
+		if hit.TaskNumber != 0 {
+			hit.TaskKey = store.FormatTaskKey(hit.KeyPrefix, hit.TaskNumber)
+		}
+	}
+
+	return hits, nil
+}
+
+func formatSnippet(snippet string) string {
+	return strings.NewReplacer(
+		snippetStart, "<mark>",
+		snippetStop, "</mark>",
+	).Replace(html.EscapeString(snippet))
+}
@@ -0,0 +1,17 @@
+package userservice
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatSnippet(t *testing.T) {
+	snippet := `fix <script>alert("` + snippetStart + `xss` + snippetStop + `")</script> & ` + snippetStart + `login` + snippetStop
+
+	assert.Equal(t,
+		`fix &lt;script&gt;alert(&#34;<mark>xss</mark>&#34;)&lt;/script&gt; &amp; <mark>login</mark>`,
+		formatSnippet(snippet),
+	)
+	assert.Equal(t, "no matches", formatSnippet("no matches"))
+}
@@ -0,0 +1,211 @@
+package userservice
+
+import (
+	"time"
+
+	"github.com/samber/lo"
+
+	"github.com/lesnoi-kot/karten-backend/src/store"
+)
+
+type GetBoardSnapshotsOptions struct {
+	BoardID store.EntityID
+	From    time.Time // First day
+	To      time.Time // Last day, inclusive
+}
+
+type CumulativeFlow struct {
+	Dates     []time.Time
+	TaskLists []*store.TaskList
+	Counts    map[store.EntityID][]int // Tasks per day by list ID
+
+	// Sums of the task estimates per day by list ID, nil for the days
+	// recorded before the estimates.
+	Estimates map[store.EntityID][]*float64
+}
+
+type BurndownPoint struct {
+	Date      time.Time
+	Remaining int
+	Done      int
+
+	// Sums of the estimates, nil if unknown for the day.
+	RemainingEstimate *float64
+	DoneEstimate      *float64
+}
+
+type Burndown struct {
+	DoneListID store.EntityID
+	Points     []BurndownPoint
+}
+
+// Returns the daily task counts of the board lists. Days without a snapshot
+// repeat the previous day.
+func (user UserService) GetBoardCumulativeFlow(args *GetBoardSnapshotsOptions) (*CumulativeFlow, error) {
+	if owns, err := user.OwnsBoard(args.BoardID); err != nil {
+		return nil, err
+	} else if !owns {
+		return nil, store.ErrNotFound
+	}
+
+	var taskLists []*store.TaskList
+	err := user.Store.ORM.NewSelect().
+		Model(&taskLists).
+		Where("board_id = ?", args.BoardID).
+		Order("position").
+		Scan(user.Context)
+	if err != nil {
+		return nil, err
+	}
+
+	from := truncateToDay(args.From)
+	to := truncateToDay(args.To)
+
+	// The latest snapshot before the range is the starting point.
+	var previous []store.BoardSnapshot
+	err = user.Store.ORM.NewSelect().
+		Model(&previous).
+		Where("board_id = ?", args.BoardID).
+		Where("date = (?)", user.Store.ORM.NewSelect().
+			Model((*store.BoardSnapshot)(nil)).
+			ColumnExpr("max(date)").
+			Where("board_id = ?", args.BoardID).
+			Where("date < ?", from)).
+		Scan(user.Context)
+	if err != nil {
+		return nil, err
+	}
+
+	var snapshots []store.BoardSnapshot
+	err = user.Store.ORM.NewSelect().
+		Model(&snapshots).
+		Where("board_id = ?", args.BoardID).
+		Where("date >= ?", from).
+		Where("date <= ?", to).
+		Scan(user.Context)
+	if err != nil {
+		return nil, err
+	}
+
+	return cumulativeFlow(taskLists, previous, snapshots, from, to), nil
+}
+
+// Lays out the snapshots of the days between from and to starting with the
+// previous ones.
+func cumulativeFlow(
+	taskLists []*store.TaskList,
+	previous, snapshots []store.BoardSnapshot,
+	from, to time.Time,
+) *CumulativeFlow {
+	byDay := lo.GroupBy(snapshots, func(snapshot store.BoardSnapshot) time.Time {
+		return truncateToDay(snapshot.Date)
+	})
+
+	current := make(map[store.EntityID]store.BoardSnapshot)
+	for _, snapshot := range previous {
+		current[snapshot.TaskListID] = snapshot
+	}
+
+	flow := &CumulativeFlow{
+		Counts:    make(map[store.EntityID][]int, len(taskLists)),
+		Estimates: make(map[store.EntityID][]*float64, len(taskLists)),
+	}
+
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		if rows, ok := byDay[day]; ok {
+			current = make(map[store.EntityID]store.BoardSnapshot, len(rows))
+			for _, snapshot := range rows {
+				current[snapshot.TaskListID] = snapshot
+			}
+		}
+
+		// A list missing from the snapshot of the day had nothing to
+		// estimate, it's unknown only for the days before the estimates.
+		var noEstimate *float64
+		if len(current) > 0 && lo.SomeBy(lo.Values(current), func(snapshot store.BoardSnapshot) bool {
+			return snapshot.Estimate != nil
+		}) {
+			noEstimate = new(float64)
+		}
+
+		flow.Dates = append(flow.Dates, day)
+		for _, taskList := range taskLists {
+			snapshot, ok := current[taskList.ID]
+			flow.Counts[taskList.ID] = append(flow.Counts[taskList.ID], snapshot.TaskCount)
+
+			estimate := snapshot.Estimate
+			if !ok {
+				estimate = noEstimate
+			}
+			flow.Estimates[taskList.ID] = append(flow.Estimates[taskList.ID], estimate)
+		}
+	}
+
+	// Archived lists matter only if they had tasks within the range.
+	flow.TaskLists = lo.Filter(taskLists, func(taskList *store.TaskList, _ int) bool {
+		return !taskList.Archived || lo.Sum(flow.Counts[taskList.ID]) > 0
+	})
+
+	return flow
+}
+
+// Tasks and estimates left and done by day. The last active list of the board
+// counts as the done one.
+func (user UserService) GetBoardBurndown(args *GetBoardSnapshotsOptions) (*Burndown, error) {
+	flow, err := user.GetBoardCumulativeFlow(args)
+	if err != nil {
+		return nil, err
+	}
+
+	return burndownOf(flow), nil
+}
+
+func burndownOf(flow *CumulativeFlow) *Burndown {
+	burndown := &Burndown{
+		Points: make([]BurndownPoint, len(flow.Dates)),
+	}
+
+	if active := lo.Filter(flow.TaskLists, func(taskList *store.TaskList, _ int) bool {
+		return !taskList.Archived
+	}); len(active) > 0 {
+		burndown.DoneListID = active[len(active)-1].ID
+	}
+
+	for i, date := range flow.Dates {
+		point := BurndownPoint{Date: date}
+		var remainingEstimate, doneEstimate float64
+		knownEstimates := true
+
+		for _, taskList := range flow.TaskLists {
+			estimate := flow.Estimates[taskList.ID][i]
+			if estimate == nil && (taskList.ID == burndown.DoneListID || !taskList.Archived) {
+				knownEstimates = false
+			}
+
+			if taskList.ID == burndown.DoneListID {
+				point.Done += flow.Counts[taskList.ID][i]
+				if estimate != nil {
+					doneEstimate += *estimate
+				}
+			} else if !taskList.Archived {
+				point.Remaining += flow.Counts[taskList.ID][i]
+				if estimate != nil {
+					remainingEstimate += *estimate
+				}
+			}
+		}
+
+		if knownEstimates {
+			point.RemainingEstimate = &remainingEstimate
+			point.DoneEstimate = &doneEstimate
+		}
+
+		burndown.Points[i] = point
+	}
+
+	return burndown
+}
+
+func truncateToDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
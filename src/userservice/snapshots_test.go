@@ -0,0 +1,171 @@
+package userservice
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lesnoi-kot/karten-backend/src/store"
+)
+
+func TestCumulativeFlow(t *testing.T) {
+	todo := &store.TaskList{ID: "todo"}
+	done := &store.TaskList{ID: "done"}
+	old := &store.TaskList{ID: "old", Archived: true}
+	unused := &store.TaskList{ID: "unused", Archived: true}
+
+	day := func(n int) time.Time { return time.Date(2026, 10, 1+n, 0, 0, 0, 0, time.UTC) }
+	previous := []store.BoardSnapshot{
+		{TaskListID: "todo", Date: day(-2), TaskCount: 3},
+		{TaskListID: "done", Date: day(-2), TaskCount: 1},
+		{TaskListID: "old", Date: day(-2), TaskCount: 1},
+	}
+	snapshots := []store.BoardSnapshot{
+		{TaskListID: "todo", Date: day(1), TaskCount: 2, Estimate: lo.ToPtr(5.0)},
+		{TaskListID: "done", Date: day(1), TaskCount: 2, Estimate: lo.ToPtr(3.0)},
+		{TaskListID: "todo", Date: day(3), TaskCount: 1, Estimate: lo.ToPtr(2.0)},
+	}
+
+	flow := cumulativeFlow([]*store.TaskList{todo, done, old, unused}, previous, snapshots, day(0), day(3))
+
+	assert.Equal(t, []time.Time{day(0), day(1), day(2), day(3)}, flow.Dates)
+	assert.Equal(t, []*store.TaskList{todo, done, old}, flow.TaskLists, "archived lists without tasks are dropped")
+
+	assert.Equal(t, []int{3, 2, 2, 1}, flow.Counts["todo"])
+	assert.Equal(t, []int{1, 2, 2, 0}, flow.Counts["done"])
+	assert.Equal(t, []int{1, 0, 0, 0}, flow.Counts["old"])
+
+	assert.Equal(t, []*float64{nil, lo.ToPtr(5.0), lo.ToPtr(5.0), lo.ToPtr(2.0)}, flow.Estimates["todo"])
+	assert.Equal(t, []*float64{nil, lo.ToPtr(3.0), lo.ToPtr(3.0), lo.ToPtr(0.0)}, flow.Estimates["done"])
+	assert.Equal(t, []*float64{nil, lo.ToPtr(0.0), lo.ToPtr(0.0), lo.ToPtr(0.0)}, flow.Estimates["old"])
+
+	burndown := burndownOf(flow)
+	assert.Equal(t, "done", burndown.DoneListID, "the last active list is the done one")
+	assert.Equal(t, []BurndownPoint{
+		{Date: day(0), Remaining: 3, Done: 1},
+		{Date: day(1), Remaining: 2, Done: 2, RemainingEstimate: lo.ToPtr(5.0), DoneEstimate: lo.ToPtr(3.0)},
+		{Date: day(2), Remaining: 2, Done: 2, RemainingEstimate: lo.ToPtr(5.0), DoneEstimate: lo.ToPtr(3.0)},
+		{Date: day(3), Remaining: 1, Done: 0, RemainingEstimate: lo.ToPtr(2.0), DoneEstimate: lo.ToPtr(0.0)},
+	}, burndown.Points)
+}
+
+func TestCumulativeFlowWithoutSnapshots(t *testing.T) {
+	day := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	flow := cumulativeFlow([]*store.TaskList{{ID: "todo"}}, nil, nil, day, day.AddDate(0, 0, 1))
+
+	assert.Equal(t, []int{0, 0}, flow.Counts["todo"])
+	assert.Equal(t, []*float64{nil, nil}, flow.Estimates["todo"])
+	assert.Empty(t, burndownOf(&CumulativeFlow{}).DoneListID)
+}
+
+func (s *userServiceSuite) TestTakeBoardSnapshots() {
+	board, lists := s.addBoard("Todo", "Done")
+	estimated := s.addTask(lists[0].ID, "Estimated")
+	s.Require().NoError(s.user.EditTask(&EditTaskOptions{TaskID: estimated.ID, Estimate: lo.ToPtr(2.0)}))
+	archived := s.addTask(lists[0].ID, "Archived")
+	s.Require().NoError(s.user.EditTask(&EditTaskOptions{TaskID: archived.ID, Estimate: lo.ToPtr(3.0)}))
+	s.Require().NoError(s.user.ArchiveTask(archived.ID))
+	s.addTask(lists[1].ID, "Done")
+
+	today := truncateToDay(time.Now().UTC())
+	_, err := s.store.TakeBoardSnapshots(context.Background(), today)
+	s.Require().NoError(err)
+
+	flow, err := s.user.GetBoardCumulativeFlow(&GetBoardSnapshotsOptions{BoardID: board.ID, From: today, To: today})
+	s.Require().NoError(err)
+	s.Equal([]int{1}, flow.Counts[lists[0].ID])
+	s.Equal([]int{1}, flow.Counts[lists[1].ID])
+	s.Equal([]*float64{lo.ToPtr(2.0)}, flow.Estimates[lists[0].ID])
+	s.Equal([]*float64{lo.ToPtr(0.0)}, flow.Estimates[lists[1].ID])
+
+	// The last snapshot of the day wins.
+	s.Require().NoError(s.user.EditTask(&EditTaskOptions{TaskID: estimated.ID, TaskListID: &lists[1].ID}))
+	_, err = s.store.TakeBoardSnapshots(context.Background(), today)
+	s.Require().NoError(err)
+
+	burndown, err := s.user.GetBoardBurndown(&GetBoardSnapshotsOptions{BoardID: board.ID, From: today, To: today})
+	s.Require().NoError(err)
+	s.Equal(lists[1].ID, burndown.DoneListID)
+	s.Equal([]BurndownPoint{
+		{Date: today, Remaining: 0, Done: 2, RemainingEstimate: lo.ToPtr(0.0), DoneEstimate: lo.ToPtr(2.0)},
+	}, burndown.Points)
+}
+
+// Replays the backfill of the snapshots migration over a made up history in
+// a transaction that is rolled back.
+func (s *userServiceSuite) TestBackfillBoardSnapshots() {
+	migration, err := os.ReadFile("../cmd/migrator/migrations/20261019160000_board_snapshots.up.sql")
+	s.Require().NoError(err)
+	backfill := string(migration[strings.Index(string(migration), "INSERT INTO board_snapshots"):])
+
+	board, lists := s.addBoard("Todo", "Done")
+	moved := s.addTask(lists[1].ID, "Moved")
+	archived := s.addTask(lists[0].ID, "Archived")
+	deleted := s.addTask(lists[0].ID, "Deleted")
+
+	ctx := context.Background()
+	tx, err := s.store.ORM.BeginTx(ctx, nil)
+	s.Require().NoError(err)
+	defer tx.Rollback()
+
+	day := func(n int) time.Time { return truncateToDay(time.Now().UTC()).AddDate(0, 0, n-3) }
+	noon := func(n int) time.Time { return day(n).Add(12 * time.Hour) }
+
+	exec := func(query string, args ...any) {
+		_, err := tx.ExecContext(ctx, query, args...)
+		s.Require().NoError(err)
+	}
+	exec("DELETE FROM board_snapshots")
+	exec("UPDATE boards SET date_created = ? WHERE id = ?", day(0), board.ID)
+	exec("DELETE FROM task_movements WHERE task_id IN (?, ?, ?)", moved.ID, archived.ID, deleted.ID)
+	exec("UPDATE tasks SET archived = true, date_archived = ? WHERE id = ?", noon(2), archived.ID)
+	exec("UPDATE tasks SET deleted_at = ? WHERE id = ?", noon(1), deleted.ID)
+
+	for _, movement := range []struct {
+		taskID   store.EntityID
+		from, to any
+		date     time.Time
+	}{
+		{moved.ID, nil, lists[0].ID, noon(0)},
+		{moved.ID, lists[0].ID, lists[1].ID, noon(1)},
+		{archived.ID, nil, lists[0].ID, noon(1)},
+		{deleted.ID, nil, lists[0].ID, noon(0)},
+	} {
+		exec(
+			"INSERT INTO task_movements (task_id, from_task_list_id, to_task_list_id, date_created) VALUES (?, ?, ?, ?)",
+			movement.taskID, movement.from, movement.to, movement.date,
+		)
+	}
+
+	exec(backfill)
+
+	var snapshots []store.BoardSnapshot
+	err = tx.NewSelect().
+		Model(&snapshots).
+		Where("board_id = ?", board.ID).
+		Where("date <= ?", day(2)).
+		Order("date").
+		Scan(ctx)
+	s.Require().NoError(err)
+
+	counts := make(map[store.EntityID][]int)
+	for _, snapshot := range snapshots {
+		s.Nil(snapshot.Estimate)
+		counts[snapshot.TaskListID] = append(counts[snapshot.TaskListID], snapshot.TaskCount)
+	}
+	s.Equal([]int{1, 1, 0}, counts[lists[0].ID], "the archived task leaves on the day of archiving")
+	s.Equal([]int{0, 1, 1}, counts[lists[1].ID])
+
+	s.Require().NoError(tx.Rollback())
+	exists, err := s.store.ORM.NewSelect().
+		Model((*store.BoardSnapshot)(nil)).
+		Where("board_id = ?", board.ID).
+		Exists(ctx)
+	s.Require().NoError(err)
+	s.False(exists)
+}
@@ -0,0 +1,520 @@
+package userservice
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/samber/lo"
+	"github.com/uptrace/bun"
+
+	"github.com/lesnoi-kot/karten-backend/src/store"
+)
+
+var (
+	ErrSprintClosed   = errors.New("Sprint is closed")
+	ErrNoNextSprint   = errors.New("There is no open sprint to roll unfinished tasks over to")
+	ErrNoActiveSprint = errors.New("Board has no active sprint")
+)
+
+type AddSprintOptions struct {
+	BoardID   store.EntityID
+	Name      string
+	Goal      string
+	DateStart time.Time
+	DateEnd   time.Time
+}
+
+type EditSprintOptions struct {
+	SprintID  store.EntityID
+	Name      *string
+	Goal      *string
+	DateStart *time.Time
+	DateEnd   *time.Time
+}
+
+type CloseSprintOptions struct {
+	SprintID store.EntityID
+	Rollover bool
+	// Target of the rollover, the next open sprint of the board by default
+	NextSprintID *store.EntityID
+}
+
+type SprintSummary struct {
+	Committed  int // Tasks in the sprint by the end of its first day
+	Added      int // Tasks added later
+	Removed    int // Tasks taken out of the sprint
+	Completed  int
+	Unfinished int
+
+	// Sums of the current estimates of the committed and the completed
+	// tasks, in the estimate unit of the board.
+	CommittedEstimate float64
+	CompletedEstimate float64
+}
+
+type SprintWithSummary struct {
+	*store.Sprint
+	Summary SprintSummary
+}
+
+type sprintCommitmentRow struct {
+	TaskID      string     `bun:"task_id"`
+	DateAdded   time.Time  `bun:"date_added"`
+	DateRemoved *time.Time `bun:"date_removed"`
+	Completed   bool       `bun:"completed"`
+	InDoneList  bool       `bun:"in_done_list"`
+	Estimate    *float64   `bun:"estimate"`
+}
+
+func (user UserService) GetSprints(boardID store.EntityID) ([]*store.Sprint, error) {
+	if owns, err := user.OwnsBoard(boardID); err != nil {
+		return nil, err
+	} else if !owns {
+		return nil, store.ErrNotFound
+	}
+
+	sprints := make([]*store.Sprint, 0)
+	err := user.Store.ORM.NewSelect().
+		Model(&sprints).
+		Where("board_id = ?", boardID).
+		Order("date_start", "date_created").
+		Scan(user.Context)
+
+	return sprints, err
+}
+
+func (user UserService) AddSprint(args *AddSprintOptions) (*store.Sprint, error) {
+	if owns, err := user.OwnsBoard(args.BoardID); err != nil {
+		return nil, err
+	} else if !owns {
+		return nil, ErrPermissionDenied
+	}
+
+	sprint := &store.Sprint{
+		BoardID:   args.BoardID,
+		UserID:    user.UserID,
+		Name:      args.Name,
+		Goal:      args.Goal,
+		DateStart: args.DateStart,
+		DateEnd:   args.DateEnd,
+	}
+
+	_, err := user.Store.ORM.NewInsert().
+		Model(sprint).
+		Column("board_id", "user_id", "name", "goal", "date_start", "date_end").
+		Returning("*").
+		Exec(user.Context)
+	if err != nil {
+		return nil, err
+	}
+
+	return sprint, nil
+}
+
+func (user UserService) getSprint(db bun.IDB, sprintID store.EntityID) (*store.Sprint, error) {
+	sprint := new(store.Sprint)
+	err := db.NewSelect().
+		Model(sprint).
+		Where("id = ?", sprintID).
+		Where("user_id = ?", user.UserID).
+		Scan(user.Context)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, store.ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	return sprint, nil
+}
+
+func (user UserService) GetSprint(sprintID store.EntityID) (*SprintWithSummary, error) {
+	sprint, err := user.getSprint(user.Store.ORM, sprintID)
+	if err != nil {
+		return nil, err
+	}
+
+	summary, err := user.getSprintSummary(sprint)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SprintWithSummary{Sprint: sprint, Summary: *summary}, nil
+}
+
+func (user UserService) EditSprint(args *EditSprintOptions) error {
+	q := user.Store.ORM.NewUpdate().
+		Model((*store.Sprint)(nil)).
+		Where("id = ?", args.SprintID).
+		Where("user_id = ?", user.UserID)
+
+	if args.Name != nil && *args.Name != "" {
+		q = q.Set("name = ?", *args.Name)
+	}
+	if args.Goal != nil {
+		q = q.Set("goal = ?", *args.Goal)
+	}
+	if args.DateStart != nil {
+		q = q.Set("date_start = ?", *args.DateStart)
+	}
+	if args.DateEnd != nil {
+		q = q.Set("date_end = ?", *args.DateEnd)
+	}
+
+	updateResult, err := q.Exec(user.Context)
+	if err != nil {
+		return err
+	} else if store.NoRowsAffected(updateResult) {
+		return store.ErrNotFound
+	}
+
+	return nil
+}
+
+func (user UserService) DeleteSprint(sprintID store.EntityID) error {
+	deleteResult, err := user.Store.ORM.NewDelete().
+		Model((*store.Sprint)(nil)).
+		Where("id = ?", sprintID).
+		Where("user_id = ?", user.UserID).
+		Exec(user.Context)
+	if err != nil {
+		return err
+	} else if store.NoRowsAffected(deleteResult) {
+		return store.ErrNotFound
+	}
+
+	return nil
+}
+
+// The open sprint of the board which covers today. The latest started one
+// wins if sprints overlap.
+func (user UserService) getActiveSprint(boardID store.EntityID) (*store.Sprint, error) {
+	today := time.Now().UTC().Format("2006-01-02")
+	sprint := new(store.Sprint)
+
+	err := user.Store.ORM.NewSelect().
+		Model(sprint).
+		Where("board_id = ?", boardID).
+		Where("user_id = ?", user.UserID).
+		Where("date_closed IS NULL").
+		Where("date_start <= ?", today).
+		Where("date_end >= ?", today).
+		Order("date_start DESC").
+		Limit(1).
+		Scan(user.Context)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNoActiveSprint
+	} else if err != nil {
+		return nil, err
+	}
+
+	return sprint, nil
+}
+
+// Selects the last active list of the board, tasks in it count as completed.
+// The board is either an ID or a column of the outer query, e.g.
+// bun.Ident("b.id").
+func doneListQuery(db bun.IDB, boardID any) *bun.SelectQuery {
+	return db.NewSelect().
+		Model((*store.TaskList)(nil)).
+		Column("id").
+		Where("board_id = ?", boardID).
+		Where("archived = ?", false).
+		Order("position DESC").
+		Limit(1)
+}
+
+func (user UserService) boardDoneListID(db bun.IDB, boardID store.EntityID) (store.EntityID, error) {
+	var taskListID store.EntityID
+
+	err := doneListQuery(db, boardID).Scan(user.Context, &taskListID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+
+	return taskListID, err
+}
+
+func (user UserService) taskListBoardID(taskListID store.EntityID) (store.EntityID, error) {
+	taskList, err := user.getTaskListRow(taskListID)
+	if err != nil {
+		return "", err
+	}
+
+	return taskList.BoardID, nil
+}
+
+func (user UserService) taskBoardID(taskID store.EntityID) (store.EntityID, error) {
+	var boardID store.EntityID
+
+	err := user.Store.ORM.NewSelect().
+		TableExpr("tasks AS t").
+		ColumnExpr("tl.board_id").
+		Join("JOIN task_lists AS tl ON tl.id = t.task_list_id").
+		Where("t.id = ?", taskID).
+		Where("t.user_id = ?", user.UserID).
+		Where("t.deleted_at IS NULL").
+		Scan(user.Context, &boardID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", store.ErrNotFound
+	}
+
+	return boardID, err
+}
+
+// Checks the sprint is open and belongs to the board.
+func (user UserService) checkSprintForBoard(sprintID, boardID store.EntityID) error {
+	sprint, err := user.getSprint(user.Store.ORM, sprintID)
+	if err != nil {
+		return err
+	}
+
+	if sprint.BoardID != boardID {
+		return ErrPermissionDenied
+	}
+	if sprint.DateClosed != nil {
+		return ErrSprintClosed
+	}
+
+	return nil
+}
+
+// Keeps the commitments in line with the task sprint change.
+func (user UserService) syncSprintCommitment(
+	ctx context.Context,
+	tx bun.Tx,
+	taskID store.EntityID,
+	previousSprintID, sprintID *store.EntityID,
+) error {
+	if lo.FromPtr(previousSprintID) == lo.FromPtr(sprintID) {
+		return nil
+	}
+
+	now := time.Now().UTC()
+
+	if previousSprintID != nil {
+		_, err := tx.NewUpdate().
+			Model((*store.SprintCommitment)(nil)).
+			Set("date_removed = ?", now).
+			Where("sprint_id = ?", *previousSprintID).
+			Where("task_id = ?", taskID).
+			Where("sprint_id IN (SELECT id FROM sprints WHERE date_closed IS NULL)").
+			Exec(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	if sprintID != nil {
+		return user.addSprintCommitments(ctx, tx, *sprintID, []store.EntityID{taskID}, now)
+	}
+
+	return nil
+}
+
+func (user UserService) addSprintCommitments(
+	ctx context.Context,
+	tx bun.Tx,
+	sprintID store.EntityID,
+	taskIDs []store.EntityID,
+	now time.Time,
+) error {
+	if len(taskIDs) == 0 {
+		return nil
+	}
+
+	commitments := make([]*store.SprintCommitment, len(taskIDs))
+	for i, taskID := range taskIDs {
+		commitments[i] = &store.SprintCommitment{
+			SprintID:  sprintID,
+			TaskID:    taskID,
+			DateAdded: now,
+		}
+	}
+
+	_, err := tx.NewInsert().
+		Model(&commitments).
+		Column("sprint_id", "task_id", "date_added").
+		On("CONFLICT (sprint_id, task_id) DO UPDATE").
+		Set("date_removed = NULL").
+		Exec(ctx)
+	return err
+}
+
+func (user UserService) getSprintSummary(sprint *store.Sprint) (*SprintSummary, error) {
+	var rows []sprintCommitmentRow
+	err := user.Store.ORM.NewSelect().
+		TableExpr("sprint_commitments AS sc").
+		ColumnExpr("sc.task_id, sc.date_added, sc.date_removed, sc.completed, t.estimate").
+		ColumnExpr("t.task_list_id IS NOT DISTINCT FROM (?) AS in_done_list", doneListQuery(user.Store.ORM, sprint.BoardID)).
+		Join("JOIN tasks AS t ON t.id = sc.task_id").
+		Where("sc.sprint_id = ?", sprint.ID).
+		Where("t.deleted_at IS NULL").
+		Scan(user.Context, &rows)
+	if err != nil {
+		return nil, err
+	}
+
+	firstDayEnd := sprint.DateStart.AddDate(0, 0, 1)
+	summary := new(SprintSummary)
+
+	for _, row := range rows {
+		if row.DateRemoved != nil {
+			summary.Removed++
+			if row.DateRemoved.Before(firstDayEnd) {
+				continue
+			}
+		}
+
+		if row.DateAdded.Before(firstDayEnd) {
+			summary.Committed++
+			if row.Estimate != nil {
+				summary.CommittedEstimate += *row.Estimate
+			}
+		} else {
+			summary.Added++
+		}
+
+		if row.DateRemoved != nil {
+			continue
+		}
+
+		// Completion of a closed sprint is fixed at the closing moment.
+		completed := row.InDoneList
+		if sprint.DateClosed != nil {
+			completed = row.Completed
+		}
+
+		if completed {
+			summary.Completed++
+			if row.Estimate != nil {
+				summary.CompletedEstimate += *row.Estimate
+			}
+		} else {
+			summary.Unfinished++
+		}
+	}
+
+	return summary, nil
+}
+
+// Closes the sprint fixing which tasks were completed. Unfinished tasks
+// either move to the next sprint or leave the sprint.
+func (user UserService) CloseSprint(args *CloseSprintOptions) (*SprintWithSummary, error) {
+	err := user.Store.ORM.RunInTx(user.Context, nil, func(ctx context.Context, tx bun.Tx) error {
+		sprint := new(store.Sprint)
+		err := tx.NewSelect().
+			Model(sprint).
+			Where("id = ?", args.SprintID).
+			Where("user_id = ?", user.UserID).
+			For("UPDATE").
+			Scan(ctx)
+		if errors.Is(err, sql.ErrNoRows) {
+			return store.ErrNotFound
+		} else if err != nil {
+			return err
+		}
+		if sprint.DateClosed != nil {
+			return ErrSprintClosed
+		}
+
+		// A board without active lists has no done list, nothing is completed.
+		doneList := doneListQuery(tx, sprint.BoardID)
+		now := time.Now().UTC()
+
+		_, err = tx.NewUpdate().
+			Model((*store.SprintCommitment)(nil)).
+			Set("completed = (SELECT t.task_list_id IS NOT DISTINCT FROM (?) FROM tasks AS t WHERE t.id = sprint_commitment.task_id)", doneList).
+			Where("sprint_id = ?", sprint.ID).
+			Where("date_removed IS NULL").
+			Exec(ctx)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.NewUpdate().
+			Model(sprint).
+			Set("date_closed = ?", now).
+			WherePK().
+			Exec(ctx)
+		if err != nil {
+			return err
+		}
+
+		var unfinished []store.EntityID
+		err = tx.NewSelect().
+			Model((*store.Task)(nil)).
+			Column("id").
+			Where("sprint_id = ?", sprint.ID).
+			Where("task_list_id IS DISTINCT FROM (?)", doneList).
+			Scan(ctx, &unfinished)
+		if err != nil || len(unfinished) == 0 {
+			return err
+		}
+
+		var nextSprintID *store.EntityID
+		if args.Rollover {
+			next, err := user.getNextSprint(tx, sprint, args.NextSprintID)
+			if err != nil {
+				return err
+			}
+			nextSprintID = &next.ID
+		}
+
+		_, err = tx.NewUpdate().
+			Model((*store.Task)(nil)).
+			Set("sprint_id = ?", nextSprintID).
+			Where("id IN (?)", bun.In(unfinished)).
+			Exec(ctx)
+		if err != nil || nextSprintID == nil {
+			return err
+		}
+
+		return user.addSprintCommitments(ctx, tx, *nextSprintID, unfinished, now)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return user.GetSprint(args.SprintID)
+}
+
+func (user UserService) getNextSprint(
+	db bun.IDB,
+	sprint *store.Sprint,
+	nextSprintID *store.EntityID,
+) (*store.Sprint, error) {
+	if nextSprintID != nil {
+		next, err := user.getSprint(db, *nextSprintID)
+		if err != nil {
+			return nil, err
+		}
+		if next.BoardID != sprint.BoardID {
+			return nil, ErrPermissionDenied
+		}
+		if next.DateClosed != nil || next.ID == sprint.ID {
+			return nil, ErrSprintClosed
+		}
+
+		return next, nil
+	}
+
+	next := new(store.Sprint)
+	err := db.NewSelect().
+		Model(next).
+		Where("board_id = ?", sprint.BoardID).
+		Where("id <> ?", sprint.ID).
+		Where("date_closed IS NULL").
+		Where("date_start >= ?", sprint.DateStart).
+		Order("date_start", "date_created").
+		Limit(1).
+		Scan(user.Context)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNoNextSprint
+	} else if err != nil {
+		return nil, err
+	}
+
+	return next, nil
+}
@@ -0,0 +1,36 @@
+package userservice
+
+import (
+	"time"
+)
+
+func (s *userServiceSuite) TestSprintOfBoardWithoutActiveLists() {
+	board, lists := s.addBoard("Todo")
+	task := s.addTask(lists[0].ID, "Task")
+
+	now := time.Now().UTC()
+	sprint, err := s.user.AddSprint(&AddSprintOptions{
+		BoardID:   board.ID,
+		Name:      "Sprint",
+		DateStart: now.Add(-time.Hour),
+		DateEnd:   now.AddDate(0, 0, 7),
+	})
+	s.Require().NoError(err)
+	s.Require().NoError(s.user.EditTask(&EditTaskOptions{TaskID: task.ID, SprintID: &sprint.ID}))
+
+	// The board has no done list once all of its lists are archived.
+	s.Require().NoError(s.user.ArchiveTaskList(lists[0].ID))
+
+	summary, err := s.user.GetSprint(sprint.ID)
+	s.Require().NoError(err)
+	s.Equal(1, summary.Summary.Committed)
+	s.Equal(0, summary.Summary.Completed)
+	s.Equal(1, summary.Summary.Unfinished)
+
+	closed, err := s.user.CloseSprint(&CloseSprintOptions{SprintID: sprint.ID})
+	s.Require().NoError(err)
+	s.NotNil(closed.DateClosed)
+	s.Equal(0, closed.Summary.Completed)
+	s.Equal(1, closed.Summary.Unfinished)
+	s.Nil(s.getTask(task.ID).SprintID, "the unfinished task leaves the sprint")
+}
@@ -0,0 +1,313 @@
+package userservice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/uptrace/bun"
+
+	"github.com/lesnoi-kot/karten-backend/src/modules/taskcsv"
+	"github.com/lesnoi-kot/karten-backend/src/store"
+)
+
+// Returned from the import transaction to roll it back.
+var errCSVImportRollback = errors.New("CSV import rolled back")
+
+type GetTaskCSVRowsOptions struct {
+	BoardID   store.EntityID // Either the board
+	ProjectID store.EntityID // or the whole project
+}
+
+type ImportTasksCSVOptions struct {
+	BoardID    store.EntityID
+	TaskListID store.EntityID // List of the rows without one, optional
+	Mapping    taskcsv.Mapping
+	Data       io.Reader
+	DryRun     bool
+}
+
+type TaskCSVImport struct {
+	Rows    int // Valid rows
+	Created int
+	Errors  []*taskcsv.RowError
+}
+
+type csvTask struct {
+	KeyPrefix string     `bun:"key_prefix"`
+	Number    int64      `bun:"number"`
+	Name      string     `bun:"name"`
+	BoardName string     `bun:"board_name"`
+	ListName  string     `bun:"list_name"`
+	Labels    []string   `bun:"labels,array"`
+	Text      string     `bun:"text"`
+	StartDate *time.Time `bun:"start_date"`
+	DueDate   *time.Time `bun:"due_date"`
+	SpentTime int64      `bun:"spent_time"`
+	Estimate  *float64   `bun:"estimate"`
+	Priority  int16      `bun:"priority"`
+	Archived  bool       `bun:"archived"`
+}
+
+// Tasks of the board or the project for the CSV export, archived ones
+// included. Ordered like on the boards.
+func (user UserService) GetTaskCSVRows(args *GetTaskCSVRowsOptions) ([]*taskcsv.Row, error) {
+	var owns bool
+	var err error
+	if args.BoardID != "" {
+		owns, err = user.OwnsBoard(args.BoardID)
+	} else {
+		owns, err = user.OwnsProject(args.ProjectID)
+	}
+	if err != nil {
+		return nil, err
+	} else if !owns {
+		return nil, store.ErrNotFound
+	}
+
+	q := user.Store.ORM.NewSelect().
+		TableExpr("tasks AS t").
+		ColumnExpr("p.key_prefix, t.number, t.name, b.name AS board_name, tl.name AS list_name").
+		ColumnExpr(`array(
+			SELECT l.name FROM task_labels AS tlb
+			JOIN labels AS l ON l.id = tlb.label_id
+			WHERE tlb.task_id = t.id
+			ORDER BY l.name
+		) AS labels`).
+		ColumnExpr("t.text, t.start_date, t.due_date, t.spent_time, t.estimate, t.priority").
+		ColumnExpr("t.archived OR tl.archived AS archived").
+		Join("JOIN task_lists AS tl ON tl.id = t.task_list_id").
+		Join("JOIN boards AS b ON b.id = tl.board_id").
+		Join("JOIN projects AS p ON p.id = b.project_id").
+		Where("t.user_id = ?", user.UserID).
+		Where("t.deleted_at IS NULL").
+		Where("tl.deleted_at IS NULL").
+		OrderExpr("b.date_created, tl.position, tl.date_created, t.position, t.date_created")
+
+	if args.BoardID != "" {
+		q = q.Where("b.id = ?", args.BoardID)
+	} else {
+		q = q.Where("b.project_id = ?", args.ProjectID).Where("b.deleted_at IS NULL")
+	}
+
+	tasks := make([]*csvTask, 0)
+	if err := q.Scan(user.Context, &tasks); err != nil {
+		return nil, err
+	}
+
+	rows := make([]*taskcsv.Row, len(tasks))
+	for i, task := range tasks {
+		rows[i] = &taskcsv.Row{
+			Key:       store.FormatTaskKey(task.KeyPrefix, task.Number),
+			Name:      task.Name,
+			Board:     task.BoardName,
+			List:      task.ListName,
+			Labels:    task.Labels,
+			Text:      task.Text,
+			StartDate: task.StartDate,
+			DueDate:   task.DueDate,
+			SpentTime: task.SpentTime,
+			Estimate:  task.Estimate,
+			Priority:  task.Priority,
+			Archived:  task.Archived,
+		}
+	}
+
+	return rows, nil
+}
+
+// Creates a task per CSV row on the board. Lists and labels are matched by
+// name. If any row is invalid or would go over a hard WIP limit nothing is
+// created and the errors are returned, the same as a dry run does.
+func (user UserService) ImportTasksCSV(args *ImportTasksCSVOptions) (*TaskCSVImport, error) {
+	if owns, err := user.OwnsBoard(args.BoardID); err != nil {
+		return nil, err
+	} else if !owns {
+		return nil, store.ErrNotFound
+	}
+
+	taskLists := make([]*store.TaskList, 0)
+	err := user.Store.ORM.NewSelect().
+		Model(&taskLists).
+		Column("id", "name").
+		Where("board_id = ?", args.BoardID).
+		Where("archived = ?", false).
+		Order("position", "date_created").
+		Scan(user.Context)
+	if err != nil {
+		return nil, err
+	}
+
+	labels := make([]*store.Label, 0)
+	err = user.Store.ORM.NewSelect().
+		Model(&labels).
+		Column("id", "name").
+		Where("board_id = ?", args.BoardID).
+		Order("id").
+		Scan(user.Context)
+	if err != nil {
+		return nil, err
+	}
+
+	if args.TaskListID != "" && findTaskList(taskLists, args.TaskListID, "") == nil {
+		return nil, store.ErrNotFound
+	}
+
+	rows, rowErrors, err := taskcsv.Read(args.Data, args.Mapping)
+	if err != nil {
+		return nil, err
+	}
+
+	type resolvedRow struct {
+		*taskcsv.ImportRow
+		TaskListID store.EntityID
+		LabelIDs   []store.LabelID
+	}
+
+	resolved := make([]*resolvedRow, 0, len(rows))
+	for _, row := range rows {
+		valid := true
+		fail := func(column taskcsv.Column, message string) {
+			rowErrors = append(rowErrors, &taskcsv.RowError{Line: row.Line, Column: column, Message: message})
+			valid = false
+		}
+
+		target := &resolvedRow{ImportRow: row, TaskListID: args.TaskListID}
+		if row.List != "" {
+			if taskList := findTaskList(taskLists, "", row.List); taskList != nil {
+				target.TaskListID = taskList.ID
+			} else {
+				fail(taskcsv.ColumnList, fmt.Sprintf("Unknown list %q", row.List))
+			}
+		} else if target.TaskListID == "" {
+			fail(taskcsv.ColumnList, "No list")
+		}
+
+		for _, name := range row.Labels {
+			if label := findLabel(labels, name); label != nil {
+				target.LabelIDs = append(target.LabelIDs, label.ID)
+			} else {
+				fail(taskcsv.ColumnLabels, fmt.Sprintf("Unknown label %q", name))
+			}
+		}
+
+		if valid {
+			resolved = append(resolved, target)
+		}
+	}
+
+	// A dry run and an import with invalid rows insert the tasks too and roll
+	// them back, so the WIP limits are checked the same way in both.
+	created := 0
+	err = user.Store.ORM.RunInTx(user.Context, nil, func(ctx context.Context, tx bun.Tx) error {
+		positions := make(map[store.EntityID]int64)
+
+		for _, row := range resolved {
+			err := user.checkWIPLimit(ctx, tx, row.TaskListID, "")
+			if errors.Is(err, ErrWIPLimitExceeded) {
+				rowErrors = append(rowErrors, &taskcsv.RowError{Line: row.Line, Column: taskcsv.ColumnList, Message: err.Error()})
+				continue
+			} else if err != nil {
+				return err
+			}
+
+			position, ok := positions[row.TaskListID]
+			if !ok {
+				err := tx.NewSelect().
+					Model((*store.Task)(nil)).
+					ColumnExpr("coalesce(max(position), 0)").
+					Where("task_list_id = ?", row.TaskListID).
+					Scan(ctx, &position)
+				if err != nil {
+					return err
+				}
+			}
+			position++
+			positions[row.TaskListID] = position
+
+			task := &store.Task{
+				UserID:     user.UserID,
+				TaskListID: row.TaskListID,
+				Name:       row.Name,
+				Text:       row.Text,
+				Position:   position,
+				StartDate:  row.StartDate,
+				DueDate:    row.DueDate,
+				Estimate:   row.Estimate,
+				Priority:   store.TaskPriority(row.Priority),
+			}
+
+			_, err = tx.NewInsert().
+				With("counter", user.reserveTaskNumberQuery(row.TaskListID)).
+				Model(task).
+				Column("task_list_id", "user_id", "number", "project_id", "name", "text", "position", "start_date", "due_date", "estimate", "priority").
+				Value("number", "(SELECT number FROM counter)").
+				Value("project_id", "(SELECT project_id FROM counter)").
+				Returning("id").
+				Exec(ctx)
+			if err != nil {
+				return err
+			}
+
+			for _, labelID := range row.LabelIDs {
+				_, err := tx.NewInsert().
+					Model(&store.LabelToTaskAssoc{TaskID: task.ID, LabelID: labelID}).
+					On("CONFLICT DO NOTHING").
+					Exec(ctx)
+				if err != nil {
+					return err
+				}
+			}
+
+			if err := user.addTaskMovement(ctx, tx, task.ID, "", task.TaskListID); err != nil {
+				return err
+			}
+			if err := user.addTaskRevision(ctx, tx, task.ID, task.Text, false); err != nil {
+				return err
+			}
+			created++
+		}
+
+		if args.DryRun || len(rowErrors) > 0 {
+			return errCSVImportRollback
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errCSVImportRollback) {
+		return nil, err
+	}
+
+	result := &TaskCSVImport{Rows: created, Errors: rowErrors}
+	if err == nil {
+		result.Created = created
+	}
+	return result, nil
+}
+
+// Finds the list by ID or else by name, ignoring the case. The first one
+// wins if names repeat.
+func findTaskList(taskLists []*store.TaskList, id store.EntityID, name string) *store.TaskList {
+	for _, taskList := range taskLists {
+		if id != "" && taskList.ID == id {
+			return taskList
+		}
+		if id == "" && strings.EqualFold(taskList.Name, name) {
+			return taskList
+		}
+	}
+
+	return nil
+}
+
+func findLabel(labels []*store.Label, name string) *store.Label {
+	for _, label := range labels {
+		if strings.EqualFold(label.Name, name) {
+			return label
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,215 @@
+package userservice
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/uptrace/bun"
+
+	"github.com/lesnoi-kot/karten-backend/src/store"
+)
+
+var (
+	ErrInvalidKeyPrefix = errors.New("Invalid project key prefix")
+	ErrKeyPrefixTaken   = errors.New("Project key prefix is already taken")
+)
+
+var keyPrefixRegexp = regexp.MustCompile(`^[A-Z][A-Z0-9]{0,9}$`)
+
+const (
+	defaultKeyPrefix = "PRJ"
+
+	// Inserts of a project with a generated prefix before giving up on the
+	// concurrently taken ones.
+	maxKeyPrefixAttempts = 5
+)
+
+type GetTaskByKeyOptions struct {
+	Key string
+}
+
+func normalizeKeyPrefix(prefix string) (string, error) {
+	prefix = strings.ToUpper(strings.TrimSpace(prefix))
+	if !keyPrefixRegexp.MatchString(prefix) {
+		return "", ErrInvalidKeyPrefix
+	}
+
+	return prefix, nil
+}
+
+// Makes a key prefix out of the first latin letters of a project name.
+func keyPrefixFromName(name string) string {
+	var b strings.Builder
+
+	for _, r := range strings.ToUpper(name) {
+		if r >= 'A' && r <= 'Z' {
+			b.WriteRune(r)
+		}
+		if b.Len() == 3 {
+			break
+		}
+	}
+
+	if b.Len() == 0 {
+		return defaultKeyPrefix
+	}
+
+	return b.String()
+}
+
+func (user UserService) isKeyPrefixTaken(prefix string) (bool, error) {
+	return user.Store.ORM.NewSelect().
+		Model((*store.Project)(nil)).
+		WhereAllWithDeleted(). // Trashed projects keep their prefix until purged
+		Where("user_id = ?", user.UserID).
+		Where("key_prefix = ?", prefix).
+		Exists(user.Context)
+}
+
+func (user UserService) generateKeyPrefix(name string) (string, error) {
+	base := keyPrefixFromName(name)
+	prefix := base
+
+	for i := 2; ; i++ {
+		taken, err := user.isKeyPrefixTaken(prefix)
+		if err != nil {
+			return "", err
+		}
+		if !taken {
+			return prefix, nil
+		}
+
+		prefix = fmt.Sprintf("%s%d", base, i)
+	}
+}
+
+// Returns a query which atomically reserves the next task number in the
+// project of the task list, returning the number and the project_id. The row
+// lock on the project serializes concurrent inserts, so it has to run in the
+// statement which uses the number.
+func (user UserService) reserveTaskNumberQuery(taskListID store.EntityID) *bun.UpdateQuery {
+	projectID := user.Store.ORM.NewSelect().
+		TableExpr("task_lists AS tl").
+		ColumnExpr("b.project_id").
+		Join("JOIN boards AS b ON b.id = tl.board_id").
+		Where("tl.id = ?", taskListID)
+
+	return user.Store.ORM.NewUpdate().
+		Model((*store.Project)(nil)).
+		Set("next_task_number = next_task_number + 1").
+		Where("id = (?)", projectID).
+		Returning("next_task_number - 1 AS number, id AS project_id")
+}
+
+func (user UserService) taskListProjectID(taskListID store.EntityID) (store.EntityID, error) {
+	var projectID store.EntityID
+
+	err := user.Store.ORM.NewSelect().
+		TableExpr("task_lists AS tl").
+		ColumnExpr("b.project_id").
+		Join("JOIN boards AS b ON b.id = tl.board_id").
+		Where("tl.id = ?", taskListID).
+		Where("tl.user_id = ?", user.UserID).
+		Where("tl.deleted_at IS NULL").
+		Scan(user.Context, &projectID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", store.ErrNotFound
+	}
+
+	return projectID, err
+}
+
+func (user UserService) taskProjectID(taskID store.EntityID) (store.EntityID, error) {
+	var projectID store.EntityID
+
+	err := user.Store.ORM.NewSelect().
+		TableExpr("tasks AS t").
+		ColumnExpr("b.project_id").
+		Join("JOIN task_lists AS tl ON tl.id = t.task_list_id").
+		Join("JOIN boards AS b ON b.id = tl.board_id").
+		Where("t.id = ?", taskID).
+		Where("t.user_id = ?", user.UserID).
+		Where("t.deleted_at IS NULL").
+		Scan(user.Context, &projectID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", store.ErrNotFound
+	}
+
+	return projectID, err
+}
+
+func (user UserService) taskKeyPrefix(taskID store.EntityID) (string, error) {
+	var prefix string
+
+	err := user.Store.ORM.NewSelect().
+		TableExpr("tasks AS t").
+		ColumnExpr("p.key_prefix").
+		Join("JOIN task_lists AS tl ON tl.id = t.task_list_id").
+		Join("JOIN boards AS b ON b.id = tl.board_id").
+		Join("JOIN projects AS p ON p.id = b.project_id").
+		Where("t.id = ?", taskID).
+		Scan(user.Context, &prefix)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+
+	return prefix, err
+}
+
+func (user UserService) boardKeyPrefix(boardID store.EntityID) (string, error) {
+	var prefix string
+
+	err := user.Store.ORM.NewSelect().
+		TableExpr("boards AS b").
+		ColumnExpr("p.key_prefix").
+		Join("JOIN projects AS p ON p.id = b.project_id").
+		Where("b.id = ?", boardID).
+		Scan(user.Context, &prefix)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+
+	return prefix, err
+}
+
+func fillTaskKeys(prefix string, tasks []*store.Task) {
+	for _, task := range tasks {
+		task.Key = store.FormatTaskKey(prefix, task.Number)
+	}
+}
+
+func (user UserService) GetTaskByKey(args *GetTaskByKeyOptions) (*store.Task, error) {
+	prefix, number, ok := store.ParseTaskKey(args.Key)
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+
+	var taskID store.EntityID
+
+	err := user.Store.ORM.NewSelect().
+		TableExpr("tasks AS t").
+		ColumnExpr("t.id").
+		Join("JOIN task_lists AS tl ON tl.id = t.task_list_id").
+		Join("JOIN boards AS b ON b.id = tl.board_id").
+		Join("JOIN projects AS p ON p.id = b.project_id").
+		Where("p.user_id = ?", user.UserID).
+		Where("p.key_prefix = ?", prefix).
+		Where("t.number = ?", number).
+		Where("t.deleted_at IS NULL").
+		Scan(user.Context, &taskID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, store.ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	return user.GetTask(&GetTaskOptions{
+		TaskID:             taskID,
+		IncludeComments:    true,
+		IncludeLabels:      true,
+		IncludeAttachments: true,
+	})
+}
@@ -0,0 +1,74 @@
+package userservice
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lesnoi-kot/karten-backend/src/store"
+)
+
+func TestParseTaskKey(t *testing.T) {
+	tests := []struct {
+		key    string
+		prefix string
+		number int64
+		ok     bool
+	}{
+		{"KAR-1", "KAR", 1, true},
+		{"kar-42", "KAR", 42, true},
+		{"A1B2-7", "A1B2", 7, true},
+		{"ABCDEFGHIJ-3", "ABCDEFGHIJ", 3, true},
+		{"ABCDEFGHIJK-3", "", 0, false},
+		{"KAR-0", "", 0, false},
+		{"KAR-01", "", 0, false},
+		{"1AR-1", "", 0, false},
+		{"KAR1", "", 0, false},
+		{"KAR-", "", 0, false},
+		{"KAR-99999999999999999999", "", 0, false},
+		{" KAR-1", "", 0, false},
+		{"", "", 0, false},
+	}
+
+	for _, test := range tests {
+		prefix, number, ok := store.ParseTaskKey(test.key)
+		assert.Equal(t, test.ok, ok, test.key)
+		assert.Equal(t, test.prefix, prefix, test.key)
+		assert.Equal(t, test.number, number, test.key)
+	}
+
+	prefix, number, _ := store.ParseTaskKey(store.FormatTaskKey("WEB", 15))
+	assert.Equal(t, "WEB", prefix)
+	assert.Equal(t, int64(15), number)
+	assert.Empty(t, store.FormatTaskKey("WEB", 0))
+}
+
+func TestKeyPrefixFromName(t *testing.T) {
+	tests := map[string]string{
+		"Karten":          "KAR",
+		"web app":         "WEB",
+		"Go":              "GO",
+		"2024 roadmap":    "ROA",
+		"A-B c!d":         "ABC",
+		"Проект":          defaultKeyPrefix,
+		"":                defaultKeyPrefix,
+		"Über Kanban 9":   "BER",
+		"x1y2z3 and more": "XYZ",
+	}
+
+	for name, prefix := range tests {
+		assert.Equal(t, prefix, keyPrefixFromName(name), name)
+		assert.Regexp(t, keyPrefixRegexp, keyPrefixFromName(name), name)
+	}
+}
+
+func TestNormalizeKeyPrefix(t *testing.T) {
+	prefix, err := normalizeKeyPrefix("  web2 ")
+	assert.NoError(t, err)
+	assert.Equal(t, "WEB2", prefix)
+
+	for _, invalid := range []string{"", "2WEB", "WEB-1", "ABCDEFGHIJK", "ВЕБ"} {
+		_, err := normalizeKeyPrefix(invalid)
+		assert.ErrorIs(t, err, ErrInvalidKeyPrefix, invalid)
+	}
+}
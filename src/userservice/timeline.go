@@ -0,0 +1,296 @@
+package userservice
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/samber/lo"
+	"github.com/uptrace/bun"
+
+	"github.com/lesnoi-kot/karten-backend/src/modules/workdays"
+	"github.com/lesnoi-kot/karten-backend/src/store"
+)
+
+var ErrDependencyCycle = errors.New("Task dependencies can't form a cycle")
+
+type AddTaskDependencyOptions struct {
+	TaskID      store.EntityID
+	DependsOnID store.EntityID
+}
+
+type DeleteTaskDependencyOptions struct {
+	TaskID      store.EntityID
+	DependsOnID store.EntityID
+}
+
+type GetProjectTimelineOptions struct {
+	ProjectID store.EntityID
+}
+
+type ShiftTasksOptions struct {
+	ProjectID store.EntityID
+	TaskIDs   []store.EntityID
+	Days      int
+	Location  *time.Location // Weekdays are taken in this location
+}
+
+type TimelineTask struct {
+	ID           store.EntityID     `bun:"id"`
+	Number       int64              `bun:"number"`
+	Key          string             `bun:"-"`
+	Name         string             `bun:"name"`
+	Priority     store.TaskPriority `bun:"priority"`
+	StartDate    *time.Time         `bun:"start_date"`
+	DueDate      *time.Time         `bun:"due_date"`
+	TaskListID   store.EntityID     `bun:"task_list_id"`
+	TaskListName string             `bun:"task_list_name"`
+	BoardID      store.EntityID     `bun:"board_id"`
+	BoardName    string             `bun:"board_name"`
+}
+
+type TimelineTaskList struct {
+	ID    store.EntityID
+	Name  string
+	Tasks []*TimelineTask
+}
+
+type TimelineBoard struct {
+	ID        store.EntityID
+	Name      string
+	TaskLists []*TimelineTaskList
+}
+
+type Timeline struct {
+	ProjectID    store.EntityID
+	SkipWeekends bool
+	Boards       []*TimelineBoard
+	Dependencies []*store.TaskDependency
+}
+
+func (user UserService) AddTaskDependency(args *AddTaskDependencyOptions) error {
+	if args.TaskID == args.DependsOnID {
+		return ErrDependencyCycle
+	}
+
+	return user.Store.ORM.RunInTx(user.Context, nil, func(ctx context.Context, tx bun.Tx) error {
+		count, err := tx.NewSelect().
+			Model((*store.Task)(nil)).
+			Where("id IN (?)", bun.In([]store.EntityID{args.TaskID, args.DependsOnID})).
+			Where("user_id = ?", user.UserID).
+			Count(ctx)
+		if err != nil {
+			return err
+		}
+		if count != 2 {
+			return store.ErrNotFound
+		}
+
+		// Serializes the concurrent cycle checks of the user.
+		_, err = tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock(hashtext('task_dependencies'), ?)", user.UserID)
+		if err != nil {
+			return err
+		}
+
+		var cycle bool
+		err = tx.NewRaw(`
+			WITH RECURSIVE upstream AS (
+				SELECT depends_on_id FROM task_dependencies WHERE task_id = ?
+				UNION
+				SELECT d.depends_on_id
+				FROM task_dependencies AS d
+				JOIN upstream AS u ON d.task_id = u.depends_on_id
+			)
+			SELECT EXISTS (SELECT 1 FROM upstream WHERE depends_on_id = ?)`,
+			args.DependsOnID, args.TaskID,
+		).Scan(ctx, &cycle)
+		if err != nil {
+			return err
+		}
+		if cycle {
+			return ErrDependencyCycle
+		}
+
+		_, err = tx.NewInsert().
+			Model(&store.TaskDependency{
+				TaskID:      args.TaskID,
+				DependsOnID: args.DependsOnID,
+				UserID:      user.UserID,
+			}).
+			Column("task_id", "depends_on_id", "user_id").
+			On("CONFLICT DO NOTHING").
+			Exec(ctx)
+
+		return err
+	})
+}
+
+func (user UserService) DeleteTaskDependency(args *DeleteTaskDependencyOptions) error {
+	result, err := user.Store.ORM.NewDelete().
+		Model((*store.TaskDependency)(nil)).
+		Where("task_id = ?", args.TaskID).
+		Where("depends_on_id = ?", args.DependsOnID).
+		Where("user_id = ?", user.UserID).
+		Exec(user.Context)
+	if err != nil {
+		return err
+	} else if store.NoRowsAffected(result) {
+		return store.ErrNotFound
+	}
+
+	return nil
+}
+
+// Returns the dated tasks of the project grouped by board and list, along
+// with the dependencies between them.
+func (user UserService) GetProjectTimeline(args *GetProjectTimelineOptions) (*Timeline, error) {
+	project := new(store.Project)
+	err := user.Store.ORM.NewSelect().
+		Model(project).
+		Column("id", "key_prefix", "skip_weekends").
+		Where("id = ?", args.ProjectID).
+		Where("user_id = ?", user.UserID).
+		Scan(user.Context)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, store.ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	tasks := make([]*TimelineTask, 0)
+	err = user.Store.ORM.NewSelect().
+		TableExpr("tasks AS t").
+		ColumnExpr("t.id, t.number, t.name, t.priority, t.start_date, t.due_date").
+		ColumnExpr("tl.id AS task_list_id, tl.name AS task_list_name").
+		ColumnExpr("b.id AS board_id, b.name AS board_name").
+		Join("JOIN task_lists AS tl ON tl.id = t.task_list_id").
+		Join("JOIN boards AS b ON b.id = tl.board_id").
+		Where("b.project_id = ?", project.ID).
+		Where("t.user_id = ?", user.UserID).
+		Where("t.archived = ?", false).
+		Where("t.deleted_at IS NULL").
+		Where("tl.archived = ?", false).
+		Where("tl.deleted_at IS NULL").
+		Where("b.archived = ?", false).
+		Where("b.deleted_at IS NULL").
+		Where("t.start_date IS NOT NULL OR t.due_date IS NOT NULL").
+		OrderExpr("b.date_created, b.id, tl.position, tl.id").
+		OrderExpr("coalesce(t.start_date, t.due_date), t.number").
+		Scan(user.Context, &tasks)
+	if err != nil {
+		return nil, err
+	}
+
+	timeline := &Timeline{
+		ProjectID:    project.ID,
+		SkipWeekends: project.SkipWeekends,
+		Boards:       make([]*TimelineBoard, 0),
+		Dependencies: make([]*store.TaskDependency, 0),
+	}
+
+	// Rows come ordered by board and list, so groups are contiguous.
+	var board *TimelineBoard
+	var taskList *TimelineTaskList
+	for _, task := range tasks {
+		task.Key = store.FormatTaskKey(project.KeyPrefix, task.Number)
+
+		if board == nil || board.ID != task.BoardID {
+			board = &TimelineBoard{ID: task.BoardID, Name: task.BoardName}
+			timeline.Boards = append(timeline.Boards, board)
+			taskList = nil
+		}
+		if taskList == nil || taskList.ID != task.TaskListID {
+			taskList = &TimelineTaskList{ID: task.TaskListID, Name: task.TaskListName}
+			board.TaskLists = append(board.TaskLists, taskList)
+		}
+
+		taskList.Tasks = append(taskList.Tasks, task)
+	}
+
+	if len(tasks) == 0 {
+		return timeline, nil
+	}
+
+	taskIDs := lo.Map(tasks, func(task *TimelineTask, _ int) store.EntityID {
+		return task.ID
+	})
+	err = user.Store.ORM.NewSelect().
+		Model(&timeline.Dependencies).
+		Where("user_id = ?", user.UserID).
+		Where("task_id IN (?)", bun.In(taskIDs)).
+		Where("depends_on_id IN (?)", bun.In(taskIDs)).
+		Scan(user.Context)
+	if err != nil {
+		return nil, err
+	}
+
+	return timeline, nil
+}
+
+// Moves the start and due dates of the given project tasks by the same
+// number of days, only counting workdays if the project skips weekends.
+func (user UserService) ShiftTasks(args *ShiftTasksOptions) error {
+	var skipWeekends bool
+	err := user.Store.ORM.NewSelect().
+		Model((*store.Project)(nil)).
+		Column("skip_weekends").
+		Where("id = ?", args.ProjectID).
+		Where("user_id = ?", user.UserID).
+		Scan(user.Context, &skipWeekends)
+	if errors.Is(err, sql.ErrNoRows) {
+		return store.ErrNotFound
+	} else if err != nil {
+		return err
+	}
+
+	taskIDs := lo.Uniq(args.TaskIDs)
+	if len(taskIDs) == 0 || args.Days == 0 {
+		return nil
+	}
+
+	shift := func(date *time.Time) *time.Time {
+		if date == nil {
+			return nil
+		}
+
+		shifted := workdays.Shift(date.In(args.Location), args.Days, skipWeekends).UTC()
+		return &shifted
+	}
+
+	return user.Store.ORM.RunInTx(user.Context, nil, func(ctx context.Context, tx bun.Tx) error {
+		var tasks []*store.Task
+		err := tx.NewSelect().
+			Model(&tasks).
+			Column("task.id", "task.start_date", "task.due_date").
+			Join("JOIN task_lists AS tl ON tl.id = task.task_list_id").
+			Join("JOIN boards AS b ON b.id = tl.board_id").
+			Where("task.id IN (?)", bun.In(taskIDs)).
+			Where("task.user_id = ?", user.UserID).
+			Where("b.project_id = ?", args.ProjectID).
+			For("UPDATE OF task").
+			Scan(ctx)
+		if err != nil {
+			return err
+		}
+		if len(tasks) != len(taskIDs) {
+			return store.ErrNotFound
+		}
+
+		for _, task := range tasks {
+			task.StartDate = shift(task.StartDate)
+			task.DueDate = shift(task.DueDate)
+
+			_, err := tx.NewUpdate().
+				Model(task).
+				Column("start_date", "due_date").
+				WherePK().
+				Exec(ctx)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
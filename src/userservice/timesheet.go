@@ -0,0 +1,225 @@
+package userservice
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/samber/lo"
+	"github.com/uptrace/bun"
+
+	"github.com/lesnoi-kot/karten-backend/src/modules/timesheet"
+	"github.com/lesnoi-kot/karten-backend/src/store"
+)
+
+type StartTaskTrackingOptions struct {
+	TaskID store.EntityID
+}
+
+type StopTaskTrackingOptions struct {
+	TaskID store.EntityID
+}
+
+type GetTimesheetOptions struct {
+	From      time.Time
+	To        time.Time
+	ProjectID store.EntityID
+	BoardID   store.EntityID
+	GroupBy   timesheet.GroupBy
+	Location  *time.Location
+	Rounding  timesheet.Rounding
+}
+
+func (user UserService) StartTaskTracking(args *StartTaskTrackingOptions) error {
+	updateResult, err := user.Store.ORM.NewUpdate().
+		Model((*store.Task)(nil)).
+		Set("date_started_tracking = ?", time.Now().UTC()).
+		Where("id = ?", args.TaskID).
+		Where("user_id = ?", user.UserID).
+		Where("date_started_tracking IS NULL").
+		Exec(user.Context)
+	if err != nil {
+		return err
+	}
+
+	if store.NoRowsAffected(updateResult) {
+		// Either already tracking or not found.
+		if owns, err := user.OwnsTask(args.TaskID); err != nil {
+			return err
+		} else if !owns {
+			return store.ErrNotFound
+		}
+	}
+
+	return nil
+}
+
+// Stops the timer of the task, adds the elapsed time to Task.SpentTime and
+// records it as a time entry.
+func (user UserService) StopTaskTracking(args *StopTaskTrackingOptions) error {
+	return user.Store.ORM.RunInTx(user.Context, nil, func(ctx context.Context, tx bun.Tx) error {
+		task := new(store.Task)
+		err := tx.NewSelect().
+			Model(task).
+			Where("id = ?", args.TaskID).
+			Where("user_id = ?", user.UserID).
+			For("UPDATE").
+			Scan(ctx)
+		if errors.Is(err, sql.ErrNoRows) {
+			return store.ErrNotFound
+		} else if err != nil {
+			return err
+		}
+
+		if task.DateStartedTracking == nil {
+			return nil
+		}
+
+		now := time.Now().UTC()
+		entry := &store.TimeEntry{
+			TaskID:      task.ID,
+			UserID:      user.UserID,
+			DateStarted: *task.DateStartedTracking,
+			DateEnded:   now,
+		}
+
+		_, err = tx.NewUpdate().
+			Model((*store.Task)(nil)).
+			Set("date_started_tracking = ?", nil).
+			Set("spent_time = ?", task.SpentTime+now.Unix()-task.DateStartedTracking.Unix()).
+			Where("id = ?", task.ID).
+			Exec(ctx)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.NewInsert().
+			Model(entry).
+			Column("task_id", "user_id", "date_started", "date_ended").
+			Exec(ctx)
+		return err
+	})
+}
+
+type timeEntryRow struct {
+	TaskID      string    `bun:"task_id"`
+	TaskName    string    `bun:"task_name"`
+	BoardID     string    `bun:"board_id"`
+	BoardName   string    `bun:"board_name"`
+	ProjectID   string    `bun:"project_id"`
+	ProjectName string    `bun:"project_name"`
+	DateStarted time.Time `bun:"date_started"`
+	DateEnded   time.Time `bun:"date_ended"`
+}
+
+type taskLabelRow struct {
+	TaskID    string `bun:"task_id"`
+	LabelID   int    `bun:"label_id"`
+	LabelName string `bun:"label_name"`
+}
+
+// Aggregates the time tracked by the user in the given range. Timers which
+// are still running are counted up to the current moment.
+func (user UserService) GetTimesheet(args *GetTimesheetOptions) (*timesheet.Report, error) {
+	var rows []timeEntryRow
+	now := time.Now().UTC()
+
+	tracked := user.Store.ORM.NewSelect().
+		TableExpr("time_entries AS te").
+		ColumnExpr("te.task_id, te.date_started, te.date_ended").
+		Where("te.user_id = ?", user.UserID).
+		Where("te.date_ended > ?", args.From).
+		Where("te.date_started < ?", args.To)
+
+	running := user.Store.ORM.NewSelect().
+		TableExpr("tasks AS t").
+		ColumnExpr("t.id AS task_id, t.date_started_tracking AS date_started, ? AS date_ended", now).
+		Where("t.user_id = ?", user.UserID).
+		Where("t.date_started_tracking IS NOT NULL").
+		Where("t.date_started_tracking < ?", args.To)
+
+	q := user.Store.ORM.NewSelect().
+		With("entries", tracked.UnionAll(running)).
+		TableExpr("entries AS e").
+		ColumnExpr("e.task_id, e.date_started, e.date_ended").
+		ColumnExpr("t.name AS task_name").
+		ColumnExpr("b.id AS board_id, b.name AS board_name").
+		ColumnExpr("p.id AS project_id, p.name AS project_name").
+		Join("JOIN tasks AS t ON t.id = e.task_id").
+		Join("JOIN task_lists AS tl ON tl.id = t.task_list_id").
+		Join("JOIN boards AS b ON b.id = tl.board_id").
+		Join("JOIN projects AS p ON p.id = b.project_id").
+		Where("t.deleted_at IS NULL")
+
+	if args.ProjectID != "" {
+		q = q.Where("p.id = ?", args.ProjectID)
+	}
+	if args.BoardID != "" {
+		q = q.Where("b.id = ?", args.BoardID)
+	}
+
+	if err := q.Scan(user.Context, &rows); err != nil {
+		return nil, err
+	}
+
+	labels, err := user.getTasksLabels(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]timesheet.Entry, len(rows))
+	for i, row := range rows {
+		entries[i] = timesheet.Entry{
+			TaskID:      row.TaskID,
+			TaskName:    row.TaskName,
+			BoardID:     row.BoardID,
+			BoardName:   row.BoardName,
+			ProjectID:   row.ProjectID,
+			ProjectName: row.ProjectName,
+			Labels:      labels[row.TaskID],
+			Start:       row.DateStarted,
+			End:         row.DateEnded,
+		}
+	}
+
+	return timesheet.Build(entries, timesheet.Options{
+		From:     args.From,
+		To:       args.To,
+		GroupBy:  args.GroupBy,
+		Location: args.Location,
+		Rounding: args.Rounding,
+	}), nil
+}
+
+func (user UserService) getTasksLabels(rows []timeEntryRow) (map[string][]timesheet.Label, error) {
+	labels := make(map[string][]timesheet.Label)
+	if len(rows) == 0 {
+		return labels, nil
+	}
+
+	taskIDs := lo.Uniq(lo.Map(rows, func(row timeEntryRow, _ int) string {
+		return row.TaskID
+	}))
+
+	var labelRows []taskLabelRow
+	err := user.Store.ORM.NewSelect().
+		TableExpr("task_labels AS tl").
+		ColumnExpr("tl.task_id, l.id AS label_id, l.name AS label_name").
+		Join("JOIN labels AS l ON l.id = tl.label_id").
+		Where("tl.task_id IN (?)", bun.In(taskIDs)).
+		OrderExpr("l.id").
+		Scan(user.Context, &labelRows)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range labelRows {
+		labels[row.TaskID] = append(labels[row.TaskID], timesheet.Label{
+			ID:   row.LabelID,
+			Name: row.LabelName,
+		})
+	}
+
+	return labels, nil
+}
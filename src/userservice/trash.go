@@ -0,0 +1,331 @@
+package userservice
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/uptrace/bun"
+
+	"github.com/lesnoi-kot/karten-backend/src/settings"
+	"github.com/lesnoi-kot/karten-backend/src/store"
+)
+
+var ErrParentInTrash = errors.New("Parent item is in the trash, restore it first")
+
+const (
+	TrashedProject  = "project"
+	TrashedBoard    = "board"
+	TrashedTaskList = "task_list"
+	TrashedTask     = "task"
+)
+
+type TrashedItem struct {
+	Type         string    `bun:"type"`
+	ID           string    `bun:"id"`
+	Name         string    `bun:"name"`
+	ProjectID    string    `bun:"project_id"`
+	ProjectName  string    `bun:"project_name"`
+	BoardID      string    `bun:"board_id"`
+	BoardName    string    `bun:"board_name"`
+	TaskListID   string    `bun:"task_list_id"`
+	TaskListName string    `bun:"task_list_name"`
+	DateDeleted  time.Time `bun:"deleted_at"`
+	DateExpires  time.Time `bun:"-"`
+}
+
+type GetTrashOptions struct {
+	Type   string
+	Limit  int
+	Offset int
+}
+
+type RestoreFromTrashOptions struct {
+	Type string
+	ID   store.EntityID
+}
+
+// Trashable entities from the root down. Each level refers to the previous
+// one by the parent column.
+var trashLevels = []struct {
+	kind   string
+	table  string
+	parent string
+}{
+	{TrashedProject, "projects", ""},
+	{TrashedBoard, "boards", "project_id"},
+	{TrashedTaskList, "task_lists", "board_id"},
+	{TrashedTask, "tasks", "task_list_id"},
+}
+
+func trashLevel(kind string) int {
+	for i, level := range trashLevels {
+		if level.kind == kind {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// Moves the matching rows of the given kind and everything under them to the
+// trash. The whole subtree gets the same deletion time, which is how it is
+// told apart from the items deleted separately before.
+func (user UserService) moveToTrash(kind string, where string, args ...any) (bool, error) {
+	trashed := false
+
+	err := user.Store.ORM.RunInTx(user.Context, nil, func(ctx context.Context, tx bun.Tx) error {
+		var err error
+		trashed, err = user.trashRows(ctx, tx, kind, where, args...)
+		return err
+	})
+
+	return trashed, err
+}
+
+// Same as moveToTrash, within the transaction.
+func (user UserService) trashRows(ctx context.Context, tx bun.Tx, kind string, where string, args ...any) (bool, error) {
+	level := trashLevel(kind)
+	now := time.Now().UTC().Truncate(time.Microsecond)
+
+	var ids []store.EntityID
+	_, err := tx.NewUpdate().
+		Table(trashLevels[level].table).
+		Set("deleted_at = ?", now).
+		Where("user_id = ?", user.UserID).
+		Where("deleted_at IS NULL").
+		Where(where, args...).
+		Returning("id").
+		Exec(ctx, &ids)
+	if err != nil {
+		return false, err
+	}
+
+	if err := user.cascadeDeletedAt(ctx, tx, level, ids, nil, &now); err != nil {
+		return false, err
+	}
+
+	return len(ids) > 0, nil
+}
+
+// Changes deleted_at of the descendants of the given rows from one value to
+// another, level by level.
+func (user UserService) cascadeDeletedAt(
+	ctx context.Context,
+	tx bun.Tx,
+	level int,
+	ids []store.EntityID,
+	from, to *time.Time,
+) error {
+	for _, child := range trashLevels[level+1:] {
+		if len(ids) == 0 {
+			return nil
+		}
+
+		q := tx.NewUpdate().
+			Table(child.table).
+			Set("deleted_at = ?", to).
+			Where("user_id = ?", user.UserID).
+			Where("? IN (?)", bun.Ident(child.parent), bun.In(ids)).
+			Returning("id")
+
+		if from == nil {
+			q = q.Where("deleted_at IS NULL")
+		} else {
+			q = q.Where("deleted_at = ?", *from)
+		}
+
+		ids = nil
+		if _, err := q.Exec(ctx, &ids); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Lists the roots of the deleted subtrees which are still restorable.
+func (user UserService) GetTrash(args *GetTrashOptions) ([]*TrashedItem, int, error) {
+	db := user.Store.ORM
+	retention := settings.AppConfig.TrashRetention()
+	notBefore := time.Now().UTC().Add(-retention)
+
+	var trash *bun.SelectQuery
+	union := func(q *bun.SelectQuery) {
+		if trash == nil {
+			trash = q
+		} else {
+			trash = trash.UnionAll(q)
+		}
+	}
+
+	if args.Type == "" || args.Type == TrashedProject {
+		union(db.NewSelect().
+			TableExpr("projects AS p").
+			ColumnExpr("? AS type", TrashedProject).
+			ColumnExpr("p.id, p.name, p.id AS project_id, p.name AS project_name").
+			ColumnExpr("NULL::uuid AS board_id, NULL::varchar AS board_name").
+			ColumnExpr("NULL::uuid AS task_list_id, NULL::varchar AS task_list_name").
+			ColumnExpr("p.deleted_at").
+			Where("p.user_id = ?", user.UserID).
+			Where("p.deleted_at >= ?", notBefore))
+	}
+
+	if args.Type == "" || args.Type == TrashedBoard {
+		union(db.NewSelect().
+			TableExpr("boards AS b").
+			ColumnExpr("? AS type", TrashedBoard).
+			ColumnExpr("b.id, b.name, p.id AS project_id, p.name AS project_name").
+			ColumnExpr("b.id AS board_id, b.name AS board_name").
+			ColumnExpr("NULL::uuid AS task_list_id, NULL::varchar AS task_list_name").
+			ColumnExpr("b.deleted_at").
+			Join("JOIN projects AS p ON p.id = b.project_id").
+			Where("b.user_id = ?", user.UserID).
+			Where("b.deleted_at >= ?", notBefore).
+			Where("p.deleted_at IS DISTINCT FROM b.deleted_at"))
+	}
+
+	if args.Type == "" || args.Type == TrashedTaskList {
+		union(db.NewSelect().
+			TableExpr("task_lists AS tl").
+			ColumnExpr("? AS type", TrashedTaskList).
+			ColumnExpr("tl.id, tl.name, p.id AS project_id, p.name AS project_name").
+			ColumnExpr("b.id AS board_id, b.name AS board_name").
+			ColumnExpr("tl.id AS task_list_id, tl.name AS task_list_name").
+			ColumnExpr("tl.deleted_at").
+			Join("JOIN boards AS b ON b.id = tl.board_id").
+			Join("JOIN projects AS p ON p.id = b.project_id").
+			Where("tl.user_id = ?", user.UserID).
+			Where("tl.deleted_at >= ?", notBefore).
+			Where("b.deleted_at IS DISTINCT FROM tl.deleted_at"))
+	}
+
+	if args.Type == "" || args.Type == TrashedTask {
+		union(db.NewSelect().
+			TableExpr("tasks AS t").
+			ColumnExpr("? AS type", TrashedTask).
+			ColumnExpr("t.id, t.name, p.id AS project_id, p.name AS project_name").
+			ColumnExpr("b.id AS board_id, b.name AS board_name").
+			ColumnExpr("tl.id AS task_list_id, tl.name AS task_list_name").
+			ColumnExpr("t.deleted_at").
+			Join("JOIN task_lists AS tl ON tl.id = t.task_list_id").
+			Join("JOIN boards AS b ON b.id = tl.board_id").
+			Join("JOIN projects AS p ON p.id = b.project_id").
+			Where("t.user_id = ?", user.UserID).
+			Where("t.deleted_at >= ?", notBefore).
+			Where("tl.deleted_at IS DISTINCT FROM t.deleted_at"))
+	}
+
+	if trash == nil {
+		return nil, 0, errors.New("Unknown trashed item type")
+	}
+
+	var total int
+	err := db.NewSelect().
+		With("trash", trash).
+		TableExpr("trash").
+		ColumnExpr("count(*)").
+		Scan(user.Context, &total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	items := make([]*TrashedItem, 0)
+	err = db.NewSelect().
+		With("trash", trash).
+		TableExpr("trash").
+		ColumnExpr("trash.*").
+		OrderExpr("trash.deleted_at DESC, trash.id").
+		Limit(args.Limit).
+		Offset(args.Offset).
+		Scan(user.Context, &items)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	for _, item := range items {
+		item.DateExpires = item.DateDeleted.Add(retention)
+	}
+
+	return items, total, nil
+}
+
+// Restores the item along with everything that was deleted together with it.
+// Items deleted separately before stay in the trash.
+func (user UserService) RestoreFromTrash(args *RestoreFromTrashOptions) error {
+	level := trashLevel(args.Type)
+	if level < 0 {
+		return errors.New("Unknown trashed item type")
+	}
+
+	current := trashLevels[level]
+	notBefore := time.Now().UTC().Add(-settings.AppConfig.TrashRetention())
+
+	return user.Store.ORM.RunInTx(user.Context, nil, func(ctx context.Context, tx bun.Tx) error {
+		var row struct {
+			DeletedAt time.Time      `bun:"deleted_at"`
+			ParentID  store.EntityID `bun:"parent_id"`
+		}
+
+		q := tx.NewSelect().
+			Table(current.table).
+			Column("deleted_at").
+			Where("id = ?", args.ID).
+			Where("user_id = ?", user.UserID).
+			Where("deleted_at >= ?", notBefore).
+			For("UPDATE")
+		if current.parent != "" {
+			q = q.ColumnExpr("? AS parent_id", bun.Ident(current.parent))
+		}
+
+		err := q.Scan(ctx, &row)
+		if errors.Is(err, sql.ErrNoRows) {
+			return store.ErrNotFound
+		} else if err != nil {
+			return err
+		}
+
+		if current.parent != "" {
+			parentInTrash, err := tx.NewSelect().
+				Table(trashLevels[level-1].table).
+				Where("id = ?", row.ParentID).
+				Where("deleted_at IS NOT NULL").
+				Exists(ctx)
+			if err != nil {
+				return err
+			}
+			if parentInTrash {
+				return ErrParentInTrash
+			}
+		}
+
+		// A restored task comes back into its list like a moved one.
+		if current.kind == TrashedTask {
+			archived, err := tx.NewSelect().
+				Table("tasks").
+				Where("id = ?", args.ID).
+				Where("archived").
+				Exists(ctx)
+			if err != nil {
+				return err
+			}
+			if !archived {
+				if err := user.checkWIPLimit(ctx, tx, row.ParentID, args.ID); err != nil {
+					return err
+				}
+			}
+		}
+
+		_, err = tx.NewUpdate().
+			Table(current.table).
+			Set("deleted_at = NULL").
+			Where("id = ?", args.ID).
+			Exec(ctx)
+		if err != nil {
+			return err
+		}
+
+		return user.cascadeDeletedAt(ctx, tx, level, []store.EntityID{args.ID}, &row.DeletedAt, nil)
+	})
+}
@@ -0,0 +1,70 @@
+package userservice
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/uptrace/bun"
+
+	"github.com/lesnoi-kot/karten-backend/src/filestorage"
+	"github.com/lesnoi-kot/karten-backend/src/modules/trello"
+	"github.com/lesnoi-kot/karten-backend/src/store"
+)
+
+type ImportTrelloBoardOptions struct {
+	ProjectID   store.EntityID
+	FileStorage filestorage.FileStorage
+	Data        []byte
+}
+
+// Creates a board in the project from a Trello board JSON export. What can't
+// be mapped is returned in the report, it doesn't fail the import.
+func (user UserService) ImportTrelloBoard(args *ImportTrelloBoardOptions) (_ *store.Board, _ *trello.Report, err error) {
+	if owns, err := user.OwnsProject(args.ProjectID); err != nil {
+		return nil, nil, err
+	} else if !owns {
+		return nil, nil, store.ErrNotFound
+	}
+
+	doc, blobs, report, err := trello.Convert(args.Data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Inline attachments are added before the transaction, they are removed
+	// if the board can't be inserted.
+	files := make(map[string]*store.File, len(doc.Files))
+	defer func() {
+		if err == nil {
+			return
+		}
+		for _, file := range files {
+			user.Store.Files.Delete(user.Context, file.ID)
+			args.FileStorage.Delete(file.StorageObjectID)
+		}
+	}()
+
+	for _, docFile := range doc.Files {
+		file, err := user.Store.Files.Add(user.Context, store.AddFileOptions{
+			Name:     docFile.Name,
+			MIMEType: docFile.MimeType,
+			Data:     bytes.NewReader(blobs[docFile.Ref]),
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		files[docFile.Ref] = file
+	}
+
+	var board *store.Board
+	err = user.Store.ORM.RunInTx(user.Context, nil, func(ctx context.Context, tx bun.Tx) error {
+		var err error
+		board, err = user.insertBoardDocument(ctx, tx, args.ProjectID, doc, files)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return board, report, nil
+}
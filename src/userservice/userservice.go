@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/samber/lo"
+	"go.uber.org/zap"
 
 	"github.com/lesnoi-kot/karten-backend/src/modules/markdown"
 	"github.com/lesnoi-kot/karten-backend/src/store"
@@ -19,6 +20,15 @@ type UserService struct {
 	Context context.Context
 	UserID  store.UserID
 	Store   *store.Store
+	Logger  *zap.SugaredLogger // Optional, for the failures which can't fail the call
+}
+
+func (user UserService) logger() *zap.SugaredLogger {
+	if user.Logger == nil {
+		return zap.NewNop().Sugar()
+	}
+
+	return user.Logger
 }
 
 type GetUserOptions struct {
@@ -36,14 +46,18 @@ type GetProjectOptions struct {
 }
 
 type AddProjectOptions struct {
-	Name     string
-	AvatarID *store.FileID
+	Name      string
+	KeyPrefix *string
+	AvatarID  *store.FileID
 }
 
 type EditProjectOptions struct {
 	ProjectID store.EntityID
 	Name      *string
+	KeyPrefix *string
 	AvatarID  *store.FileID
+
+	SkipWeekends *bool
 }
 
 type DeleteProjectOptions struct {
@@ -56,6 +70,9 @@ type GetBoardOptions struct {
 	IncludeTaskLists         bool
 	IncludeTasks             bool
 	IncludeProject           bool
+	SprintID                 store.EntityID // Only tasks of the sprint
+	ActiveSprint             bool           // Only tasks of the active sprint
+	ViewID                   store.EntityID // Filter, sort and group tasks by the saved view
 }
 
 type AddBoardOptions struct {
@@ -72,6 +89,10 @@ type EditBoardOptions struct {
 	Color    *store.Color
 	Favorite *bool
 	CoverID  *store.FileID
+
+	// Existing estimates are kept as is, only their meaning changes.
+	EstimateUnit *string
+	LaneMode     *string
 }
 
 type GetTaskListOptions struct {
@@ -85,6 +106,8 @@ type EditTaskListOptions struct {
 	Archived   *bool
 	Color      *store.Color
 	Position   *int64
+	WIPLimit   *int // Zero removes the limit
+	WIPMode    *string
 }
 
 type DeleteBoardOptions struct {
@@ -96,6 +119,8 @@ type AddTaskListOptions struct {
 	Name     string
 	Color    store.Color
 	Position int64
+	WIPLimit *int
+	WIPMode  string
 }
 
 type ClearTaskListOptions struct {
@@ -120,7 +145,11 @@ type AddTaskOptions struct {
 	Name       string
 	Text       string
 	Position   int64
+	StartDate  *time.Time
 	DueDate    *time.Time
+	Estimate   *float64 // Zero is the same as no estimate
+	Priority   store.TaskPriority
+	LaneID     store.EntityID
 }
 
 type EditTaskOptions struct {
@@ -130,8 +159,14 @@ type EditTaskOptions struct {
 	Text                *string
 	Position            *int64
 	SpentTime           *int64
-	DueDate             *time.Time
+	Archived            *bool
+	StartDate           *time.Time // Zero removes the start date
+	DueDate             *time.Time // Zero removes the due date
 	DateStartedTracking *time.Time
+	SprintID            *store.EntityID // Empty string takes the task out of its sprint
+	Estimate            *float64        // Zero removes the estimate
+	Priority            *store.TaskPriority
+	LaneID              *store.EntityID // Empty string moves the task out of the lanes
 }
 
 type DeleteTaskOptions struct {
@@ -243,7 +278,9 @@ func (user UserService) GetProjects(args *GetProjectsOptions) ([]*store.Project,
 		Relation("Avatar.Thumbnails")
 
 	if args.IncludeBoards {
-		q = q.Relation("Boards").Relation("Boards.Cover")
+		q = q.Relation("Boards", func(q *bun.SelectQuery) *bun.SelectQuery {
+			return q.Where("board.archived = ?", false)
+		}).Relation("Boards.Cover")
 	}
 
 	err := q.Scan(user.Context)
@@ -264,7 +301,9 @@ func (user UserService) GetProject(args *GetProjectOptions) (*store.Project, err
 		Relation("Avatar.Thumbnails")
 
 	if args.IncludeBoards {
-		q = q.Relation("Boards").Relation("Boards.Cover")
+		q = q.Relation("Boards", func(q *bun.SelectQuery) *bun.SelectQuery {
+			return q.Where("board.archived = ?", false)
+		}).Relation("Boards.Cover")
 	}
 
 	err := q.Scan(user.Context)
@@ -283,6 +322,23 @@ func (user UserService) AddProject(args *AddProjectOptions) (*store.Project, err
 		Name:   args.Name,
 	}
 
+	generated := args.KeyPrefix == nil
+	if !generated {
+		prefix, err := normalizeKeyPrefix(*args.KeyPrefix)
+		if err != nil {
+			return nil, err
+		}
+
+		project.KeyPrefix = prefix
+	} else {
+		prefix, err := user.generateKeyPrefix(args.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		project.KeyPrefix = prefix
+	}
+
 	if args.AvatarID != nil {
 		avatarFile, err := user.Store.Files.GetImage(user.Context, *args.AvatarID)
 		if err != nil {
@@ -293,20 +349,34 @@ func (user UserService) AddProject(args *AddProjectOptions) (*store.Project, err
 		project.Avatar = avatarFile
 	}
 
-	_, err := user.Store.ORM.NewInsert().
-		Model(project).
-		Column("user_id", "name", "avatar_id").
-		Returning("*").
-		Exec(user.Context)
-	if err != nil {
-		return nil, err
-	}
+	for attempt := 1; ; attempt++ {
+		_, err := user.Store.ORM.NewInsert().
+			Model(project).
+			Column("user_id", "name", "key_prefix", "avatar_id").
+			Returning("*").
+			Exec(user.Context)
+		if err == nil {
+			return project, nil
+		} else if !store.IsUniqueViolation(err) {
+			return nil, err
+		}
 
-	return project, nil
+		// A project made at the same time took the generated prefix, the
+		// next free one is taken instead. A prefix typed by the user is
+		// never changed.
+		if !generated || attempt == maxKeyPrefixAttempts {
+			return nil, ErrKeyPrefixTaken
+		}
+
+		project.KeyPrefix, err = user.generateKeyPrefix(args.Name)
+		if err != nil {
+			return nil, err
+		}
+	}
 }
 
 func (user UserService) EditProject(args *EditProjectOptions) error {
-	if args.AvatarID == nil && args.Name == nil {
+	if args.AvatarID == nil && args.Name == nil && args.KeyPrefix == nil && args.SkipWeekends == nil {
 		return nil
 	}
 
@@ -321,9 +391,22 @@ func (user UserService) EditProject(args *EditProjectOptions) error {
 	if args.AvatarID != nil {
 		q = q.Set("avatar_id = ?", *args.AvatarID)
 	}
+	if args.KeyPrefix != nil {
+		prefix, err := normalizeKeyPrefix(*args.KeyPrefix)
+		if err != nil {
+			return err
+		}
+
+		q = q.Set("key_prefix = ?", prefix)
+	}
+	if args.SkipWeekends != nil {
+		q = q.Set("skip_weekends = ?", *args.SkipWeekends)
+	}
 
 	updateResult, err := q.Exec(user.Context)
-	if err != nil {
+	if store.IsUniqueViolation(err) {
+		return ErrKeyPrefixTaken
+	} else if err != nil {
 		return err
 	} else if store.NoRowsAffected(updateResult) {
 		return store.ErrNotFound
@@ -341,33 +424,24 @@ func (user UserService) ClearProject(projectID store.EntityID) error {
 		return ErrPermissionDenied
 	}
 
-	_, err = user.Store.ORM.NewDelete().
-		Model((*store.Board)(nil)).
-		Where("project_id = ?", projectID).
-		Exec(user.Context)
-
+	_, err = user.moveToTrash(TrashedBoard, "project_id = ?", projectID)
 	return err
 }
 
 func (user UserService) DeleteProject(args *DeleteProjectOptions) error {
-	result, err := user.Store.ORM.NewDelete().
-		Model((*store.Project)(nil)).
-		Where("id = ?", args.ProjectID).
-		Where("user_id = ?", user.UserID).
-		Exec(user.Context)
-
-	if store.NoRowsAffected(result) {
+	trashed, err := user.moveToTrash(TrashedProject, "id = ?", args.ProjectID)
+	if err != nil {
+		return err
+	}
+	if !trashed {
 		return store.ErrNotFound
 	}
 
-	return err
+	return nil
 }
 
 func (user UserService) DeleteAllProjects() error {
-	_, err := user.Store.ORM.NewDelete().
-		Model((*store.Project)(nil)).
-		Where("user_id = ?", user.UserID).
-		Exec(user.Context)
+	_, err := user.moveToTrash(TrashedProject, "TRUE")
 	return err
 }
 
@@ -382,6 +456,31 @@ func (user UserService) OwnsProject(projectID store.EntityID) (bool, error) {
 func (user UserService) GetBoard(args *GetBoardOptions) (*store.Board, error) {
 	board := &store.Board{ID: args.BoardID, UserID: user.UserID}
 
+	if args.ActiveSprint {
+		sprint, err := user.getActiveSprint(args.BoardID)
+		if err != nil {
+			return nil, err
+		}
+		board.Sprint = sprint
+	} else if args.SprintID != "" {
+		sprint, err := user.getSprint(user.Store.ORM, args.SprintID)
+		if err != nil {
+			return nil, err
+		}
+		if sprint.BoardID != args.BoardID {
+			return nil, store.ErrNotFound
+		}
+		board.Sprint = sprint
+	}
+
+	if args.ViewID != "" {
+		view, err := user.getViewForBoard(args.ViewID, args.BoardID)
+		if err != nil {
+			return nil, err
+		}
+		board.View = view
+	}
+
 	q := user.Store.ORM.NewSelect().
 		Model(board).
 		WherePK("id", "user_id").
@@ -394,10 +493,25 @@ func (user UserService) GetBoard(args *GetBoardOptions) (*store.Board, error) {
 	}
 
 	if args.IncludeTaskLists {
-		q = q.Relation("TaskLists")
+		q = q.Relation("TaskLists", func(q *bun.SelectQuery) *bun.SelectQuery {
+			return q.Where("task_list.archived = ?", false)
+		})
 
 		if args.IncludeTasks {
-			q = q.Relation("TaskLists.Tasks").
+			q = q.
+				Relation("TaskLists.Tasks", func(q *bun.SelectQuery) *bun.SelectQuery {
+					if board.View != nil {
+						q = viewArchivedFilter(q, &board.View.Filter)
+						q = applyViewFilter(q, &board.View.Filter, board.ID, time.Now())
+						q = viewTaskOrder(q, &board.View.Filter)
+					} else {
+						q = q.Where("task.archived = ?", false)
+					}
+					if board.Sprint != nil {
+						q = q.Where("task.sprint_id = ?", board.Sprint.ID)
+					}
+					return q
+				}).
 				Relation("TaskLists.Tasks.Comments").
 				Relation("TaskLists.Tasks.Attachments").
 				Relation("TaskLists.Tasks.Labels")
@@ -416,6 +530,29 @@ func (user UserService) GetBoard(args *GetBoardOptions) (*store.Board, error) {
 		return nil, errors.New("Forbidden")
 	}
 
+	if args.IncludeTasks {
+		prefix, err := user.boardKeyPrefix(board.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, taskList := range board.TaskLists {
+			fillTaskKeys(prefix, taskList.Tasks)
+		}
+	}
+
+	if err := user.fillBoardRollups(board); err != nil {
+		return nil, err
+	}
+
+	if args.IncludeTasks {
+		if err := user.fillLaneMatrix(board); err != nil {
+			return nil, err
+		}
+
+		fillTaskGroups(board)
+	}
+
 	if !args.SkipDateLastViewedUpdate {
 		board.DateLastViewed = time.Now().UTC()
 
@@ -479,7 +616,7 @@ func (user UserService) EditBoard(args *EditBoardOptions) error {
 		changedFields++
 	}
 	if args.Archived != nil {
-		q = q.Set("archived = ?", *args.Archived)
+		q = setArchived(q, *args.Archived)
 		changedFields++
 	}
 	if args.Color != nil {
@@ -495,6 +632,14 @@ func (user UserService) EditBoard(args *EditBoardOptions) error {
 		q = q.Set("cover_id = ?", *args.CoverID)
 		changedFields++
 	}
+	if args.EstimateUnit != nil {
+		q = q.Set("estimate_unit = ?", *args.EstimateUnit)
+		changedFields++
+	}
+	if args.LaneMode != nil {
+		q = q.Set("lane_mode = ?", *args.LaneMode)
+		changedFields++
+	}
 
 	if changedFields == 0 {
 		return nil
@@ -511,17 +656,15 @@ func (user UserService) EditBoard(args *EditBoardOptions) error {
 }
 
 func (user UserService) DeleteBoard(args *DeleteBoardOptions) error {
-	deleteResult, err := user.Store.ORM.NewDelete().
-		Model((*store.Board)(nil)).
-		Where("id = ?", args.BoardID).
-		Where("user_id = ?", user.UserID).
-		Exec(user.Context)
-
-	if store.NoRowsAffected(deleteResult) {
+	trashed, err := user.moveToTrash(TrashedBoard, "id = ?", args.BoardID)
+	if err != nil {
+		return err
+	}
+	if !trashed {
 		return store.ErrNotFound
 	}
 
-	return err
+	return nil
 }
 
 func (user UserService) OwnsBoard(boardID store.EntityID) (bool, error) {
@@ -549,13 +692,28 @@ func (user UserService) GetTaskList(args *GetTaskListOptions) (*store.TaskList,
 		Where("task_list.archived = ?", false)
 
 	if args.IncludeTasks {
-		q = q.Relation("Tasks")
+		q = q.Relation("Tasks", func(q *bun.SelectQuery) *bun.SelectQuery {
+			return q.Where("task.archived = ?", false)
+		})
 	}
 
 	if err := q.Scan(user.Context); err != nil {
 		return nil, err
 	}
 
+	if args.IncludeTasks {
+		prefix, err := user.boardKeyPrefix(taskList.BoardID)
+		if err != nil {
+			return nil, err
+		}
+
+		fillTaskKeys(prefix, taskList.Tasks)
+	}
+
+	if err := user.fillTaskListRollups(taskList); err != nil {
+		return nil, err
+	}
+
 	return taskList, nil
 }
 
@@ -575,11 +733,16 @@ func (user UserService) AddTaskList(args *AddTaskListOptions) (*store.TaskList,
 		Name:     args.Name,
 		Color:    args.Color,
 		Position: args.Position,
+		WIPLimit: args.WIPLimit,
+		WIPMode:  args.WIPMode,
+	}
+	if taskList.WIPMode == "" {
+		taskList.WIPMode = store.WIPModeSoft
 	}
 
 	_, err = user.Store.ORM.NewInsert().
 		Model(taskList).
-		Column("board_id", "user_id", "name", "color", "position").
+		Column("board_id", "user_id", "name", "color", "position", "wip_limit", "wip_mode").
 		Returning("*").
 		Exec(user.Context)
 	if err != nil {
@@ -599,7 +762,7 @@ func (user UserService) EditTaskList(args *EditTaskListOptions) error {
 		q = q.Set("name = ?", *args.Name)
 	}
 	if args.Archived != nil {
-		q = q.Set("archived = ?", *args.Archived)
+		q = setArchived(q, *args.Archived)
 	}
 	if args.Color != nil {
 		q = q.Set("color = ?", *args.Color)
@@ -607,6 +770,14 @@ func (user UserService) EditTaskList(args *EditTaskListOptions) error {
 	if args.Position != nil {
 		q = q.Set("position = ?", *args.Position)
 	}
+	if args.WIPLimit != nil && *args.WIPLimit == 0 {
+		q = q.Set("wip_limit = NULL")
+	} else if args.WIPLimit != nil {
+		q = q.Set("wip_limit = ?", *args.WIPLimit)
+	}
+	if args.WIPMode != nil {
+		q = q.Set("wip_mode = ?", *args.WIPMode)
+	}
 
 	updateResult, err := q.Exec(user.Context)
 	if err != nil {
@@ -619,27 +790,20 @@ func (user UserService) EditTaskList(args *EditTaskListOptions) error {
 }
 
 func (user UserService) ClearTaskList(args *ClearTaskListOptions) error {
-	_, err := user.Store.ORM.NewDelete().
-		Model((*store.Task)(nil)).
-		Where("task_list_id = ?", args.TaskListID).
-		Where("user_id = ?", user.UserID).
-		Exec(user.Context)
-
+	_, err := user.moveToTrash(TrashedTask, "task_list_id = ?", args.TaskListID)
 	return err
 }
 
 func (user UserService) DeleteTaskList(args *DeleteTaskListOptions) error {
-	deleteResult, err := user.Store.ORM.NewDelete().
-		Model((*store.TaskList)(nil)).
-		Where("id = ?", args.TaskListID).
-		Where("user_id = ?", user.UserID).
-		Exec(user.Context)
-
-	if store.NoRowsAffected(deleteResult) {
+	trashed, err := user.moveToTrash(TrashedTaskList, "id = ?", args.TaskListID)
+	if err != nil {
+		return err
+	}
+	if !trashed {
 		return store.ErrNotFound
 	}
 
-	return err
+	return nil
 }
 
 func (user UserService) GetTask(args *GetTaskOptions) (*store.Task, error) {
@@ -684,6 +848,13 @@ func (user UserService) GetTask(args *GetTaskOptions) (*store.Task, error) {
 		task.HTML = markdown.Render(task.Text)
 	}
 
+	prefix, err := user.taskKeyPrefix(task.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	task.Key = store.FormatTaskKey(prefix, task.Number)
+
 	return task, nil
 }
 
@@ -694,19 +865,130 @@ func (user UserService) AddTask(args *AddTaskOptions) (*store.Task, error) {
 		Name:       args.Name,
 		Text:       args.Text,
 		Position:   args.Position,
+		StartDate:  args.StartDate,
 		DueDate:    args.DueDate,
+		Priority:   args.Priority,
+	}
+	if args.Estimate != nil && *args.Estimate != 0 {
+		task.Estimate = args.Estimate
 	}
 
-	_, err := user.Store.ORM.NewInsert().
+	var laneLabelID store.LabelID
+	if args.LaneID != "" {
+		boardID, err := user.taskListBoardID(args.TaskListID)
+		if err != nil {
+			return nil, err
+		}
+		mode, err := user.boardLaneMode(boardID)
+		if err != nil {
+			return nil, err
+		}
+		if mode == store.LaneModeNone {
+			return nil, ErrNoLanes
+		}
+
+		laneLabelID, err = user.checkLaneForBoard(args.LaneID, boardID, mode)
+		if err != nil {
+			return nil, err
+		}
+		if mode == store.LaneModeExplicit {
+			task.LaneID = &args.LaneID
+		}
+	}
+
+	if err := user.createTask(task, laneLabelID, nil, nil); err != nil {
+		return nil, err
+	}
+
+	return task, nil
+}
+
+// Saves the new task with its labels and queues its webhooks in one
+// transaction, the optional inTx function runs in it too. Then fills the task
+// key and runs the automations of the labels.
+func (user UserService) createTask(
+	task *store.Task,
+	laneLabelID store.LabelID,
+	labels []*store.Label,
+	inTx func(ctx context.Context, tx bun.Tx) error,
+) error {
+	err := user.Store.ORM.RunInTx(user.Context, nil, func(ctx context.Context, tx bun.Tx) error {
+		if err := user.insertTask(ctx, tx, task, laneLabelID); err != nil {
+			return err
+		}
+
+		for _, label := range labels {
+			_, err := tx.NewInsert().
+				Model(&store.LabelToTaskAssoc{TaskID: task.ID, LabelID: label.ID}).
+				On("CONFLICT DO NOTHING").
+				Exec(ctx)
+			if err != nil {
+				return err
+			}
+		}
+
+		if inTx != nil {
+			if err := inTx(ctx, tx); err != nil {
+				return err
+			}
+		}
+
+		return user.queueTaskWebhooks(ctx, tx, &webhookPayload{Event: store.WebhookEventTaskCreated}, task.ID)
+	})
+	if err != nil {
+		return err
+	}
+
+	prefix, err := user.taskKeyPrefix(task.ID)
+	if err != nil {
+		return err
+	}
+	task.Key = store.FormatTaskKey(prefix, task.Number)
+
+	if labels != nil {
+		task.Labels = labels
+	}
+	for _, label := range labels {
+		user.runSavedAutomations(&AutomationEvent{
+			Trigger: store.AutomationTriggerLabelAdded,
+			TaskID:  task.ID,
+			LabelID: label.ID,
+		})
+	}
+
+	return nil
+}
+
+// Inserts the task with a new number, checking the WIP limit of its list.
+// The lane label is optional.
+func (user UserService) insertTask(ctx context.Context, tx bun.Tx, task *store.Task, laneLabelID store.LabelID) error {
+	if err := user.checkWIPLimit(ctx, tx, task.TaskListID, ""); err != nil {
+		return err
+	}
+
+	_, err := tx.NewInsert().
+		With("counter", user.reserveTaskNumberQuery(task.TaskListID)).
 		Model(task).
-		Column("task_list_id", "user_id", "name", "text", "position", "due_date").
+		Column("task_list_id", "user_id", "number", "project_id", "name", "text", "position", "start_date", "due_date", "estimate", "priority", "lane_id").
+		Value("number", "(SELECT number FROM counter)").
+		Value("project_id", "(SELECT project_id FROM counter)").
 		Returning("*").
-		Exec(user.Context)
+		Exec(ctx)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	return task, nil
+	if err := user.addTaskMovement(ctx, tx, task.ID, "", task.TaskListID); err != nil {
+		return err
+	}
+
+	if laneLabelID != 0 {
+		if err := user.setTaskLaneLabel(ctx, tx, task.ID, laneLabelID); err != nil {
+			return err
+		}
+	}
+
+	return user.addTaskRevision(ctx, tx, task.ID, task.Text, false)
 }
 
 func (user UserService) EditTask(args *EditTaskOptions) error {
@@ -717,6 +999,23 @@ func (user UserService) EditTask(args *EditTaskOptions) error {
 
 	if args.TaskListID != nil {
 		q = q.Set("task_list_id = ?", *args.TaskListID)
+
+		// Numbers are kept within a project, a task moved to another
+		// project gets a new one.
+		currentProjectID, err := user.taskProjectID(args.TaskID)
+		if err != nil {
+			return err
+		}
+		targetProjectID, err := user.taskListProjectID(*args.TaskListID)
+		if err != nil {
+			return err
+		}
+
+		if currentProjectID != targetProjectID {
+			q = q.With("counter", user.reserveTaskNumberQuery(*args.TaskListID)).
+				Set("number = (SELECT number FROM counter)").
+				Set("project_id = (SELECT project_id FROM counter)")
+		}
 	}
 	if args.Name != nil && *args.Name != "" {
 		q = q.Set("name = ?", *args.Name)
@@ -727,7 +1026,17 @@ func (user UserService) EditTask(args *EditTaskOptions) error {
 	if args.Position != nil {
 		q = q.Set("position = ?", *args.Position)
 	}
-	if args.DueDate != nil {
+	if args.Archived != nil {
+		q = setArchived(q, *args.Archived)
+	}
+	if args.StartDate != nil && args.StartDate.IsZero() {
+		q = q.Set("start_date = NULL")
+	} else if args.StartDate != nil {
+		q = q.Set("start_date = ?", *args.StartDate)
+	}
+	if args.DueDate != nil && args.DueDate.IsZero() {
+		q = q.Set("due_date = NULL")
+	} else if args.DueDate != nil {
 		q = q.Set("due_date = ?", *args.DueDate)
 	}
 	if args.DateStartedTracking != nil {
@@ -740,29 +1049,198 @@ func (user UserService) EditTask(args *EditTaskOptions) error {
 	if args.SpentTime != nil {
 		q = q.Set("spent_time = ?", *args.SpentTime)
 	}
+	if args.Priority != nil {
+		q = q.Set("priority = ?", *args.Priority)
+	}
+	if args.Estimate != nil && *args.Estimate == 0 {
+		q = q.Set("estimate = NULL")
+	} else if args.Estimate != nil {
+		q = q.Set("estimate = ?", *args.Estimate)
+	}
+	// The board the task ends up on.
+	targetBoardID := func() (store.EntityID, error) {
+		if args.TaskListID != nil {
+			return user.taskListBoardID(*args.TaskListID)
+		}
 
-	updateResult, err := q.Exec(user.Context)
+		return user.taskBoardID(args.TaskID)
+	}
+
+	if args.SprintID != nil && *args.SprintID == "" {
+		q = q.Set("sprint_id = NULL")
+	} else if args.SprintID != nil {
+		boardID, err := targetBoardID()
+		if err != nil {
+			return err
+		}
+
+		if err := user.checkSprintForBoard(*args.SprintID, boardID); err != nil {
+			return err
+		}
+
+		q = q.Set("sprint_id = ?", *args.SprintID)
+	} else if args.TaskListID != nil {
+		// Sprints belong to a board, a task moved to another board leaves
+		// its sprint.
+		q = q.Set(`sprint_id = CASE
+			WHEN (SELECT board_id FROM task_lists WHERE id = ?) = (SELECT board_id FROM task_lists WHERE id = task.task_list_id)
+			THEN task.sprint_id
+		END`, *args.TaskListID)
+	}
+
+	// Set in the label mode, where the lane of a task is its label.
+	var laneLabelID *store.LabelID
+	if args.LaneID != nil {
+		boardID, err := targetBoardID()
+		if err != nil {
+			return err
+		}
+		mode, err := user.boardLaneMode(boardID)
+		if err != nil {
+			return err
+		}
+		if mode == store.LaneModeNone {
+			return ErrNoLanes
+		}
+
+		var labelID store.LabelID
+		if *args.LaneID != "" {
+			labelID, err = user.checkLaneForBoard(*args.LaneID, boardID, mode)
+			if err != nil {
+				return err
+			}
+		}
+
+		if mode == store.LaneModeLabel {
+			laneLabelID = &labelID
+		} else if *args.LaneID == "" {
+			q = q.Set("lane_id = NULL")
+		} else {
+			q = q.Set("lane_id = ?", *args.LaneID)
+		}
+	} else if args.TaskListID != nil {
+		// Lanes belong to a board as well.
+		q = q.Set(`lane_id = CASE
+			WHEN (SELECT board_id FROM task_lists WHERE id = ?) = (SELECT board_id FROM task_lists WHERE id = task.task_list_id)
+			THEN task.lane_id
+		END`, *args.TaskListID)
+	}
+
+	var previousTaskListID store.EntityID
+	err := user.Store.ORM.RunInTx(user.Context, nil, func(ctx context.Context, tx bun.Tx) error {
+		previous := new(store.Task)
+		if args.TaskListID != nil || args.SprintID != nil || args.Archived != nil {
+			err := tx.NewSelect().
+				Model(previous).
+				Column("task_list_id", "sprint_id", "archived").
+				Where("id = ?", args.TaskID).
+				Where("user_id = ?", user.UserID).
+				For("UPDATE").
+				Scan(ctx)
+			if errors.Is(err, sql.ErrNoRows) {
+				return store.ErrNotFound
+			} else if err != nil {
+				return err
+			}
+		}
+		previousTaskListID = previous.TaskListID
+
+		// Unarchived tasks come back into their list, so they count against
+		// its limit like the moved ones.
+		moved := args.TaskListID != nil && *args.TaskListID != previousTaskListID
+		unarchived := args.Archived != nil && !*args.Archived && previous.Archived
+		if moved || unarchived {
+			targetListID := previousTaskListID
+			if args.TaskListID != nil {
+				targetListID = *args.TaskListID
+			}
+
+			if err := user.checkWIPLimit(ctx, tx, targetListID, args.TaskID); err != nil {
+				return err
+			}
+		}
+
+		updateResult, err := q.Conn(tx).Exec(ctx)
+		if err != nil {
+			return err
+		} else if store.NoRowsAffected(updateResult) {
+			return store.ErrNotFound
+		}
+
+		if args.TaskListID != nil && *args.TaskListID != previousTaskListID {
+			err := user.addTaskMovement(ctx, tx, args.TaskID, previousTaskListID, *args.TaskListID)
+			if err != nil {
+				return err
+			}
+		}
+
+		if args.TaskListID != nil || args.SprintID != nil {
+			var sprintID *store.EntityID
+			err := tx.NewSelect().
+				Model((*store.Task)(nil)).
+				Column("sprint_id").
+				Where("id = ?", args.TaskID).
+				Scan(ctx, &sprintID)
+			if err != nil {
+				return err
+			}
+
+			err = user.syncSprintCommitment(ctx, tx, args.TaskID, previous.SprintID, sprintID)
+			if err != nil {
+				return err
+			}
+		}
+
+		if laneLabelID != nil {
+			if err := user.setTaskLaneLabel(ctx, tx, args.TaskID, *laneLabelID); err != nil {
+				return err
+			}
+		}
+
+		if args.Text != nil {
+			if err := user.addTaskRevision(ctx, tx, args.TaskID, *args.Text, true); err != nil {
+				return err
+			}
+		}
+
+		payload := &webhookPayload{Event: store.WebhookEventTaskUpdated}
+		if args.TaskListID != nil && *args.TaskListID != previousTaskListID {
+			payload = &webhookPayload{
+				Event:          store.WebhookEventTaskMoved,
+				FromTaskListID: previousTaskListID,
+			}
+		}
+
+		return user.queueTaskWebhooks(ctx, tx, payload, args.TaskID)
+	})
 	if err != nil {
 		return err
-	} else if store.NoRowsAffected(updateResult) {
-		return store.ErrNotFound
+	}
+
+	if args.TaskListID != nil && *args.TaskListID != previousTaskListID {
+		user.runSavedAutomations(&AutomationEvent{
+			Trigger:        store.AutomationTriggerTaskMoved,
+			TaskID:         args.TaskID,
+			TaskListID:     *args.TaskListID,
+			FromTaskListID: previousTaskListID,
+		})
 	}
 
 	return nil
 }
 
 func (user UserService) DeleteTask(args *DeleteTaskOptions) error {
-	deleteResult, err := user.Store.ORM.NewDelete().
-		Model((*store.Task)(nil)).
-		Where("id = ?", args.TaskID).
-		Where("user_id = ?", user.UserID).
-		Exec(user.Context)
-
-	if store.NoRowsAffected(deleteResult) {
-		return store.ErrNotFound
-	}
+	return user.Store.ORM.RunInTx(user.Context, nil, func(ctx context.Context, tx bun.Tx) error {
+		trashed, err := user.trashRows(ctx, tx, TrashedTask, "id = ?", args.TaskID)
+		if err != nil {
+			return err
+		}
+		if !trashed {
+			return store.ErrNotFound
+		}
 
-	return err
+		return user.queueTaskWebhooks(ctx, tx, &webhookPayload{Event: store.WebhookEventTaskDeleted}, args.TaskID)
+	})
 }
 
 func (user UserService) AddLabelToTask(args *AddLabelToTaskOptions) error {
@@ -778,7 +1256,16 @@ func (user UserService) AddLabelToTask(args *AddLabelToTaskOptions) error {
 	}
 
 	_, err := user.Store.ORM.NewInsert().Model(assoc).Exec(context.Background())
-	return err
+	if err != nil {
+		return err
+	}
+
+	user.runSavedAutomations(&AutomationEvent{
+		Trigger: store.AutomationTriggerLabelAdded,
+		TaskID:  args.TaskID,
+		LabelID: args.LabelID,
+	})
+	return nil
 }
 
 func (user UserService) DeleteLabelFromTask(args *AddLabelToTaskOptions) error {
@@ -826,23 +1313,29 @@ func (user UserService) AddComment(args *AddCommentOptions) (*store.Comment, err
 		Text:   args.Text,
 	}
 
-	_, err := user.Store.ORM.NewInsert().
-		Model(comment).
-		Column("task_id", "user_id", "text").
-		Returning("id").
-		Exec(user.Context)
+	err := user.Store.ORM.RunInTx(user.Context, nil, func(ctx context.Context, tx bun.Tx) error {
+		if err := user.insertComment(ctx, tx, comment); err != nil {
+			return err
+		}
+
+		return user.queueTaskWebhooks(ctx, tx, commentAddedPayload(comment), comment.TaskID)
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	comment, err = user.GetComment(&GetCommentOptions{
+	return user.GetComment(&GetCommentOptions{
 		CommentID: comment.ID,
 	})
-	if err != nil {
-		return nil, err
-	}
+}
 
-	return comment, nil
+func (user UserService) insertComment(ctx context.Context, db bun.IDB, comment *store.Comment) error {
+	_, err := db.NewInsert().
+		Model(comment).
+		Column("task_id", "user_id", "text").
+		Returning("id, date_created").
+		Exec(ctx)
+	return err
 }
 
 func (user UserService) EditComment(args *EditCommentOptions) error {
@@ -988,19 +1481,23 @@ func (user UserService) AttachFilesToTask(args *AttachFilesToTask) error {
 		return ErrPermissionDenied
 	}
 
-	assocs := lo.Map(args.FilesID, func(fileID store.FileID, _ int) *store.AttachmentToTaskAssoc {
+	return attachFilesToTask(context.Background(), user.Store.ORM, args.TaskID, args.FilesID)
+}
+
+func attachFilesToTask(ctx context.Context, db bun.IDB, taskID store.EntityID, fileIDs []store.FileID) error {
+	if len(fileIDs) == 0 {
+		return nil
+	}
+
+	assocs := lo.Map(fileIDs, func(fileID store.FileID, _ int) *store.AttachmentToTaskAssoc {
 		return &store.AttachmentToTaskAssoc{
-			TaskID: args.TaskID,
+			TaskID: taskID,
 			FileID: fileID,
 		}
 	})
 
-	_, err := user.Store.ORM.NewInsert().Model(&assocs).Exec(context.Background())
-	if err != nil {
-		return err
-	}
-
-	return nil
+	_, err := db.NewInsert().Model(&assocs).Exec(ctx)
+	return err
 }
 
 func (user UserService) AttachFilesToComment(args *AttachFilesToComment) error {
@@ -1014,17 +1511,21 @@ func (user UserService) AttachFilesToComment(args *AttachFilesToComment) error {
 		return ErrPermissionDenied
 	}
 
-	assocs := lo.Map(args.FilesID, func(fileID store.FileID, _ int) *store.AttachmentToCommentAssoc {
+	return attachFilesToComment(context.Background(), user.Store.ORM, args.CommentID, args.FilesID)
+}
+
+func attachFilesToComment(ctx context.Context, db bun.IDB, commentID store.EntityID, fileIDs []store.FileID) error {
+	if len(fileIDs) == 0 {
+		return nil
+	}
+
+	assocs := lo.Map(fileIDs, func(fileID store.FileID, _ int) *store.AttachmentToCommentAssoc {
 		return &store.AttachmentToCommentAssoc{
-			CommentID: args.CommentID,
+			CommentID: commentID,
 			FileID:    fileID,
 		}
 	})
 
-	_, err := user.Store.ORM.NewInsert().Model(&assocs).Exec(context.Background())
-	if err != nil {
-		return err
-	}
-
-	return nil
+	_, err := db.NewInsert().Model(&assocs).Exec(ctx)
+	return err
 }
@@ -0,0 +1,101 @@
+package userservice
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/zap"
+
+	"github.com/lesnoi-kot/karten-backend/src/store"
+)
+
+// Runs the service against a migrated database, a fresh user per test.
+type userServiceSuite struct {
+	suite.Suite
+
+	store *store.Store
+	user  UserService
+}
+
+func TestUserService(t *testing.T) {
+	if os.Getenv("STORE_DSN") == "" {
+		t.Skip("User service integration tests are skipped!")
+	}
+
+	suite.Run(t, new(userServiceSuite))
+}
+
+func (s *userServiceSuite) SetupTest() {
+	var err error
+
+	s.store, err = store.NewStore(store.StoreConfig{
+		DSN:    os.Getenv("STORE_DSN"),
+		Logger: zap.NewNop().Sugar(),
+	})
+	s.Require().NoError(err)
+
+	user := &store.User{
+		SocialID: fmt.Sprintf("userservice-test-%d", time.Now().UnixNano()),
+		Name:     "test",
+	}
+	_, err = s.store.ORM.NewInsert().
+		Model(user).
+		Column("social_id", "name").
+		Returning("id").
+		Exec(context.Background())
+	s.Require().NoError(err)
+
+	s.user = UserService{Context: context.Background(), UserID: user.ID, Store: s.store}
+}
+
+func (s *userServiceSuite) TearDownTest() {
+	_, err := s.store.ORM.NewDelete().
+		Model((*store.User)(nil)).
+		Where("id = ?", s.user.UserID).
+		Exec(context.Background())
+	s.NoError(err)
+	s.NoError(s.store.Close())
+}
+
+// Adds a project with a board holding the lists, in the given order.
+func (s *userServiceSuite) addBoard(listNames ...string) (*store.Board, []*store.TaskList) {
+	project, err := s.user.AddProject(&AddProjectOptions{Name: "Test"})
+	s.Require().NoError(err)
+
+	return s.addProjectBoard(project.ID, listNames...)
+}
+
+func (s *userServiceSuite) addProjectBoard(projectID store.EntityID, listNames ...string) (*store.Board, []*store.TaskList) {
+	board, err := s.user.AddBoard(&AddBoardOptions{ProjectID: projectID, Name: "Board"})
+	s.Require().NoError(err)
+
+	taskLists := make([]*store.TaskList, len(listNames))
+	for i, name := range listNames {
+		taskLists[i], err = s.user.AddTaskList(&AddTaskListOptions{
+			BoardID:  board.ID,
+			Name:     name,
+			Position: int64(i + 1),
+		})
+		s.Require().NoError(err)
+	}
+
+	return board, taskLists
+}
+
+func (s *userServiceSuite) addTask(taskListID store.EntityID, name string) *store.Task {
+	task, err := s.user.AddTask(&AddTaskOptions{TaskListID: taskListID, Name: name})
+	s.Require().NoError(err)
+
+	return task
+}
+
+func (s *userServiceSuite) getTask(taskID store.EntityID) *store.Task {
+	task, err := s.user.GetTask(&GetTaskOptions{TaskID: taskID, SkipTextRender: true, SkipCommentTextRender: true})
+	s.Require().NoError(err)
+
+	return task
+}
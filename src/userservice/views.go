@@ -0,0 +1,393 @@
+package userservice
+
+import (
+	"database/sql"
+	"errors"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/samber/lo"
+	"github.com/uptrace/bun"
+
+	"github.com/lesnoi-kot/karten-backend/src/store"
+)
+
+const (
+	ViewGroupByList     = "list"
+	ViewGroupByLabel    = "label"
+	ViewGroupByPriority = "priority"
+	ViewGroupByDueDate  = "due_date"
+)
+
+const (
+	ViewCompletionOpen = "open"
+	ViewCompletionDone = "done"
+)
+
+const (
+	ViewArchivedInclude = "include"
+	ViewArchivedOnly    = "only"
+)
+
+var priorityNames = map[store.TaskPriority]string{
+	store.TaskPriorityNone:   "none",
+	store.TaskPriorityLow:    "low",
+	store.TaskPriorityMedium: "medium",
+	store.TaskPriorityHigh:   "high",
+	store.TaskPriorityUrgent: "urgent",
+}
+
+type AddViewOptions struct {
+	BoardID   store.EntityID // Either the board
+	ProjectID store.EntityID // or the project
+	Name      string
+	Filter    store.ViewFilter
+}
+
+type EditViewOptions struct {
+	ViewID store.EntityID
+	Name   *string
+	Filter *store.ViewFilter
+}
+
+// Views of the board itself and of its project.
+func (user UserService) GetBoardViews(boardID store.EntityID) ([]*store.View, error) {
+	board := new(store.Board)
+	err := user.Store.ORM.NewSelect().
+		Model(board).
+		Column("id", "project_id").
+		Where("id = ?", boardID).
+		Where("user_id = ?", user.UserID).
+		Scan(user.Context)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, store.ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	views := make([]*store.View, 0)
+	err = user.Store.ORM.NewSelect().
+		Model(&views).
+		Where("user_id = ?", user.UserID).
+		WhereGroup(" AND ", func(q *bun.SelectQuery) *bun.SelectQuery {
+			return q.
+				Where("board_id = ?", board.ID).
+				WhereOr("project_id = ?", board.ProjectID)
+		}).
+		Order("date_created").
+		Scan(user.Context)
+	if err != nil {
+		return nil, err
+	}
+
+	return views, nil
+}
+
+func (user UserService) GetProjectViews(projectID store.EntityID) ([]*store.View, error) {
+	if owns, err := user.OwnsProject(projectID); err != nil {
+		return nil, err
+	} else if !owns {
+		return nil, store.ErrNotFound
+	}
+
+	views := make([]*store.View, 0)
+	err := user.Store.ORM.NewSelect().
+		Model(&views).
+		Where("project_id = ?", projectID).
+		Order("date_created").
+		Scan(user.Context)
+	if err != nil {
+		return nil, err
+	}
+
+	return views, nil
+}
+
+func (user UserService) GetView(viewID store.EntityID) (*store.View, error) {
+	view := new(store.View)
+	err := user.Store.ORM.NewSelect().
+		Model(view).
+		Where("id = ?", viewID).
+		Where("user_id = ?", user.UserID).
+		Scan(user.Context)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, store.ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	return view, nil
+}
+
+func (user UserService) AddView(args *AddViewOptions) (*store.View, error) {
+	view := &store.View{
+		UserID: user.UserID,
+		Name:   args.Name,
+		Filter: args.Filter,
+	}
+
+	if args.BoardID != "" {
+		if owns, err := user.OwnsBoard(args.BoardID); err != nil {
+			return nil, err
+		} else if !owns {
+			return nil, store.ErrNotFound
+		}
+		view.BoardID = &args.BoardID
+	} else {
+		if owns, err := user.OwnsProject(args.ProjectID); err != nil {
+			return nil, err
+		} else if !owns {
+			return nil, store.ErrNotFound
+		}
+		view.ProjectID = &args.ProjectID
+	}
+
+	_, err := user.Store.ORM.NewInsert().
+		Model(view).
+		Column("user_id", "board_id", "project_id", "name", "filter").
+		Returning("*").
+		Exec(user.Context)
+	if err != nil {
+		return nil, err
+	}
+
+	return view, nil
+}
+
+func (user UserService) EditView(args *EditViewOptions) error {
+	if args.Name == nil && args.Filter == nil {
+		return nil
+	}
+
+	q := user.Store.ORM.NewUpdate().
+		Model((*store.View)(nil)).
+		Where("id = ?", args.ViewID).
+		Where("user_id = ?", user.UserID)
+
+	if args.Name != nil && *args.Name != "" {
+		q = q.Set("name = ?", *args.Name)
+	}
+	if args.Filter != nil {
+		q = q.Set("filter = ?", args.Filter)
+	}
+
+	result, err := q.Exec(user.Context)
+	if err != nil {
+		return err
+	} else if store.NoRowsAffected(result) {
+		return store.ErrNotFound
+	}
+
+	return nil
+}
+
+func (user UserService) DeleteView(viewID store.EntityID) error {
+	result, err := user.Store.ORM.NewDelete().
+		Model((*store.View)(nil)).
+		Where("id = ?", viewID).
+		Where("user_id = ?", user.UserID).
+		Exec(user.Context)
+	if err != nil {
+		return err
+	} else if store.NoRowsAffected(result) {
+		return store.ErrNotFound
+	}
+
+	return nil
+}
+
+// Returns the view if it can be applied to the board.
+func (user UserService) getViewForBoard(viewID, boardID store.EntityID) (*store.View, error) {
+	view := new(store.View)
+	err := user.Store.ORM.NewSelect().
+		Model(view).
+		Where("view.id = ?", viewID).
+		Where("view.user_id = ?", user.UserID).
+		Where(`view.board_id = ? OR view.project_id = (
+			SELECT project_id FROM boards WHERE id = ?
+		)`, boardID, boardID).
+		Scan(user.Context)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, store.ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	return view, nil
+}
+
+// Narrows down a query of tasks aliased as "task" on the given board. The
+// archived state is left to the caller, see viewArchivedFilter.
+func applyViewFilter(
+	q *bun.SelectQuery,
+	filter *store.ViewFilter,
+	boardID store.EntityID,
+	now time.Time,
+) *bun.SelectQuery {
+	if len(filter.LabelIDs) > 0 {
+		q = q.Where(`EXISTS (
+			SELECT 1 FROM task_labels AS tlb
+			WHERE tlb.task_id = task.id AND tlb.label_id IN (?)
+		)`, bun.In(filter.LabelIDs))
+	}
+
+	hasDueRange := filter.DueFrom != nil || filter.DueTo != nil || filter.DueWithinDays != nil
+	if hasDueRange || filter.NoDueDate {
+		q = q.WhereGroup(" AND ", func(q *bun.SelectQuery) *bun.SelectQuery {
+			if hasDueRange {
+				q = q.WhereGroup(" OR ", func(q *bun.SelectQuery) *bun.SelectQuery {
+					if filter.DueFrom != nil {
+						q = q.Where("task.due_date >= ?", *filter.DueFrom)
+					}
+					if filter.DueTo != nil {
+						q = q.Where("task.due_date <= ?", *filter.DueTo)
+					}
+					if filter.DueWithinDays != nil {
+						today := now.UTC().Truncate(24 * time.Hour)
+						q = q.
+							Where("task.due_date >= ?", today).
+							Where("task.due_date < ?", today.AddDate(0, 0, *filter.DueWithinDays+1))
+					}
+					return q
+				})
+			}
+			if filter.NoDueDate {
+				q = q.WhereOr("task.due_date IS NULL")
+			}
+			return q
+		})
+	}
+
+	if filter.Text != "" {
+		pattern := likePattern(filter.Text)
+		q = q.Where("task.name ILIKE ? OR task.text ILIKE ?", pattern, pattern)
+	}
+
+	if filter.MinPriority > 0 {
+		q = q.Where("task.priority >= ?", filter.MinPriority)
+	}
+
+	switch filter.Completion {
+	case ViewCompletionOpen:
+		q = q.Where("task.task_list_id IS DISTINCT FROM (?)", doneListQuery(q.DB(), boardID))
+	case ViewCompletionDone:
+		q = q.Where("task.task_list_id = (?)", doneListQuery(q.DB(), boardID))
+	}
+
+	return q
+}
+
+func viewArchivedFilter(q *bun.SelectQuery, filter *store.ViewFilter) *bun.SelectQuery {
+	switch filter.Archived {
+	case ViewArchivedInclude:
+		return q
+	case ViewArchivedOnly:
+		return q.Where("task.archived = ?", true)
+	}
+
+	return q.Where("task.archived = ?", false)
+}
+
+func viewTaskOrder(q *bun.SelectQuery, filter *store.ViewFilter) *bun.SelectQuery {
+	direction := "ASC"
+	if filter.SortDesc {
+		direction = "DESC"
+	}
+
+	switch filter.SortBy {
+	case "due_date":
+		return q.OrderExpr("task.due_date " + direction + " NULLS LAST")
+	case "priority":
+		return q.OrderExpr("task.priority " + direction)
+	case "date_created":
+		return q.OrderExpr("task.date_created " + direction)
+	case "name":
+		return q.OrderExpr("task.name " + direction)
+	case "position":
+		return q.OrderExpr("task.position " + direction)
+	}
+
+	return q
+}
+
+// Groups the loaded tasks of the board by the GroupBy of its view. Lists are
+// the natural grouping of a board, so nothing is done for them.
+func fillTaskGroups(board *store.Board) {
+	if board.View == nil {
+		return
+	}
+
+	var tasks []*store.Task
+	for _, taskList := range board.TaskLists {
+		tasks = append(tasks, taskList.Tasks...)
+	}
+
+	var groups []*store.TaskGroup
+	byKey := make(map[string]*store.TaskGroup)
+	add := func(key, name string, task *store.Task) {
+		group, ok := byKey[key]
+		if !ok {
+			group = &store.TaskGroup{Key: key, Name: name, TaskIDs: make([]store.EntityID, 0)}
+			byKey[key] = group
+			groups = append(groups, group)
+		}
+		group.TaskIDs = append(group.TaskIDs, task.ID)
+	}
+
+	switch board.View.Filter.GroupBy {
+	case ViewGroupByLabel:
+		for _, task := range tasks {
+			if len(task.Labels) == 0 {
+				add("", "", task)
+			}
+			for _, label := range task.Labels {
+				add(strconv.Itoa(label.ID), label.Name, task)
+			}
+		}
+		sortGroups(groups, func(a, b *store.TaskGroup) bool {
+			idA, _ := strconv.Atoi(a.Key)
+			idB, _ := strconv.Atoi(b.Key)
+			return idA < idB
+		})
+
+	case ViewGroupByPriority:
+		priorities := make(map[string]store.TaskPriority)
+		for _, task := range tasks {
+			key := strconv.Itoa(int(task.Priority))
+			priorities[key] = task.Priority
+			add(key, priorityNames[task.Priority], task)
+		}
+		sort.SliceStable(groups, func(i, j int) bool {
+			return priorities[groups[i].Key] > priorities[groups[j].Key]
+		})
+
+	case ViewGroupByDueDate:
+		for _, task := range tasks {
+			if task.DueDate == nil {
+				add("", "", task)
+			} else {
+				day := task.DueDate.UTC().Format("2006-01-02")
+				add(day, day, task)
+			}
+		}
+		sortGroups(groups, func(a, b *store.TaskGroup) bool {
+			return a.Key < b.Key
+		})
+
+	default:
+		return
+	}
+
+	board.Groups = lo.Ternary(groups != nil, groups, []*store.TaskGroup{})
+}
+
+// Sorts the groups keeping the one with the empty key last.
+func sortGroups(groups []*store.TaskGroup, less func(a, b *store.TaskGroup) bool) {
+	sort.SliceStable(groups, func(i, j int) bool {
+		if groups[i].Key == "" || groups[j].Key == "" {
+			return groups[j].Key == "" && groups[i].Key != ""
+		}
+		return less(groups[i], groups[j])
+	})
+}
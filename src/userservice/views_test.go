@@ -0,0 +1,75 @@
+package userservice
+
+import (
+	"testing"
+	"time"
+
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lesnoi-kot/karten-backend/src/store"
+)
+
+func TestFillTaskGroups(t *testing.T) {
+	bug := &store.Label{ID: 10, Name: "Bug"}
+	chore := &store.Label{ID: 9, Name: "Chore"}
+	due := func(day int, hour int) *time.Time {
+		return lo.ToPtr(time.Date(2026, 10, day, hour, 0, 0, 0, time.UTC))
+	}
+
+	lists := [][]*store.Task{
+		{
+			{ID: "a", Labels: []*store.Label{bug, chore}, Priority: store.TaskPriorityLow, DueDate: due(20, 9)},
+			{ID: "b", Priority: store.TaskPriorityUrgent},
+		},
+		{
+			{ID: "c", Labels: []*store.Label{bug}, Priority: store.TaskPriorityNone, DueDate: due(19, 23)},
+			{ID: "d", Priority: store.TaskPriorityUrgent, DueDate: due(20, 18)},
+		},
+	}
+
+	tests := []struct {
+		groupBy string
+		groups  []*store.TaskGroup
+	}{
+		{ViewGroupByLabel, []*store.TaskGroup{
+			{Key: "9", Name: "Chore", TaskIDs: []store.EntityID{"a"}},
+			{Key: "10", Name: "Bug", TaskIDs: []store.EntityID{"a", "c"}},
+			{Key: "", Name: "", TaskIDs: []store.EntityID{"b", "d"}},
+		}},
+		{ViewGroupByPriority, []*store.TaskGroup{
+			{Key: "4", Name: "urgent", TaskIDs: []store.EntityID{"b", "d"}},
+			{Key: "1", Name: "low", TaskIDs: []store.EntityID{"a"}},
+			{Key: "0", Name: "none", TaskIDs: []store.EntityID{"c"}},
+		}},
+		{ViewGroupByDueDate, []*store.TaskGroup{
+			{Key: "2026-10-19", Name: "2026-10-19", TaskIDs: []store.EntityID{"c"}},
+			{Key: "2026-10-20", Name: "2026-10-20", TaskIDs: []store.EntityID{"a", "d"}},
+			{Key: "", Name: "", TaskIDs: []store.EntityID{"b"}},
+		}},
+		{ViewGroupByList, nil},
+	}
+
+	for _, test := range tests {
+		board := &store.Board{View: &store.View{Filter: store.ViewFilter{GroupBy: test.groupBy}}}
+		for _, tasks := range lists {
+			board.TaskLists = append(board.TaskLists, &store.TaskList{Tasks: tasks})
+		}
+
+		fillTaskGroups(board)
+		assert.Equal(t, test.groups, board.Groups, test.groupBy)
+	}
+
+	board := &store.Board{View: &store.View{Filter: store.ViewFilter{GroupBy: ViewGroupByLabel}}}
+	fillTaskGroups(board)
+	assert.Equal(t, []*store.TaskGroup{}, board.Groups, "no tasks, no groups")
+}
+
+func TestSortGroups(t *testing.T) {
+	groups := []*store.TaskGroup{{Key: "b"}, {Key: ""}, {Key: "c"}, {Key: "a"}}
+	sortGroups(groups, func(a, b *store.TaskGroup) bool { return a.Key < b.Key })
+
+	assert.Equal(t, []string{"a", "b", "c", ""}, lo.Map(groups, func(group *store.TaskGroup, _ int) string {
+		return group.Key
+	}))
+}
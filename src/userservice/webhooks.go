@@ -0,0 +1,411 @@
+package userservice
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/samber/lo"
+	"github.com/uptrace/bun"
+
+	"github.com/lesnoi-kot/karten-backend/src/modules/webhook"
+	"github.com/lesnoi-kot/karten-backend/src/store"
+	"github.com/lesnoi-kot/karten-backend/src/urlprovider"
+)
+
+var ErrInvalidWebhook = errors.New("Invalid webhook")
+
+const (
+	// Deliveries sent in one run of the job.
+	webhookDeliveryBatch = 20
+
+	// A claimed delivery is not picked again for this long, so a crashed
+	// sender doesn't lose it.
+	webhookDeliveryLease = 5 * time.Minute
+
+	// Finished deliveries are kept in the log for this long.
+	webhookDeliveryRetention = 30 * 24 * time.Hour
+)
+
+type AddWebhookOptions struct {
+	ProjectID store.EntityID
+	URL       string
+	Secret    string // Generated if empty
+	Events    []string
+	Enabled   bool
+}
+
+type EditWebhookOptions struct {
+	WebhookID store.EntityID
+	URL       *string
+	Secret    *string
+	Events    *[]string
+	Enabled   *bool
+}
+
+type GetWebhookDeliveriesOptions struct {
+	WebhookID store.EntityID
+	Limit     int
+}
+
+type RedeliverWebhookOptions struct {
+	WebhookID  store.EntityID
+	DeliveryID store.EntityID
+}
+
+// Body of the webhook calls.
+type webhookPayload struct {
+	Event          string          `json:"event"`
+	DateCreated    time.Time       `json:"date_created"`
+	Task           *webhookTask    `json:"task"`
+	Comment        *webhookComment `json:"comment,omitempty"`
+	FromTaskListID string          `json:"from_task_list_id,omitempty"` // task.moved
+}
+
+type webhookTask struct {
+	ID          store.EntityID `bun:"id" json:"id"`
+	Key         string         `bun:"-" json:"key,omitempty"`
+	KeyPrefix   string         `bun:"key_prefix" json:"-"`
+	Number      int64          `bun:"number" json:"-"`
+	Name        string         `bun:"name" json:"name"`
+	Text        string         `bun:"text" json:"text"`
+	ProjectID   store.EntityID `bun:"project_id" json:"project_id"`
+	BoardID     store.EntityID `bun:"board_id" json:"board_id"`
+	TaskListID  store.EntityID `bun:"task_list_id" json:"task_list_id"`
+	ListName    string         `bun:"list_name" json:"list_name"`
+	Position    int64          `bun:"position" json:"position"`
+	Archived    bool           `bun:"archived" json:"archived"`
+	DueDate     *time.Time     `bun:"due_date" json:"due_date"`
+	DateCreated time.Time      `bun:"date_created" json:"date_created"`
+	URL         string         `bun:"-" json:"url"`
+}
+
+type webhookComment struct {
+	ID          store.EntityID `json:"id"`
+	Text        string         `json:"text"`
+	DateCreated time.Time      `json:"date_created"`
+}
+
+// Delivery claimed by the job along with its webhook.
+type claimedWebhookDelivery struct {
+	ID       store.EntityID  `bun:"id"`
+	Event    string          `bun:"event"`
+	Payload  json.RawMessage `bun:"payload"`
+	Attempts int             `bun:"attempts"`
+	URL      string          `bun:"url"`
+	Secret   string          `bun:"secret"`
+}
+
+func (user UserService) GetWebhooks(projectID store.EntityID) ([]*store.Webhook, error) {
+	if owns, err := user.OwnsProject(projectID); err != nil {
+		return nil, err
+	} else if !owns {
+		return nil, store.ErrNotFound
+	}
+
+	webhooks := make([]*store.Webhook, 0)
+	err := user.Store.ORM.NewSelect().
+		Model(&webhooks).
+		Where("project_id = ?", projectID).
+		Order("date_created").
+		Scan(user.Context)
+	if err != nil {
+		return nil, err
+	}
+
+	return webhooks, nil
+}
+
+func (user UserService) GetWebhook(webhookID store.EntityID) (*store.Webhook, error) {
+	hook := new(store.Webhook)
+	err := user.Store.ORM.NewSelect().
+		Model(hook).
+		Where("id = ?", webhookID).
+		Where("user_id = ?", user.UserID).
+		Scan(user.Context)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, store.ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	return hook, nil
+}
+
+func (user UserService) AddWebhook(args *AddWebhookOptions) (*store.Webhook, error) {
+	if owns, err := user.OwnsProject(args.ProjectID); err != nil {
+		return nil, err
+	} else if !owns {
+		return nil, store.ErrNotFound
+	}
+
+	hook := &store.Webhook{
+		ProjectID: args.ProjectID,
+		UserID:    user.UserID,
+		URL:       args.URL,
+		Secret:    args.Secret,
+		Events:    lo.Uniq(args.Events),
+		Enabled:   args.Enabled,
+	}
+	if err := validateWebhook(hook); err != nil {
+		return nil, err
+	}
+
+	if hook.Secret == "" {
+		secret := make([]byte, 24)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, err
+		}
+		hook.Secret = base64.RawURLEncoding.EncodeToString(secret)
+	}
+
+	_, err := user.Store.ORM.NewInsert().
+		Model(hook).
+		Column("project_id", "user_id", "url", "secret", "events", "enabled").
+		Returning("*").
+		Exec(user.Context)
+	if err != nil {
+		return nil, err
+	}
+
+	return hook, nil
+}
+
+func (user UserService) EditWebhook(args *EditWebhookOptions) error {
+	hook, err := user.GetWebhook(args.WebhookID)
+	if err != nil {
+		return err
+	}
+
+	if args.URL != nil {
+		hook.URL = *args.URL
+	}
+	if args.Secret != nil && *args.Secret != "" {
+		hook.Secret = *args.Secret
+	}
+	if args.Events != nil {
+		hook.Events = lo.Uniq(*args.Events)
+	}
+	if args.Enabled != nil {
+		hook.Enabled = *args.Enabled
+	}
+	if err := validateWebhook(hook); err != nil {
+		return err
+	}
+
+	_, err = user.Store.ORM.NewUpdate().
+		Model(hook).
+		Column("url", "secret", "events", "enabled").
+		WherePK().
+		Exec(user.Context)
+	return err
+}
+
+func (user UserService) DeleteWebhook(webhookID store.EntityID) error {
+	result, err := user.Store.ORM.NewDelete().
+		Model((*store.Webhook)(nil)).
+		Where("id = ?", webhookID).
+		Where("user_id = ?", user.UserID).
+		Exec(user.Context)
+	if err != nil {
+		return err
+	} else if store.NoRowsAffected(result) {
+		return store.ErrNotFound
+	}
+
+	return nil
+}
+
+// The delivery log of the webhook, latest first.
+func (user UserService) GetWebhookDeliveries(args *GetWebhookDeliveriesOptions) ([]*store.WebhookDelivery, error) {
+	if _, err := user.GetWebhook(args.WebhookID); err != nil {
+		return nil, err
+	}
+
+	deliveries := make([]*store.WebhookDelivery, 0)
+	err := user.Store.ORM.NewSelect().
+		Model(&deliveries).
+		Where("webhook_id = ?", args.WebhookID).
+		Order("date_created DESC").
+		Limit(args.Limit).
+		Scan(user.Context)
+	if err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+// Queues the payload of the delivery again as a new delivery, whatever the
+// outcome of the original one was.
+func (user UserService) RedeliverWebhook(args *RedeliverWebhookOptions) (*store.WebhookDelivery, error) {
+	original := new(store.WebhookDelivery)
+	err := user.Store.ORM.NewSelect().
+		Model(original).
+		Join("JOIN webhooks AS w ON w.id = webhook_delivery.webhook_id").
+		Where("webhook_delivery.id = ?", args.DeliveryID).
+		Where("webhook_delivery.webhook_id = ?", args.WebhookID).
+		Where("w.user_id = ?", user.UserID).
+		Scan(user.Context)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, store.ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	delivery := &store.WebhookDelivery{
+		WebhookID: original.WebhookID,
+		Event:     original.Event,
+		Payload:   original.Payload,
+	}
+	_, err = user.Store.ORM.NewInsert().
+		Model(delivery).
+		Column("webhook_id", "event", "payload").
+		Returning("*").
+		Exec(user.Context)
+	if err != nil {
+		return nil, err
+	}
+
+	return delivery, nil
+}
+
+func validateWebhook(hook *store.Webhook) error {
+	if err := webhook.ValidateURL(hook.URL); err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidWebhook, err)
+	}
+
+	if len(hook.Events) == 0 {
+		return ErrInvalidWebhook
+	}
+	for _, event := range hook.Events {
+		if !lo.Contains(store.WebhookEvents, event) {
+			return ErrInvalidWebhook
+		}
+	}
+
+	return nil
+}
+
+func commentAddedPayload(comment *store.Comment) *webhookPayload {
+	return &webhookPayload{
+		Event: store.WebhookEventCommentAdded,
+		Comment: &webhookComment{
+			ID:          comment.ID,
+			Text:        comment.Text,
+			DateCreated: comment.DateCreated,
+		},
+	}
+}
+
+// Queues a delivery of the task event to every enabled webhook of the task
+// project subscribed to it. Called in the transaction of the change, so that
+// the deliveries are queued only if it commits.
+func (user UserService) queueTaskWebhooks(ctx context.Context, db bun.IDB, payload *webhookPayload, taskID store.EntityID) error {
+	task := new(webhookTask)
+	err := db.NewSelect().
+		TableExpr("tasks AS t").
+		ColumnExpr("t.id, p.key_prefix, t.number, t.name, t.text, t.position, t.archived, t.due_date, t.date_created").
+		ColumnExpr("p.id AS project_id, b.id AS board_id, tl.id AS task_list_id, tl.name AS list_name").
+		Join("JOIN task_lists AS tl ON tl.id = t.task_list_id").
+		Join("JOIN boards AS b ON b.id = tl.board_id").
+		Join("JOIN projects AS p ON p.id = b.project_id").
+		Where("t.id = ?", taskID).
+		Scan(ctx, task)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	task.Key = store.FormatTaskKey(task.KeyPrefix, task.Number)
+	task.URL = urlprovider.GetTaskURL(task.ID)
+
+	payload.Task = task
+	payload.DateCreated = time.Now().UTC()
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.ExecContext(ctx, `
+		INSERT INTO webhook_deliveries (webhook_id, event, payload)
+		SELECT id, ?, ? FROM webhooks
+		WHERE project_id = ? AND enabled AND ? = ANY(events)
+	`, payload.Event, string(body), task.ProjectID, payload.Event)
+	return err
+}
+
+// Sends the due webhook deliveries, retrying the failed ones later with an
+// exponential backoff. Returns how many were sent. Several instances can run
+// this at once, each delivery is claimed by one of them.
+func DeliverWebhooks(ctx context.Context, s *store.Store, client *http.Client, now time.Time) (int, error) {
+	claimed := make([]*claimedWebhookDelivery, 0)
+	err := s.ORM.NewRaw(`
+		UPDATE webhook_deliveries AS d
+		SET attempts = d.attempts + 1, next_attempt_at = ?
+		FROM webhooks AS w
+		WHERE w.id = d.webhook_id AND d.id IN (
+			SELECT wd.id FROM webhook_deliveries AS wd
+			JOIN webhooks AS wh ON wh.id = wd.webhook_id
+			WHERE wd.status = ? AND wd.next_attempt_at <= ? AND wh.enabled
+			ORDER BY wd.next_attempt_at
+			LIMIT ?
+			FOR UPDATE OF wd SKIP LOCKED
+		)
+		RETURNING d.id, d.event, d.payload, d.attempts, w.url, w.secret
+	`, now.Add(webhookDeliveryLease), store.WebhookDeliveryPending, now, webhookDeliveryBatch).
+		Scan(ctx, &claimed)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, delivery := range claimed {
+		result := webhook.Deliver(ctx, client, &webhook.Request{
+			URL:        delivery.URL,
+			Secret:     delivery.Secret,
+			Event:      delivery.Event,
+			DeliveryID: delivery.ID,
+			Payload:    delivery.Payload,
+		})
+
+		q := s.ORM.NewUpdate().
+			Model((*store.WebhookDelivery)(nil)).
+			Set("response_code = ?", lo.Ternary(result.StatusCode != 0, &result.StatusCode, nil)).
+			Set("error = ?", result.Error).
+			Set("duration_ms = ?", result.Duration.Milliseconds()).
+			Where("id = ?", delivery.ID)
+
+		finished := time.Now().UTC()
+		if result.OK() {
+			q = q.Set("status = ?", store.WebhookDeliverySucceeded).
+				Set("date_delivered = ?", finished)
+		} else if delivery.Attempts >= webhook.MaxAttempts {
+			q = q.Set("status = ?", store.WebhookDeliveryFailed)
+		} else {
+			q = q.Set("next_attempt_at = ?", finished.Add(webhook.Backoff(delivery.Attempts)))
+		}
+
+		if _, err := q.Exec(ctx); err != nil {
+			return 0, err
+		}
+	}
+
+	_, err = s.ORM.NewDelete().
+		Model((*store.WebhookDelivery)(nil)).
+		Where("status <> ?", store.WebhookDeliveryPending).
+		Where("date_created < ?", now.Add(-webhookDeliveryRetention)).
+		Exec(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	return len(claimed), nil
+}
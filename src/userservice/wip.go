@@ -0,0 +1,85 @@
+package userservice
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/uptrace/bun"
+
+	"github.com/lesnoi-kot/karten-backend/src/store"
+)
+
+var ErrWIPLimitExceeded = errors.New("Task list is at its work in progress limit")
+
+// Fails with ErrWIPLimitExceeded if the list has a hard limit and is already
+// full without the given task. The list row stays locked till the end of the
+// transaction, so concurrent moves into it can't both pass.
+func (user UserService) checkWIPLimit(
+	ctx context.Context,
+	tx bun.Tx,
+	taskListID store.EntityID,
+	taskID store.EntityID,
+) error {
+	taskList := new(store.TaskList)
+	err := tx.NewSelect().
+		Model(taskList).
+		Column("wip_limit", "wip_mode").
+		Where("id = ?", taskListID).
+		Where("user_id = ?", user.UserID).
+		For("UPDATE").
+		Scan(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return store.ErrNotFound
+	} else if err != nil {
+		return err
+	}
+
+	if taskList.WIPLimit == nil || taskList.WIPMode != store.WIPModeHard {
+		return nil
+	}
+
+	q := tx.NewSelect().
+		Model((*store.Task)(nil)).
+		Where("task_list_id = ?", taskListID).
+		Where("archived = ?", false)
+	if taskID != "" {
+		q = q.Where("id <> ?", taskID)
+	}
+
+	count, err := q.Count(ctx)
+	if err != nil {
+		return err
+	}
+	if count >= *taskList.WIPLimit {
+		return ErrWIPLimitExceeded
+	}
+
+	return nil
+}
+
+// Tells if the list has a soft limit and holds more tasks than it allows.
+func (user UserService) IsOverSoftWIPLimit(taskListID store.EntityID) (bool, error) {
+	taskList := new(store.TaskList)
+	err := user.Store.ORM.NewSelect().
+		Model(taskList).
+		Column("id", "wip_limit", "wip_mode").
+		Where("id = ?", taskListID).
+		Where("user_id = ?", user.UserID).
+		Scan(user.Context)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, store.ErrNotFound
+	} else if err != nil {
+		return false, err
+	}
+
+	if taskList.WIPLimit == nil || taskList.WIPMode != store.WIPModeSoft {
+		return false, nil
+	}
+
+	if err := user.fillTaskListRollups(taskList); err != nil {
+		return false, err
+	}
+
+	return taskList.TaskCount > *taskList.WIPLimit, nil
+}
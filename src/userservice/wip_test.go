@@ -0,0 +1,76 @@
+package userservice
+
+import (
+	"github.com/samber/lo"
+
+	"github.com/lesnoi-kot/karten-backend/src/store"
+)
+
+func (s *userServiceSuite) setWIPLimit(taskListID store.EntityID, limit int, mode string) {
+	s.Require().NoError(s.user.EditTaskList(&EditTaskListOptions{
+		TaskListID: taskListID,
+		WIPLimit:   &limit,
+		WIPMode:    &mode,
+	}))
+}
+
+func (s *userServiceSuite) TestHardWIPLimit() {
+	_, lists := s.addBoard("Todo", "Doing")
+	s.setWIPLimit(lists[1].ID, 1, store.WIPModeHard)
+
+	doing := s.addTask(lists[1].ID, "Doing")
+	_, err := s.user.AddTask(&AddTaskOptions{TaskListID: lists[1].ID, Name: "Too many"})
+	s.ErrorIs(err, ErrWIPLimitExceeded)
+
+	// Editing a task within the full list is fine.
+	s.NoError(s.user.EditTask(&EditTaskOptions{TaskID: doing.ID, Name: lo.ToPtr("Still doing")}))
+
+	todo := s.addTask(lists[0].ID, "Todo")
+	err = s.user.EditTask(&EditTaskOptions{TaskID: todo.ID, TaskListID: &lists[1].ID})
+	s.ErrorIs(err, ErrWIPLimitExceeded)
+	s.Equal(lists[0].ID, s.getTask(todo.ID).TaskListID)
+
+	// Archived tasks don't count, but coming back they do.
+	s.Require().NoError(s.user.ArchiveTask(doing.ID))
+	s.Require().NoError(s.user.EditTask(&EditTaskOptions{TaskID: todo.ID, TaskListID: &lists[1].ID}))
+	err = s.user.UnarchiveTask(&UnarchiveTaskOptions{TaskID: doing.ID})
+	s.ErrorIs(err, ErrWIPLimitExceeded)
+	s.True(s.getTask(doing.ID).Archived)
+
+	// So do the tasks restored from the trash.
+	s.Require().NoError(s.user.DeleteTask(&DeleteTaskOptions{TaskID: todo.ID}))
+	s.addTask(lists[1].ID, "Replacement")
+	err = s.user.RestoreFromTrash(&RestoreFromTrashOptions{Type: TrashedTask, ID: todo.ID})
+	s.ErrorIs(err, ErrWIPLimitExceeded)
+
+	// Without the limit everything goes.
+	s.setWIPLimit(lists[1].ID, 0, store.WIPModeHard)
+	s.NoError(s.user.RestoreFromTrash(&RestoreFromTrashOptions{Type: TrashedTask, ID: todo.ID}))
+	s.NoError(s.user.UnarchiveTask(&UnarchiveTaskOptions{TaskID: doing.ID}))
+}
+
+func (s *userServiceSuite) TestSoftWIPLimit() {
+	_, lists := s.addBoard("Todo", "Doing")
+	s.setWIPLimit(lists[1].ID, 1, store.WIPModeSoft)
+
+	s.addTask(lists[1].ID, "First")
+	over, err := s.user.IsOverSoftWIPLimit(lists[1].ID)
+	s.Require().NoError(err)
+	s.False(over, "at the limit is not over it")
+
+	// A soft limit only warns.
+	todo := s.addTask(lists[0].ID, "Todo")
+	s.Require().NoError(s.user.EditTask(&EditTaskOptions{TaskID: todo.ID, TaskListID: &lists[1].ID}))
+	over, err = s.user.IsOverSoftWIPLimit(lists[1].ID)
+	s.Require().NoError(err)
+	s.True(over)
+
+	s.setWIPLimit(lists[1].ID, 1, store.WIPModeHard)
+	over, err = s.user.IsOverSoftWIPLimit(lists[1].ID)
+	s.Require().NoError(err)
+	s.False(over, "hard limits are not soft ones")
+
+	over, err = s.user.IsOverSoftWIPLimit(lists[0].ID)
+	s.Require().NoError(err)
+	s.False(over, "no limit")
+}